@@ -0,0 +1,306 @@
+// Copyright Contributors to the Open Cluster Management project.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/stolostron/cluster-curator-controller/pkg/controller/launcher"
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/status"
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/utils"
+	"k8s.io/klog/v2"
+)
+
+// Exit codes of the curator command
+const (
+	exitOK = 0
+	// The step failed for a reason that was not classified
+	exitFailed = 1
+	// The command line is invalid
+	exitUsage = 2
+	// The ClusterCurator, the provider credential or the kubeconfig is invalid
+	exitInvalidSpec = 3
+	// A resource is missing or can not be accessed
+	exitNotFound = 4
+	// The step did not complete in time
+	exitTimeout = 5
+	// The provisioning, upgrade, destroy, import or AnsibleJob failed
+	exitStepFailed = 6
+	// The step was canceled with SIGTERM or SIGINT
+	exitCanceled = 7
+	// A transient API error, the curator job runs the step again in a new pod
+	exitRetry = launcher.RetryExitCode
+)
+
+var reasonExitCodes = map[utils.Reason]int{
+	utils.ReasonInvalidSpec:       exitInvalidSpec,
+	utils.ReasonInvalidCredential: exitInvalidSpec,
+	utils.ReasonNotFound:          exitNotFound,
+	utils.ReasonForbidden:         exitNotFound,
+	utils.ReasonTimeout:           exitTimeout,
+	utils.ReasonProvisionFailed:   exitStepFailed,
+	utils.ReasonDestroyFailed:     exitStepFailed,
+	utils.ReasonUpgradeFailed:     exitStepFailed,
+	utils.ReasonImportFailed:      exitStepFailed,
+	utils.ReasonAnsibleJobFailed:  exitStepFailed,
+	utils.ReasonCanceled:          exitCanceled,
+}
+
+// Service account namespace, used as the cluster namespace inside the curator job
+var serviceAccountNamespacePath = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+type step struct {
+	name        string
+	description string
+}
+
+// The steps the curator job runs as init containers, in the order of the help
+var steps = []step{
+	{"applycloudprovider", "Creates the provider and Ansible Tower secrets, the provider is detected from the credential and the cluster"},
+	{"applycloudprovider-aws", "Creates the AWS and Ansible Tower secrets from the provider credential"},
+	{"applycloudprovider-gcp", "Creates the GCP and Ansible Tower secrets from the provider credential"},
+	{"applycloudprovider-azure", "Creates the Azure and Ansible Tower secrets from the provider credential"},
+	{"applycloudprovider-vsphere", "Creates the vSphere, vCenter CA and Ansible Tower secrets from the provider credential"},
+	{"applycloudprovider-vmware", "Same as applycloudprovider-vsphere"},
+	{"applycloudprovider-openstack", "Creates the OpenStack clouds.yaml and Ansible Tower secrets from the provider credential"},
+	{"applycloudprovider-ibmcloud", "Creates the IBM Cloud and Ansible Tower secrets from the provider credential"},
+	{"applycloudprovider-kubevirt", "Creates the KubeVirt infrastructure kubeconfig and Ansible Tower secrets from the provider credential"},
+	{"applycloudprovider-baremetal", "Creates the libvirt SSH key, BMC and Ansible Tower secrets from the provider credential"},
+	{"applycloudprovider-ansible", "Creates the Ansible Tower secret from the provider credential"},
+	{"prehook-ansiblejob", "Runs the prehook AnsibleJobs of the desired curation"},
+	{"posthook-ansiblejob", "Runs the posthook AnsibleJobs of the desired curation"},
+	{"activate-and-monitor", "Starts the provisioning of the cluster and monitors it to completion"},
+	{"monitor", "Monitors the provisioning of the cluster"},
+	{"monitor-import", "Monitors the import of the ManagedCluster"},
+	{"upgrade-cluster", "Starts the upgrade of the cluster to the desired update"},
+	{"monitor-upgrade", "Monitors the upgrade of the cluster"},
+	{"intermediate-upgrade-cluster", "Starts the upgrade of the cluster to the intermediate EUS update"},
+	{"intermediate-monitor-upgrade", "Monitors the upgrade of the cluster to the intermediate EUS update"},
+	{"final-upgrade-cluster", "Starts the upgrade of the cluster from the intermediate to the desired EUS update"},
+	{"destroy-cluster", "Starts the destroy of the cluster"},
+	{"monitor-destroy", "Monitors the destroy of the cluster"},
+	{"detach-nowait", "Detaches the ManagedCluster without waiting"},
+	{"delete-cluster-namespace", "Requests the deletion of the cluster namespace"},
+	{"done", "Records the completed curation on the ClusterCurator"},
+}
+
+// Read-only commands, they render the curation progress and do not change the ClusterCurator
+var commands = []step{
+	{"status", "Shows the steps of the current curation, their status and elapsed time"},
+	{"describe", "Same as status"},
+	{credentialsCommand, "Lists the source credentials of the generated secrets and the clusters using them"},
+}
+
+// Command that is not about one cluster
+const credentialsCommand = "credentials"
+
+// Step used by the unit tests, it only records the ClusterCurator conditions
+const skipAllTesting = "SKIP_ALL_TESTING"
+
+type options struct {
+	step             string
+	clusterName      string
+	clusterNamespace string
+	kubeconfig       string
+	context          string
+	output           string
+}
+
+// An invalid command line
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+func newUsageError(format string, args ...interface{}) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+func isCommand(name string) bool {
+	for _, c := range commands {
+		if c.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func isStep(name string) bool {
+	if name == skipAllTesting {
+		return true
+	}
+	for _, s := range steps {
+		if s.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Maps the error returned by a step to the exit code of the curator
+func exitCode(err error) int {
+	var usageErr *usageError
+	if err == nil {
+		return exitOK
+	}
+	if errors.As(err, &usageErr) {
+		return exitUsage
+	}
+	if utils.IsTransientError(err) {
+		return exitRetry
+	}
+	if code, ok := reasonExitCodes[utils.ReasonForError(err)]; ok {
+		return code
+	}
+	return exitFailed
+}
+
+func printUsage(out io.Writer, fs *flag.FlagSet) {
+	fmt.Fprintf(out, "Usage: curator STEP|COMMAND [CLUSTER_NAME] [flags]\n\nSteps:\n")
+	for _, s := range steps {
+		fmt.Fprintf(out, "  %-30s%s\n", s.name, s.description)
+	}
+	fmt.Fprintf(out, "\nCommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(out, "  %-30s%s\n", c.name, c.description)
+	}
+	fmt.Fprintf(out, "\nFlags:\n")
+	fs.SetOutput(out)
+	fs.PrintDefaults()
+	fmt.Fprintf(out, "\nExit codes:\n"+
+		"  %d  the step completed\n"+
+		"  %d  the step failed\n"+
+		"  %d  invalid command line\n"+
+		"  %d  invalid ClusterCurator, provider credential or kubeconfig\n"+
+		"  %d  a resource is missing or forbidden\n"+
+		"  %d  the step timed out\n"+
+		"  %d  the provisioning, upgrade, destroy, import or AnsibleJob failed\n"+
+		"  %d  the step was canceled\n"+
+		"  %d  a transient API error, the step is retried\n",
+		exitOK, exitFailed, exitUsage, exitInvalidSpec, exitNotFound, exitTimeout, exitStepFailed, exitCanceled,
+		exitRetry)
+}
+
+func printStepUsage(out io.Writer, fs *flag.FlagSet, s step) {
+	fmt.Fprintf(out, "Usage: curator %s [CLUSTER_NAME] [flags]\n\n%s\n\nFlags:\n", s.name, s.description)
+	fs.SetOutput(out)
+	fs.PrintDefaults()
+}
+
+// Prints the help of the step named in args, or the usage when there is none
+func printHelp(out io.Writer, fs *flag.FlagSet, args []string) error {
+	if len(args) == 0 {
+		printUsage(out, fs)
+		return flag.ErrHelp
+	}
+	for _, s := range append(append([]step{}, steps...), commands...) {
+		if s.name == args[0] {
+			printStepUsage(out, fs, s)
+			return flag.ErrHelp
+		}
+	}
+	return newUsageError("invalid step: \"%s\", run \"curator help\" for the list of steps", args[0])
+}
+
+/* parseArgs - Parses "curator STEP [CLUSTER_NAME] [flags]". Flags can be placed before or after the
+ * positional arguments. "curator help [STEP]", -h and --help print the help to out and return flag.ErrHelp.
+ * The cluster namespace defaults to CLUSTER_NAME, then to the service account namespace of the curator
+ * job and last to the cluster name, the cluster name defaults to the cluster namespace.
+ */
+func parseArgs(args []string, out io.Writer) (*options, error) {
+	opts := &options{}
+
+	fs := flag.NewFlagSet("curator", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	// Only the klog verbosity is exposed, to keep the help readable
+	klogFlags := flag.NewFlagSet("klog", flag.ContinueOnError)
+	klog.InitFlags(klogFlags)
+	utils.LogWarning(klogFlags.Set("v", strconv.Itoa(utils.LogVerbosity)))
+	fs.Var(klogFlags.Lookup("v").Value, "v", "Log verbosity")
+	fs.StringVar(&opts.kubeconfig, "kubeconfig", "",
+		"Path to the kubeconfig of the hub, the in-cluster config is used when empty and KUBECONFIG is not set")
+	fs.StringVar(&opts.context, "context", "", "Name of the kubeconfig context to use")
+	fs.StringVar(&opts.clusterName, "cluster", "", "Name of the cluster, the ClusterCurator has the same name")
+	fs.StringVar(&opts.clusterNamespace, "namespace", "", "Namespace of the cluster and its ClusterCurator")
+	fs.StringVar(&opts.output, "output", status.OutputTable, "Output format of status and describe: table, json or yaml")
+	fs.StringVar(&opts.output, "o", status.OutputTable, "Shorthand for --output")
+
+	// Flags can follow the positional arguments
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				if len(positional) > 0 && positional[0] == "help" {
+					positional = positional[1:]
+				}
+				return nil, printHelp(out, fs, positional)
+			}
+			return nil, newUsageError("%s, run \"curator help\" for the usage", err.Error())
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+
+	if len(positional) == 0 {
+		return nil, newUsageError("missing step, run \"curator help\" for the list of steps")
+	}
+
+	if positional[0] == "help" {
+		return nil, printHelp(out, fs, positional[1:])
+	}
+
+	opts.step = positional[0]
+	if !isStep(opts.step) && !isCommand(opts.step) {
+		return nil, newUsageError("invalid step: \"%s\", run \"curator help\" for the list of steps", opts.step)
+	}
+
+	switch opts.output {
+	case status.OutputTable, status.OutputJSON, status.OutputYAML:
+	default:
+		return nil, newUsageError("unsupported output format: \"%s\", use table, json or yaml", opts.output)
+	}
+
+	switch {
+	case len(positional) > 2:
+		return nil, newUsageError("unexpected arguments: %s", strings.Join(positional[2:], " "))
+	case len(positional) == 2 && opts.clusterName != "" && opts.clusterName != positional[1]:
+		return nil, newUsageError("the cluster name \"%s\" conflicts with --cluster %s", positional[1], opts.clusterName)
+	case len(positional) == 2:
+		// Hypershift clusters name != namespace, the curator job passes the name as an argument
+		opts.clusterName = positional[1]
+	}
+
+	if opts.clusterNamespace == "" {
+		opts.clusterNamespace = os.Getenv("CLUSTER_NAME")
+	}
+	if opts.clusterNamespace == "" {
+		if data, err := os.ReadFile(serviceAccountNamespacePath); err == nil {
+			opts.clusterNamespace = strings.TrimSpace(string(data))
+		}
+	}
+	if opts.clusterNamespace == "" {
+		opts.clusterNamespace = opts.clusterName
+	}
+	if opts.clusterName == "" {
+		opts.clusterName = opts.clusterNamespace
+	}
+	if opts.clusterName == "" && opts.step != credentialsCommand {
+		return nil, newUsageError("missing the cluster, use --cluster or set CLUSTER_NAME")
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,156 @@
+// Copyright Contributors to the Open Cluster Management project.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/utils"
+	"github.com/stretchr/testify/assert"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+func setServiceAccountNamespace(t *testing.T, namespace string) {
+	path := filepath.Join(t.TempDir(), "namespace")
+	if namespace != "" {
+		assert.Nil(t, os.WriteFile(path, []byte(namespace), 0600))
+	}
+	previous := serviceAccountNamespacePath
+	serviceAccountNamespacePath = path
+	t.Cleanup(func() { serviceAccountNamespacePath = previous })
+}
+
+func TestParseArgsCuratorJob(t *testing.T) {
+	t.Setenv("CLUSTER_NAME", "")
+	setServiceAccountNamespace(t, ClusterNamespace)
+
+	opts, err := parseArgs([]string{"monitor", ClusterName}, &bytes.Buffer{})
+	assert.Nil(t, err)
+	assert.Equal(t, "monitor", opts.step)
+	assert.Equal(t, ClusterName, opts.clusterName)
+	assert.Equal(t, ClusterNamespace, opts.clusterNamespace, "the service account namespace is the cluster namespace")
+
+	t.Setenv("CLUSTER_NAME", "env-namespace")
+	opts, err = parseArgs([]string{"done"}, &bytes.Buffer{})
+	assert.Nil(t, err)
+	assert.Equal(t, "env-namespace", opts.clusterNamespace, "CLUSTER_NAME takes precedence over the service account")
+	assert.Equal(t, "env-namespace", opts.clusterName, "the cluster name defaults to the namespace")
+}
+
+func TestParseArgsWorkstation(t *testing.T) {
+	t.Setenv("CLUSTER_NAME", "")
+	setServiceAccountNamespace(t, "")
+
+	opts, err := parseArgs([]string{"monitor-upgrade", "--cluster", "foo"}, &bytes.Buffer{})
+	assert.Nil(t, err)
+	assert.Equal(t, "foo", opts.clusterName)
+	assert.Equal(t, "foo", opts.clusterNamespace, "the namespace defaults to the cluster name")
+
+	opts, err = parseArgs([]string{
+		"--kubeconfig", "/tmp/hub.kubeconfig", "monitor-upgrade", "foo",
+		"--namespace", "clusters", "--context", "hub", "-v", "4"}, &bytes.Buffer{})
+	assert.Nil(t, err)
+	assert.Equal(t, "monitor-upgrade", opts.step)
+	assert.Equal(t, "foo", opts.clusterName)
+	assert.Equal(t, "clusters", opts.clusterNamespace)
+	assert.Equal(t, "/tmp/hub.kubeconfig", opts.kubeconfig)
+	assert.Equal(t, "hub", opts.context)
+}
+
+func TestParseArgsUsageErrors(t *testing.T) {
+	t.Setenv("CLUSTER_NAME", "")
+	setServiceAccountNamespace(t, "")
+
+	for _, args := range [][]string{
+		{},
+		{"something-wrong", "--cluster", "foo"},
+		{"monitor"},
+		{"monitor", "foo", "bar"},
+		{"monitor", "foo", "--cluster", "bar"},
+		{"monitor", "--unknown-flag"},
+		{"help", "something-wrong"},
+	} {
+		_, err := parseArgs(args, &bytes.Buffer{})
+		assert.NotNil(t, err, "%v", args)
+		assert.Equal(t, exitUsage, exitCode(err), "%v", args)
+	}
+}
+
+func TestParseArgsHelp(t *testing.T) {
+	out := &bytes.Buffer{}
+	_, err := parseArgs([]string{"help"}, out)
+	assert.True(t, errors.Is(err, flag.ErrHelp))
+	assert.Contains(t, out.String(), "monitor-upgrade")
+	assert.Contains(t, out.String(), "-kubeconfig")
+	assert.Contains(t, out.String(), "Exit codes:")
+
+	out.Reset()
+	_, err = parseArgs([]string{"monitor-upgrade", "--help"}, out)
+	assert.True(t, errors.Is(err, flag.ErrHelp))
+	assert.Contains(t, out.String(), "Usage: curator monitor-upgrade")
+	assert.Contains(t, out.String(), "Monitors the upgrade of the cluster")
+
+	out.Reset()
+	_, err = parseArgs([]string{"help", "destroy-cluster"}, out)
+	assert.True(t, errors.Is(err, flag.ErrHelp))
+	assert.Contains(t, out.String(), "Usage: curator destroy-cluster")
+}
+
+func TestExitCode(t *testing.T) {
+	assert.Equal(t, exitOK, exitCode(nil))
+	assert.Equal(t, exitFailed, exitCode(errors.New("failed")))
+	assert.Equal(t, exitUsage, exitCode(newUsageError("bad")))
+	assert.Equal(t, exitInvalidSpec, exitCode(utils.NewError(utils.ReasonInvalidCredential, "bad secret")))
+	assert.Equal(t, exitTimeout, exitCode(utils.ErrWaitTimeout))
+	assert.Equal(t, exitStepFailed, exitCode(utils.NewError(utils.ReasonAnsibleJobFailed, "failed")))
+	assert.Equal(t, exitCanceled, exitCode(context.Canceled))
+	assert.Equal(t, exitRetry, exitCode(fmt.Errorf("monitor: %w", k8serrors.NewServiceUnavailable("unavailable"))))
+}
+
+func TestRunUsage(t *testing.T) {
+	assert.Equal(t, exitOK, run([]string{"help"}))
+	assert.Equal(t, exitUsage, run([]string{"something-wrong"}))
+}
+
+func TestParseArgsStatus(t *testing.T) {
+	t.Setenv("CLUSTER_NAME", "")
+	setServiceAccountNamespace(t, "")
+
+	opts, err := parseArgs([]string{"status", "foo", "-o", "yaml"}, &bytes.Buffer{})
+	assert.Nil(t, err)
+	assert.Equal(t, "status", opts.step)
+	assert.Equal(t, "foo", opts.clusterName)
+	assert.Equal(t, "yaml", opts.output)
+	assert.True(t, isCommand(opts.step))
+	assert.False(t, isStep(opts.step), "status is not a curator job step")
+
+	opts, err = parseArgs([]string{"describe", "--cluster", "foo"}, &bytes.Buffer{})
+	assert.Nil(t, err)
+	assert.Equal(t, "table", opts.output, "a table by default")
+
+	_, err = parseArgs([]string{"status", "foo", "--output", "xml"}, &bytes.Buffer{})
+	assert.Equal(t, exitUsage, exitCode(err))
+
+	assert.Equal(t, exitUsage, exitCode(curatorRun(context.TODO(), nil, nil, "status", "foo", "foo")),
+		"status can not run as a step")
+}
+
+func TestParseArgsCredentials(t *testing.T) {
+	t.Setenv("CLUSTER_NAME", "")
+	setServiceAccountNamespace(t, "")
+
+	opts, err := parseArgs([]string{"credentials", "-o", "json"}, &bytes.Buffer{})
+	assert.Nil(t, err, "the credentials command is not about one cluster")
+	assert.Equal(t, credentialsCommand, opts.step)
+	assert.Equal(t, "json", opts.output)
+
+	_, err = parseArgs([]string{"status"}, &bytes.Buffer{})
+	assert.Equal(t, exitUsage, exitCode(err), "status needs the cluster")
+}
@@ -5,10 +5,13 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
 	"fmt"
-	"io/ioutil"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	"k8s.io/klog/v2"
 
@@ -19,7 +22,9 @@ import (
 	"github.com/stolostron/cluster-curator-controller/pkg/jobs/hypershift"
 	"github.com/stolostron/cluster-curator-controller/pkg/jobs/importer"
 	"github.com/stolostron/cluster-curator-controller/pkg/jobs/secrets"
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/status"
 	"github.com/stolostron/cluster-curator-controller/pkg/jobs/utils"
+	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/rest"
@@ -29,64 +34,102 @@ import (
 const CuratorJob = "clustercurator-job"
 
 /* Uses the following environment variables:
- * ./curator applycloudprovider
+ * ./curator applycloudprovider[-PROVIDER]
  *    export CLUSTER_NAME=                  # The name of the cluster
  *    export PROVIDER_CREDENTIAL_PATH=      # The NAMESPACE/SECRET_NAME for the Cloud Provider
  */
 func main() {
-	var clusterName = os.Getenv("CLUSTER_NAME")
+	os.Exit(run(os.Args[1:]))
+}
+
+// Runs the curator command line and returns the process exit code
+func run(args []string) int {
+	defer klog.Flush()
+
+	opts, err := parseArgs(args, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return exitUsage
+	}
+
+	// Build a connection to the Hub OCP
+	utils.Kubeconfig = opts.kubeconfig
+	utils.KubeContext = opts.context
+
+	config, err := utils.GetConfig()
+	if err != nil {
+		klog.Errorf("Unable to load the hub connection: %v", err)
+		return exitCode(err)
+	}
 
-	utils.InitKlog(utils.LogVerbosity)
+	client, err := utils.GetClient()
+	if err != nil {
+		klog.Errorf("Unable to create the hub client: %v", err)
+		return exitCode(err)
+	}
 
-	if clusterName == "" {
-		data, err := ioutil.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace")
+	if opts.step == credentialsCommand {
+		kubeset, err := utils.GetKubeset()
+		if err == nil {
+			var usage []secrets.CredentialUsage
+			if usage, err = secrets.GetCredentialUsage(context.Background(), kubeset); err == nil {
+				err = status.PrintCredentialUsage(os.Stdout, usage, opts.output)
+			}
+		}
 		if err != nil {
-			klog.Warning("Missing the environment variable CLUSTER_NAME")
+			klog.Errorf("%v (reason: %v)", err.Error(), utils.ReasonForError(err))
 		}
-		utils.CheckError(err)
-		clusterName = string(data)
+		return exitCode(err)
 	}
 
-	clusterNamespace := clusterName
-	// Hypershift clusters name != namespace
-	// The code in main sets clusterName = namespace but this doesn't work for Hypershift
-	if len(os.Args) == 3 {
-		clusterName = os.Args[2]
+	if isCommand(opts.step) {
+		curation, err := status.GetCuration(context.Background(), client, opts.clusterName, opts.clusterNamespace, time.Now())
+		if err == nil {
+			err = status.Print(os.Stdout, curation, opts.output)
+		}
+		if err != nil {
+			klog.Errorf("%v (reason: %v)", err.Error(), utils.ReasonForError(err))
+		}
+		return exitCode(err)
 	}
 
-	// Build a connection to the Hub OCP
-	config, err := rest.InClusterConfig()
-	utils.CheckError(err)
+	// SIGTERM, sent when the curator Job is deleted, cancels the running step
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
 
-	client, err := utils.GetClient()
-	utils.CheckError(err)
+	if err := curatorRun(ctx, config, client, opts.step, opts.clusterName, opts.clusterNamespace); err != nil {
+		klog.Errorf("%v (reason: %v)", err.Error(), utils.ReasonForError(err))
+		return exitCode(err)
+	}
+	return exitOK
+}
 
-	curatorRun(config, client, clusterName, clusterNamespace)
+// Records the failed step, with the machine-readable reason of err, and returns err
+func failStep(client clientv1.Client, clusterName string, clusterNamespace string, jobChoice string, err error) error {
+	utils.LogWarning(utils.RecordFailedCuratorStatusError(
+		client,
+		clusterName,
+		clusterNamespace,
+		jobChoice,
+		err))
+	return err
 }
 
-func curatorRun(config *rest.Config, client clientv1.Client, clusterName string, clusterNamespace string) {
-
-	var err error
-	var cmdErrorMsg = errors.New("Invalid Parameter: \"" + os.Args[1] +
-		"\"\nCommand: ./curator [monitor-import|monitor|activate-and-monitor|applycloudprovider-aws|" +
-		"applycloudprovider-gcp|applycloudprovider-azure|upgrade-cluster|intermediate-upgrade-cluster|" +
-		"final-upgrade-cluster|monitor-upgrade|intermediate-monitor-upgrade|prehook-ansiblejob|posthook-ansiblejob|done]")
-
-	if len(os.Args) > 1 {
-		switch os.Args[1] {
-		case "applycloudprovider-aws", "applycloudprovider-ansible", "monitor-import", "monitor",
-			"applycloudprovider-gcp", "applycloudprovider-azure", "activate-and-monitor", "upgrade-cluster",
-			"intermediate-upgrade-cluster", "final-upgrade-cluster", "monitor-upgrade", "intermediate-monitor-upgrade",
-			"SKIP_ALL_TESTING", "prehook-ansiblejob", "posthook-ansiblejob", "done", "destroy-cluster", "monitor-destroy",
-			"detach-nowait", "delete-cluster-namespace":
-		default:
-			utils.CheckError(cmdErrorMsg)
-		}
-		klog.V(2).Info("Mode: " + os.Args[1] + " Cluster")
-	} else {
-		utils.CheckError(cmdErrorMsg)
+func curatorRun(
+	ctx context.Context,
+	config *rest.Config,
+	client clientv1.Client,
+	jobChoice string,
+	clusterName string,
+	clusterNamespace string) (runErr error) {
+
+	if !isStep(jobChoice) {
+		return newUsageError("invalid step: \"%s\", run \"curator help\" for the list of steps", jobChoice)
 	}
-	jobChoice := os.Args[1]
+	klog.V(2).Info("Mode: " + jobChoice + " Cluster")
 
 	providerCredentialPath := os.Getenv("PROVIDER_CREDENTIAL_PATH")
 
@@ -95,49 +138,71 @@ func curatorRun(config *rest.Config, client clientv1.Client, clusterName string,
 	var desiredCuration string
 	if curator != nil {
 		desiredCuration = curator.Spec.DesiredCuration
-		if curator.Operation != nil && curator.Operation.RetryPosthook != "" {
-			if curator.Operation.RetryPosthook == "installPosthook" {
-				desiredCuration = "install"
-			}
-			if curator.Operation.RetryPosthook == "upgradePosthook" {
-				desiredCuration = "upgrade"
-			}
+		if op := utils.CurrentOperation(curator); op != nil {
+			desiredCuration = op.Curation
 		}
 	}
 
+	// The Job of the curation, its pods are retried
+	var curatorJob *batchv1.Job
+
 	// Allow an override with the PROVIDER_CREDENTIAL_PATH
 	if err == nil {
 		klog.V(2).Info("Found clusterCurator resource \"" + curator.Namespace + "\" ✓")
 
-		utils.CheckError(utils.RecordCurrentStatusCondition(
+		if err := utils.RecordCurrentStatusCondition(
 			client,
 			clusterName,
 			clusterNamespace,
 			CuratorJob,
 			v1.ConditionFalse,
-			curator.Spec.CuratingJob+" DesiredCuration: "+desiredCuration))
+			utils.CuratingJob(curator)+" DesiredCuration: "+desiredCuration); err != nil {
+			return err
+		}
+
+		// A retried curator job skips the steps completed by its earlier pods
+		curatorJob = getCuratorJob(ctx, client, curator)
+		if jobChoice != launcher.DoneDoneDone && curatorJob != nil &&
+			utils.StepCompleted(curator, jobChoice, curatorJob.CreationTimestamp.Time) {
+			klog.V(0).Infof("Step %v was completed by an earlier pod of job %v ✓", jobChoice, curatorJob.Name)
+			return nil
+		}
 
 		// Special case
 		if jobChoice != launcher.DoneDoneDone {
-			utils.CheckError(utils.RecordCurrentStatusCondition(
+			if err := utils.RecordCurrentStatusCondition(
 				client,
 				clusterName,
 				clusterNamespace,
 				jobChoice,
 				v1.ConditionFalse,
-				"Executing init container "+jobChoice))
+				"Executing init container "+jobChoice); err != nil {
+				return err
+			}
 		}
 		providerCredentialPath = curator.Spec.ProviderCredentialPath
 
 		// This makes sure we set the curator-job condition to false when there is a failure
 		defer func() {
-			if r := recover(); r != nil {
-				message := curator.Spec.CuratingJob + " DesiredCuration: " + desiredCuration
+			// The Job runs the step again in a new pod, the curation is not failed
+			if runErr != nil && utils.IsTransientError(runErr) && canRetry(curatorJob) {
+				klog.Warningf("Step %v will be retried by job %v: %v", jobChoice, curatorJob.Name, runErr)
+				utils.LogWarning(utils.RecordCurrentStatusCondition(
+					client,
+					clusterName,
+					clusterNamespace,
+					jobChoice,
+					v1.ConditionFalse,
+					"Retrying init container "+jobChoice+" after: "+runErr.Error()))
+				return
+			}
+			if runErr != nil {
+				message := utils.CuratingJob(curator) + " DesiredCuration: " + desiredCuration
 				if desiredCuration == "upgrade" {
 					message = message + " Version (" + utils.GetCurrentVersionInfo(curator) + ")"
 				}
-				message = message + " Failed - " + fmt.Sprintf("%v", r)
-				utils.CheckError(utils.RecordFailedCuratorStatusCondition(
+				message = message + " Failed - " + runErr.Error()
+				utils.LogWarning(utils.RecordFailedCuratorStatusCondition(
 					client,
 					clusterName,
 					clusterNamespace,
@@ -145,113 +210,127 @@ func curatorRun(config *rest.Config, client clientv1.Client, clusterName string,
 					v1.ConditionTrue,
 					message))
 				// Remove curatingJob and desiredCuration from curator resource for failed job
-				updateFailingClusterCurator(client, curator)
-				panic(r)
+				utils.LogWarning(utils.UpdateFailingClusterCurator(client, curator))
 			}
 		}()
-	} else if err != nil && providerCredentialPath == "" {
-		utils.CheckError(err)
+	} else if providerCredentialPath == "" {
+		return err
 
 	} else {
 		klog.V(0).Info("Using PROVIDER_CREDNETIAL_PATH to find the Cloud Provider secret")
 	}
 
-	if providerCredentialPath == "" && strings.Contains(jobChoice, "applycloudprovider-") {
+	isApplyCloudProvider := strings.HasPrefix(jobChoice, secrets.ApplyCloudProvider)
+	if providerCredentialPath == "" && isApplyCloudProvider {
 		klog.Warningf("providerCredentialPath: " + providerCredentialPath)
-		utils.CheckError(errors.New("Missing spec.providerCredentialPath in ClusterCurator: " + clusterName))
+		return utils.NewError(utils.ReasonInvalidSpec, "Missing spec.providerCredentialPath in ClusterCurator: %s", clusterName)
 	}
 
-	var secretData *map[string]string
-	if strings.Contains(jobChoice, "applycloudprovider-") {
+	if isApplyCloudProvider {
 
 		kubeset, err := utils.GetKubeset()
-		utils.CheckError(err)
+		if err != nil {
+			return err
+		}
 
-		secretData = secrets.GetSecretData(kubeset, providerCredentialPath)
+		secretData, err := secrets.GetSecretData(ctx, kubeset, providerCredentialPath)
+		if err != nil {
+			return err
+		}
 		klog.V(2).Info("=> Applying Provider credential \"" + providerCredentialPath + "\" to cluster " + clusterName)
 
-		if jobChoice == "applycloudprovider-aws" {
-			err := secrets.CreateAWSSecrets(kubeset, *secretData, clusterName)
-			utils.CheckError(err)
-		} else if jobChoice == "applycloudprovider-gcp" {
-			err := secrets.CreateGCPSecrets(kubeset, *secretData, clusterName)
-			utils.CheckError(err)
-		} else if jobChoice == "applycloudprovider-azure" {
-			err := secrets.CreateAzureSecrets(kubeset, *secretData, clusterName)
-			utils.CheckError(err)
+		// applycloudprovider-ansible only creates the Ansible Tower secret
+		requested := strings.TrimPrefix(strings.TrimPrefix(jobChoice, secrets.ApplyCloudProvider), "-")
+		provider := secrets.ProviderAnsible
+		if requested != secrets.ProviderAnsible {
+			credentialProvider, err := secrets.GetCredentialProvider(ctx, kubeset, providerCredentialPath, *secretData)
+			if err != nil {
+				return err
+			}
+
+			platformProvider, err := secrets.GetClusterProvider(ctx, client, clusterName, clusterNamespace)
+			if err != nil {
+				return err
+			}
+
+			provider, err = secrets.ResolveProvider(requested, credentialProvider, platformProvider)
+			if err != nil {
+				return failStep(client, clusterName, clusterNamespace, jobChoice, err)
+			}
+		}
+
+		// Nothing is written when the credential is invalid
+		if err = secrets.ValidateProviderCredential(provider, *secretData); err != nil {
+			return failStep(client, clusterName, clusterNamespace, jobChoice, err)
+		}
+
+		if provider != secrets.ProviderAnsible {
+			klog.V(2).Infof("=> Creating the %v provider secrets", provider)
+
+			if err = secrets.CreateProviderSecrets(ctx, kubeset, provider, *secretData, clusterName); err != nil {
+				return failStep(client, clusterName, clusterNamespace, jobChoice, err)
+			}
 		}
-		err = secrets.CreateAnsibleSecret(kubeset, *secretData, clusterName)
-		utils.CheckError(err)
 
+		if err = secrets.CreateAnsibleSecret(ctx, kubeset, *secretData, clusterName); err != nil {
+			return err
+		}
+
+		// Labelled with their source credential, so they are re-synced on rotation and deleted on destroy
+		if err = secrets.TrackGeneratedSecrets(ctx, kubeset, clusterName,
+			secrets.GeneratedSecretNames(provider, *secretData, clusterName),
+			secrets.Source{
+				Path:     providerCredentialPath,
+				Provider: provider,
+				Hash:     secrets.CredentialHash(*secretData),
+			}); err != nil {
+			return err
+		}
 	}
 
 	if jobChoice == "activate-and-monitor" {
 		dynclient, dErr := utils.GetDynset(nil)
-		utils.CheckError(dErr)
+		if dErr != nil {
+			return dErr
+		}
 
-		clusterType, ctErr := utils.GetClusterType(client, dynclient, clusterName, clusterNamespace, false)
-		utils.CheckError(ctErr)
+		clusterType, ctErr := utils.GetClusterType(ctx, client, dynclient, clusterName, clusterNamespace, false)
+		if ctErr != nil {
+			return ctErr
+		}
 
 		if clusterType == utils.StandaloneClusterType {
-			if err = hive.ActivateDeploy(client, clusterName); err != nil {
-				utils.CheckError(utils.RecordFailedCuratorStatusCondition(
-					client,
-					clusterName,
-					clusterNamespace,
-					jobChoice,
-					v1.ConditionTrue,
-					err.Error()))
-				klog.Error(err.Error())
-				panic(err)
+			if err = hive.ActivateDeploy(ctx, client, clusterName); err != nil {
+				return failStep(client, clusterName, clusterNamespace, jobChoice, err)
 			}
 		} else if clusterType == utils.HypershiftClusterType {
-			if err = hypershift.ActivateDeploy(dynclient, clusterName, clusterNamespace); err != nil {
-				utils.CheckError(utils.RecordFailedCuratorStatusCondition(
-					client,
-					clusterName,
-					clusterNamespace,
-					jobChoice,
-					v1.ConditionTrue,
-					err.Error()))
-				klog.Error(err.Error())
-				panic(err)
+			if err = hypershift.ActivateDeploy(ctx, dynclient, clusterName, clusterNamespace); err != nil {
+				return failStep(client, clusterName, clusterNamespace, jobChoice, err)
 			}
 		}
 	}
 
 	if jobChoice == "monitor" || jobChoice == "activate-and-monitor" {
 		dynclient, dErr := utils.GetDynset(nil)
-		utils.CheckError(dErr)
+		if dErr != nil {
+			return dErr
+		}
 
-		clusterType, ctErr := utils.GetClusterType(client, dynclient, clusterName, clusterNamespace, false)
-		utils.CheckError(ctErr)
+		clusterType, ctErr := utils.GetClusterType(ctx, client, dynclient, clusterName, clusterNamespace, false)
+		if ctErr != nil {
+			return ctErr
+		}
 
 		if clusterType == utils.StandaloneClusterType {
-			if err := hive.MonitorClusterStatus(config, clusterName, utils.Installing, curator); err != nil {
-				utils.CheckError(utils.RecordFailedCuratorStatusCondition(
-					client,
-					clusterName,
-					clusterNamespace,
-					jobChoice,
-					v1.ConditionTrue,
-					err.Error()))
-				klog.Error(err.Error())
-				panic(err)
+			if err := hive.MonitorClusterStatus(ctx, config, clusterName, utils.Installing, curator); err != nil {
+				return failStep(client, clusterName, clusterNamespace, jobChoice, err)
 			}
 		} else if clusterType == utils.HypershiftClusterType {
-			if err = hypershift.MonitorClusterStatus(dynclient,
+			if err = hypershift.MonitorClusterStatus(ctx, dynclient,
 				client,
 				clusterName,
 				clusterNamespace, utils.Installing, utils.GetMonitorAttempts(utils.Installing, curator)); err != nil {
-				utils.CheckError(utils.RecordFailedCuratorStatusCondition(
-					client,
-					clusterName,
-					clusterNamespace,
-					jobChoice,
-					v1.ConditionTrue,
-					err.Error()))
-				klog.Error(err.Error())
-				panic(err)
+				return failStep(client, clusterName, clusterNamespace, jobChoice, err)
 			}
 		}
 	}
@@ -259,154 +338,110 @@ func curatorRun(config *rest.Config, client clientv1.Client, clusterName string,
 	// Create a client for the manageclusterV1 CustomResourceDefinitions
 	if jobChoice == "monitor-import" {
 		dynclient, err := utils.GetDynset(nil)
-		utils.CheckError(err)
+		if err != nil {
+			return err
+		}
 
-		if err = importer.MonitorMCInfoImport(dynclient, clusterName, curator); err != nil {
-			utils.CheckError(utils.RecordFailedCuratorStatusCondition(
-				client,
-				clusterName,
-				clusterNamespace,
-				jobChoice,
-				v1.ConditionTrue,
-				err.Error()))
-			klog.Error(err.Error())
-			panic(err)
+		if err = importer.MonitorMCInfoImport(ctx, dynclient, clusterName, curator); err != nil {
+			return failStep(client, clusterName, clusterNamespace, jobChoice, err)
 		}
 	}
 
 	if jobChoice == "destroy-cluster" {
 		dynclient, dErr := utils.GetDynset(nil)
-		utils.CheckError(dErr)
+		if dErr != nil {
+			return dErr
+		}
 
-		clusterType, ctErr := utils.GetClusterType(client, dynclient, clusterName, clusterNamespace, false)
-		utils.CheckError(ctErr)
+		clusterType, ctErr := utils.GetClusterType(ctx, client, dynclient, clusterName, clusterNamespace, false)
+		if ctErr != nil {
+			return ctErr
+		}
 
 		if clusterType == utils.StandaloneClusterType {
-			if err = hive.DestroyClusterDeployment(client, clusterName); err != nil {
-				utils.CheckError(utils.RecordFailedCuratorStatusCondition(
-					client,
-					clusterName,
-					clusterNamespace,
-					jobChoice,
-					v1.ConditionTrue,
-					err.Error()))
-				klog.Error(err.Error())
-				panic(err)
+			if err = hive.DestroyClusterDeployment(ctx, client, clusterName); err != nil {
+				return failStep(client, clusterName, clusterNamespace, jobChoice, err)
 			}
 		} else if clusterType == utils.HypershiftClusterType {
-			if err = hypershift.DetachAndMonitor(dynclient, clusterName, curator); err != nil {
-				utils.CheckError(utils.RecordFailedCuratorStatusCondition(
-					client,
-					clusterName,
-					clusterNamespace,
-					jobChoice,
-					v1.ConditionTrue,
-					err.Error()))
-				klog.Error(err.Error())
-				panic(err)
+			if err = hypershift.DetachAndMonitor(ctx, dynclient, clusterName, curator); err != nil {
+				return failStep(client, clusterName, clusterNamespace, jobChoice, err)
 			}
 
-			if err = hypershift.DestroyHostedCluster(dynclient, clusterName, clusterNamespace); err != nil {
-				utils.CheckError(utils.RecordFailedCuratorStatusCondition(
-					client,
-					clusterName,
-					clusterNamespace,
-					jobChoice,
-					v1.ConditionTrue,
-					err.Error()))
-				klog.Error(err.Error())
-				panic(err)
+			if err = hypershift.DestroyHostedCluster(ctx, dynclient, clusterName, clusterNamespace); err != nil {
+				return failStep(client, clusterName, clusterNamespace, jobChoice, err)
 			}
 		}
 	}
 
 	if jobChoice == "monitor-destroy" {
 		dynclient, dErr := utils.GetDynset(nil)
-		utils.CheckError(dErr)
+		if dErr != nil {
+			return dErr
+		}
 
-		clusterType, ctErr := utils.GetClusterType(client, dynclient, clusterName, clusterNamespace, false)
-		utils.CheckError(ctErr)
+		clusterType, ctErr := utils.GetClusterType(ctx, client, dynclient, clusterName, clusterNamespace, false)
+		if ctErr != nil {
+			return ctErr
+		}
 
 		if clusterType == utils.StandaloneClusterType {
-			if err := hive.MonitorClusterStatus(config, clusterName, utils.Destroying, curator); err != nil {
-				utils.CheckError(utils.RecordFailedCuratorStatusCondition(
-					client,
-					clusterName,
-					clusterNamespace,
-					jobChoice,
-					v1.ConditionTrue,
-					err.Error()))
-				klog.Error(err.Error())
-				panic(err)
+			if err := hive.MonitorClusterStatus(ctx, config, clusterName, utils.Destroying, curator); err != nil {
+				return failStep(client, clusterName, clusterNamespace, jobChoice, err)
 			}
 		} else if clusterType == utils.HypershiftClusterType {
 			if err = hypershift.MonitorClusterStatus(
+				ctx,
 				dynclient,
 				client,
 				clusterName,
 				clusterNamespace,
 				utils.Destroying,
 				utils.GetMonitorAttempts(utils.Installing, curator)); err != nil {
-				utils.CheckError(utils.RecordFailedCuratorStatusCondition(
-					client,
-					clusterName,
-					clusterNamespace,
-					jobChoice,
-					v1.ConditionTrue,
-					err.Error()))
-				klog.Error(err.Error())
-				panic(err)
+				return failStep(client, clusterName, clusterNamespace, jobChoice, err)
 			}
 		}
+
+		// The cluster is gone, so are the secrets generated for it
+		names := secrets.ClusterSecretNames(clusterName)
+		if curator != nil {
+			names = secrets.CuratorSecretNames(curator)
+		}
+		if kubeset, err := utils.GetKubeset(); err != nil {
+			utils.LogWarning(err)
+		} else {
+			utils.LogWarning(secrets.DeleteGeneratedSecrets(ctx, kubeset, clusterName, names))
+		}
 	}
 
 	if jobChoice == "detach-nowait" {
 		dynclient, err := utils.GetDynset(nil)
-		utils.CheckError(err)
+		if err != nil {
+			return err
+		}
 
-		if err = importer.DetachCluster(dynclient, clusterName); err != nil {
-			utils.CheckError(utils.RecordFailedCuratorStatusCondition(
-				client,
-				clusterName,
-				clusterNamespace,
-				jobChoice,
-				v1.ConditionTrue,
-				err.Error()))
-			klog.Error(err.Error())
-			panic(err)
+		if err = importer.DetachCluster(ctx, dynclient, clusterName); err != nil {
+			return failStep(client, clusterName, clusterNamespace, jobChoice, err)
 		}
 	}
 
 	if jobChoice == "upgrade-cluster" {
 		dynclient, dErr := utils.GetDynset(nil)
-		utils.CheckError(dErr)
+		if dErr != nil {
+			return dErr
+		}
 
-		clusterType, ctErr := utils.GetClusterType(client, dynclient, clusterName, clusterNamespace, true)
-		utils.CheckError(ctErr)
+		clusterType, ctErr := utils.GetClusterType(ctx, client, dynclient, clusterName, clusterNamespace, true)
+		if ctErr != nil {
+			return ctErr
+		}
 
 		if clusterType == utils.StandaloneClusterType {
-			if err = hive.UpgradeCluster(client, clusterName, curator); err != nil {
-				utils.CheckError(utils.RecordFailedCuratorStatusCondition(
-					client,
-					clusterName,
-					clusterNamespace,
-					jobChoice,
-					v1.ConditionTrue,
-					err.Error()))
-				klog.Error(err.Error())
-				panic(err)
+			if err = hive.UpgradeCluster(ctx, client, clusterName, curator); err != nil {
+				return failStep(client, clusterName, clusterNamespace, jobChoice, err)
 			}
 		} else if clusterType == utils.HypershiftClusterType {
-			if err = hypershift.UpgradeCluster(client, dynclient, clusterName, curator); err != nil {
-				utils.CheckError(utils.RecordFailedCuratorStatusCondition(
-					client,
-					clusterName,
-					clusterNamespace,
-					jobChoice,
-					v1.ConditionTrue,
-					err.Error()))
-				klog.Error(err.Error())
-				panic(err)
+			if err = hypershift.UpgradeCluster(ctx, client, dynclient, clusterName, curator); err != nil {
+				return failStep(client, clusterName, clusterNamespace, jobChoice, err)
 			}
 		}
 	}
@@ -418,64 +453,36 @@ func curatorRun(config *rest.Config, client clientv1.Client, clusterName string,
 			isInterVersion = false
 		}
 
-		if err := hive.EUSUpgradeCluster(client, clusterName, curator, isInterVersion); err != nil {
-			utils.CheckError(utils.RecordFailedCuratorStatusCondition(
-				client,
-				clusterName,
-				clusterNamespace,
-				jobChoice,
-				v1.ConditionTrue,
-				err.Error()))
-			klog.Error(err.Error())
-			panic(err)
+		if err := hive.EUSUpgradeCluster(ctx, client, clusterName, curator, isInterVersion); err != nil {
+			return failStep(client, clusterName, clusterNamespace, jobChoice, err)
 		}
 	}
 
 	if jobChoice == "intermediate-monitor-upgrade" {
 		// no need to check cluster type, only hive EUS upgrade supported for now
-		if err = hive.MonitorUpgradeStatus(client, clusterName, curator, true); err != nil {
-			utils.CheckError(utils.RecordFailedCuratorStatusCondition(
-				client,
-				clusterName,
-				clusterNamespace,
-				jobChoice,
-				v1.ConditionTrue,
-				err.Error()))
-			klog.Error(err.Error())
-			panic(err)
+		if err = hive.MonitorUpgradeStatus(ctx, client, clusterName, curator, true); err != nil {
+			return failStep(client, clusterName, clusterNamespace, jobChoice, err)
 		}
 	}
 
 	if jobChoice == "monitor-upgrade" {
 		dynclient, dErr := utils.GetDynset(nil)
-		utils.CheckError(dErr)
+		if dErr != nil {
+			return dErr
+		}
 
-		clusterType, ctErr := utils.GetClusterType(client, dynclient, clusterName, clusterNamespace, true)
-		utils.CheckError(ctErr)
+		clusterType, ctErr := utils.GetClusterType(ctx, client, dynclient, clusterName, clusterNamespace, true)
+		if ctErr != nil {
+			return ctErr
+		}
 
 		if clusterType == utils.StandaloneClusterType {
-			if err = hive.MonitorUpgradeStatus(client, clusterName, curator, false); err != nil {
-				utils.CheckError(utils.RecordFailedCuratorStatusCondition(
-					client,
-					clusterName,
-					clusterNamespace,
-					jobChoice,
-					v1.ConditionTrue,
-					err.Error()))
-				klog.Error(err.Error())
-				panic(err)
+			if err = hive.MonitorUpgradeStatus(ctx, client, clusterName, curator, false); err != nil {
+				return failStep(client, clusterName, clusterNamespace, jobChoice, err)
 			}
 		} else if clusterType == utils.HypershiftClusterType {
-			if err = hypershift.MonitorUpgradeStatus(dynclient, client, clusterName, curator); err != nil {
-				utils.CheckError(utils.RecordFailedCuratorStatusCondition(
-					client,
-					clusterName,
-					clusterNamespace,
-					jobChoice,
-					v1.ConditionTrue,
-					err.Error()))
-				klog.Error(err.Error())
-				panic(err)
+			if err = hypershift.MonitorUpgradeStatus(ctx, dynclient, client, clusterName, curator); err != nil {
+				return failStep(client, clusterName, clusterNamespace, jobChoice, err)
 			}
 		}
 	}
@@ -483,29 +490,20 @@ func curatorRun(config *rest.Config, client clientv1.Client, clusterName string,
 	if jobChoice == "delete-cluster-namespace" {
 
 		if err := updateDeleteClusternamespace(client, curator); err != nil {
-			utils.CheckError(utils.RecordFailedCuratorStatusCondition(
-				client,
-				clusterName,
-				clusterNamespace,
-				jobChoice,
-				v1.ConditionTrue,
-				err.Error()))
-			klog.Error(err.Error())
-			panic(err)
+			return failStep(client, clusterName, clusterNamespace, jobChoice, err)
 		}
 	}
 
 	if jobChoice == "prehook-ansiblejob" || jobChoice == "posthook-ansiblejob" {
-		if err = ansible.Job(client, curator); err != nil {
-			utils.CheckError(utils.RecordFailedCuratorStatusCondition(
-				client,
-				clusterName,
-				clusterNamespace,
-				jobChoice,
-				v1.ConditionTrue,
-				err.Error()))
-			klog.Error(err.Error())
-			panic(err)
+		// The kubeset is only used to collect the AnsibleJob runner logs
+		kubeset, kErr := utils.GetKubeset()
+		if kErr != nil {
+			klog.Warningf("AnsibleJob logs will not be collected: %v", kErr)
+			kubeset = nil
+		}
+
+		if err = ansible.Job(ctx, client, kubeset, curator); err != nil {
+			return failStep(client, clusterName, clusterNamespace, jobChoice, err)
 		}
 	}
 
@@ -515,7 +513,7 @@ func curatorRun(config *rest.Config, client clientv1.Client, clusterName string,
 
 	if jobChoice == "done" {
 		jobChoice = CuratorJob
-		msg = curator.Spec.CuratingJob + " DesiredCuration: " + desiredCuration
+		msg = utils.CuratingJob(curator) + " DesiredCuration: " + desiredCuration
 		condition = v1.ConditionTrue
 
 		if desiredCuration == "upgrade" {
@@ -523,48 +521,76 @@ func curatorRun(config *rest.Config, client clientv1.Client, clusterName string,
 		}
 
 		// Remove DesireCuration, CuratingJob, Status from curator resource
-		updateDoneClusterCurator(client, curator, clusterName)
+		if err := updateDoneClusterCurator(client, curator, clusterName); err != nil {
+			return err
+		}
 	}
 
 	// Used to signal end of job as well as end of init container
-	utils.CheckError(utils.RecordCurrentStatusCondition(
+	if err := utils.RecordCurrentStatusCondition(
 		client,
 		clusterName,
 		clusterNamespace,
 		jobChoice,
 		condition,
-		msg))
+		msg); err != nil {
+		return err
+	}
 
 	klog.V(2).Info("Done!")
+	return nil
 }
 
-func updateDoneClusterCurator(client clientv1.Client, curator *clustercuratorv1.ClusterCurator, clusterName string) {
-	if curator.Spec.DesiredCuration == "upgrade" {
-		patch := []byte(`{"spec":{"curatorJob": null},"status": null, "operation": null}`)
-		err := client.Patch(context.Background(), curator, clientv1.RawPatch(types.MergePatchType, patch))
-		utils.CheckError(err)
-		return
+// The curator Job running the step, nil when it can not be read
+func getCuratorJob(ctx context.Context, client clientv1.Client, curator *clustercuratorv1.ClusterCurator) *batchv1.Job {
+	if utils.CuratingJob(curator) == "" {
+		return nil
+	}
+	job := &batchv1.Job{}
+	if err := client.Get(ctx, types.NamespacedName{Namespace: curator.Namespace, Name: utils.CuratingJob(curator)},
+		job); err != nil {
+		klog.Warningf("The steps are not checkpointed, the curator job was not read: %v", err)
+		return nil
 	}
+	return job
+}
 
-	patch := []byte(`{"spec":{"curatorJob": null, "desiredCuration": null},"status": null, "operation": null}`)
-	err := client.Patch(context.Background(), curator, clientv1.RawPatch(types.MergePatchType, patch))
-	utils.CheckError(err)
+// The Job creates another pod when this one fails
+func canRetry(job *batchv1.Job) bool {
+	return job != nil && job.Spec.BackoffLimit != nil && job.Status.Failed < *job.Spec.BackoffLimit
 }
 
-func updateFailingClusterCurator(client clientv1.Client, curator *clustercuratorv1.ClusterCurator) {
+func updateDoneClusterCurator(client clientv1.Client, curator *clustercuratorv1.ClusterCurator, clusterName string) error {
+	// An operation does not complete the curation of the spec
+	if utils.CurrentOperation(curator) != nil {
+		return utils.RecordOperationResult(client, curator, clustercuratorv1.OperationSucceeded)
+	}
+	if utils.GenerationTriggered(curator) {
+		return utils.RecordCompletedCuration(client, curator, clustercuratorv1.CurationSucceeded)
+	}
+
+	// The status is a subresource, it is removed before the curation is completed in the spec
+	statusPatch := []byte(`{"status": null}`)
+	if err := client.Status().Patch(context.Background(), curator,
+		clientv1.RawPatch(types.MergePatchType, statusPatch)); err != nil {
+		return err
+	}
+
 	if curator.Spec.DesiredCuration == "upgrade" {
 		patch := []byte(`{"spec":{"curatorJob": null}, "operation": null}`)
-		err := client.Patch(context.Background(), curator, clientv1.RawPatch(types.MergePatchType, patch))
-		utils.CheckError(err)
-		return
+		return client.Patch(context.Background(), curator, clientv1.RawPatch(types.MergePatchType, patch))
 	}
 
 	patch := []byte(`{"spec":{"curatorJob": null, "desiredCuration": null}, "operation": null}`)
-	err := client.Patch(context.Background(), curator, clientv1.RawPatch(types.MergePatchType, patch))
-	utils.CheckError(err)
+	return client.Patch(context.Background(), curator, clientv1.RawPatch(types.MergePatchType, patch))
 }
 
 func updateDeleteClusternamespace(client clientv1.Client, curator *clustercuratorv1.ClusterCurator) error {
+	// The controller deletes the namespace once the destroy succeeded, the step condition requests it
+	if utils.GenerationTriggered(curator) {
+		return nil
+	}
+
 	patch := []byte(`{"spec":{"curatorJob": null, "desiredCuration": "delete-cluster-namespace"}}`)
 	return client.Patch(context.Background(), curator, clientv1.RawPatch(types.MergePatchType, patch))
 }
@@ -15,10 +15,13 @@ import (
 	clusterversionv1 "github.com/openshift/api/config/v1"
 	hivev1 "github.com/openshift/hive/apis/hive/v1"
 	clustercuratorv1 "github.com/stolostron/cluster-curator-controller/pkg/api/v1beta1"
+	"github.com/stolostron/cluster-curator-controller/pkg/controller/launcher"
 	"github.com/stolostron/cluster-curator-controller/pkg/jobs/utils"
 	managedclusterviewv1beta1 "github.com/stolostron/cluster-lifecycle-api/view/v1beta1"
 	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -218,60 +221,31 @@ func getEUSClusterVersionManagedClusterView() *managedclusterviewv1beta1.Managed
 }
 func TestCuratorRunNoParam(t *testing.T) {
 
-	defer func() {
-		r := recover()
-		t.Log(r.(error).Error())
-
-		if !strings.Contains(r.(error).Error(), "Command: ./curator [") &&
-			!strings.Contains(r.(error).Error(), "Invalid Parameter: \"\"") {
-			t.Fatal(r)
-		}
-		t.Log("Detected missing paramter")
-	}()
-
-	os.Args[1] = ""
-
-	curatorRun(nil, nil, ClusterName, ClusterName)
+	err := curatorRun(context.TODO(), nil, nil, "", ClusterName, ClusterName)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "invalid step: \"\"")
+	assert.Equal(t, exitUsage, exitCode(err))
 }
 
 func TestCuratorRunWrongParam(t *testing.T) {
 
-	defer func() {
-		r := recover()
-		t.Log(r.(error).Error())
-
-		if !strings.Contains(r.(error).Error(), "Command: ./curator [") &&
-			!strings.Contains(r.(error).Error(), "something-wrong") {
-			t.Fatal(r)
-		}
-		t.Log("Detected wrong paramter")
-	}()
-
-	os.Args[1] = "something-wrong"
-
-	curatorRun(nil, nil, ClusterName, ClusterName)
+	err := curatorRun(context.TODO(), nil, nil, "something-wrong", ClusterName, ClusterName)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "something-wrong")
+	assert.Equal(t, exitUsage, exitCode(err))
 }
 
 func TestCuratorRunNoClusterCurator(t *testing.T) {
 
-	defer func() {
-		r := recover()
-		t.Log(r.(error).Error())
-
-		if !strings.Contains(r.(error).Error(), "clustercurators.cluster.open-cluster-management.io \"my-cluster\"") {
-			t.Fatal(r)
-		}
-		t.Log("Detected missing ClusterCurator resource")
-	}()
-
 	s := scheme.Scheme
 	s.AddKnownTypes(utils.CCGVR.GroupVersion(), &clustercuratorv1.ClusterCurator{})
 
 	client := clientfake.NewClientBuilder().WithScheme(s).Build()
 
-	os.Args[1] = "SKIP_ALL_TESTING"
-
-	curatorRun(nil, client, ClusterName, ClusterName)
+	err := curatorRun(context.TODO(), nil, client, "SKIP_ALL_TESTING", ClusterName, ClusterName)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "clustercurators.cluster.open-cluster-management.io \"my-cluster\"")
+	assert.Equal(t, exitNotFound, exitCode(err))
 }
 
 func TestCuratorRunClusterCurator(t *testing.T) {
@@ -279,118 +253,66 @@ func TestCuratorRunClusterCurator(t *testing.T) {
 	s := scheme.Scheme
 	s.AddKnownTypes(utils.CCGVR.GroupVersion(), &clustercuratorv1.ClusterCurator{})
 
-	client := clientfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(getClusterCurator()).Build()
-
-	os.Args[1] = "SKIP_ALL_TESTING"
+	client := clientfake.NewClientBuilder().WithScheme(s).WithStatusSubresource(&clustercuratorv1.ClusterCurator{}).WithRuntimeObjects(getClusterCurator()).Build()
 
-	assert.NotPanics(t, func() { curatorRun(nil, client, ClusterName, ClusterName) }, "no panic when ClusterCurator found and skip test")
+	assert.Nil(t, curatorRun(context.TODO(), nil, client, "SKIP_ALL_TESTING", ClusterName, ClusterName), "err nil, when ClusterCurator found and skip test")
 }
 
 func TestCuratorRunClusterCuratorInstallUpgradeOperation(t *testing.T) {
 	s := scheme.Scheme
 	s.AddKnownTypes(utils.CCGVR.GroupVersion(), &clustercuratorv1.ClusterCurator{})
-	client := clientfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(getClusterCuratorWithInstallOperation()).Build()
+	client := clientfake.NewClientBuilder().WithScheme(s).WithStatusSubresource(&clustercuratorv1.ClusterCurator{}).WithRuntimeObjects(getClusterCuratorWithInstallOperation()).Build()
 
-	os.Args[1] = "SKIP_ALL_TESTING"
+	assert.Nil(t, curatorRun(context.TODO(), nil, client, "SKIP_ALL_TESTING", ClusterName, ClusterName), "err nil, when ClusterCurator found and skip test")
 
-	assert.NotPanics(t, func() { curatorRun(nil, client, ClusterName, ClusterName) }, "no panic when ClusterCurator found and skip test")
+	client = clientfake.NewClientBuilder().WithScheme(s).WithStatusSubresource(&clustercuratorv1.ClusterCurator{}).WithRuntimeObjects(getClusterCuratorWithUpgradeOperation()).Build()
 
-	client = clientfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(getClusterCuratorWithUpgradeOperation()).Build()
-
-	assert.NotPanics(t, func() { curatorRun(nil, client, ClusterName, ClusterName) }, "no panic when ClusterCurator found and skip test")
+	assert.Nil(t, curatorRun(context.TODO(), nil, client, "SKIP_ALL_TESTING", ClusterName, ClusterName), "err nil, when ClusterCurator found and skip test")
 }
 
 func TestCuratorRunNoProviderCredentialPath(t *testing.T) {
 
-	defer func() {
-		r := recover()
-		t.Log(r.(error).Error())
-
-		if !strings.Contains(r.(error).Error(), "Missing spec.providerCredentialPath") {
-			t.Fatal(r)
-		}
-		t.Log("Detected missing provierCredentialPath")
-	}()
-
 	s := scheme.Scheme
 	hivev1.AddToScheme(s)
 	s.AddKnownTypes(utils.CCGVR.GroupVersion(), &clustercuratorv1.ClusterCurator{})
 
-	client := clientfake.NewClientBuilder().WithRuntimeObjects(getClusterCurator()).WithScheme(s).Build()
-
-	os.Args[1] = "applycloudprovider-ansible"
+	client := clientfake.NewClientBuilder().WithStatusSubresource(&clustercuratorv1.ClusterCurator{}).WithRuntimeObjects(getClusterCurator()).WithScheme(s).Build()
 
-	curatorRun(nil, client, ClusterName, ClusterName)
+	err := curatorRun(context.TODO(), nil, client, "applycloudprovider-ansible", ClusterName, ClusterName)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Missing spec.providerCredentialPath")
+	assert.Equal(t, exitInvalidSpec, exitCode(err))
 }
 
 func TestCuratorRunProviderCredentialPathEnv(t *testing.T) {
 
-	defer func() {
-		r := recover()
-		t.Log(r.(error).Error())
-
-		if !strings.Contains(r.(error).Error(), "secrets \"secretname\"") {
-			t.Fatal(r)
-		}
-		t.Log("Detected missing namespace/secretName")
-	}()
-
 	os.Setenv("PROVIDER_CREDENTIAL_PATH", "namespace/secretname")
 	client := clientfake.NewClientBuilder().WithScheme(s).Build()
 
-	os.Args[1] = "applycloudprovider-ansible"
-
-	curatorRun(nil, client, ClusterName, ClusterName)
+	err := curatorRun(context.TODO(), nil, client, "applycloudprovider-ansible", ClusterName, ClusterName)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "secrets \"secretname\"")
 }
 
 func TestInvokeMonitor(t *testing.T) {
-	defer func() {
-		if r := recover(); r == nil {
-			t.Fatal("expected recover, but failed")
-		}
-	}()
-
 	os.Setenv("PROVIDER_CREDENTIAL_PATH", "namespace/secretname")
-	os.Args[1] = "monitor"
 
-	curatorRun(nil, clientfake.NewClientBuilder().Build(), ClusterName, ClusterName)
+	assert.NotNil(t, curatorRun(context.TODO(), nil, clientfake.NewClientBuilder().Build(), "monitor", ClusterName, ClusterName))
 }
 
 func TestInvokeMonitorImport(t *testing.T) {
-	defer func() {
-		if r := recover(); r == nil {
-			t.Fatal("expected recover, but failed")
-		}
-	}()
-
 	os.Setenv("PROVIDER_CREDENTIAL_PATH", "namespace/secretname")
-	os.Args[1] = "monitor-import"
 
-	curatorRun(nil, clientfake.NewClientBuilder().Build(), ClusterName, ClusterName)
+	assert.NotNil(t, curatorRun(context.TODO(), nil, clientfake.NewClientBuilder().Build(), "monitor-import", ClusterName, ClusterName))
 }
 
 func TestInvokeMonitorDestroy(t *testing.T) {
-	defer func() {
-		if r := recover(); r == nil {
-			t.Fatal("expected recover, but failed")
-		}
-	}()
-
 	os.Setenv("PROVIDER_CREDENTIAL_PATH", "namespace/secretname")
-	os.Args[1] = "monitor-destroy"
 
-	curatorRun(nil, clientfake.NewClientBuilder().Build(), ClusterName, ClusterName)
+	assert.NotNil(t, curatorRun(context.TODO(), nil, clientfake.NewClientBuilder().Build(), "monitor-destroy", ClusterName, ClusterName))
 }
 
 func TestUpgradFailed(t *testing.T) {
-	defer func() {
-		if r := recover(); r == nil {
-			t.Fatal("expected recover, but failed")
-		}
-	}()
-
-	os.Args[1] = "upgrade-cluster"
-
 	s := scheme.Scheme
 	s.AddKnownTypes(utils.CCGVR.GroupVersion(), &clustercuratorv1.ClusterCurator{})
 
@@ -409,22 +331,14 @@ func TestUpgradFailed(t *testing.T) {
 		},
 	).Build()
 
-	curatorRun(nil, client, ClusterName, ClusterName)
+	assert.NotNil(t, curatorRun(context.TODO(), nil, client, "upgrade-cluster", ClusterName, ClusterName))
 }
 
 func TestUpgradDone(t *testing.T) {
-	defer func() {
-		if r := recover(); r != nil {
-			t.Fatalf("unexpected error %v", r)
-		}
-	}()
-
-	os.Args[1] = "done"
-
 	s := scheme.Scheme
 	s.AddKnownTypes(utils.CCGVR.GroupVersion(), &clustercuratorv1.ClusterCurator{})
 
-	client := clientfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(
+	client := clientfake.NewClientBuilder().WithScheme(s).WithStatusSubresource(&clustercuratorv1.ClusterCurator{}).WithRuntimeObjects(
 		&clustercuratorv1.ClusterCurator{
 			ObjectMeta: v1.ObjectMeta{
 				Name:      ClusterName,
@@ -439,21 +353,55 @@ func TestUpgradDone(t *testing.T) {
 		},
 	).Build()
 
-	curatorRun(nil, client, ClusterName, ClusterName)
+	assert.Nil(t, curatorRun(context.TODO(), nil, client, "done", ClusterName, ClusterName))
+}
+
+func TestDoneGenerationTrigger(t *testing.T) {
+	s := scheme.Scheme
+	s.AddKnownTypes(utils.CCGVR.GroupVersion(), &clustercuratorv1.ClusterCurator{})
+
+	curator := getClusterCurator()
+	curator.Generation = 2
+	curator.Spec.CurationTrigger = clustercuratorv1.CurationTriggerGeneration
+	curator.Status.CuratorJob = "curator-job-ABCDE"
+	curator.Status.ObservedGeneration = 2
+	client := clientfake.NewClientBuilder().WithScheme(s).WithStatusSubresource(&clustercuratorv1.ClusterCurator{}).WithRuntimeObjects(curator).Build()
+
+	assert.Nil(t, curatorRun(context.TODO(), nil, client, "done", ClusterName, ClusterName))
+
+	assert.Nil(t, client.Get(context.TODO(), types.NamespacedName{Namespace: ClusterName, Name: ClusterName}, curator))
+	assert.Equal(t, "install", curator.Spec.DesiredCuration, "the spec is not changed")
+	assert.Empty(t, curator.Status.CuratorJob)
+	assert.Equal(t, &clustercuratorv1.CompletedCuration{Curation: "install", Generation: 2,
+		CuratorJob: "curator-job-ABCDE", Result: clustercuratorv1.CurationSucceeded,
+		CompletionTime: curator.Status.LastCompletedCuration.CompletionTime}, curator.Status.LastCompletedCuration)
+	assert.True(t, meta.IsStatusConditionTrue(curator.Status.Conditions, CuratorJob))
+}
+
+func TestDoneOperation(t *testing.T) {
+	s := scheme.Scheme
+	s.AddKnownTypes(utils.CCGVR.GroupVersion(), &clustercuratorv1.ClusterCurator{})
+
+	curator := getClusterCurator()
+	curator.Spec.CuratingJob = "curator-job-ABCDE"
+	curator.Operation = &clustercuratorv1.Operation{Action: clustercuratorv1.OperationRerun,
+		Phase: launcher.MonImport, Curation: "install", Nonce: "1"}
+	curator.Status.LastOperation = utils.NewOperationStatus(curator.Operation, clustercuratorv1.OperationRunning)
+	client := clientfake.NewClientBuilder().WithScheme(s).WithStatusSubresource(&clustercuratorv1.ClusterCurator{}).WithRuntimeObjects(curator).Build()
+
+	assert.Nil(t, curatorRun(context.TODO(), nil, client, "done", ClusterName, ClusterName))
+
+	assert.Nil(t, client.Get(context.TODO(), types.NamespacedName{Namespace: ClusterName, Name: ClusterName}, curator))
+	assert.Equal(t, "install", curator.Spec.DesiredCuration, "the curation is not changed")
+	assert.Empty(t, curator.Spec.CuratingJob)
+	assert.Equal(t, clustercuratorv1.OperationSucceeded, curator.Status.LastOperation.Result)
+	assert.Equal(t, "curator-job-ABCDE", curator.Status.LastOperation.CuratorJob)
+	assert.Nil(t, utils.CurrentOperation(curator))
 }
 
 func TestHypershiftActivate(t *testing.T) {
 	// Test will fail because we can't pass in a fake dynamic client
 	// But that's ok, we just need to test the curator code
-	defer func() { // recover from not having a hub config object
-		if r := recover(); r == nil {
-			t.Fatal("expected recover, but failed")
-		}
-	}()
-
-	os.Args[1] = "activate-and-monitor"
-	os.Args[2] = ClusterName
-
 	s.AddKnownTypes(clustercuratorv1.SchemeBuilder.GroupVersion, &clustercuratorv1.ClusterCurator{})
 	client := clientfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(
 		getHypershiftClusterCurator(),
@@ -463,21 +411,12 @@ func TestHypershiftActivate(t *testing.T) {
 
 	config, _ := rest.InClusterConfig()
 
-	curatorRun(config, client, ClusterNamespace, ClusterName)
+	assert.NotNil(t, curatorRun(context.TODO(), config, client, "activate-and-monitor", ClusterNamespace, ClusterName))
 }
 
 func TestHypershiftMonitor(t *testing.T) {
 	// Test will fail because we can't pass in a fake dynamic client
 	// But that's ok, we just need to test the curator code
-	defer func() { // recover from not having a hub config object
-		if r := recover(); r == nil {
-			t.Fatal("expected recover, but failed")
-		}
-	}()
-
-	os.Args[1] = "monitor"
-	os.Args[2] = ClusterName
-
 	s.AddKnownTypes(clustercuratorv1.SchemeBuilder.GroupVersion, &clustercuratorv1.ClusterCurator{})
 	client := clientfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(
 		getHypershiftClusterCurator(),
@@ -487,21 +426,12 @@ func TestHypershiftMonitor(t *testing.T) {
 
 	config, _ := rest.InClusterConfig()
 
-	curatorRun(config, client, ClusterNamespace, ClusterName)
+	assert.NotNil(t, curatorRun(context.TODO(), config, client, "monitor", ClusterNamespace, ClusterName))
 }
 
 func TestHypershiftDestroyCluster(t *testing.T) {
 	// Test will fail because we can't pass in a fake dynamic client
 	// But that's ok, we just need to test the curator code
-	defer func() { // recover from not having a hub config object
-		if r := recover(); r == nil {
-			t.Fatal("expected recover, but failed")
-		}
-	}()
-
-	os.Args[1] = "destroy-cluster"
-	os.Args[2] = ClusterName
-
 	s.AddKnownTypes(clustercuratorv1.SchemeBuilder.GroupVersion, &clustercuratorv1.ClusterCurator{})
 	client := clientfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(
 		getHypershiftClusterCurator(),
@@ -511,21 +441,12 @@ func TestHypershiftDestroyCluster(t *testing.T) {
 
 	config, _ := rest.InClusterConfig()
 
-	curatorRun(config, client, ClusterNamespace, ClusterName)
+	assert.NotNil(t, curatorRun(context.TODO(), config, client, "destroy-cluster", ClusterNamespace, ClusterName))
 }
 
 func TestHypershiftMonitorDestroy(t *testing.T) {
 	// Test will fail because we can't pass in a fake dynamic client
 	// But that's ok, we just need to test the curator code
-	defer func() { // recover from not having a hub config object
-		if r := recover(); r == nil {
-			t.Fatal("expected recover, but failed")
-		}
-	}()
-
-	os.Args[1] = "monitor-destroy"
-	os.Args[2] = ClusterName
-
 	s.AddKnownTypes(clustercuratorv1.SchemeBuilder.GroupVersion, &clustercuratorv1.ClusterCurator{})
 	client := clientfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(
 		getHypershiftClusterCurator(),
@@ -535,21 +456,12 @@ func TestHypershiftMonitorDestroy(t *testing.T) {
 
 	config, _ := rest.InClusterConfig()
 
-	curatorRun(config, client, ClusterNamespace, ClusterName)
+	assert.NotNil(t, curatorRun(context.TODO(), config, client, "monitor-destroy", ClusterNamespace, ClusterName))
 }
 
 func TestHypershiftUpgradeCluster(t *testing.T) {
 	// Test will fail because we can't pass in a fake dynamic client
 	// But that's ok, we just need to test the curator code
-	defer func() { // recover from not having a hub config object
-		if r := recover(); r == nil {
-			t.Fatal("expected recover, but failed")
-		}
-	}()
-
-	os.Args[1] = "upgrade-cluster"
-	os.Args[2] = ClusterName
-
 	s.AddKnownTypes(clustercuratorv1.SchemeBuilder.GroupVersion, &clustercuratorv1.ClusterCurator{})
 	client := clientfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(
 		getHypershiftClusterCurator(),
@@ -559,21 +471,12 @@ func TestHypershiftUpgradeCluster(t *testing.T) {
 
 	config, _ := rest.InClusterConfig()
 
-	curatorRun(config, client, ClusterNamespace, ClusterName)
+	assert.NotNil(t, curatorRun(context.TODO(), config, client, "upgrade-cluster", ClusterNamespace, ClusterName))
 }
 
 func TestHypershiftMonitorUpgrade(t *testing.T) {
 	// Test will fail because we can't pass in a fake dynamic client
 	// But that's ok, we just need to test the curator code
-	defer func() { // recover from not having a hub config object
-		if r := recover(); r == nil {
-			t.Fatal("expected recover, but failed")
-		}
-	}()
-
-	os.Args[1] = "monitor-upgrade"
-	os.Args[2] = ClusterName
-
 	s.AddKnownTypes(clustercuratorv1.SchemeBuilder.GroupVersion, &clustercuratorv1.ClusterCurator{})
 	client := clientfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(
 		getHypershiftClusterCurator(),
@@ -583,21 +486,12 @@ func TestHypershiftMonitorUpgrade(t *testing.T) {
 
 	config, _ := rest.InClusterConfig()
 
-	curatorRun(config, client, ClusterNamespace, ClusterName)
+	assert.NotNil(t, curatorRun(context.TODO(), config, client, "monitor-upgrade", ClusterNamespace, ClusterName))
 }
 
 func TestEUSIntermediateUpgrade(t *testing.T) {
 	// Test will fail because we don't have all the objects
 	// But that's ok, we just need to test the curator code
-	defer func() {
-		if r := recover(); r == nil {
-			t.Fatal("expected recover, but failed")
-		}
-	}()
-
-	os.Args[1] = "intermediate-upgrade-cluster"
-	os.Args[2] = ClusterName
-
 	s.AddKnownTypes(clustercuratorv1.SchemeBuilder.GroupVersion, &clustercuratorv1.ClusterCurator{})
 	client := clientfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(
 		getEUSUpgradeClusterCurator(),
@@ -605,21 +499,12 @@ func TestEUSIntermediateUpgrade(t *testing.T) {
 
 	config, _ := rest.InClusterConfig()
 
-	curatorRun(config, client, ClusterName, ClusterName)
+	assert.NotNil(t, curatorRun(context.TODO(), config, client, "intermediate-upgrade-cluster", ClusterName, ClusterName))
 }
 
 func TestEUSFinalUpgrade(t *testing.T) {
 	// Test will fail because we don't have all the objects
 	// But that's ok, we just need to test the curator code
-	defer func() {
-		if r := recover(); r == nil {
-			t.Fatal("expected recover, but failed")
-		}
-	}()
-
-	os.Args[1] = "final-upgrade-cluster"
-	os.Args[2] = ClusterName
-
 	s.AddKnownTypes(clustercuratorv1.SchemeBuilder.GroupVersion, &clustercuratorv1.ClusterCurator{})
 	client := clientfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(
 		getEUSUpgradeClusterCurator(),
@@ -627,22 +512,14 @@ func TestEUSFinalUpgrade(t *testing.T) {
 
 	config, _ := rest.InClusterConfig()
 
-	curatorRun(config, client, ClusterName, ClusterName)
+	assert.NotNil(t, curatorRun(context.TODO(), config, client, "final-upgrade-cluster", ClusterName, ClusterName))
 }
 
 func TestEUSMonitorUpgrade(t *testing.T) {
 	// Test will fail because we don't have all the objects
 	// But that's ok, we just need to test the curator code
-	defer func() {
-		if r := recover(); r == nil {
-			t.Fatal("expected recover, but failed")
-		}
-	}()
-
-	os.Args[1] = "intermediate-monitor-upgrade"
-	os.Args[2] = ClusterName
-
 	s.AddKnownTypes(clustercuratorv1.SchemeBuilder.GroupVersion, &clustercuratorv1.ClusterCurator{})
+	assert.Nil(t, managedclusterviewv1beta1.AddToScheme(s))
 	client := clientfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(
 		getEUSUpgradeClusterCurator(),
 		getEUSClusterVersionManagedClusterView(),
@@ -650,7 +527,7 @@ func TestEUSMonitorUpgrade(t *testing.T) {
 
 	config, _ := rest.InClusterConfig()
 
-	curatorRun(config, client, ClusterName, ClusterName)
+	assert.NotNil(t, curatorRun(context.TODO(), config, client, "intermediate-monitor-upgrade", ClusterName, ClusterName))
 }
 
 func TestIntermediateUpdateImmutability(t *testing.T) {
@@ -891,3 +768,13 @@ func TestAddIntermediateUpdateToExistingCurator(t *testing.T) {
 	assert.True(t, strings.Contains(err.Error(), "The intermediateUpdate cannot be added via update if desiredUpdate already exists"),
 		"Cannot add intermediateUpdate to existing curator validation successful")
 }
+
+func TestCanRetry(t *testing.T) {
+	var backoffLimit int32 = launcher.CuratorJobBackoffLimit
+	job := &batchv1.Job{Spec: batchv1.JobSpec{BackoffLimit: &backoffLimit}}
+
+	assert.True(t, canRetry(job), "the job runs a new pod")
+	job.Status.Failed = backoffLimit
+	assert.False(t, canRetry(job), "the job fails with this pod")
+	assert.False(t, canRetry(nil), "a step run from a workstation is not retried")
+}
@@ -3,20 +3,29 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
 	"time"
 
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/selection"
 	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	"k8s.io/klog"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	"github.com/stolostron/cluster-curator-controller/controllers"
 	clusteropenclustermanagementiov1beta1 "github.com/stolostron/cluster-curator-controller/pkg/api/v1beta1"
+	"github.com/stolostron/cluster-curator-controller/pkg/controller/launcher"
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/secrets"
 	"github.com/stolostron/cluster-curator-controller/pkg/jobs/utils"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	// +kubebuilder:scaffold:imports
@@ -40,6 +49,11 @@ func main() {
 	var leaderElectionLeaseDuration time.Duration
 	var leaderElectionRenewDeadline time.Duration
 	var leaderElectionRetryPeriod time.Duration
+	var jobTemplateConfigMap string
+	var imagePullTimeout time.Duration
+	var ansibleJobRetention int
+	var maxConcurrentReconciles int
+	var maxRunningJobs string
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. "+
@@ -69,17 +83,52 @@ func main() {
 		"The duration the clients should wait between attempting acquisition and renewal "+
 			"of a leadership. This is only applicable if leader election is enabled.",
 	)
+	flag.StringVar(&jobTemplateConfigMap, "job-template-configmap", "",
+		"NAMESPACE/NAME of the ConfigMap with the default jobTemplate of the curator Jobs, "+
+			"by default "+launcher.JobTemplateConfigMap+" in the namespace of the controller.")
+	flag.DurationVar(&imagePullTimeout, "image-pull-timeout", controllers.DefaultImagePullTimeout,
+		"How long a curator job pod can wait on an image pull before its curation is failed.")
+	flag.IntVar(&ansibleJobRetention, "ansiblejob-retention", controllers.DefaultAnsibleJobRetention,
+		"The number of past curation runs of a ClusterCurator whose AnsibleJobs are kept, 0 keeps them all.")
+	flag.IntVar(&maxConcurrentReconciles, "max-concurrent-reconciles", 1,
+		"The number of ClusterCurators that are reconciled at once.")
+	flag.StringVar(&maxRunningJobs, "max-running-curator-jobs", "",
+		"The curator jobs of a curation type that run at once on the hub, format: TYPE=COUNT,TYPE=COUNT, "+
+			"for example install=20,upgrade=50. The other curations wait with a Queued condition.")
 	flag.Parse()
 
 	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
 
+	maxRunningJobsByType, err := controllers.ParseMaxRunningJobs(maxRunningJobs)
+	if err != nil {
+		setupLog.Error(err, "invalid --max-running-curator-jobs")
+		os.Exit(1)
+	}
+
 	setupLog.Info("Leader election settings", "enableLeaderElection", enableLeaderElection,
 		"leaseDuration", leaderElectionLeaseDuration,
 		"renewDeadline", leaderElectionRenewDeadline,
 		"retryPeriod", leaderElectionRetryPeriod)
 
+	// Only the ACM credentials are cached, their changes re-sync the secrets generated from them
+	credentials, err := labels.NewRequirement(secrets.CredentialsLabel, selection.Exists, nil)
+	if err != nil {
+		setupLog.Error(err, "invalid credentials label selector")
+		os.Exit(1)
+	}
+
+	curatorJobs := labels.SelectorFromSet(labels.Set{launcher.CuratorJobLabel: launcher.CuratorJobLabelValue})
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme: scheme,
+		Cache: cache.Options{
+			ByObject: map[client.Object]cache.ByObject{
+				&corev1.Secret{}: {Label: labels.NewSelector().Add(*credentials)},
+				// Only the curator jobs and their pods are watched
+				&batchv1.Job{}: {Label: curatorJobs},
+				&corev1.Pod{}:  {Label: curatorJobs},
+			},
+		},
 		// Port:               9443,
 		Metrics: metricsserver.Options{
 			BindAddress: metricsAddr,
@@ -95,6 +144,23 @@ func main() {
 		os.Exit(1)
 	}
 
+	// The metadata of the generated secrets is cached apart, the Secret cache of the manager only has the credentials
+	generatedSecrets, err := cache.New(mgr.GetConfig(), cache.Options{
+		Scheme:               mgr.GetScheme(),
+		Mapper:               mgr.GetRESTMapper(),
+		DefaultLabelSelector: labels.SelectorFromSet(labels.Set{secrets.GeneratedLabel: "true"}),
+	})
+	if err == nil {
+		err = controllers.IndexGeneratedSecrets(context.Background(), generatedSecrets)
+	}
+	if err == nil {
+		err = mgr.Add(generatedSecrets)
+	}
+	if err != nil {
+		setupLog.Error(err, "unable to create the cache of the generated secrets")
+		os.Exit(1)
+	}
+
 	kubeset, err := kubernetes.NewForConfig(mgr.GetConfig())
 	if err != nil {
 		setupLog.Error(err, "unable to connect to kubernetes rest")
@@ -106,12 +172,24 @@ func main() {
 		klog.Warning("IMAGE_URI=" + imageURI + ", because environment variable was not set")
 	}
 
+	// The default jobTemplate of the curator Jobs is read from a ConfigMap of the controller namespace
+	if jobTemplateConfigMap == "" && os.Getenv("POD_NAMESPACE") != "" {
+		jobTemplateConfigMap = os.Getenv("POD_NAMESPACE") + "/" + launcher.JobTemplateConfigMap
+	}
+
 	if err = (&controllers.ClusterCuratorReconciler{
-		Client:   mgr.GetClient(),
-		Kubeset:  kubeset,
-		Log:      ctrl.Log.WithName("controllers").WithName("ClusterCurator"),
-		Scheme:   mgr.GetScheme(),
-		ImageURI: imageURI,
+		Client:                  mgr.GetClient(),
+		Kubeset:                 kubeset,
+		GeneratedSecrets:        generatedSecrets,
+		Log:                     ctrl.Log.WithName("controllers").WithName("ClusterCurator"),
+		Scheme:                  mgr.GetScheme(),
+		ImageURI:                imageURI,
+		JobTemplateConfigMap:    jobTemplateConfigMap,
+		ImagePullTimeout:        imagePullTimeout,
+		Recorder:                mgr.GetEventRecorderFor("clustercurator-controller"),
+		AnsibleJobRetention:     ansibleJobRetention,
+		MaxConcurrentReconciles: maxConcurrentReconciles,
+		MaxRunningJobs:          maxRunningJobsByType,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ClusterCurator")
 		os.Exit(1)
@@ -0,0 +1,316 @@
+// Copyright Contributors to the Open Cluster Management project.
+
+package controllers
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	clustercuratorv1 "github.com/stolostron/cluster-curator-controller/pkg/api/v1beta1"
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/ansible"
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/hive"
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/secrets"
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/utils"
+	managedclusteractionv1beta1 "github.com/stolostron/cluster-lifecycle-api/action/v1beta1"
+	managedclusterviewv1beta1 "github.com/stolostron/cluster-lifecycle-api/view/v1beta1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// Finalizer of the ClusterCurators, the objects of their curations are removed or orphaned before they are deleted
+const CuratorFinalizer = "cluster.open-cluster-management.io/clustercurator-cleanup"
+
+// Reasons of the Event recorded when a ClusterCurator is deleted
+const (
+	ReasonCurationCleanedUp = "CurationCleanedUp"
+	ReasonCurationOrphaned  = "CurationOrphaned"
+)
+
+// The AnsibleJobs of the last curation runs that are kept by default
+const DefaultAnsibleJobRetention = 5
+
+var ansibleJobGVK = schema.GroupVersionKind{Group: "tower.ansible.com", Version: "v1alpha1", Kind: "AnsibleJob"}
+
+// The objects of the curations, the kinds that are not installed on the hub are skipped
+type curationObjectQuery struct {
+	gvk       schema.GroupVersionKind
+	namespace string
+	labels    client.MatchingLabels
+	// Selects the objects of the ClusterCurator among those with the labels, all are selected when nil
+	filter func(curator *clustercuratorv1.ClusterCurator, obj *unstructured.Unstructured) bool
+}
+
+/* finalizeCurator - Deletes the objects of the curations of a deleted ClusterCurator, or keeps them with
+ * the Orphan deletionPolicy. The cloud credentials Hive needs to deprovision the cluster are kept until it is
+ * destroyed. What was done is recorded in an Event, then the finalizer is removed.
+ */
+func (r *ClusterCuratorReconciler) finalizeCurator(ctx context.Context, curator *clustercuratorv1.ClusterCurator) error {
+	if !controllerutil.ContainsFinalizer(curator, CuratorFinalizer) {
+		return nil
+	}
+	log := r.Log.WithValues("clustercurator", types.NamespacedName{Namespace: curator.Namespace, Name: curator.Name})
+
+	objects, err := r.curationObjects(ctx, curator)
+	if err != nil {
+		return err
+	}
+	deprovision, err := r.deprovisionSecrets(ctx, curator)
+	if err != nil {
+		return err
+	}
+
+	orphan := curator.Spec.DeletionPolicy == clustercuratorv1.DeletionPolicyOrphan
+	names, kept := []string{}, []string{}
+	for i := range objects {
+		obj := &objects[i]
+		name := obj.GetKind() + " " + obj.GetNamespace() + "/" + obj.GetName()
+		// The deprovision secrets are kept, even when an earlier version made the ClusterCurator their owner
+		keep := !orphan && obj.GetKind() == "Secret" && deprovision[obj.GetName()]
+		if orphan || keep {
+			err = r.orphanObject(ctx, curator, obj)
+		} else {
+			log.V(2).Info("Deleting " + name)
+			err = r.Delete(ctx, obj, client.PropagationPolicy(v1.DeletePropagationBackground))
+		}
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return err
+		}
+		if keep {
+			kept = append(kept, name)
+		} else {
+			names = append(names, name)
+		}
+	}
+
+	reason, message := ReasonCurationOrphaned, "Orphaned "+strings.Join(names, ", ")
+	if len(names) == 0 {
+		message = "There were no curation objects to orphan"
+	}
+	if !orphan {
+		if err := r.removeRBAC(ctx, curator.Namespace, curator.Name); err != nil {
+			return err
+		}
+		reason, message = ReasonCurationCleanedUp, "Deleted "+strings.Join(append(names, "the curator RBAC"), ", ")
+		if len(kept) > 0 {
+			message += ", kept " + strings.Join(kept, ", ") + " to deprovision the cluster"
+		}
+	}
+	log.V(0).Info(message)
+	if r.Recorder != nil {
+		r.Recorder.Event(curator, corev1.EventTypeNormal, reason, message)
+	}
+
+	if err := r.releaseClusterLock(ctx, curator); err != nil {
+		return err
+	}
+	controllerutil.RemoveFinalizer(curator, CuratorFinalizer)
+	return r.Update(ctx, curator)
+}
+
+/* curationObjects - The curator Jobs, AnsibleJobs and their artifacts, upgrade ManagedClusterViews and
+ * ManagedClusterActions, and generated secrets of the ClusterCurator. The upgrade objects and the secrets
+ * generated from the Provider credential are in the namespace of the managed cluster, named after the
+ * ClusterCurator. The Tower secrets of an external store are in the ClusterCurator namespace.
+ */
+func (r *ClusterCuratorReconciler) curationObjects(
+	ctx context.Context,
+	curator *clustercuratorv1.ClusterCurator) ([]unstructured.Unstructured, error) {
+
+	curatorLabel := client.MatchingLabels{utils.ClusterCuratorLabel: curator.Name}
+	upgradeLabel := client.MatchingLabels{hive.MCVUpgradeLabel: curator.Name}
+	generatedLabel := client.MatchingLabels{secrets.GeneratedLabel: "true"}
+	queries := []curationObjectQuery{
+		{batchv1.SchemeGroupVersion.WithKind("Job"), curator.Namespace, curatorLabel, nil},
+		{ansibleJobGVK, curator.Namespace, curatorLabel, nil},
+		{managedclusterviewv1beta1.SchemeGroupVersion.WithKind("ManagedClusterView"), curator.Name, upgradeLabel, nil},
+		{managedclusteractionv1beta1.SchemeGroupVersion.WithKind("ManagedClusterAction"), curator.Name, upgradeLabel,
+			nil},
+		{corev1.SchemeGroupVersion.WithKind("ConfigMap"), curator.Namespace,
+			client.MatchingLabels{"open-cluster-management": ansible.ARTIFACTS_LABEL}, ownedBy},
+		{corev1.SchemeGroupVersion.WithKind("Secret"), curator.Namespace, generatedLabel, generatedFor},
+	}
+	if curator.Name != curator.Namespace {
+		queries = append(queries, curationObjectQuery{corev1.SchemeGroupVersion.WithKind("Secret"), curator.Name,
+			generatedLabel, generatedFor})
+	}
+
+	objects := []unstructured.Unstructured{}
+	for _, query := range queries {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(query.gvk.GroupVersion().WithKind(query.gvk.Kind + "List"))
+		if err := r.List(ctx, list, client.InNamespace(query.namespace), query.labels); meta.IsNoMatchError(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		sort.Slice(list.Items, func(i, j int) bool { return list.Items[i].GetName() < list.Items[j].GetName() })
+
+		for _, obj := range list.Items {
+			if query.filter != nil && !query.filter(curator, &obj) {
+				continue
+			}
+			obj.SetGroupVersionKind(query.gvk)
+			objects = append(objects, obj)
+		}
+	}
+
+	// The running curator job can predate the label
+	if run := utils.CuratingJob(curator); run != "" && !containsObject(objects, "Job", run) {
+		job := &unstructured.Unstructured{}
+		job.SetGroupVersionKind(batchv1.SchemeGroupVersion.WithKind("Job"))
+		if err := r.Get(ctx, types.NamespacedName{Namespace: curator.Namespace, Name: run}, job); err == nil {
+			objects = append(objects, *job)
+		} else if !k8serrors.IsNotFound(err) {
+			return nil, err
+		}
+	}
+	return objects, nil
+}
+
+// The object is owned by the ClusterCurator
+func ownedBy(curator *clustercuratorv1.ClusterCurator, obj *unstructured.Unstructured) bool {
+	for _, owner := range obj.GetOwnerReferences() {
+		if owner.UID == curator.UID {
+			return true
+		}
+	}
+	return false
+}
+
+// The secret was generated for the ClusterCurator, the namespace of a hosted cluster is shared
+func generatedFor(curator *clustercuratorv1.ClusterCurator, obj *unstructured.Unstructured) bool {
+	for _, name := range secrets.CuratorSecretNames(curator) {
+		if obj.GetName() == name {
+			return true
+		}
+	}
+	return false
+}
+
+func containsObject(objects []unstructured.Unstructured, kind string, name string) bool {
+	for _, obj := range objects {
+		if obj.GetKind() == kind && obj.GetName() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Removes the owner reference to the ClusterCurator, the object is not garbage collected with it
+func (r *ClusterCuratorReconciler) orphanObject(
+	ctx context.Context,
+	curator *clustercuratorv1.ClusterCurator,
+	obj *unstructured.Unstructured) error {
+
+	owners := []v1.OwnerReference{}
+	for _, owner := range obj.GetOwnerReferences() {
+		if owner.UID != curator.UID {
+			owners = append(owners, owner)
+		}
+	}
+	if len(owners) == len(obj.GetOwnerReferences()) {
+		return nil
+	}
+	obj.SetOwnerReferences(owners)
+	return r.Update(ctx, obj)
+}
+
+/* deprovisionSecrets - The generated secrets kept for the deprovision of the cluster, while its
+ * ClusterDeployment or HostedCluster exists. The monitor-destroy step deletes them once it is destroyed.
+ */
+func (r *ClusterCuratorReconciler) deprovisionSecrets(
+	ctx context.Context,
+	curator *clustercuratorv1.ClusterCurator) (map[string]bool, error) {
+
+	cluster := &unstructured.Unstructured{}
+	cluster.SetGroupVersionKind(schema.GroupVersionKind{Group: "hive.openshift.io", Version: "v1",
+		Kind: "ClusterDeployment"})
+	if curator.Name != curator.Namespace {
+		cluster.SetGroupVersionKind(utils.HCGVR.GroupVersion().WithKind("HostedCluster"))
+	}
+	err := r.Get(ctx, types.NamespacedName{Namespace: curator.Namespace, Name: curator.Name}, cluster)
+	if k8serrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+		return map[string]bool{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	names := map[string]bool{}
+	for _, name := range secrets.DeprovisionSecretNames(curator.Name) {
+		names[name] = true
+	}
+	return names, nil
+}
+
+/* pruneAnsibleJobs - Deletes the AnsibleJobs of the ClusterCurator and their artifacts, except those of the
+ * last AnsibleJobRetention curation runs. The runs are ordered by their newest AnsibleJob, the AnsibleJobs
+ * without a curation-run label are kept.
+ */
+func (r *ClusterCuratorReconciler) pruneAnsibleJobs(ctx context.Context, curator *clustercuratorv1.ClusterCurator) error {
+	if r.AnsibleJobRetention <= 0 {
+		return nil
+	}
+
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(ansibleJobGVK.GroupVersion().WithKind(ansibleJobGVK.Kind + "List"))
+	if err := r.List(ctx, list, client.InNamespace(curator.Namespace),
+		client.MatchingLabels{utils.ClusterCuratorLabel: curator.Name},
+		client.HasLabels{utils.CurationRunLabel}); meta.IsNoMatchError(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	runs := map[string][]unstructured.Unstructured{}
+	lastCreated := map[string]v1.Time{}
+	for _, ansibleJob := range list.Items {
+		run := ansibleJob.GetLabels()[utils.CurationRunLabel]
+		runs[run] = append(runs[run], ansibleJob)
+		last, created := lastCreated[run], ansibleJob.GetCreationTimestamp()
+		if last.Before(&created) {
+			lastCreated[run] = created
+		}
+	}
+	if len(runs) <= r.AnsibleJobRetention {
+		return nil
+	}
+
+	names := make([]string, 0, len(runs))
+	for run := range runs {
+		names = append(names, run)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		ti, tj := lastCreated[names[i]], lastCreated[names[j]]
+		if ti.Equal(&tj) {
+			return names[i] > names[j]
+		}
+		return tj.Before(&ti)
+	})
+
+	for _, run := range names[r.AnsibleJobRetention:] {
+		r.Log.V(2).Info("Pruning the AnsibleJobs of the curation run " + run)
+		for i := range runs[run] {
+			ansibleJob := &runs[run][i]
+			ansibleJob.SetGroupVersionKind(ansibleJobGVK)
+			if err := r.Delete(ctx, ansibleJob,
+				client.PropagationPolicy(v1.DeletePropagationBackground)); err != nil && !k8serrors.IsNotFound(err) {
+				return err
+			}
+			artifacts := &corev1.ConfigMap{ObjectMeta: v1.ObjectMeta{
+				Namespace: ansibleJob.GetNamespace(), Name: ansible.GetArtifactsConfigMapName(ansibleJob.GetName())}}
+			if err := r.Delete(ctx, artifacts); err != nil && !k8serrors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
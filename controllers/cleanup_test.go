@@ -0,0 +1,257 @@
+// Copyright Contributors to the Open Cluster Management project.
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	hivev1 "github.com/openshift/hive/apis/hive/v1"
+	ajv1 "github.com/stolostron/cluster-curator-controller/pkg/api/v1alpha1"
+	clustercuratorv1 "github.com/stolostron/cluster-curator-controller/pkg/api/v1beta1"
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/ansible"
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/hive"
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/rbac"
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/utils"
+	managedclusteractionv1beta1 "github.com/stolostron/cluster-lifecycle-api/action/v1beta1"
+	managedclusterviewv1beta1 "github.com/stolostron/cluster-lifecycle-api/view/v1beta1"
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var curatorRequest = reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "my-cluster", Name: "my-cluster"}}
+
+func getDeletedCurator(policy clustercuratorv1.DeletionPolicy) *clustercuratorv1.ClusterCurator {
+	return &clustercuratorv1.ClusterCurator{
+		ObjectMeta: v1.ObjectMeta{Name: "my-cluster", Namespace: "my-cluster", UID: "curator-uid",
+			Finalizers: []string{CuratorFinalizer}, DeletionTimestamp: &v1.Time{Time: podStart}},
+		Spec: clustercuratorv1.ClusterCuratorSpec{DesiredCuration: "upgrade", CuratingJob: "curator-job-running",
+			DeletionPolicy: policy},
+	}
+}
+
+// The objects of the curations of my-cluster, and of another ClusterCurator of the namespace
+func getCurationObjects() []client.Object {
+	curatorLabels := map[string]string{utils.ClusterCuratorLabel: "my-cluster"}
+	upgradeLabels := map[string]string{hive.MCVUpgradeLabel: "my-cluster"}
+	curatorOwner := v1.OwnerReference{APIVersion: clustercuratorv1.GroupVersion.String(), Kind: "ClusterCurator",
+		Name: "my-cluster", UID: "curator-uid"}
+	otherOwner := v1.OwnerReference{APIVersion: clustercuratorv1.GroupVersion.String(), Kind: "ClusterCurator",
+		Name: "other", UID: "other-uid"}
+
+	return []client.Object{
+		&batchv1.Job{ObjectMeta: v1.ObjectMeta{Name: "curator-job-done", Namespace: "my-cluster", Labels: curatorLabels}},
+		&batchv1.Job{ObjectMeta: v1.ObjectMeta{Name: "curator-job-running", Namespace: "my-cluster"}},
+		&batchv1.Job{ObjectMeta: v1.ObjectMeta{Name: "other-job", Namespace: "my-cluster"}},
+		&ajv1.AnsibleJob{ObjectMeta: v1.ObjectMeta{Name: "prehookjob-8dnd2", Namespace: "my-cluster",
+			Labels: curatorLabels}},
+		&managedclusterviewv1beta1.ManagedClusterView{ObjectMeta: v1.ObjectMeta{Name: "my-cluster",
+			Namespace: "my-cluster", Labels: upgradeLabels}},
+		&managedclusteractionv1beta1.ManagedClusterAction{ObjectMeta: v1.ObjectMeta{Name: "my-cluster",
+			Namespace: "my-cluster", Labels: upgradeLabels}},
+		getGeneratedSecret("my-cluster-creds", "my-cluster", "default/aws", curatorOwner),
+		getGeneratedSecret("my-cluster-pull-secret", "my-cluster", "default/aws"),
+		getGeneratedSecret("other-creds", "my-cluster", "default/aws", otherOwner),
+		&corev1.ConfigMap{ObjectMeta: v1.ObjectMeta{Name: "prehookjob-8dnd2-artifacts", Namespace: "my-cluster",
+			Labels:          map[string]string{"open-cluster-management": ansible.ARTIFACTS_LABEL},
+			OwnerReferences: []v1.OwnerReference{curatorOwner}}},
+		&corev1.ConfigMap{ObjectMeta: v1.ObjectMeta{Name: "other-artifacts", Namespace: "my-cluster",
+			Labels: map[string]string{"open-cluster-management": ansible.ARTIFACTS_LABEL}}},
+	}
+}
+
+func getCleanupReconciler(t *testing.T, objects ...client.Object) *ClusterCuratorReconciler {
+	s := runtime.NewScheme()
+	assert.Nil(t, clientgoscheme.AddToScheme(s))
+	assert.Nil(t, clustercuratorv1.AddToScheme(s))
+	assert.Nil(t, ajv1.AddToScheme(s))
+	assert.Nil(t, managedclusterviewv1beta1.AddToScheme(s))
+	assert.Nil(t, managedclusteractionv1beta1.AddToScheme(s))
+	assert.Nil(t, hivev1.AddToScheme(s))
+
+	return &ClusterCuratorReconciler{
+		Client:   clientfake.NewClientBuilder().WithScheme(s).WithStatusSubresource(&clustercuratorv1.ClusterCurator{}).WithObjects(objects...).Build(),
+		Kubeset:  fake.NewSimpleClientset(),
+		Log:      logr.Discard(),
+		Recorder: record.NewFakeRecorder(10),
+	}
+}
+
+func exists(r *ClusterCuratorReconciler, obj client.Object) bool {
+	return !k8serrors.IsNotFound(r.Get(context.TODO(), client.ObjectKeyFromObject(obj), obj))
+}
+
+func TestReconcileAddsFinalizer(t *testing.T) {
+	curator := &clustercuratorv1.ClusterCurator{
+		ObjectMeta: v1.ObjectMeta{Name: "my-cluster", Namespace: "my-cluster"},
+		Spec:       clustercuratorv1.ClusterCuratorSpec{DesiredCuration: "install"},
+	}
+	r := getCleanupReconciler(t, curator)
+
+	_, err := r.Reconcile(context.TODO(), curatorRequest)
+	assert.Nil(t, err)
+
+	assert.Nil(t, r.Get(context.TODO(), curatorRequest.NamespacedName, curator))
+	assert.True(t, controllerutil.ContainsFinalizer(curator, CuratorFinalizer), "the curation records the finalizer")
+	jobs, err := r.Kubeset.BatchV1().Jobs("my-cluster").List(context.TODO(), v1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, jobs.Items, 1)
+}
+
+func TestReconcileIdleCuratorHasNoFinalizer(t *testing.T) {
+	curator := &clustercuratorv1.ClusterCurator{
+		ObjectMeta: v1.ObjectMeta{Name: "my-cluster", Namespace: "my-cluster"},
+	}
+	r := getCleanupReconciler(t, curator)
+
+	_, err := r.Reconcile(context.TODO(), curatorRequest)
+	assert.Nil(t, err)
+
+	assert.Nil(t, r.Get(context.TODO(), curatorRequest.NamespacedName, curator))
+	assert.False(t, controllerutil.ContainsFinalizer(curator, CuratorFinalizer), "there is no curation to do")
+}
+
+func TestReconcileDeletedCuratorCleansUp(t *testing.T) {
+	objects := getCurationObjects()
+	r := getCleanupReconciler(t, append(objects, getDeletedCurator(""))...)
+	_, err := rbac.ApplyCurationRBAC(context.TODO(), r.Kubeset, rbac.CurationUpgrade, "my-cluster", "my-cluster", "", nil)
+	assert.Nil(t, err)
+
+	_, err = r.Reconcile(context.TODO(), curatorRequest)
+	assert.Nil(t, err)
+
+	assert.False(t, exists(r, &clustercuratorv1.ClusterCurator{ObjectMeta: v1.ObjectMeta{Name: "my-cluster",
+		Namespace: "my-cluster"}}), "the finalizer is removed")
+	for _, obj := range objects {
+		switch obj.GetName() {
+		case "other-job", "other-creds", "other-artifacts":
+			assert.True(t, exists(r, obj), "%v is not an object of the ClusterCurator", obj.GetName())
+		default:
+			assert.False(t, exists(r, obj), "%v is deleted", obj.GetName())
+		}
+	}
+	bindings, err := r.Kubeset.RbacV1().RoleBindings("my-cluster").List(context.TODO(), v1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Empty(t, bindings.Items, "the curator RBAC of the namespace is removed with its last ClusterCurator")
+
+	event := <-r.Recorder.(*record.FakeRecorder).Events
+	assert.Contains(t, event, ReasonCurationCleanedUp)
+	assert.Contains(t, event, "Job my-cluster/curator-job-running")
+	assert.Contains(t, event, "AnsibleJob my-cluster/prehookjob-8dnd2")
+	assert.Contains(t, event, "ManagedClusterAction my-cluster/my-cluster")
+	assert.Contains(t, event, "Secret my-cluster/my-cluster-creds", "the cluster is destroyed")
+}
+
+func TestReconcileDeletedCuratorKeepsDeprovisionSecrets(t *testing.T) {
+	objects := getCurationObjects()
+	cluster := &hivev1.ClusterDeployment{ObjectMeta: v1.ObjectMeta{Name: "my-cluster", Namespace: "my-cluster"}}
+	r := getCleanupReconciler(t, append(objects, getDeletedCurator(""), cluster)...)
+
+	_, err := r.Reconcile(context.TODO(), curatorRequest)
+	assert.Nil(t, err)
+
+	secret := &corev1.Secret{}
+	assert.Nil(t, r.Get(context.TODO(), types.NamespacedName{Namespace: "my-cluster", Name: "my-cluster-creds"}, secret),
+		"Hive needs the credentials to deprovision the cluster")
+	assert.Empty(t, secret.OwnerReferences, "the secret is not garbage collected with the ClusterCurator")
+	assert.False(t, exists(r, &corev1.Secret{ObjectMeta: v1.ObjectMeta{Name: "my-cluster-pull-secret",
+		Namespace: "my-cluster"}}), "the other generated secrets are deleted")
+
+	event := <-r.Recorder.(*record.FakeRecorder).Events
+	assert.Contains(t, event, "Secret my-cluster/my-cluster-pull-secret")
+	assert.Contains(t, event, "kept Secret my-cluster/my-cluster-creds to deprovision the cluster")
+}
+
+func TestReconcileDeletedCuratorOrphans(t *testing.T) {
+	objects := getCurationObjects()
+	r := getCleanupReconciler(t, append(objects, getDeletedCurator(clustercuratorv1.DeletionPolicyOrphan))...)
+	_, err := rbac.ApplyCurationRBAC(context.TODO(), r.Kubeset, rbac.CurationUpgrade, "my-cluster", "my-cluster", "", nil)
+	assert.Nil(t, err)
+
+	_, err = r.Reconcile(context.TODO(), curatorRequest)
+	assert.Nil(t, err)
+
+	for _, obj := range objects {
+		assert.True(t, exists(r, obj), "%v is kept", obj.GetName())
+	}
+	secret := &corev1.Secret{}
+	assert.Nil(t, r.Get(context.TODO(), types.NamespacedName{Namespace: "my-cluster", Name: "my-cluster-creds"}, secret))
+	assert.Empty(t, secret.OwnerReferences, "the secret is not garbage collected with the ClusterCurator")
+	artifacts := &corev1.ConfigMap{}
+	assert.Nil(t, r.Get(context.TODO(), types.NamespacedName{Namespace: "my-cluster",
+		Name: "prehookjob-8dnd2-artifacts"}, artifacts))
+	assert.Empty(t, artifacts.OwnerReferences)
+	bindings, err := r.Kubeset.RbacV1().RoleBindings("my-cluster").List(context.TODO(), v1.ListOptions{})
+	assert.Nil(t, err)
+	assert.NotEmpty(t, bindings.Items, "the curator RBAC is kept")
+
+	event := <-r.Recorder.(*record.FakeRecorder).Events
+	assert.Contains(t, event, ReasonCurationOrphaned)
+	assert.Contains(t, event, "Job my-cluster/curator-job-running")
+}
+
+func TestClusterCuratorPredicateDeletion(t *testing.T) {
+	old := getDeletedCurator("")
+	old.DeletionTimestamp = nil
+	old.Operation = &clustercuratorv1.Operation{RetryPosthook: "upgradePosthook"}
+	deleted := old.DeepCopy()
+	deleted.DeletionTimestamp = &v1.Time{Time: podStart}
+
+	assert.True(t, newClusterCuratorPredicate().Update(event.UpdateEvent{ObjectOld: old, ObjectNew: deleted}),
+		"the deletion of a ClusterCurator is reconciled")
+}
+
+func getRunAnsibleJob(name string, curatorName string, run string, created time.Time) *ajv1.AnsibleJob {
+	labels := map[string]string{utils.ClusterCuratorLabel: curatorName}
+	if run != "" {
+		labels[utils.CurationRunLabel] = run
+	}
+	return &ajv1.AnsibleJob{ObjectMeta: v1.ObjectMeta{Name: name, Namespace: "my-cluster", Labels: labels,
+		CreationTimestamp: v1.NewTime(created)}}
+}
+
+func TestPruneAnsibleJobs(t *testing.T) {
+	objects := []client.Object{
+		getRunAnsibleJob("prehookjob-1", "my-cluster", "curator-job-1", podStart),
+		getRunAnsibleJob("posthookjob-1", "my-cluster", "curator-job-1", podStart.Add(time.Minute)),
+		getRunAnsibleJob("prehookjob-2", "my-cluster", "curator-job-2", podStart.Add(time.Hour)),
+		getRunAnsibleJob("prehookjob-3", "my-cluster", "curator-job-3", podStart.Add(2*time.Hour)),
+		getRunAnsibleJob("prehookjob-unlabeled", "my-cluster", "", podStart.Add(-time.Hour)),
+		getRunAnsibleJob("prehookjob-other", "other", "curator-job-0", podStart.Add(-time.Hour)),
+		&corev1.ConfigMap{ObjectMeta: v1.ObjectMeta{Name: ansible.GetArtifactsConfigMapName("prehookjob-1"),
+			Namespace: "my-cluster"}},
+	}
+	r := getCleanupReconciler(t, objects...)
+	curator := &clustercuratorv1.ClusterCurator{ObjectMeta: v1.ObjectMeta{Name: "my-cluster", Namespace: "my-cluster"}}
+
+	assert.Nil(t, r.pruneAnsibleJobs(context.TODO(), curator), "all the AnsibleJobs are kept by default")
+	for _, obj := range objects {
+		assert.True(t, exists(r, obj), "%v is kept", obj.GetName())
+	}
+
+	r.AnsibleJobRetention = 2
+	assert.Nil(t, r.pruneAnsibleJobs(context.TODO(), curator))
+	for _, obj := range objects {
+		switch obj.GetName() {
+		case "prehookjob-1", "posthookjob-1", ansible.GetArtifactsConfigMapName("prehookjob-1"):
+			assert.False(t, exists(r, obj), "%v of the oldest run is pruned", obj.GetName())
+		default:
+			assert.True(t, exists(r, obj), "%v is kept", obj.GetName())
+		}
+	}
+}
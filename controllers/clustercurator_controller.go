@@ -5,22 +5,32 @@ package controllers
 import (
 	"context"
 	"reflect"
+	"sync"
+	"time"
 
 	"github.com/go-logr/logr"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
 	clustercuratorv1 "github.com/stolostron/cluster-curator-controller/pkg/api/v1beta1"
 	"github.com/stolostron/cluster-curator-controller/pkg/controller/launcher"
 	"github.com/stolostron/cluster-curator-controller/pkg/jobs/rbac"
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/secrets"
 	"github.com/stolostron/cluster-curator-controller/pkg/jobs/utils"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 )
 
 const DeleteNamespace = "delete-cluster-namespace"
@@ -32,6 +42,29 @@ type ClusterCuratorReconciler struct {
 	Log      logr.Logger
 	Scheme   *runtime.Scheme
 	ImageURI string
+	// NAMESPACE/NAME of the ConfigMap with the default jobTemplate of the curator Jobs
+	JobTemplateConfigMap string
+	// How long a curator pod can wait on an image pull, DefaultImagePullTimeout when it is not set
+	ImagePullTimeout time.Duration
+	// Records the cleanup of the deleted ClusterCurators
+	Recorder record.EventRecorder
+	// The AnsibleJobs of the last AnsibleJobRetention curation runs of a ClusterCurator are kept, all when 0
+	AnsibleJobRetention int
+	// The ClusterCurators that are reconciled at once, 1 when it is not set
+	MaxConcurrentReconciles int
+	// The curator jobs of a curation type that run at once on the hub, the types that are not set are not capped
+	MaxRunningJobs map[string]int
+
+	// Metadata of the secrets generated by the curator, cached and indexed by IndexGeneratedSecrets
+	GeneratedSecrets client.Reader
+
+	// Guards the ClusterCurators whose generated secrets are re-synced, after an event of their source credential
+	resyncLock sync.Mutex
+	resync     map[types.NamespacedName]bool
+
+	// Guards the admission of the curations, and the curations admitted whose curator job is being created
+	admission sync.Mutex
+	launching map[types.NamespacedName]launch
 }
 
 // +kubebuilder:rbac:groups=cluster.open-cluster-management.io.cluster.open-cluster-management.io,resources=clustercurators,verbs=get;list;watch;create;update;patch;delete
@@ -42,14 +75,22 @@ func (r *ClusterCuratorReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 	log := r.Log.WithValues("clustercurator", req.NamespacedName)
 
 	var curator clustercuratorv1.ClusterCurator
-	if err := r.Get(ctx, req.NamespacedName, &curator); err != nil {
+	if err := r.Get(ctx, req.NamespacedName, &curator); k8serrors.IsNotFound(err) {
 		log.V(2).Info("Resource deleted")
-		return ctrl.Result{}, nil
+		return ctrl.Result{}, r.removeRBAC(ctx, req.Namespace, req.Name)
+	} else if err != nil {
+		return ctrl.Result{}, err
 	}
 
-	if curator.Spec.DesiredCuration == DeleteNamespace {
+	if !curator.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, r.finalizeCurator(ctx, &curator)
+	}
+	// Regenerate the cluster secrets when their source credential was rotated
+	r.resyncGeneratedSecrets(ctx, &curator)
+
+	if namespaceDeletionRequested(&curator) {
 		log.V(0).Info("Deleting namespace " + curator.Namespace)
-		err := utils.DeleteClusterNamespace(r.Kubeset, curator.Namespace)
+		err := utils.DeleteClusterNamespace(ctx, r.Kubeset, curator.Namespace)
 
 		if err != nil {
 			log.V(0).Info(" Deleted namespace ✓ " + curator.Namespace)
@@ -58,44 +99,77 @@ func (r *ClusterCuratorReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 	}
 
 	log.V(3).Info("Reconcile: %v, DesiredCuration: %v, Previous CuratingJob: %v",
-		req.NamespacedName, curator.Spec.DesiredCuration, curator.Spec.CuratingJob)
+		req.NamespacedName, curator.Spec.DesiredCuration, utils.CuratingJob(&curator))
 
-	isPosthookOnly := curator.Operation != nil && curator.Operation.RetryPosthook != ""
+	// An operation re-runs one phase of a curation, it is launched even after a failed curation
+	operation := utils.CurrentOperation(&curator)
 
-	// Curating work has already started OR no curation work supplied curator.Spec.CuratingJob != "" ||
-	if (curator.Spec.CuratingJob != "" || curator.Spec.DesiredCuration == "") && !isPosthookOnly {
+	// Curating work has already started, its Job can terminate without the curator recording a failure
+	if utils.CuratingJob(&curator) != "" && !utils.OperationPending(&curator) {
+		return r.checkCuratorJob(ctx, &curator)
+	}
+
+	// No curation work supplied
+	if curator.Spec.DesiredCuration == "" && operation == nil {
 		log.V(3).Info("No curation to do for %v", req.NamespacedName)
-		return ctrl.Result{}, nil
+		return ctrl.Result{}, r.releaseClusterLock(ctx, &curator)
+	}
+
+	// The curated spec did not change, the desiredCuration is kept with the Generation trigger
+	if utils.GenerationTriggered(&curator) && !utils.CurationPending(&curator) && operation == nil {
+		log.V(3).Info("The spec was curated", "generation", curator.Status.ObservedGeneration)
+		return ctrl.Result{}, r.releaseClusterLock(ctx, &curator)
 	}
 
 	// Override upgrade if there's an operation requested
-	if curator.Spec.DesiredCuration == "upgrade" && !isPosthookOnly {
+	if curator.Spec.DesiredCuration == "upgrade" && operation == nil {
 		needed, err := utils.NeedToUpgrade(curator)
 		if err != nil {
 			return ctrl.Result{}, err
 		}
 		if !needed {
-			return ctrl.Result{}, nil
+			return ctrl.Result{}, r.releaseClusterLock(ctx, &curator)
 		}
 	}
 
-	// Curation flow begins here
-	// Apply RBAC required by the curation job
-	err := rbac.ApplyRBAC(r.Kubeset, req.Namespace)
+	// The phase of the operation is not in its curation, the operation fails without a curator job
+	if operation != nil {
+		if err := launcher.ValidateOperation(&curator); err != nil {
+			log.V(0).Info("The operation can not run", "phase", operation.Phase, "curation", operation.Curation,
+				"error", err.Error())
+			return ctrl.Result{}, r.rejectOperation(ctx, &curator, operation, err)
+		}
+	}
+
+	// Curation flow begins here, once a curator job slot of the curation type is free
+	admitted, err := r.admitCuration(ctx, &curator)
 	if err := utils.LogError(err); err != nil {
 		return ctrl.Result{}, err
 	}
+	if !admitted {
+		return ctrl.Result{RequeueAfter: QueuedRequeueAfter}, nil
+	}
+	created := false
+	defer func() { r.launched(req.NamespacedName, created) }()
 
-	// Hypershift clusters need additional RBAC
+	// The curation creates its objects once the finalizer is recorded, they are removed with the ClusterCurator
+	if controllerutil.AddFinalizer(&curator, CuratorFinalizer) {
+		log.V(2).Info("Adding the finalizer " + CuratorFinalizer)
+		if err := utils.LogError(r.Update(ctx, &curator)); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Hypershift clusters have their own cluster namespace
 	if curator.Name != curator.Namespace {
 		log.V(2).Info("Check if cluster namespace " + curator.Name + " exists")
 		if _, err := r.Kubeset.CoreV1().Namespaces().Get(
-			context.TODO(), curator.Name, v1.GetOptions{}); k8serrors.IsNotFound(err) {
+			ctx, curator.Name, v1.GetOptions{}); k8serrors.IsNotFound(err) {
 			log.V(2).Info("Creating cluster namespace " + curator.Name)
 			clusterNS := &corev1.Namespace{
 				ObjectMeta: v1.ObjectMeta{Name: curator.Name},
 			}
-			_, err = r.Kubeset.CoreV1().Namespaces().Create(context.TODO(), clusterNS, v1.CreateOptions{})
+			_, err = r.Kubeset.CoreV1().Namespaces().Create(ctx, clusterNS, v1.CreateOptions{})
 			if err := utils.LogError(err); err != nil {
 				return ctrl.Result{}, err
 			}
@@ -103,45 +177,188 @@ func (r *ClusterCuratorReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		} else if err != nil {
 			return ctrl.Result{}, err
 		}
+	}
 
-		err = rbac.ApplyRBACHypershift(r.Kubeset, curator.Name, curator.Namespace)
-		if err := utils.LogError(err); err != nil {
-			return ctrl.Result{}, err
-		}
+	// The managed cluster is changed by one curation at a time
+	locked, err := r.acquireClusterLock(ctx, &curator)
+	if err := utils.LogError(err); err != nil {
+		return ctrl.Result{}, err
+	}
+	if !locked {
+		return ctrl.Result{RequeueAfter: ClusterLockRequeueAfter}, nil
+	}
+
+	// Apply RBAC required by the curation job
+	drifted, err := r.applyRBAC(ctx, curator)
+	if err := utils.LogError(err); err != nil {
+		return ctrl.Result{}, err
+	}
+	if len(drifted) > 0 {
+		log.V(0).Info("Reconciled the curator RBAC that drifted", "objects", drifted)
+	}
+
+	if err := utils.LogError(r.pruneAnsibleJobs(ctx, &curator)); err != nil {
+		return ctrl.Result{}, err
 	}
 
 	// Launch the curation job
-	jobLaunch := launcher.NewLauncher(r.Client, r.Kubeset, r.ImageURI, curator)
-	if err := utils.LogError(jobLaunch.CreateJob()); err != nil {
+	jobDefaults, err := launcher.GetJobTemplateDefaults(ctx, r.Kubeset, r.JobTemplateConfigMap)
+	if err := utils.LogError(err); err != nil {
+		return ctrl.Result{}, err
+	}
+	proxy, err := launcher.GetProxyConfig(ctx, r.Client, r.Kubeset)
+	if err := utils.LogError(err); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := utils.LogError(launcher.ApplyTrustedCABundle(ctx, r.Kubeset, curator.Namespace, proxy)); err != nil {
+		return ctrl.Result{}, err
+	}
+	// The operation is running until the curator records its result
+	if operation != nil {
+		curator.Status.LastOperation = utils.NewOperationStatus(operation, clustercuratorv1.OperationRunning)
+		if err := utils.LogError(r.Status().Update(ctx, &curator)); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	jobLaunch := launcher.NewLauncher(r.Client, r.Kubeset, r.ImageURI, curator).
+		WithJobTemplateDefaults(jobDefaults).
+		WithProxy(proxy)
+	if err := utils.LogError(jobLaunch.CreateJob(ctx)); err != nil {
 		return ctrl.Result{}, err
 	}
+	created = true
 
 	return ctrl.Result{}, nil
 }
 
+/* applyRBAC - The curator Job runs with the least privileged service account of its curation type. An
+ * overrideJob runs with the cluster-installer service account, bound to the curator ClusterRole and to the
+ * secrets generated for its cluster. The hooks can not create secrets, the Tower secrets of the
+ * towerAuthSecrets kept in an external store are reserved for them.
+ */
+func (r *ClusterCuratorReconciler) applyRBAC(ctx context.Context, curator clustercuratorv1.ClusterCurator) ([]string, error) {
+	if err := secrets.ReserveTowerSecrets(ctx, r.Kubeset, &curator); err != nil {
+		return []string{}, err
+	}
+	if curator.Spec.Install.OverrideJob == nil {
+		curationType := rbac.CurationType(curator.Spec.DesiredCuration)
+		if op := utils.CurrentOperation(&curator); op != nil {
+			curationType = rbac.PhaseCurationType(op.Curation, op.Phase)
+		}
+		return rbac.ApplyCurationRBAC(ctx, r.Kubeset, curationType, curator.Name, curator.Namespace,
+			curator.Spec.ProviderCredentialPath, secrets.CuratorSecretNames(&curator))
+	}
+
+	drifted, err := rbac.ApplyRBAC(ctx, r.Kubeset, curator.Namespace)
+	if err != nil {
+		return drifted, err
+	}
+	secretsDrifted, err := rbac.ApplyGeneratedSecretsRBAC(ctx, r.Kubeset, curator.Name, curator.Namespace,
+		secrets.CuratorSecretNames(&curator))
+	drifted = append(drifted, secretsDrifted...)
+	if err != nil || curator.Name == curator.Namespace {
+		return drifted, err
+	}
+	// Hypershift clusters need additional RBAC
+	hypershiftDrifted, err := rbac.ApplyRBACHypershift(ctx, r.Kubeset, curator.Name, curator.Namespace)
+	return append(drifted, hypershiftDrifted...), err
+}
+
+// rejectOperation - Records the failure of an operation that can not run, a retryPosthook is removed
+func (r *ClusterCuratorReconciler) rejectOperation(
+	ctx context.Context,
+	curator *clustercuratorv1.ClusterCurator,
+	operation *clustercuratorv1.Operation,
+	failure error) error {
+
+	now := v1.Now()
+	curator.Status.LastOperation = utils.NewOperationStatus(operation, clustercuratorv1.OperationFailed)
+	curator.Status.LastOperation.Message = failure.Error()
+	curator.Status.LastOperation.CompletionTime = &now
+	if operation.RetryPosthook != "" {
+		curator.Operation = nil
+		return utils.UpdateClusterCurator(ctx, r.Client, curator)
+	}
+	return r.Status().Update(ctx, curator)
+}
+
+/* removeRBAC - Removes the curator RoleBindings of a deleted ClusterCurator. The RoleBinding of its
+ * namespace is shared by the ClusterCurators of the namespace, it is removed with the last one.
+ */
+func (r *ClusterCuratorReconciler) removeRBAC(ctx context.Context, namespace string, name string) error {
+	if err := rbac.RemoveCurationRBAC(ctx, r.Kubeset, name); err != nil {
+		return utils.LogError(err)
+	}
+	if name != namespace {
+		if err := rbac.RemoveRBACHypershift(ctx, r.Kubeset, name); err != nil {
+			return utils.LogError(err)
+		}
+	}
+
+	curators := &clustercuratorv1.ClusterCuratorList{}
+	if err := r.List(ctx, curators, client.InNamespace(namespace)); err != nil {
+		return err
+	}
+	for _, curator := range curators.Items {
+		// The ClusterCurators being deleted wait on their finalizer
+		if curator.DeletionTimestamp.IsZero() {
+			return nil
+		}
+	}
+	r.Log.V(0).Info("Removing the curator RBAC of namespace " + namespace)
+	return utils.LogError(rbac.RemoveRBAC(ctx, r.Kubeset, namespace))
+}
+
 func (r *ClusterCuratorReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&clustercuratorv1.ClusterCurator{}).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.curatorsForCredential)).
+		Watches(&batchv1.Job{}, handler.EnqueueRequestsFromMapFunc(r.curatorsForJob)).
+		Watches(&corev1.Pod{}, handler.EnqueueRequestsFromMapFunc(r.curatorsForJob)).
 		WithEventFilter(newClusterCuratorPredicate()).
+		WithOptions(controller.Options{MaxConcurrentReconciles: r.MaxConcurrentReconciles}).
 		Complete(r)
 }
 
+/* namespaceDeletionRequested - The delete-cluster-namespace step of a destroy sets the desiredCuration to
+ * delete-cluster-namespace. With the Generation trigger, the spec is not changed, the step condition is
+ * recorded once the destroy succeeded.
+ */
+func namespaceDeletionRequested(curator *clustercuratorv1.ClusterCurator) bool {
+	if curator.Spec.DesiredCuration == DeleteNamespace {
+		return true
+	}
+	completed := curator.Status.LastCompletedCuration
+	return utils.GenerationTriggered(curator) && completed != nil && completed.Curation == "destroy" &&
+		completed.Result == clustercuratorv1.CurationSucceeded &&
+		meta.IsStatusConditionTrue(curator.Status.Conditions, DeleteNamespace)
+}
+
 func newClusterCuratorPredicate() predicate.Predicate {
 	return predicate.Funcs{
 		UpdateFunc: func(e event.UpdateEvent) bool {
 			newClusterCurator, okNew := e.ObjectNew.(*clustercuratorv1.ClusterCurator)
 			oldClusterCurator, okOld := e.ObjectOld.(*clustercuratorv1.ClusterCurator)
 			if okNew && okOld {
+				if !newClusterCurator.DeletionTimestamp.IsZero() {
+					return true
+				}
 				if !reflect.DeepEqual(newClusterCurator.Status, oldClusterCurator.Status) {
 					return false
 				}
 				if newClusterCurator.Spec.DesiredCuration == DeleteNamespace {
 					return true
 				}
-				if (newClusterCurator.Operation != nil && oldClusterCurator.Operation != nil) && (newClusterCurator.Operation.RetryPosthook == oldClusterCurator.Operation.RetryPosthook) {
+				// The spec is not changed by the controller, a new generation is a change of the user
+				if utils.GenerationTriggered(newClusterCurator) {
+					return newClusterCurator.Generation != oldClusterCurator.Generation
+				}
+				if (newClusterCurator.Operation != nil && oldClusterCurator.Operation != nil) && (newClusterCurator.Operation.RetryPosthook == oldClusterCurator.Operation.RetryPosthook) && newClusterCurator.Operation.RetryPosthook != "" {
 					return false
 				}
-				if newClusterCurator.Operation != nil && newClusterCurator.Operation.RetryPosthook != "" {
+				// A new operation, or a new nonce of the operation, runs its phase again
+				if utils.RequestedOperation(newClusterCurator) != nil &&
+					!reflect.DeepEqual(newClusterCurator.Operation, oldClusterCurator.Operation) {
 					return true
 				}
 				if newClusterCurator.Spec.DesiredCuration != oldClusterCurator.Spec.DesiredCuration && newClusterCurator.Spec.DesiredCuration == "" {
@@ -0,0 +1,262 @@
+// Copyright Contributors to the Open Cluster Management project.
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	clustercuratorv1 "github.com/stolostron/cluster-curator-controller/pkg/api/v1beta1"
+	"github.com/stolostron/cluster-curator-controller/pkg/controller/launcher"
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/rbac"
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/secrets"
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/utils"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func getGeneratedSecret(name string, namespace string, source string, owners ...v1.OwnerReference) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				secrets.GeneratedLabel: "true",
+				secrets.SourceLabel:    secrets.SourceLabelValue(source),
+			},
+			OwnerReferences: owners,
+		},
+	}
+}
+
+func TestCuratorsForCredential(t *testing.T) {
+	s := runtime.NewScheme()
+	assert.Nil(t, clustercuratorv1.AddToScheme(s))
+	assert.Nil(t, corev1.AddToScheme(s))
+
+	generated := clientfake.NewClientBuilder().WithScheme(s).
+		WithIndex(generatedSecretMeta(), GeneratedSecretSourceIndex, IndexGeneratedSecretSource).
+		WithRuntimeObjects(
+			getGeneratedSecret("cluster-a-creds", "cluster-a", "default/aws"),
+			getGeneratedSecret("cluster-a-pull-secret", "cluster-a", "default/aws"),
+			getGeneratedSecret("hosted-a-creds", "clusters", "default/aws"),
+			getGeneratedSecret("cluster-b-creds", "cluster-b", "default/gcp"),
+		).Build()
+	r := &ClusterCuratorReconciler{
+		Client: clientfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(
+			&clustercuratorv1.ClusterCurator{ObjectMeta: v1.ObjectMeta{Name: "cluster-a", Namespace: "cluster-a"}},
+			&clustercuratorv1.ClusterCurator{ObjectMeta: v1.ObjectMeta{Name: "hosted-a", Namespace: "clusters"}},
+			&clustercuratorv1.ClusterCurator{ObjectMeta: v1.ObjectMeta{Name: "hosted-b", Namespace: "clusters"}},
+			&clustercuratorv1.ClusterCurator{ObjectMeta: v1.ObjectMeta{Name: "cluster-b", Namespace: "cluster-b"}},
+		).Build(),
+		GeneratedSecrets: generated,
+		Log:              logr.Discard(),
+	}
+
+	credential := &corev1.Secret{ObjectMeta: v1.ObjectMeta{Name: "aws", Namespace: "default"}}
+	requests := r.curatorsForCredential(context.TODO(), credential)
+
+	assert.ElementsMatch(t, []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Namespace: "cluster-a", Name: "cluster-a"}},
+		{NamespacedName: types.NamespacedName{Namespace: "clusters", Name: "hosted-a"}},
+		{NamespacedName: types.NamespacedName{Namespace: "clusters", Name: "hosted-b"}},
+	}, requests, "one request per ClusterCurator of the namespaces using the credential")
+
+	assert.True(t, r.takeResync(types.NamespacedName{Namespace: "cluster-a", Name: "cluster-a"}),
+		"the credential event requests the re-sync")
+	assert.False(t, r.takeResync(types.NamespacedName{Namespace: "cluster-a", Name: "cluster-a"}),
+		"the re-sync request is consumed")
+	assert.False(t, r.takeResync(types.NamespacedName{Namespace: "cluster-b", Name: "cluster-b"}),
+		"the curators of the other credentials are not re-synced")
+}
+
+func TestReconcileDeletedCuratorRemovesRBAC(t *testing.T) {
+	s := runtime.NewScheme()
+	assert.Nil(t, clustercuratorv1.AddToScheme(s))
+
+	remaining := &clustercuratorv1.ClusterCurator{ObjectMeta: v1.ObjectMeta{Name: "hosted-b", Namespace: "clusters"}}
+	r := &ClusterCuratorReconciler{
+		Client:  clientfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(remaining).Build(),
+		Kubeset: fake.NewSimpleClientset(),
+		Log:     logr.Discard(),
+	}
+	for _, hosted := range []string{"hosted-a", "hosted-b"} {
+		_, err := rbac.ApplyRBAC(context.TODO(), r.Kubeset, "clusters")
+		assert.Nil(t, err)
+		_, err = rbac.ApplyRBACHypershift(context.TODO(), r.Kubeset, hosted, "clusters")
+		assert.Nil(t, err)
+	}
+
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{
+		NamespacedName: types.NamespacedName{Namespace: "clusters", Name: "hosted-a"}})
+	assert.Nil(t, err)
+
+	_, err = r.Kubeset.RbacV1().RoleBindings("hosted-a").Get(context.TODO(), "curator", v1.GetOptions{})
+	assert.True(t, k8serrors.IsNotFound(err), "the hosted cluster RoleBinding is removed")
+	_, err = r.Kubeset.RbacV1().RoleBindings("clusters").Get(context.TODO(), "curator", v1.GetOptions{})
+	assert.Nil(t, err, "the namespace RoleBinding is kept for hosted-b")
+
+	assert.Nil(t, r.Delete(context.TODO(), remaining))
+	_, err = r.Reconcile(context.TODO(), reconcile.Request{
+		NamespacedName: types.NamespacedName{Namespace: "clusters", Name: "hosted-b"}})
+	assert.Nil(t, err)
+
+	_, err = r.Kubeset.RbacV1().RoleBindings("clusters").Get(context.TODO(), "curator", v1.GetOptions{})
+	assert.True(t, k8serrors.IsNotFound(err), "the namespace RoleBinding is removed with the last ClusterCurator")
+	_, err = r.Kubeset.RbacV1().ClusterRoleBindings().Get(context.TODO(), "curator-crb", v1.GetOptions{})
+	assert.True(t, k8serrors.IsNotFound(err))
+}
+
+func getGenerationCurator(generation int64) *clustercuratorv1.ClusterCurator {
+	curator := getInstallCurator("my-cluster", 0)
+	curator.Generation = generation
+	curator.Spec.CurationTrigger = clustercuratorv1.CurationTriggerGeneration
+	return curator
+}
+
+func TestReconcileGenerationTrigger(t *testing.T) {
+	r := getCleanupReconciler(t, getGenerationCurator(1))
+
+	_, curator := reconcileCurator(t, r, "my-cluster")
+	assert.Equal(t, "install", curator.Spec.DesiredCuration, "the spec is not changed")
+	assert.Empty(t, curator.Spec.CuratingJob)
+	assert.Equal(t, int64(1), curator.Status.ObservedGeneration)
+	assert.Equal(t, utils.SpecHash(curator), curator.Status.ObservedSpecHash)
+
+	// The curation is done, its status changes increment the generation
+	curator.Generation = 4
+	curator.Status.LastCompletedCuration = &clustercuratorv1.CompletedCuration{Curation: "install", Generation: 1,
+		Result: clustercuratorv1.CurationSucceeded, CompletionTime: v1.Now()}
+	assert.Nil(t, r.Update(context.TODO(), curator))
+	_, curator = reconcileCurator(t, r, "my-cluster")
+	jobs, err := r.Kubeset.BatchV1().Jobs("my-cluster").List(context.TODO(), v1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, jobs.Items, 1, "the curated spec is not curated again")
+
+	// A new generation of the spec is curated, the fake clientset does not generate the job names
+	assert.Nil(t, r.Kubeset.BatchV1().Jobs("my-cluster").Delete(context.TODO(), jobs.Items[0].Name,
+		v1.DeleteOptions{}))
+	curator.Generation = 5
+	curator.Spec.Install.Prehook = []clustercuratorv1.Hook{{Name: "Service now App Update"}}
+	assert.Nil(t, r.Update(context.TODO(), curator))
+	_, curator = reconcileCurator(t, r, "my-cluster")
+	jobs, err = r.Kubeset.BatchV1().Jobs("my-cluster").List(context.TODO(), v1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, jobs.Items, 1)
+	assert.Equal(t, int64(5), curator.Status.ObservedGeneration)
+}
+
+func TestReconcileDeletesNamespaceAfterGenerationDestroy(t *testing.T) {
+	curator := getGenerationCurator(3)
+	curator.Spec.DesiredCuration = "destroy"
+	curator.Status.LastCompletedCuration = &clustercuratorv1.CompletedCuration{Curation: "destroy", Generation: 3,
+		Result: clustercuratorv1.CurationSucceeded, CompletionTime: v1.Now()}
+	meta.SetStatusCondition(&curator.Status.Conditions, v1.Condition{Type: DeleteNamespace,
+		Status: v1.ConditionTrue, Reason: utils.JobHasFinished, Message: "Completed executing init container"})
+	r := getCleanupReconciler(t, curator)
+	r.Kubeset = fake.NewSimpleClientset(&corev1.Namespace{ObjectMeta: v1.ObjectMeta{Name: "my-cluster"}})
+
+	reconcileCurator(t, r, "my-cluster")
+	_, err := r.Kubeset.CoreV1().Namespaces().Get(context.TODO(), "my-cluster", v1.GetOptions{})
+	assert.True(t, k8serrors.IsNotFound(err), "the cluster namespace is deleted")
+}
+
+func TestClusterCuratorPredicateGenerationTrigger(t *testing.T) {
+	old := getGenerationCurator(1)
+	updated := old.DeepCopy()
+	updated.Labels = map[string]string{"owner": "gitops"}
+	assert.False(t, newClusterCuratorPredicate().Update(event.UpdateEvent{ObjectOld: old, ObjectNew: updated}))
+
+	updated.Generation = 2
+	updated.Spec.DesiredCuration = "upgrade"
+	assert.True(t, newClusterCuratorPredicate().Update(event.UpdateEvent{ObjectOld: old, ObjectNew: updated}))
+
+	updated = old.DeepCopy()
+	updated.Generation = 2
+	updated.Status.CuratorJob = "curator-job-ABCDE"
+	assert.False(t, newClusterCuratorPredicate().Update(event.UpdateEvent{ObjectOld: old, ObjectNew: updated}),
+		"the curation is tracked in the status")
+}
+
+func TestReconcileOperation(t *testing.T) {
+	curator := getInstallCurator("my-cluster", 0)
+	curator.Spec.DesiredCuration = ""
+	curator.Operation = &clustercuratorv1.Operation{Action: clustercuratorv1.OperationRerun,
+		Phase: launcher.MonImport, Curation: "install", Nonce: "1"}
+	r := getCleanupReconciler(t, curator)
+
+	_, curator = reconcileCurator(t, r, "my-cluster")
+	jobs, err := r.Kubeset.BatchV1().Jobs("my-cluster").List(context.TODO(), v1.ListOptions{})
+	assert.Nil(t, err)
+	if assert.Len(t, jobs.Items, 1) {
+		assert.Equal(t, launcher.MonImport, jobs.Items[0].Spec.Template.Spec.InitContainers[0].Name)
+	}
+	if assert.NotNil(t, curator.Status.LastOperation) {
+		assert.Equal(t, "1", curator.Status.LastOperation.Nonce)
+		assert.Equal(t, clustercuratorv1.OperationRunning, curator.Status.LastOperation.Result)
+	}
+
+	// The curator records the result, the nonce is not run again
+	assert.Nil(t, utils.RecordOperationResult(r.Client, curator, clustercuratorv1.OperationSucceeded))
+	_, curator = reconcileCurator(t, r, "my-cluster")
+	assert.Equal(t, clustercuratorv1.OperationSucceeded, curator.Status.LastOperation.Result)
+	jobs, err = r.Kubeset.BatchV1().Jobs("my-cluster").List(context.TODO(), v1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, jobs.Items, 1)
+
+	// A new nonce runs the phase again, the fake clientset does not generate the job names
+	assert.Nil(t, r.Kubeset.BatchV1().Jobs("my-cluster").Delete(context.TODO(), jobs.Items[0].Name,
+		v1.DeleteOptions{}))
+	curator.Operation.Nonce = "2"
+	assert.Nil(t, r.Update(context.TODO(), curator))
+	_, curator = reconcileCurator(t, r, "my-cluster")
+	jobs, err = r.Kubeset.BatchV1().Jobs("my-cluster").List(context.TODO(), v1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, jobs.Items, 1)
+	assert.Equal(t, "2", curator.Status.LastOperation.Nonce)
+	assert.Equal(t, clustercuratorv1.OperationRunning, curator.Status.LastOperation.Result)
+}
+
+func TestReconcileOperationInvalidPhase(t *testing.T) {
+	curator := getInstallCurator("my-cluster", 0)
+	curator.Operation = &clustercuratorv1.Operation{Action: clustercuratorv1.OperationRerun,
+		Phase: utils.PosthookPhase, Curation: "install", Nonce: "1"}
+	r := getCleanupReconciler(t, curator)
+
+	_, curator = reconcileCurator(t, r, "my-cluster")
+	jobs, err := r.Kubeset.BatchV1().Jobs("my-cluster").List(context.TODO(), v1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Empty(t, jobs.Items, "no curator job runs the operation")
+	if assert.NotNil(t, curator.Status.LastOperation) {
+		assert.Equal(t, clustercuratorv1.OperationFailed, curator.Status.LastOperation.Result)
+		assert.Contains(t, curator.Status.LastOperation.Message, "no posthook")
+	}
+	assert.Nil(t, utils.CurrentOperation(curator))
+}
+
+func TestClusterCuratorPredicateOperation(t *testing.T) {
+	old := getInstallCurator("my-cluster", 0)
+	old.Operation = &clustercuratorv1.Operation{Action: clustercuratorv1.OperationRerun,
+		Phase: launcher.MonImport, Curation: "install", Nonce: "1"}
+	updated := old.DeepCopy()
+	updated.Labels = map[string]string{"owner": "gitops"}
+	assert.True(t, newClusterCuratorPredicate().Update(event.UpdateEvent{ObjectOld: old, ObjectNew: updated}))
+
+	updated.Spec.DesiredCuration = ""
+	assert.False(t, newClusterCuratorPredicate().Update(event.UpdateEvent{ObjectOld: old, ObjectNew: updated}),
+		"the curation is done")
+
+	updated.Operation.Nonce = "2"
+	assert.True(t, newClusterCuratorPredicate().Update(event.UpdateEvent{ObjectOld: old, ObjectNew: updated}),
+		"a new nonce runs the phase again")
+}
@@ -0,0 +1,294 @@
+// Copyright Contributors to the Open Cluster Management project.
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	clustercuratorv1 "github.com/stolostron/cluster-curator-controller/pkg/api/v1beta1"
+	"github.com/stolostron/cluster-curator-controller/pkg/controller/launcher"
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/utils"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// How long a curator pod can wait on an image pull before the curation is failed
+const DefaultImagePullTimeout = 5 * time.Minute
+
+// Condition of the curator job, the curator records the failed curations in it
+const curatorJobCondition = "clustercurator-job"
+
+// The Job controller labels the pods of a Job with its name
+const jobNameLabel = "job-name"
+
+// Waiting reasons of a container whose image can not be pulled
+var imagePullReasons = map[string]bool{
+	"ErrImagePull":      true,
+	"ImagePullBackOff":  true,
+	"InvalidImageName":  true,
+	"ErrImageNeverPull": true,
+}
+
+/* checkCuratorJob - Fails the curation when its Job terminated without the curator recording the failure,
+ * the curator can not record an OOM kill, an eviction, an image that is never pulled or an exceeded
+ * activeDeadlineSeconds. A Job that was deleted also fails the curation. The bookkeeping is cleared the way
+ * the curator does for a failed step.
+ */
+func (r *ClusterCuratorReconciler) checkCuratorJob(ctx context.Context, curator *clustercuratorv1.ClusterCurator) (ctrl.Result, error) {
+	log := r.Log.WithValues("clustercurator", types.NamespacedName{Namespace: curator.Namespace, Name: curator.Name})
+
+	job := &batchv1.Job{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: curator.Namespace, Name: utils.CuratingJob(curator)},
+		job); k8serrors.IsNotFound(err) {
+		return r.checkDeletedCuratorJob(ctx, curator)
+	} else if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(job.Namespace), client.MatchingLabels{jobNameLabel: job.Name}); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	imagePullTimeout := r.ImagePullTimeout
+	if imagePullTimeout == 0 {
+		imagePullTimeout = DefaultImagePullTimeout
+	}
+	step, requeueAfter, failure := jobFailure(job, pods.Items, time.Now(), imagePullTimeout)
+	if failure == nil {
+		// The lease of the managed cluster is renewed while the job runs
+		if lockedCurations[launcher.CurationType(curator)] && getJobCondition(job, batchv1.JobComplete) == nil {
+			if err := r.renewClusterLock(ctx, curator); err != nil {
+				return ctrl.Result{}, err
+			}
+			if requeueAfter == 0 || requeueAfter > clusterLockRenewInterval {
+				requeueAfter = clusterLockRenewInterval
+			}
+		}
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
+	}
+	// The curator recorded the failure of the job before it exited
+	if cond := meta.FindStatusCondition(curator.Status.Conditions, curatorJobCondition); cond != nil &&
+		cond.Status == v1.ConditionTrue && !cond.LastTransitionTime.Before(&job.CreationTimestamp) {
+		return ctrl.Result{}, nil
+	}
+
+	log.V(0).Info("The curator job terminated abnormally", "job", job.Name, "step", step,
+		"reason", utils.ReasonForError(failure), "message", failure.Error())
+	if err := r.failCuration(ctx, curator, job, step, failure); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, r.releaseClusterLock(ctx, curator)
+}
+
+/* checkDeletedCuratorJob - The cache only has the Jobs with the curator job label, a Job missing from it is
+ * read from the API server. A Job that no longer exists can not complete the curation, it is failed so the
+ * curatorJob is cleared and the lease of the managed cluster is released.
+ */
+func (r *ClusterCuratorReconciler) checkDeletedCuratorJob(
+	ctx context.Context,
+	curator *clustercuratorv1.ClusterCurator) (ctrl.Result, error) {
+
+	log := r.Log.WithValues("clustercurator", types.NamespacedName{Namespace: curator.Namespace, Name: curator.Name})
+	name := utils.CuratingJob(curator)
+	if _, err := r.Kubeset.BatchV1().Jobs(curator.Namespace).Get(ctx, name, v1.GetOptions{}); err == nil {
+		log.V(3).Info("The curator job is not watched", "job", name)
+		return ctrl.Result{}, nil
+	} else if !k8serrors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+
+	failure := utils.NewError(utils.ReasonJobDeleted, "The curator job %v was deleted before the curation completed",
+		name)
+	log.V(0).Info("The curator job was deleted", "job", name, "reason", utils.ReasonForError(failure))
+	job := &batchv1.Job{ObjectMeta: v1.ObjectMeta{Name: name, Namespace: curator.Namespace}}
+	if err := r.failCuration(ctx, curator, job, "", failure); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, r.releaseClusterLock(ctx, curator)
+}
+
+// Records the failure on the step and the curator job conditions, stops the Job and clears the bookkeeping
+func (r *ClusterCuratorReconciler) failCuration(
+	ctx context.Context,
+	curator *clustercuratorv1.ClusterCurator,
+	job *batchv1.Job,
+	step string,
+	failure error) error {
+
+	if step != "" {
+		if err := utils.RecordFailedCuratorStatusError(r.Client, curator.Name, curator.Namespace, step,
+			failure); err != nil {
+			return err
+		}
+	}
+
+	message := job.Name + " DesiredCuration: " + curator.Spec.DesiredCuration
+	if curator.Spec.DesiredCuration == "upgrade" {
+		message = message + " Version (" + utils.GetCurrentVersionInfo(curator) + ")"
+	}
+	if err := utils.RecordFailedCuratorStatusError(r.Client, curator.Name, curator.Namespace, curatorJobCondition,
+		utils.NewError(utils.ReasonForError(failure), "%v Failed - %v", message, failure.Error())); err != nil {
+		return err
+	}
+
+	// A pod waiting on its image would keep the Job running
+	if !isJobFinished(job) {
+		propagation := v1.DeletePropagationBackground
+		if err := r.Kubeset.BatchV1().Jobs(job.Namespace).Delete(ctx, job.Name,
+			v1.DeleteOptions{PropagationPolicy: &propagation}); err != nil && !k8serrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return utils.UpdateFailingClusterCurator(r.Client, curator)
+}
+
+/* jobFailure - Returns the failure of the curator job and the step it failed in, the step is empty when
+ * it is not known. A job still waiting on an image pull is re-checked after requeueAfter. The job runs its
+ * pod again after an eviction or a transient API error, the pods only tell why the job failed.
+ */
+func jobFailure(
+	job *batchv1.Job,
+	pods []corev1.Pod,
+	now time.Time,
+	imagePullTimeout time.Duration) (step string, requeueAfter time.Duration, failure error) {
+
+	// A pod that can not pull its image does not fail, the job waits on it
+	for _, pod := range pods {
+		for _, status := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+			waiting := status.State.Waiting
+			if waiting == nil || !imagePullReasons[waiting.Reason] {
+				continue
+			}
+			since := pod.CreationTimestamp.Time
+			if pod.Status.StartTime != nil {
+				since = pod.Status.StartTime.Time
+			}
+			if remaining := since.Add(imagePullTimeout).Sub(now); remaining > 0 {
+				if requeueAfter == 0 || remaining < requeueAfter {
+					requeueAfter = remaining
+				}
+				continue
+			}
+			return stepForContainer(pod, status.Name), 0, utils.NewError(utils.ReasonImagePullBackOff,
+				"The image %v of the curator container %v was not pulled in %v: %v", status.Image, status.Name,
+				imagePullTimeout, waiting.Message)
+		}
+	}
+
+	failed := getJobCondition(job, batchv1.JobFailed)
+	if failed == nil {
+		return "", requeueAfter, nil
+	}
+	if failed.Reason == batchv1.JobReasonDeadlineExceeded {
+		return "", 0, utils.NewError(utils.ReasonDeadlineExceeded,
+			"The curator job %v exceeded its activeDeadlineSeconds: %v", job.Name, failed.Message)
+	}
+
+	for _, pod := range pods {
+		for _, status := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+			if terminated := status.State.Terminated; terminated != nil && terminated.Reason == "OOMKilled" {
+				return stepForContainer(pod, status.Name), 0, utils.NewError(utils.ReasonOOMKilled,
+					"The curator container %v was OOMKilled, raise its memory limit with spec.jobTemplate.resources",
+					status.Name)
+			}
+		}
+	}
+
+	for _, pod := range pods {
+		if pod.Status.Reason == "Evicted" {
+			return runningStep(pod), 0, utils.NewError(utils.ReasonEvicted,
+				"The curator pod %v was evicted: %v", pod.Name, pod.Status.Message)
+		}
+		for _, cond := range pod.Status.Conditions {
+			if cond.Type == corev1.DisruptionTarget && cond.Status == corev1.ConditionTrue {
+				return runningStep(pod), 0, utils.NewError(utils.ReasonEvicted,
+					"The curator pod %v was disrupted (%v): %v", pod.Name, cond.Reason, cond.Message)
+			}
+		}
+	}
+
+	return "", 0, utils.NewError(utils.ReasonJobFailed, "The curator job %v failed (%v): %v", job.Name,
+		failed.Reason, failed.Message)
+}
+
+func getJobCondition(job *batchv1.Job, conditionType batchv1.JobConditionType) *batchv1.JobCondition {
+	for i := range job.Status.Conditions {
+		if job.Status.Conditions[i].Type == conditionType && job.Status.Conditions[i].Status == corev1.ConditionTrue {
+			return &job.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+func isJobFinished(job *batchv1.Job) bool {
+	return getJobCondition(job, batchv1.JobComplete) != nil || getJobCondition(job, batchv1.JobFailed) != nil
+}
+
+// The step of the first container of the pod that did not complete
+func runningStep(pod corev1.Pod) string {
+	for _, status := range append(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses...) {
+		if status.State.Terminated == nil || status.State.Terminated.ExitCode != 0 {
+			return stepForContainer(pod, status.Name)
+		}
+	}
+	return ""
+}
+
+// The curator steps record their condition with the step of their command, an upgrade runs a step twice
+func stepForContainer(pod corev1.Pod, containerName string) string {
+	for _, container := range append(pod.Spec.InitContainers, pod.Spec.Containers...) {
+		if container.Name != containerName {
+			continue
+		}
+		if len(container.Command) > 1 && container.Command[0] == launcher.CurCmd {
+			return container.Command[1]
+		}
+		return container.Name
+	}
+	return containerName
+}
+
+/* curatorsForJob - Maps a curator Job, or one of its pods, to the ClusterCurator curating with it. Only
+ * the labeled curator jobs are watched. A finished Job is also mapped to its ClusterCurator once the curation
+ * is done, to release the cluster lock, and to the curations queued for its slot.
+ */
+func (r *ClusterCuratorReconciler) curatorsForJob(ctx context.Context, obj client.Object) []reconcile.Request {
+	jobName := obj.GetName()
+	if _, ok := obj.(*corev1.Pod); ok {
+		jobName = obj.GetLabels()[jobNameLabel]
+	}
+	if jobName == "" {
+		return nil
+	}
+
+	curators := &clustercuratorv1.ClusterCuratorList{}
+	if err := r.List(ctx, curators, client.InNamespace(obj.GetNamespace())); err != nil {
+		r.Log.Error(err, "Unable to list the ClusterCurators of the curator job", "job", jobName)
+		return nil
+	}
+
+	requests := []reconcile.Request{}
+	finished := false
+	if job, ok := obj.(*batchv1.Job); ok {
+		requests = r.queuedCurators(ctx, job)
+		finished = isJobFinished(job)
+	}
+	for _, curator := range curators.Items {
+		if utils.CuratingJob(&curator) == jobName ||
+			(finished && utils.CuratingJob(&curator) == "" && curator.Name == obj.GetLabels()[utils.ClusterCuratorLabel]) {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: curator.Namespace, Name: curator.Name}})
+		}
+	}
+	return requests
+}
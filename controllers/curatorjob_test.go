@@ -0,0 +1,278 @@
+// Copyright Contributors to the Open Cluster Management project.
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	clustercuratorv1 "github.com/stolostron/cluster-curator-controller/pkg/api/v1beta1"
+	"github.com/stolostron/cluster-curator-controller/pkg/controller/launcher"
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/utils"
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const curatorJobName = "curator-job-d9pwh"
+
+var podStart = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func getCuratorJob(conditions ...batchv1.JobCondition) *batchv1.Job {
+	return &batchv1.Job{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      curatorJobName,
+			Namespace: "my-cluster",
+			Labels:    map[string]string{launcher.CuratorJobLabel: launcher.CuratorJobLabelValue},
+		},
+		Status: batchv1.JobStatus{Conditions: conditions},
+	}
+}
+
+func getFailedCuratorJob() *batchv1.Job {
+	return getCuratorJob(batchv1.JobCondition{Type: batchv1.JobFailed, Status: corev1.ConditionTrue,
+		Reason: batchv1.JobReasonBackoffLimitExceeded})
+}
+
+// A curator pod of an upgrade, its final-monitor-upgrade container runs the monitor-upgrade step
+func getCuratorPod(statuses ...corev1.ContainerStatus) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      curatorJobName + "-x7k2p",
+			Namespace: "my-cluster",
+			Labels: map[string]string{
+				launcher.CuratorJobLabel: launcher.CuratorJobLabelValue,
+				jobNameLabel:             curatorJobName,
+			},
+		},
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{
+				{Name: launcher.FinalUpgradeCluster, Command: []string{launcher.CurCmd, launcher.UpgradeCluster, "my-cluster"}},
+				{Name: launcher.FinalMonUpgrade, Command: []string{launcher.CurCmd, launcher.MonUpgrade, "my-cluster"}},
+			},
+			Containers: []corev1.Container{{Name: launcher.DoneDoneDone}},
+		},
+		Status: corev1.PodStatus{
+			StartTime:             &v1.Time{Time: podStart},
+			InitContainerStatuses: statuses,
+		},
+	}
+}
+
+func completed(name string) corev1.ContainerStatus {
+	return corev1.ContainerStatus{Name: name, State: corev1.ContainerState{
+		Terminated: &corev1.ContainerStateTerminated{Reason: "Completed"}}}
+}
+
+func TestJobFailureOOMKilled(t *testing.T) {
+	pod := getCuratorPod(completed(launcher.FinalUpgradeCluster), corev1.ContainerStatus{
+		Name:  launcher.FinalMonUpgrade,
+		State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "OOMKilled", ExitCode: 137}},
+	})
+
+	step, _, failure := jobFailure(getFailedCuratorJob(), []corev1.Pod{*pod}, podStart, DefaultImagePullTimeout)
+	assert.Equal(t, utils.ReasonOOMKilled, utils.ReasonForError(failure))
+	assert.Equal(t, launcher.MonUpgrade, step, "the condition of the step is the step of the container command")
+}
+
+func TestJobFailureEvicted(t *testing.T) {
+	pod := getCuratorPod(completed(launcher.FinalUpgradeCluster), corev1.ContainerStatus{Name: launcher.FinalMonUpgrade})
+	pod.Status.Phase = corev1.PodFailed
+	pod.Status.Reason = "Evicted"
+	pod.Status.Message = "The node was low on resource: memory."
+
+	_, _, failure := jobFailure(getCuratorJob(), []corev1.Pod{*pod}, podStart, DefaultImagePullTimeout)
+	assert.Nil(t, failure, "the job runs an evicted pod again")
+
+	step, _, failure := jobFailure(getFailedCuratorJob(), []corev1.Pod{*pod}, podStart, DefaultImagePullTimeout)
+	assert.Equal(t, utils.ReasonEvicted, utils.ReasonForError(failure))
+	assert.Contains(t, failure.Error(), "low on resource")
+	assert.Equal(t, launcher.MonUpgrade, step, "the step that was running")
+
+	pod = getCuratorPod(corev1.ContainerStatus{Name: launcher.FinalUpgradeCluster})
+	pod.Status.Phase = corev1.PodFailed
+	pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.DisruptionTarget, Status: corev1.ConditionTrue,
+		Reason: "DeletionByTaintManager", Message: "Taint manager: deleting due to NoExecute taint"}}
+
+	step, _, failure = jobFailure(getFailedCuratorJob(), []corev1.Pod{*pod}, podStart, DefaultImagePullTimeout)
+	assert.Equal(t, utils.ReasonEvicted, utils.ReasonForError(failure), "the pod was lost with its node")
+	assert.Equal(t, launcher.UpgradeCluster, step)
+}
+
+func TestJobFailureImagePullBackOff(t *testing.T) {
+	pod := getCuratorPod(corev1.ContainerStatus{
+		Name:  launcher.FinalUpgradeCluster,
+		Image: "registry.example.com/curator:missing",
+		State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff",
+			Message: "Back-off pulling image"}},
+	})
+
+	_, requeueAfter, failure := jobFailure(getCuratorJob(), []corev1.Pod{*pod}, podStart.Add(time.Minute),
+		DefaultImagePullTimeout)
+	assert.Nil(t, failure, "the image pull is retried until the timeout")
+	assert.Equal(t, 4*time.Minute, requeueAfter)
+
+	step, _, failure := jobFailure(getCuratorJob(), []corev1.Pod{*pod}, podStart.Add(DefaultImagePullTimeout),
+		DefaultImagePullTimeout)
+	assert.Equal(t, utils.ReasonImagePullBackOff, utils.ReasonForError(failure))
+	assert.Contains(t, failure.Error(), "registry.example.com/curator:missing")
+	assert.Equal(t, launcher.UpgradeCluster, step)
+}
+
+func TestJobFailureDeadlineExceeded(t *testing.T) {
+	job := getCuratorJob(batchv1.JobCondition{Type: batchv1.JobFailed, Status: corev1.ConditionTrue,
+		Reason: batchv1.JobReasonDeadlineExceeded, Message: "Job was active longer than specified deadline"})
+
+	step, _, failure := jobFailure(job, nil, podStart, DefaultImagePullTimeout)
+	assert.Equal(t, utils.ReasonDeadlineExceeded, utils.ReasonForError(failure))
+	assert.Equal(t, "", step, "the pods are deleted with the deadline")
+
+	job.Status.Conditions[0].Reason = batchv1.JobReasonBackoffLimitExceeded
+	_, _, failure = jobFailure(job, nil, podStart, DefaultImagePullTimeout)
+	assert.Equal(t, utils.ReasonJobFailed, utils.ReasonForError(failure))
+}
+
+func TestJobFailureRunning(t *testing.T) {
+	pod := getCuratorPod(completed(launcher.FinalUpgradeCluster), corev1.ContainerStatus{
+		Name:  launcher.FinalMonUpgrade,
+		State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+	})
+
+	step, requeueAfter, failure := jobFailure(getCuratorJob(), []corev1.Pod{*pod}, podStart, DefaultImagePullTimeout)
+	assert.Nil(t, failure)
+	assert.Equal(t, "", step)
+	assert.Equal(t, time.Duration(0), requeueAfter)
+}
+
+func getCuratorJobReconciler(t *testing.T, objects ...runtime.Object) *ClusterCuratorReconciler {
+	s := runtime.NewScheme()
+	assert.Nil(t, clientgoscheme.AddToScheme(s))
+	assert.Nil(t, clustercuratorv1.AddToScheme(s))
+
+	return &ClusterCuratorReconciler{
+		Client:  clientfake.NewClientBuilder().WithScheme(s).WithStatusSubresource(&clustercuratorv1.ClusterCurator{}).WithRuntimeObjects(objects...).Build(),
+		Kubeset: fake.NewSimpleClientset(getCuratorJob()),
+		Log:     logr.Discard(),
+	}
+}
+
+func TestReconcileFailsOOMKilledCuration(t *testing.T) {
+	curator := &clustercuratorv1.ClusterCurator{
+		ObjectMeta: v1.ObjectMeta{Name: "my-cluster", Namespace: "my-cluster", Finalizers: []string{CuratorFinalizer}},
+		Spec:       clustercuratorv1.ClusterCuratorSpec{DesiredCuration: "install", CuratingJob: curatorJobName},
+	}
+	pod := getCuratorPod(corev1.ContainerStatus{
+		Name:  launcher.FinalUpgradeCluster,
+		State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "OOMKilled", ExitCode: 137}},
+	})
+	job := getFailedCuratorJob()
+	r := getCuratorJobReconciler(t, curator, job, pod)
+
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{
+		NamespacedName: types.NamespacedName{Namespace: "my-cluster", Name: "my-cluster"}})
+	assert.Nil(t, err)
+
+	assert.Nil(t, r.Get(context.TODO(), types.NamespacedName{Namespace: "my-cluster", Name: "my-cluster"}, curator))
+	assert.Equal(t, "", curator.Spec.CuratingJob, "the curatorJob is cleared")
+	assert.Equal(t, "", curator.Spec.DesiredCuration, "the desiredCuration is cleared")
+
+	cond := meta.FindStatusCondition(curator.Status.Conditions, curatorJobCondition)
+	assert.Equal(t, v1.ConditionTrue, cond.Status)
+	assert.Equal(t, string(utils.ReasonOOMKilled), cond.Reason)
+	cond = meta.FindStatusCondition(curator.Status.Conditions, launcher.UpgradeCluster)
+	assert.Equal(t, string(utils.ReasonOOMKilled), cond.Reason, "the step is failed")
+
+	_, err = r.Kubeset.BatchV1().Jobs("my-cluster").Get(context.TODO(), curatorJobName, v1.GetOptions{})
+	assert.Nil(t, err, "the failed job is kept for its logs")
+}
+
+func TestReconcileDeletesImagePullBackOffJob(t *testing.T) {
+	curator := &clustercuratorv1.ClusterCurator{
+		ObjectMeta: v1.ObjectMeta{Name: "my-cluster", Namespace: "my-cluster", Finalizers: []string{CuratorFinalizer}},
+		Spec:       clustercuratorv1.ClusterCuratorSpec{DesiredCuration: "upgrade", CuratingJob: curatorJobName},
+	}
+	pod := getCuratorPod(corev1.ContainerStatus{
+		Name:  launcher.FinalUpgradeCluster,
+		State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ErrImagePull"}},
+	})
+	r := getCuratorJobReconciler(t, curator, getCuratorJob(), pod)
+
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{
+		NamespacedName: types.NamespacedName{Namespace: "my-cluster", Name: "my-cluster"}})
+	assert.Nil(t, err)
+
+	assert.Nil(t, r.Get(context.TODO(), types.NamespacedName{Namespace: "my-cluster", Name: "my-cluster"}, curator))
+	assert.Equal(t, "", curator.Spec.CuratingJob)
+	assert.Equal(t, "upgrade", curator.Spec.DesiredCuration, "the desiredCuration of an upgrade is kept")
+	assert.Equal(t, string(utils.ReasonImagePullBackOff),
+		meta.FindStatusCondition(curator.Status.Conditions, curatorJobCondition).Reason)
+
+	_, err = r.Kubeset.BatchV1().Jobs("my-cluster").Get(context.TODO(), curatorJobName, v1.GetOptions{})
+	assert.True(t, k8serrors.IsNotFound(err), "the job waiting on its image is deleted")
+}
+
+func TestReconcileFailsDeletedJob(t *testing.T) {
+	curator := getInstallCurator("my-cluster", 0)
+	curator.Spec.CuratingJob = curatorJobName
+	meta.SetStatusCondition(&curator.Status.Conditions, v1.Condition{Type: ClusterLockCondition,
+		Status: v1.ConditionTrue, Reason: ReasonLockHeld})
+	r := getCuratorJobReconciler(t, curator)
+	assert.Nil(t, r.Kubeset.BatchV1().Jobs("my-cluster").Delete(context.TODO(), curatorJobName, v1.DeleteOptions{}))
+	_, err := r.Kubeset.CoordinationV1().Leases("my-cluster").Create(context.TODO(),
+		getClusterLock("clustercurator/my-cluster/my-cluster", podStart, nil), v1.CreateOptions{})
+	assert.Nil(t, err)
+
+	_, curator = reconcileCurator(t, r, "my-cluster")
+	assert.Equal(t, "", curator.Spec.CuratingJob, "the curatorJob is cleared")
+	assert.Equal(t, string(utils.ReasonJobDeleted),
+		meta.FindStatusCondition(curator.Status.Conditions, curatorJobCondition).Reason)
+	_, err = r.Kubeset.CoordinationV1().Leases("my-cluster").Get(context.TODO(), ClusterLockLease, v1.GetOptions{})
+	assert.True(t, k8serrors.IsNotFound(err), "the lease is released")
+}
+
+func TestReconcileKeepsRecordedFailure(t *testing.T) {
+	curator := &clustercuratorv1.ClusterCurator{
+		ObjectMeta: v1.ObjectMeta{Name: "my-cluster", Namespace: "my-cluster", Finalizers: []string{CuratorFinalizer}},
+		Spec:       clustercuratorv1.ClusterCuratorSpec{DesiredCuration: "install", CuratingJob: curatorJobName},
+		Status: clustercuratorv1.ClusterCuratorStatus{Conditions: []v1.Condition{{Type: curatorJobCondition,
+			Status: v1.ConditionTrue, Reason: utils.JobFailed, Message: "prehook failed"}}},
+	}
+	job := getFailedCuratorJob()
+	r := getCuratorJobReconciler(t, curator, job)
+
+	_, err := r.Reconcile(context.TODO(), reconcile.Request{
+		NamespacedName: types.NamespacedName{Namespace: "my-cluster", Name: "my-cluster"}})
+	assert.Nil(t, err)
+
+	assert.Nil(t, r.Get(context.TODO(), types.NamespacedName{Namespace: "my-cluster", Name: "my-cluster"}, curator))
+	assert.Equal(t, "prehook failed", meta.FindStatusCondition(curator.Status.Conditions, curatorJobCondition).Message,
+		"the failure recorded by the curator is kept")
+}
+
+func TestCuratorsForJob(t *testing.T) {
+	curator := &clustercuratorv1.ClusterCurator{
+		ObjectMeta: v1.ObjectMeta{Name: "my-cluster", Namespace: "my-cluster"},
+		Spec:       clustercuratorv1.ClusterCuratorSpec{CuratingJob: curatorJobName},
+	}
+	idle := &clustercuratorv1.ClusterCurator{ObjectMeta: v1.ObjectMeta{Name: "other", Namespace: "my-cluster"}}
+	r := getCuratorJobReconciler(t, curator, idle)
+
+	expected := []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: "my-cluster", Name: "my-cluster"}}}
+	assert.Equal(t, expected, r.curatorsForJob(context.TODO(), getCuratorJob()))
+	assert.Equal(t, expected, r.curatorsForJob(context.TODO(), getCuratorPod()), "a pod maps with its job-name")
+
+	pod := getCuratorPod()
+	delete(pod.Labels, jobNameLabel)
+	assert.Empty(t, r.curatorsForJob(context.TODO(), pod))
+}
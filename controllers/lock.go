@@ -0,0 +1,263 @@
+// Copyright Contributors to the Open Cluster Management project.
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	clustercuratorv1 "github.com/stolostron/cluster-curator-controller/pkg/api/v1beta1"
+	"github.com/stolostron/cluster-curator-controller/pkg/controller/launcher"
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/utils"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Lease of a managed cluster, in its namespace. It is held by the ClusterCurator curating the cluster, or by
+// an external actor that changes the ClusterDeployment or HostedCluster.
+const ClusterLockLease = "curation-lock"
+
+// Holder identity of the lease when a ClusterCurator holds it, clustercurator/NAMESPACE/NAME
+const ClusterLockHolderPrefix = "clustercurator/"
+
+// Condition of the cluster lock, it is true while the ClusterCurator holds it
+const ClusterLockCondition = "ClusterLock"
+
+// Reasons of the ClusterLock condition
+const (
+	ReasonLockHeld         = "LockHeld"
+	ReasonLockedByCurator  = "LockedByClusterCurator"
+	ReasonLockedExternally = "LockedExternally"
+	ReasonLockReleased     = "LockReleased"
+)
+
+// How often a curation waiting on the lock checks it
+const ClusterLockRequeueAfter = 30 * time.Second
+
+// Duration of the lease held by a ClusterCurator, it is renewed while its curator job runs
+const clusterLockDuration = 2 * time.Minute
+
+// How often the lease is renewed while the curator job runs
+const clusterLockRenewInterval = clusterLockDuration / 4
+
+// The curations that change the cluster and run one at a time
+var lockedCurations = map[string]bool{"install": true, "upgrade": true, "destroy": true}
+
+func clusterLockHolder(curator *clustercuratorv1.ClusterCurator) string {
+	return ClusterLockHolderPrefix + curator.Namespace + "/" + curator.Name
+}
+
+/* acquireClusterLock - The install, upgrade and destroy curations hold the lease of their managed cluster,
+ * named after the ClusterCurator. A lease held by another ClusterCurator that is not curating, or an external
+ * lease that expired, is taken over. Otherwise the curation waits, the ClusterLock condition tells who holds
+ * the lease. The lease is released when the curation is done.
+ */
+func (r *ClusterCuratorReconciler) acquireClusterLock(ctx context.Context, curator *clustercuratorv1.ClusterCurator) (bool, error) {
+	if !lockedCurations[launcher.CurationType(curator)] {
+		return true, nil
+	}
+
+	holder := clusterLockHolder(curator)
+	now := v1.NewMicroTime(time.Now())
+	duration := int32(clusterLockDuration.Seconds())
+	leases := r.Kubeset.CoordinationV1().Leases(curator.Name)
+	lease, err := leases.Get(ctx, ClusterLockLease, v1.GetOptions{})
+	switch {
+	case k8serrors.IsNotFound(err):
+		lease = &coordinationv1.Lease{
+			ObjectMeta: v1.ObjectMeta{Name: ClusterLockLease, Namespace: curator.Name},
+			Spec: coordinationv1.LeaseSpec{HolderIdentity: &holder, AcquireTime: &now, RenewTime: &now,
+				LeaseDurationSeconds: &duration},
+		}
+		if _, err := leases.Create(ctx, lease, v1.CreateOptions{}); k8serrors.IsAlreadyExists(err) {
+			return false, r.recordClusterLock(ctx, curator, ReasonLockedByCurator,
+				"The lease of the managed cluster was acquired by another curation")
+		} else if err != nil {
+			return false, err
+		}
+	case err != nil:
+		return false, err
+	default:
+		current := ""
+		if lease.Spec.HolderIdentity != nil {
+			current = *lease.Spec.HolderIdentity
+		}
+		if current != holder && current != "" {
+			reason, message, held, err := r.clusterLockHeld(ctx, lease, current)
+			if err != nil {
+				return false, err
+			}
+			if held {
+				return false, r.recordClusterLock(ctx, curator, reason, message)
+			}
+			r.Log.V(0).Info("Taking over the lease of the managed cluster "+curator.Name, "holder", current)
+		}
+		if current != holder {
+			lease.Spec.HolderIdentity = &holder
+			lease.Spec.AcquireTime = &now
+			lease.Spec.LeaseTransitions = increment(lease.Spec.LeaseTransitions)
+		}
+		lease.Spec.RenewTime = &now
+		lease.Spec.LeaseDurationSeconds = &duration
+		// A conflict means another curation changed the lease first
+		if _, err := leases.Update(ctx, lease, v1.UpdateOptions{}); k8serrors.IsConflict(err) {
+			return false, r.recordClusterLock(ctx, curator, ReasonLockedByCurator,
+				"The lease of the managed cluster was acquired by another curation")
+		} else if err != nil {
+			return false, err
+		}
+	}
+
+	return true, r.recordClusterLock(ctx, curator, ReasonLockHeld,
+		"Holding the lease "+curator.Name+"/"+ClusterLockLease+" of the managed cluster during the "+
+			launcher.CurationType(curator)+" curation")
+}
+
+/* clusterLockHeld - A ClusterCurator holds the lease while it has a curator job or renewed the lease, an
+ * external holder until the lease expires. A lease without a duration is held until the external holder
+ * releases it.
+ */
+func (r *ClusterCuratorReconciler) clusterLockHeld(
+	ctx context.Context,
+	lease *coordinationv1.Lease,
+	holder string) (reason string, message string, held bool, err error) {
+
+	if strings.HasPrefix(holder, ClusterLockHolderPrefix) {
+		namespace, name, _ := strings.Cut(strings.TrimPrefix(holder, ClusterLockHolderPrefix), "/")
+		other := &clustercuratorv1.ClusterCurator{}
+		if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, other); k8serrors.IsNotFound(err) {
+			return "", "", false, nil
+		} else if err != nil {
+			return "", "", false, err
+		}
+		if utils.CuratingJob(other) == "" && leaseExpired(lease, clusterLockDuration) {
+			return "", "", false, nil
+		}
+		return ReasonLockedByCurator, fmt.Sprintf("The managed cluster is locked by the ClusterCurator %v/%v, "+
+			"it is curating it", namespace, name), true, nil
+	}
+
+	if leaseExpired(lease, 0) {
+		return "", "", false, nil
+	}
+	return ReasonLockedExternally, fmt.Sprintf("The managed cluster is locked by %v with the lease %v/%v, "+
+		"the curation starts once it is released", holder, lease.Namespace, lease.Name), true, nil
+}
+
+/* leaseExpired - The lease was not renewed within its duration. A lease without a duration lasts the default
+ * duration, it does not expire when there is no default.
+ */
+func leaseExpired(lease *coordinationv1.Lease, defaultDuration time.Duration) bool {
+	duration := defaultDuration
+	if lease.Spec.LeaseDurationSeconds != nil {
+		duration = time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second
+	}
+	if duration == 0 {
+		return false
+	}
+	renewed := lease.Spec.RenewTime
+	if renewed == nil {
+		renewed = lease.Spec.AcquireTime
+	}
+	return renewed == nil || renewed.Add(duration).Before(time.Now())
+}
+
+/* renewClusterLock - Renews the lease of the managed cluster while the curator job of the ClusterCurator
+ * runs, so the other holders see it is still curating. A conflict is retried with the next renewal.
+ */
+func (r *ClusterCuratorReconciler) renewClusterLock(ctx context.Context, curator *clustercuratorv1.ClusterCurator) error {
+	leases := r.Kubeset.CoordinationV1().Leases(curator.Name)
+	lease, err := leases.Get(ctx, ClusterLockLease, v1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != clusterLockHolder(curator) {
+		return nil
+	}
+	if lease.Spec.RenewTime != nil && time.Since(lease.Spec.RenewTime.Time) < clusterLockRenewInterval {
+		return nil
+	}
+
+	now := v1.NewMicroTime(time.Now())
+	duration := int32(clusterLockDuration.Seconds())
+	lease.Spec.RenewTime = &now
+	lease.Spec.LeaseDurationSeconds = &duration
+	if _, err := leases.Update(ctx, lease, v1.UpdateOptions{}); err != nil && !k8serrors.IsConflict(err) {
+		return err
+	}
+	return nil
+}
+
+/* releaseClusterLock - Releases the lease of the managed cluster when the ClusterCurator holds it, its
+ * curation is done. The ClusterLock condition records that it holds the lease, an idle ClusterCurator does not
+ * read the lease.
+ */
+func (r *ClusterCuratorReconciler) releaseClusterLock(ctx context.Context, curator *clustercuratorv1.ClusterCurator) error {
+	cond := meta.FindStatusCondition(curator.Status.Conditions, ClusterLockCondition)
+	if cond == nil || cond.Status != v1.ConditionTrue || cond.Reason != ReasonLockHeld {
+		return nil
+	}
+
+	leases := r.Kubeset.CoordinationV1().Leases(curator.Name)
+	lease, err := leases.Get(ctx, ClusterLockLease, v1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != clusterLockHolder(curator) {
+		return nil
+	}
+
+	r.Log.V(2).Info("Releasing the lease of the managed cluster " + curator.Name)
+	// A conflict means another curation took over the lease
+	if err := leases.Delete(ctx, ClusterLockLease, v1.DeleteOptions{Preconditions: &v1.Preconditions{
+		ResourceVersion: &lease.ResourceVersion}}); err != nil && !k8serrors.IsNotFound(err) &&
+		!k8serrors.IsConflict(err) {
+		return err
+	}
+	if curator.DeletionTimestamp != nil {
+		return nil
+	}
+	return r.recordClusterLock(ctx, curator, ReasonLockReleased, "The curation is done, the lease was released")
+}
+
+// Records the ClusterLock condition, the ClusterCurator is only updated when it changed
+func (r *ClusterCuratorReconciler) recordClusterLock(
+	ctx context.Context,
+	curator *clustercuratorv1.ClusterCurator,
+	reason string,
+	message string) error {
+
+	status := v1.ConditionFalse
+	if reason == ReasonLockHeld {
+		status = v1.ConditionTrue
+	} else if reason != ReasonLockReleased {
+		r.Log.V(2).Info(message, "clustercurator", types.NamespacedName{Namespace: curator.Namespace,
+			Name: curator.Name})
+	}
+	if !meta.SetStatusCondition(&curator.Status.Conditions, v1.Condition{
+		Type:    ClusterLockCondition,
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	}) {
+		return nil
+	}
+	return r.Status().Update(ctx, curator)
+}
+
+func increment(transitions *int32) *int32 {
+	count := int32(1)
+	if transitions != nil {
+		count = *transitions + 1
+	}
+	return &count
+}
@@ -0,0 +1,189 @@
+// Copyright Contributors to the Open Cluster Management project.
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	clustercuratorv1 "github.com/stolostron/cluster-curator-controller/pkg/api/v1beta1"
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/utils"
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func getClusterLock(holder string, renewed time.Time, durationSeconds *int32) *coordinationv1.Lease {
+	renewTime := v1.NewMicroTime(renewed)
+	return &coordinationv1.Lease{
+		ObjectMeta: v1.ObjectMeta{Name: ClusterLockLease, Namespace: "my-cluster"},
+		Spec: coordinationv1.LeaseSpec{HolderIdentity: &holder, AcquireTime: &renewTime, RenewTime: &renewTime,
+			LeaseDurationSeconds: durationSeconds},
+	}
+}
+
+func assertClusterLock(t *testing.T, r *ClusterCuratorReconciler, curator *clustercuratorv1.ClusterCurator,
+	reason string) {
+
+	cond := meta.FindStatusCondition(curator.Status.Conditions, ClusterLockCondition)
+	if assert.NotNil(t, cond) {
+		assert.Equal(t, reason, cond.Reason, cond.Message)
+	}
+	jobs, err := r.Kubeset.BatchV1().Jobs(curator.Namespace).List(context.TODO(), v1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, reason == ReasonLockHeld, len(jobs.Items) == 1, "the curator job runs with the lock")
+}
+
+func TestReconcileAcquiresClusterLock(t *testing.T) {
+	r := getCleanupReconciler(t, getInstallCurator("my-cluster", 0))
+
+	result, curator := reconcileCurator(t, r, "my-cluster")
+	assert.Zero(t, result.RequeueAfter)
+	assertClusterLock(t, r, curator, ReasonLockHeld)
+	assert.True(t, meta.IsStatusConditionTrue(curator.Status.Conditions, ClusterLockCondition))
+
+	lease, err := r.Kubeset.CoordinationV1().Leases("my-cluster").Get(context.TODO(), ClusterLockLease,
+		v1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "clustercurator/my-cluster/my-cluster", *lease.Spec.HolderIdentity)
+}
+
+func TestReconcileClusterLockedByCurator(t *testing.T) {
+	// The ClusterCurator of a hosted cluster, in the namespace of its HostedCluster
+	hosted := getInstallCurator("my-cluster", 0)
+	hosted.Namespace = "clusters"
+	hosted.Spec.CuratingJob = "curator-job-hosted"
+	r := getCleanupReconciler(t, getInstallCurator("my-cluster", 0), hosted)
+	_, err := r.Kubeset.CoordinationV1().Leases("my-cluster").Create(context.TODO(),
+		getClusterLock("clustercurator/clusters/my-cluster", podStart, nil), v1.CreateOptions{})
+	assert.Nil(t, err)
+
+	result, curator := reconcileCurator(t, r, "my-cluster")
+	assert.Equal(t, ClusterLockRequeueAfter, result.RequeueAfter)
+	assertClusterLock(t, r, curator, ReasonLockedByCurator)
+	assert.Contains(t, meta.FindStatusCondition(curator.Status.Conditions, ClusterLockCondition).Message,
+		"ClusterCurator clusters/my-cluster")
+
+	// The curation of the hosted cluster is done
+	hosted.Spec.CuratingJob = ""
+	assert.Nil(t, r.Update(context.TODO(), hosted))
+
+	_, curator = reconcileCurator(t, r, "my-cluster")
+	assertClusterLock(t, r, curator, ReasonLockHeld)
+	lease, err := r.Kubeset.CoordinationV1().Leases("my-cluster").Get(context.TODO(), ClusterLockLease,
+		v1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "clustercurator/my-cluster/my-cluster", *lease.Spec.HolderIdentity)
+	assert.Equal(t, int32(1), *lease.Spec.LeaseTransitions)
+}
+
+func TestReconcileClusterLockedExternally(t *testing.T) {
+	r := getCleanupReconciler(t, getInstallCurator("my-cluster", 0))
+	lease, err := r.Kubeset.CoordinationV1().Leases("my-cluster").Create(context.TODO(),
+		getClusterLock("gitops-maintenance", podStart, nil), v1.CreateOptions{})
+	assert.Nil(t, err)
+
+	result, curator := reconcileCurator(t, r, "my-cluster")
+	assert.Equal(t, ClusterLockRequeueAfter, result.RequeueAfter)
+	assertClusterLock(t, r, curator, ReasonLockedExternally)
+	assert.Contains(t, meta.FindStatusCondition(curator.Status.Conditions, ClusterLockCondition).Message,
+		"locked by gitops-maintenance with the lease my-cluster/curation-lock")
+
+	// The external lease expired
+	duration := int32(60)
+	lease.Spec.LeaseDurationSeconds = &duration
+	_, err = r.Kubeset.CoordinationV1().Leases("my-cluster").Update(context.TODO(), lease, v1.UpdateOptions{})
+	assert.Nil(t, err)
+
+	_, curator = reconcileCurator(t, r, "my-cluster")
+	assertClusterLock(t, r, curator, ReasonLockHeld)
+}
+
+func TestReconcileRenewsClusterLock(t *testing.T) {
+	curator := getInstallCurator("my-cluster", 0)
+	curator.Spec.CuratingJob = curatorJobName
+	r := getCuratorJobReconciler(t, curator, getCuratorJob())
+	_, err := r.Kubeset.CoordinationV1().Leases("my-cluster").Create(context.TODO(),
+		getClusterLock("clustercurator/my-cluster/my-cluster", podStart, nil), v1.CreateOptions{})
+	assert.Nil(t, err)
+
+	// The curator job runs
+	result, _ := reconcileCurator(t, r, "my-cluster")
+	assert.Equal(t, clusterLockRenewInterval, result.RequeueAfter)
+
+	lease, err := r.Kubeset.CoordinationV1().Leases("my-cluster").Get(context.TODO(), ClusterLockLease,
+		v1.GetOptions{})
+	assert.Nil(t, err)
+	assert.True(t, lease.Spec.RenewTime.After(podStart), "the lease is renewed")
+	assert.Equal(t, int32(clusterLockDuration.Seconds()), *lease.Spec.LeaseDurationSeconds)
+	assert.False(t, leaseExpired(lease, 0))
+}
+
+func TestReconcileScaleIsNotLocked(t *testing.T) {
+	curator := getInstallCurator("my-cluster", 0)
+	curator.Spec.DesiredCuration = "scale"
+	r := getCleanupReconciler(t, curator)
+	_, err := r.Kubeset.CoordinationV1().Leases("my-cluster").Create(context.TODO(),
+		getClusterLock("gitops-maintenance", time.Now(), nil), v1.CreateOptions{})
+	assert.Nil(t, err)
+
+	_, curator = reconcileCurator(t, r, "my-cluster")
+	assert.Nil(t, meta.FindStatusCondition(curator.Status.Conditions, ClusterLockCondition))
+}
+
+func TestReconcileReleasesClusterLock(t *testing.T) {
+	done := getInstallCurator("my-cluster", 0)
+	done.Spec.DesiredCuration = ""
+	meta.SetStatusCondition(&done.Status.Conditions, v1.Condition{Type: ClusterLockCondition,
+		Status: v1.ConditionTrue, Reason: ReasonLockHeld})
+	r := getCleanupReconciler(t, done)
+	_, err := r.Kubeset.CoordinationV1().Leases("my-cluster").Create(context.TODO(),
+		getClusterLock("clustercurator/my-cluster/my-cluster", podStart, nil), v1.CreateOptions{})
+	assert.Nil(t, err)
+
+	// The curation is done when its job finishes
+	job := getRunningCuratorJob("my-cluster", "install")
+	job.Labels[utils.ClusterCuratorLabel] = "my-cluster"
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+	assert.Equal(t, []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: "my-cluster",
+		Name: "my-cluster"}}}, r.curatorsForJob(context.TODO(), job))
+
+	_, curator := reconcileCurator(t, r, "my-cluster")
+	cond := meta.FindStatusCondition(curator.Status.Conditions, ClusterLockCondition)
+	assert.Equal(t, v1.ConditionFalse, cond.Status)
+	assert.Equal(t, ReasonLockReleased, cond.Reason)
+	_, err = r.Kubeset.CoordinationV1().Leases("my-cluster").Get(context.TODO(), ClusterLockLease, v1.GetOptions{})
+	assert.True(t, k8serrors.IsNotFound(err), "the lease is released")
+}
+
+func TestReconcileIdleCuratorDoesNotReadClusterLock(t *testing.T) {
+	idle := getInstallCurator("my-cluster", 0)
+	idle.Spec.DesiredCuration = ""
+	r := getCleanupReconciler(t, idle)
+
+	reconcileCurator(t, r, "my-cluster")
+	for _, action := range r.Kubeset.(*fake.Clientset).Actions() {
+		assert.NotEqual(t, "leases", action.GetResource().Resource, "the idle curator did not lock the cluster")
+	}
+}
+
+func TestReleaseClusterLockHeldByOther(t *testing.T) {
+	curator := getInstallCurator("my-cluster", 0)
+	r := getCleanupReconciler(t, curator)
+	_, err := r.Kubeset.CoordinationV1().Leases("my-cluster").Create(context.TODO(),
+		getClusterLock("gitops-maintenance", podStart, nil), v1.CreateOptions{})
+	assert.Nil(t, err)
+
+	assert.Nil(t, r.releaseClusterLock(context.TODO(), curator))
+	_, err = r.Kubeset.CoordinationV1().Leases("my-cluster").Get(context.TODO(), ClusterLockLease, v1.GetOptions{})
+	assert.Nil(t, err, "the lease of another holder is kept")
+	assert.Nil(t, meta.FindStatusCondition(curator.Status.Conditions, ClusterLockCondition))
+}
@@ -0,0 +1,217 @@
+// Copyright Contributors to the Open Cluster Management project.
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	clustercuratorv1 "github.com/stolostron/cluster-curator-controller/pkg/api/v1beta1"
+	"github.com/stolostron/cluster-curator-controller/pkg/controller/launcher"
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/utils"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Condition of the curations that wait for a curator job slot of their curation type
+const QueuedCondition = "Queued"
+
+// Reasons of the Queued condition
+const (
+	ReasonCurationQueued  = "CurationQueued"
+	ReasonCurationStarted = "CurationStarted"
+)
+
+// How often a queued curation checks for a free slot, it is also checked when a curator job finishes
+const QueuedRequeueAfter = 30 * time.Second
+
+// How long a launched curation counts as running at most, while its curator job is not in the cache
+const launchCacheTimeout = 2 * time.Minute
+
+// A curation admitted to a curator job slot, counted as running until the cache has its curator job
+type launch struct {
+	curationType string
+	admitted     time.Time
+}
+
+/* ParseMaxRunningJobs - Parses the caps on the running curator jobs of the hub, format:
+ * TYPE=COUNT,TYPE=COUNT, for example install=20,upgrade=50. The curation types that are not listed are
+ * not capped.
+ */
+func ParseMaxRunningJobs(value string) (map[string]int, error) {
+	maxRunningJobs := map[string]int{}
+	for _, entry := range strings.Split(value, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		curationType, count, found := strings.Cut(entry, "=")
+		max, err := strconv.Atoi(strings.TrimSpace(count))
+		if !found || strings.TrimSpace(curationType) == "" || err != nil || max < 1 {
+			return nil, fmt.Errorf("invalid max running curator jobs %q, format: TYPE=COUNT, COUNT above 0", entry)
+		}
+		maxRunningJobs[strings.TrimSpace(curationType)] = max
+	}
+	return maxRunningJobs, nil
+}
+
+/* admitCuration - A curation starts when fewer curator jobs of its curation type are running on the hub
+ * than the cap of the type, and no queued curation is ahead of it. The others wait with a Queued condition,
+ * ordered by their spec.priority then by when they were queued. The running curator jobs are counted from
+ * the cache, an admitted curation counts as running until the cache has its curator job.
+ */
+func (r *ClusterCuratorReconciler) admitCuration(ctx context.Context, curator *clustercuratorv1.ClusterCurator) (bool, error) {
+	curationType := launcher.CurationType(curator)
+	max, capped := r.MaxRunningJobs[curationType]
+	queued := meta.FindStatusCondition(curator.Status.Conditions, QueuedCondition)
+	if !capped {
+		return true, r.recordStarted(ctx, curator, queued)
+	}
+
+	r.admission.Lock()
+	defer r.admission.Unlock()
+
+	jobs := &batchv1.JobList{}
+	if err := r.List(ctx, jobs, client.MatchingLabels{
+		launcher.CuratorJobLabel:   launcher.CuratorJobLabelValue,
+		launcher.CurationTypeLabel: curationType}); err != nil {
+		return false, err
+	}
+	running := 0
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		if isJobFinished(job) {
+			continue
+		}
+		running++
+		// The curator job of a launched curation is in the cache, it is counted once
+		delete(r.launching, types.NamespacedName{Namespace: job.Namespace, Name: job.Labels[utils.ClusterCuratorLabel]})
+	}
+	key := types.NamespacedName{Namespace: curator.Namespace, Name: curator.Name}
+	for launching, l := range r.launching {
+		if time.Since(l.admitted) > launchCacheTimeout {
+			delete(r.launching, launching)
+		} else if launching != key && l.curationType == curationType {
+			running++
+		}
+	}
+
+	curators := &clustercuratorv1.ClusterCuratorList{}
+	if err := r.List(ctx, curators); err != nil {
+		return false, err
+	}
+	queuedSince := v1.Now()
+	if queued != nil && queued.Status == v1.ConditionTrue {
+		queuedSince = queued.LastTransitionTime
+	}
+	ahead := 0
+	for i := range curators.Items {
+		other := &curators.Items[i]
+		if other.Namespace == curator.Namespace && other.Name == curator.Name {
+			continue
+		}
+		cond := meta.FindStatusCondition(other.Status.Conditions, QueuedCondition)
+		if cond == nil || cond.Status != v1.ConditionTrue || launcher.CurationType(other) != curationType {
+			continue
+		}
+		if queuedAhead(other, cond.LastTransitionTime, curator, queuedSince) {
+			ahead++
+		}
+	}
+
+	if running+ahead < max {
+		if r.launching == nil {
+			r.launching = map[types.NamespacedName]launch{}
+		}
+		r.launching[key] = launch{curationType: curationType, admitted: time.Now()}
+		return true, r.recordStarted(ctx, curator, queued)
+	}
+
+	message := fmt.Sprintf("Waiting for one of the %d running %v curator jobs to complete, %d queued curations "+
+		"are ahead", running, curationType, ahead)
+	if queued != nil && queued.Status == v1.ConditionTrue && queued.Message == message {
+		return false, nil
+	}
+	r.Log.V(2).Info(message, "clustercurator", key)
+	meta.SetStatusCondition(&curator.Status.Conditions, v1.Condition{
+		Type:    QueuedCondition,
+		Status:  v1.ConditionTrue,
+		Reason:  ReasonCurationQueued,
+		Message: message,
+	})
+	return false, r.Status().Update(ctx, curator)
+}
+
+// The queued curation starts before the other one
+func queuedAhead(
+	queued *clustercuratorv1.ClusterCurator,
+	queuedSince v1.Time,
+	other *clustercuratorv1.ClusterCurator,
+	otherSince v1.Time) bool {
+
+	if queued.Spec.Priority != other.Spec.Priority {
+		return queued.Spec.Priority > other.Spec.Priority
+	}
+	if !queuedSince.Equal(&otherSince) {
+		return queuedSince.Before(&otherSince)
+	}
+	return queued.Namespace+"/"+queued.Name < other.Namespace+"/"+other.Name
+}
+
+// Closes the Queued condition of a curation that waited for a slot
+func (r *ClusterCuratorReconciler) recordStarted(
+	ctx context.Context,
+	curator *clustercuratorv1.ClusterCurator,
+	queued *v1.Condition) error {
+
+	if queued == nil || queued.Status != v1.ConditionTrue {
+		return nil
+	}
+	meta.SetStatusCondition(&curator.Status.Conditions, v1.Condition{
+		Type:    QueuedCondition,
+		Status:  v1.ConditionFalse,
+		Reason:  ReasonCurationStarted,
+		Message: "The curator job slot was granted",
+	})
+	return r.Status().Update(ctx, curator)
+}
+
+// The curator job of the admitted curation failed to be created, its slot is free. A created curator job holds
+// the slot until the cache has it
+func (r *ClusterCuratorReconciler) launched(curator types.NamespacedName, created bool) {
+	if created {
+		return
+	}
+	r.admission.Lock()
+	defer r.admission.Unlock()
+	delete(r.launching, curator)
+}
+
+// A finished curator job frees a slot, the queued curations of its curation type are reconciled
+func (r *ClusterCuratorReconciler) queuedCurators(ctx context.Context, job *batchv1.Job) []reconcile.Request {
+	curationType := job.Labels[launcher.CurationTypeLabel]
+	if _, capped := r.MaxRunningJobs[curationType]; !capped || !isJobFinished(job) {
+		return nil
+	}
+
+	curators := &clustercuratorv1.ClusterCuratorList{}
+	if err := r.List(ctx, curators); err != nil {
+		r.Log.Error(err, "Unable to list the queued ClusterCurators", "job", job.Name)
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, curator := range curators.Items {
+		if meta.IsStatusConditionTrue(curator.Status.Conditions, QueuedCondition) &&
+			launcher.CurationType(&curator) == curationType {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: curator.Namespace, Name: curator.Name}})
+		}
+	}
+	return requests
+}
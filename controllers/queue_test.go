@@ -0,0 +1,188 @@
+// Copyright Contributors to the Open Cluster Management project.
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	clustercuratorv1 "github.com/stolostron/cluster-curator-controller/pkg/api/v1beta1"
+	"github.com/stolostron/cluster-curator-controller/pkg/controller/launcher"
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/utils"
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func getInstallCurator(name string, priority int32) *clustercuratorv1.ClusterCurator {
+	return &clustercuratorv1.ClusterCurator{
+		ObjectMeta: v1.ObjectMeta{Name: name, Namespace: name, Finalizers: []string{CuratorFinalizer}},
+		Spec:       clustercuratorv1.ClusterCuratorSpec{DesiredCuration: "install", Priority: priority},
+	}
+}
+
+func getRunningCuratorJob(namespace string, curationType string) *batchv1.Job {
+	return &batchv1.Job{ObjectMeta: v1.ObjectMeta{Name: "curator-job-running", Namespace: namespace,
+		Labels: map[string]string{
+			launcher.CuratorJobLabel:   launcher.CuratorJobLabelValue,
+			launcher.CurationTypeLabel: curationType,
+		}}}
+}
+
+func reconcileCurator(t *testing.T, r *ClusterCuratorReconciler, name string) (reconcile.Result, *clustercuratorv1.ClusterCurator) {
+	key := types.NamespacedName{Namespace: name, Name: name}
+	result, err := r.Reconcile(context.TODO(), reconcile.Request{NamespacedName: key})
+	assert.Nil(t, err)
+
+	curator := &clustercuratorv1.ClusterCurator{}
+	assert.Nil(t, r.Get(context.TODO(), key, curator))
+	return result, curator
+}
+
+func TestParseMaxRunningJobs(t *testing.T) {
+	maxRunningJobs, err := ParseMaxRunningJobs("install=20, upgrade=50,")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"install": 20, "upgrade": 50}, maxRunningJobs)
+
+	maxRunningJobs, err = ParseMaxRunningJobs("")
+	assert.Nil(t, err)
+	assert.Empty(t, maxRunningJobs, "the curations are not capped by default")
+
+	for _, value := range []string{"install", "install=0", "=2", "install=many"} {
+		_, err = ParseMaxRunningJobs(value)
+		assert.NotNil(t, err, "%v is invalid", value)
+	}
+}
+
+func TestReconcileQueuesCuration(t *testing.T) {
+	r := getCleanupReconciler(t, getInstallCurator("cluster-a", 0))
+	r.MaxRunningJobs = map[string]int{"install": 1}
+	running := getRunningCuratorJob("cluster-b", "install")
+	assert.Nil(t, r.Create(context.TODO(), running))
+	assert.Nil(t, r.Create(context.TODO(), getRunningCuratorJob("cluster-c", "upgrade")))
+
+	result, curator := reconcileCurator(t, r, "cluster-a")
+	assert.Equal(t, QueuedRequeueAfter, result.RequeueAfter)
+	queued := meta.FindStatusCondition(curator.Status.Conditions, QueuedCondition)
+	assert.NotNil(t, queued)
+	assert.Equal(t, v1.ConditionTrue, queued.Status)
+	assert.Equal(t, ReasonCurationQueued, queued.Reason)
+	assert.Contains(t, queued.Message, "1 running install curator jobs")
+	jobs, err := r.Kubeset.BatchV1().Jobs("cluster-a").List(context.TODO(), v1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Empty(t, jobs.Items, "the curator job waits for a slot")
+
+	running.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+	assert.Nil(t, r.Status().Update(context.TODO(), running))
+
+	result, curator = reconcileCurator(t, r, "cluster-a")
+	assert.Zero(t, result.RequeueAfter)
+	queued = meta.FindStatusCondition(curator.Status.Conditions, QueuedCondition)
+	assert.Equal(t, v1.ConditionFalse, queued.Status)
+	assert.Equal(t, ReasonCurationStarted, queued.Reason)
+	jobs, err = r.Kubeset.BatchV1().Jobs("cluster-a").List(context.TODO(), v1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, jobs.Items, 1)
+	assert.Equal(t, "install", jobs.Items[0].Labels[launcher.CurationTypeLabel])
+	assert.Contains(t, r.launching, types.NamespacedName{Namespace: "cluster-a", Name: "cluster-a"},
+		"the launched curation holds its slot until the cache has its curator job")
+}
+
+func TestReconcileQueuedCurationPriority(t *testing.T) {
+	low, high := getInstallCurator("cluster-low", 0), getInstallCurator("cluster-high", 10)
+	r := getCleanupReconciler(t, low, high)
+	r.MaxRunningJobs = map[string]int{"install": 1}
+	running := getRunningCuratorJob("cluster-b", "install")
+	assert.Nil(t, r.Create(context.TODO(), running))
+
+	// The low priority curation is queued first
+	_, low = reconcileCurator(t, r, "cluster-low")
+	_, high = reconcileCurator(t, r, "cluster-high")
+	assert.True(t, meta.IsStatusConditionTrue(low.Status.Conditions, QueuedCondition))
+	assert.True(t, meta.IsStatusConditionTrue(high.Status.Conditions, QueuedCondition))
+
+	running.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}}
+	assert.Nil(t, r.Status().Update(context.TODO(), running))
+	requests := r.curatorsForJob(context.TODO(), running)
+	assert.ElementsMatch(t, []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Namespace: "cluster-low", Name: "cluster-low"}},
+		{NamespacedName: types.NamespacedName{Namespace: "cluster-high", Name: "cluster-high"}},
+	}, requests, "the queued curations are reconciled when a slot is free")
+
+	_, low = reconcileCurator(t, r, "cluster-low")
+	assert.True(t, meta.IsStatusConditionTrue(low.Status.Conditions, QueuedCondition), "a higher priority is ahead")
+	assert.Contains(t, meta.FindStatusCondition(low.Status.Conditions, QueuedCondition).Message,
+		"1 queued curations are ahead")
+
+	_, high = reconcileCurator(t, r, "cluster-high")
+	assert.False(t, meta.IsStatusConditionTrue(high.Status.Conditions, QueuedCondition))
+	jobs, err := r.Kubeset.BatchV1().Jobs("cluster-high").List(context.TODO(), v1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, jobs.Items, 1)
+
+	_, low = reconcileCurator(t, r, "cluster-low")
+	assert.Contains(t, meta.FindStatusCondition(low.Status.Conditions, QueuedCondition).Message,
+		"1 running install curator jobs", "the started curation holds the slot")
+}
+
+func TestAdmitCurationCountsLaunchingCurations(t *testing.T) {
+	curator := getInstallCurator("cluster-a", 0)
+	r := getCleanupReconciler(t, curator)
+	r.MaxRunningJobs = map[string]int{"install": 1, "upgrade": 1}
+	launching := types.NamespacedName{Namespace: "cluster-b", Name: "cluster-b"}
+	r.launching = map[types.NamespacedName]launch{launching: {curationType: "install", admitted: time.Now()}}
+
+	admitted, err := r.admitCuration(context.TODO(), curator)
+	assert.Nil(t, err)
+	assert.False(t, admitted, "the curator job of the admitted curation is not created yet")
+
+	r.launched(launching, true)
+	assert.Nil(t, r.Get(context.TODO(), client.ObjectKeyFromObject(curator), curator))
+	admitted, err = r.admitCuration(context.TODO(), curator)
+	assert.Nil(t, err)
+	assert.False(t, admitted, "the curator job of the launched curation is not in the cache yet")
+
+	// The cache has the curator job of the launched curation, it is counted once
+	running := getRunningCuratorJob("cluster-b", "install")
+	running.Labels[utils.ClusterCuratorLabel] = "cluster-b"
+	assert.Nil(t, r.Create(context.TODO(), running))
+	assert.Nil(t, r.Get(context.TODO(), client.ObjectKeyFromObject(curator), curator))
+	admitted, err = r.admitCuration(context.TODO(), curator)
+	assert.Nil(t, err)
+	assert.False(t, admitted)
+	assert.Empty(t, r.launching)
+	assert.Contains(t, meta.FindStatusCondition(curator.Status.Conditions, QueuedCondition).Message,
+		"1 running install curator jobs")
+
+	running.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+	assert.Nil(t, r.Status().Update(context.TODO(), running))
+	assert.Nil(t, r.Get(context.TODO(), client.ObjectKeyFromObject(curator), curator))
+	admitted, err = r.admitCuration(context.TODO(), curator)
+	assert.Nil(t, err)
+	assert.True(t, admitted)
+}
+
+func TestAdmitCurationFreesFailedLaunches(t *testing.T) {
+	curator := getInstallCurator("cluster-a", 0)
+	r := getCleanupReconciler(t, curator)
+	r.MaxRunningJobs = map[string]int{"install": 1}
+	failed := types.NamespacedName{Namespace: "cluster-b", Name: "cluster-b"}
+	expired := types.NamespacedName{Namespace: "cluster-c", Name: "cluster-c"}
+	r.launching = map[types.NamespacedName]launch{
+		failed:  {curationType: "install", admitted: time.Now()},
+		expired: {curationType: "install", admitted: time.Now().Add(-launchCacheTimeout - time.Second)},
+	}
+
+	r.launched(failed, false)
+	admitted, err := r.admitCuration(context.TODO(), curator)
+	assert.Nil(t, err)
+	assert.True(t, admitted, "the curator job of cluster-b was not created, the one of cluster-c was not cached")
+	assert.Len(t, r.launching, 1)
+	assert.Contains(t, r.launching, types.NamespacedName{Namespace: "cluster-a", Name: "cluster-a"})
+}
@@ -0,0 +1,129 @@
+// Copyright Contributors to the Open Cluster Management project.
+
+package controllers
+
+import (
+	"context"
+
+	clustercuratorv1 "github.com/stolostron/cluster-curator-controller/pkg/api/v1beta1"
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/secrets"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Index of the cached generated secrets, by the SourceLabel of their source credential
+const GeneratedSecretSourceIndex = "metadata.labels.curator-source"
+
+// The generated secrets are cached as metadata only, their data is never read by the controller
+func generatedSecretMeta() *v1.PartialObjectMetadata {
+	secret := &v1.PartialObjectMetadata{}
+	secret.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Secret"))
+	return secret
+}
+
+func generatedSecretMetaList() *v1.PartialObjectMetadataList {
+	secretList := &v1.PartialObjectMetadataList{}
+	secretList.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("SecretList"))
+	return secretList
+}
+
+// IndexGeneratedSecretSource - Indexes the generated secrets by the SourceLabel of their source credential
+func IndexGeneratedSecretSource(obj client.Object) []string {
+	if source := obj.GetLabels()[secrets.SourceLabel]; source != "" {
+		return []string{source}
+	}
+	return nil
+}
+
+// IndexGeneratedSecrets - Registers GeneratedSecretSourceIndex on the cache of the generated secrets
+func IndexGeneratedSecrets(ctx context.Context, indexer client.FieldIndexer) error {
+	return indexer.IndexField(ctx, generatedSecretMeta(), GeneratedSecretSourceIndex, IndexGeneratedSecretSource)
+}
+
+/* curatorsForCredential - Maps a Provider credential to the ClusterCurators of the namespaces with secrets
+ * generated from it, and requests the re-sync of their generated secrets. Both are read from the cache.
+ */
+func (r *ClusterCuratorReconciler) curatorsForCredential(ctx context.Context, obj client.Object) []reconcile.Request {
+	path := obj.GetNamespace() + "/" + obj.GetName()
+	generated := generatedSecretMetaList()
+	err := r.GeneratedSecrets.List(ctx, generated,
+		client.MatchingFields{GeneratedSecretSourceIndex: secrets.SourceLabelValue(path)})
+	if err != nil {
+		r.Log.Error(err, "Unable to list the secrets generated from the Provider credential", "credential", path)
+		return nil
+	}
+
+	namespaces := map[string]bool{}
+	requests := []reconcile.Request{}
+	for _, secret := range generated.Items {
+		if namespaces[secret.Namespace] {
+			continue
+		}
+		namespaces[secret.Namespace] = true
+
+		curators := &clustercuratorv1.ClusterCuratorList{}
+		if err := r.List(ctx, curators, client.InNamespace(secret.Namespace)); err != nil {
+			r.Log.Error(err, "Unable to list the ClusterCurators", "namespace", secret.Namespace)
+			continue
+		}
+		for _, curator := range curators.Items {
+			key := types.NamespacedName{Namespace: curator.Namespace, Name: curator.Name}
+			r.requestResync(key)
+			requests = append(requests, reconcile.Request{NamespacedName: key})
+		}
+	}
+	return requests
+}
+
+// requestResync - Records that the generated secrets of the ClusterCurator are re-synced on its next reconcile
+func (r *ClusterCuratorReconciler) requestResync(curator types.NamespacedName) {
+	r.resyncLock.Lock()
+	defer r.resyncLock.Unlock()
+
+	if r.resync == nil {
+		r.resync = map[types.NamespacedName]bool{}
+	}
+	r.resync[curator] = true
+}
+
+// takeResync - Whether a re-sync of the ClusterCurator was requested, the request is consumed
+func (r *ClusterCuratorReconciler) takeResync(curator types.NamespacedName) bool {
+	r.resyncLock.Lock()
+	defer r.resyncLock.Unlock()
+
+	requested := r.resync[curator]
+	delete(r.resync, curator)
+	return requested
+}
+
+/* resyncGeneratedSecrets - Regenerates the secrets of the ClusterCurator namespace whose source credential
+ * was rotated, only after an event of the credential. A failed re-sync is requested again.
+ */
+func (r *ClusterCuratorReconciler) resyncGeneratedSecrets(ctx context.Context, curator *clustercuratorv1.ClusterCurator) {
+	key := types.NamespacedName{Namespace: curator.Namespace, Name: curator.Name}
+	if !r.takeResync(key) {
+		return
+	}
+	log := r.Log.WithValues("ClusterCurator", key)
+
+	generated := generatedSecretMetaList()
+	if err := r.GeneratedSecrets.List(ctx, generated, client.InNamespace(curator.Namespace)); err != nil {
+		log.Error(err, "Unable to list the secrets generated from the Provider credential")
+		r.requestResync(key)
+		return
+	}
+	metadata := make([]v1.ObjectMeta, 0, len(generated.Items))
+	for _, secret := range generated.Items {
+		metadata = append(metadata, secret.ObjectMeta)
+	}
+
+	if resynced, err := secrets.ResyncGeneratedSecrets(ctx, r.Kubeset, curator.Namespace, metadata); err != nil {
+		log.Error(err, "Unable to re-sync the secrets generated from the Provider credential")
+		r.requestResync(key)
+	} else if len(resynced) > 0 {
+		log.V(0).Info("Re-synced the secrets generated from the Provider credential", "sources", resynced)
+	}
+}
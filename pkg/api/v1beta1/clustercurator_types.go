@@ -2,6 +2,7 @@
 package v1beta1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
@@ -18,7 +19,8 @@ type ClusterCuratorSpec struct {
 	// +kubebuilder:validation:Enum={install,scale,upgrade,destroy,delete-cluster-namespace}
 	DesiredCuration string `json:"desiredCuration,omitempty"`
 
-	// Points to the Cloud Provider or Ansible Provider secret, format: namespace/secretName
+	// Points to the Cloud Provider or Ansible Provider secret, format: namespace/secretName,
+	// or to a credential in an external store, format: vault://mount/path
 	ProviderCredentialPath string `json:"providerCredentialPath,omitempty"`
 
 	// An install curation runs these prehooks and posthooks.
@@ -44,6 +46,96 @@ type ClusterCuratorSpec struct {
 
 	// Inventory values are supplied for use with the pre/post jobs.
 	Inventory string `json:"inventory,omitempty"`
+
+	// Customizes the pod of the curator Job, over the defaults of the controller.
+	// It is not applied to an overrideJob.
+	// +optional
+	JobTemplate *JobTemplate `json:"jobTemplate,omitempty"`
+
+	// What happens to the objects of the curations when the ClusterCurator is deleted. 'Delete' removes the
+	// curator Jobs, AnsibleJobs, upgrade ManagedClusterViews and ManagedClusterActions, the curator RBAC and
+	// the generated secrets. The generated cloud credentials, the -creds, -vsphere-certs and -infra-kubeconfig
+	// secrets, are kept while the ClusterDeployment or HostedCluster exists, to deprovision the cluster: the
+	// destroy curation deletes them. 'Orphan' keeps them all. By default, they are deleted.
+	// +kubebuilder:validation:Enum={Delete,Orphan}
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// Order of the curation when it waits for a curator Job slot of the hub, the curations with a higher
+	// priority start first. By default, the priority is 0.
+	// +optional
+	Priority int32 `json:"priority,omitempty"`
+
+	// How the curations are started. With 'DesiredCuration', setting desiredCuration starts a curation, the
+	// controller removes desiredCuration and curatorJob when it is done. With 'Generation', a change of the spec
+	// starts the desiredCuration, the spec is never changed by the controller and the curation is tracked in
+	// the status. By default, it is 'DesiredCuration'.
+	// +kubebuilder:validation:Enum={DesiredCuration,Generation}
+	// +optional
+	CurationTrigger CurationTrigger `json:"curationTrigger,omitempty"`
+}
+
+// JobTemplate customizes the pod of the curator Job. Only the fields that are set replace the defaults,
+// the labels, annotations and nodeSelector are merged. The container settings apply to every step.
+type JobTemplate struct {
+	// Labels added to the pod of the curator Job.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations added to the pod of the curator Job.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// Service account of the curator Job, instead of the curator service account of the curation type.
+	// It must be allowed what the steps of the curation do.
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// Pull policy of the curator image.
+	// +kubebuilder:validation:Enum={Always,IfNotPresent,Never}
+	// +optional
+	ImagePullPolicy corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
+
+	// Secrets of the cluster namespace used to pull the curator image from a private registry.
+	// +optional
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+
+	// CPU and memory requests and limits of each step.
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// Security context of the pod.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +optional
+	SecurityContext *corev1.PodSecurityContext `json:"securityContext,omitempty"`
+
+	// Security context of each step.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +optional
+	ContainerSecurityContext *corev1.SecurityContext `json:"containerSecurityContext,omitempty"`
+
+	// Seconds the finished Job is kept, 3600 by default.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	TTLSecondsAfterFinished *int32 `json:"ttlSecondsAfterFinished,omitempty"`
+
+	// Seconds the Job can run before it is terminated, it is not limited by default.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	ActiveDeadlineSeconds *int64 `json:"activeDeadlineSeconds,omitempty"`
 }
 
 type Hook struct {
@@ -72,11 +164,17 @@ type Hook struct {
 	// of Ansible tasks in a job should not be run.
 	// +optional
 	SkipTags string `json:"skip_tags,omitempty"`
+
+	// TowerAuthSecret overrides the TowerAuthSecret of the hooks for this hook, when its template is in
+	// another Ansible Tower. Format: secretName or vault://mount/path
+	// +optional
+	TowerAuthSecret string `json:"towerAuthSecret,omitempty"`
 }
 
 type Hooks struct {
 
 	// TowerAuthSecret is an Ansible secret used in the template to provide authentication to an Ansbile tower.
+	// It can reference a credential in an external store, format: vault://mount/path
 	// +kubebuilder:validation:Required
 	TowerAuthSecret string `json:"towerAuthSecret,omitempty"`
 
@@ -102,6 +200,7 @@ type Hooks struct {
 type UpgradeHooks struct {
 
 	// TowerAuthSecret is an Ansible secret used in the template to provide authentication to an Ansbile tower.
+	// It can reference a credential in an external store, format: vault://mount/path
 	// +kubebuilder:validation:Required
 	TowerAuthSecret string `json:"towerAuthSecret,omitempty"`
 
@@ -149,10 +248,57 @@ type UpgradeHooks struct {
 }
 
 // ClusterCuratorStatus defines the observed state of ClusterCurator work.
+// The status is a subresource, it is only written with the status subresource: a status written with an
+// update or patch of the ClusterCurator is dropped.
 type ClusterCuratorStatus struct {
 	// Track the conditions for each step in the desired curation that is being
 	// executed as a job.
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Generation of the ClusterCurator when its last curation started, with the Generation curation trigger.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Hash of the spec curated by the last curation, with the Generation curation trigger. A curation starts
+	// when the generation changed and the spec differs from the curated one.
+	// +optional
+	ObservedSpecHash string `json:"observedSpecHash,omitempty"`
+
+	// Kubernetes job resource of the running curation, with the Generation curation trigger.
+	// +optional
+	CuratorJob string `json:"curatorJob,omitempty"`
+
+	// The last curation that succeeded or failed, with the Generation curation trigger.
+	// +optional
+	LastCompletedCuration *CompletedCuration `json:"lastCompletedCuration,omitempty"`
+
+	// The last operation requested with the operation field, and its result.
+	// +optional
+	LastOperation *OperationStatus `json:"lastOperation,omitempty"`
+}
+
+// CompletedCuration is a curation that succeeded or failed
+type CompletedCuration struct {
+	// The curation, install, scale, upgrade or destroy.
+	Curation string `json:"curation"`
+
+	// Generation of the ClusterCurator when the curation started.
+	// +optional
+	Generation int64 `json:"generation,omitempty"`
+
+	// Kubernetes job resource of the curation.
+	// +optional
+	CuratorJob string `json:"curatorJob,omitempty"`
+
+	// Result of the curation, Succeeded or Failed.
+	Result CurationResult `json:"result"`
+
+	// Why the curation failed.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// When the curation completed.
+	CompletionTime metav1.Time `json:"completionTime"`
 }
 
 // HookType indicates the type for the hook. It can be 'Job' or 'Workflow'
@@ -167,15 +313,132 @@ const (
 	HookTypeWorkflow HookType = "Workflow"
 )
 
+// DeletionPolicy of the objects of the curations. It can be 'Delete' or 'Orphan'
+// +kubebuilder:validation:Enum=Delete;Orphan
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete, the objects are deleted with the ClusterCurator
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+
+	// DeletionPolicyOrphan, the objects are kept when the ClusterCurator is deleted
+	DeletionPolicyOrphan DeletionPolicy = "Orphan"
+)
+
+// CurationTrigger is how the curations are started. It can be 'DesiredCuration' or 'Generation'
+// +kubebuilder:validation:Enum=DesiredCuration;Generation
+type CurationTrigger string
+
+const (
+	// CurationTriggerDesiredCuration, setting desiredCuration starts a curation, it is removed when done
+	CurationTriggerDesiredCuration CurationTrigger = "DesiredCuration"
+
+	// CurationTriggerGeneration, a change of the spec starts the desiredCuration, the spec is not changed
+	CurationTriggerGeneration CurationTrigger = "Generation"
+)
+
+// CurationResult is the result of a completed curation. It can be 'Succeeded' or 'Failed'
+type CurationResult string
+
+const (
+	// CurationSucceeded, the curation completed its steps
+	CurationSucceeded CurationResult = "Succeeded"
+
+	// CurationFailed, a step of the curation failed
+	CurationFailed CurationResult = "Failed"
+)
+
 // +kubebuilder:object:root=true
 
 // Operation contains information about a requested or running operation
+// +kubebuilder:validation:XValidation:rule="!has(self.action) || (has(self.phase) && has(self.curation))",message="The rerun action needs a phase and a curation"
 type Operation struct {
 	// Option for retrying a failed posthook job. The supported options are 'installPosthook' or 'upgradePosthook'.
+	// It is the rerun action of the posthook phase, it is removed once the posthook ran.
 	// +kubebuilder:validation:Enum={installPosthook,upgradePosthook}
 	RetryPosthook string `json:"retryPosthook,omitempty"`
+
+	// Action of the operation, 'rerun' runs one phase of a curation again in a new curator Job.
+	// +kubebuilder:validation:Enum={rerun}
+	// +optional
+	Action OperationAction `json:"action,omitempty"`
+
+	// The phase that runs again, 'prehook', 'posthook' or a step of the curation, for example
+	// 'activate-and-monitor', 'monitor-import', 'upgrade-cluster', 'monitor-upgrade', 'destroy-cluster' or
+	// 'monitor-destroy'.
+	// +optional
+	Phase string `json:"phase,omitempty"`
+
+	// The curation of the phase, 'install', 'upgrade', 'destroy' or 'scale'.
+	// +kubebuilder:validation:Enum={install,upgrade,destroy,scale}
+	// +optional
+	Curation string `json:"curation,omitempty"`
+
+	// Identifies the request, the phase runs again for each new nonce. The operation is kept once it ran, its
+	// result is reported in status.lastOperation.
+	// +optional
+	Nonce string `json:"nonce,omitempty"`
 }
 
+// OperationAction is the action of an operation. It can be 'rerun'
+// +kubebuilder:validation:Enum=rerun
+type OperationAction string
+
+const (
+	// OperationRerun, one phase of a curation runs again
+	OperationRerun OperationAction = "rerun"
+)
+
+// OperationStatus is the last operation that was run
+type OperationStatus struct {
+	// Action of the operation.
+	Action OperationAction `json:"action"`
+
+	// The phase that ran again.
+	Phase string `json:"phase"`
+
+	// The curation of the phase.
+	Curation string `json:"curation"`
+
+	// Nonce of the operation request.
+	// +optional
+	Nonce string `json:"nonce,omitempty"`
+
+	// Kubernetes job resource of the operation.
+	// +optional
+	CuratorJob string `json:"curatorJob,omitempty"`
+
+	// Result of the operation, Running, Succeeded or Failed.
+	Result OperationResult `json:"result"`
+
+	// Why the operation failed.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// When the operation started.
+	StartTime metav1.Time `json:"startTime"`
+
+	// When the operation completed.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// OperationResult is the result of an operation. It can be 'Running', 'Succeeded' or 'Failed'
+type OperationResult string
+
+const (
+	// OperationRunning, the curator Job of the operation runs
+	OperationRunning OperationResult = "Running"
+
+	// OperationSucceeded, the phase ran again
+	OperationSucceeded OperationResult = "Succeeded"
+
+	// OperationFailed, the phase failed or can not run
+	OperationFailed OperationResult = "Failed"
+)
+
+// +kubebuilder:subresource:status
+
 // ClusterCurator is the custom resource for the clustercurators API.
 // This kind allows you to run Ansible prehook and posthook jobs before provisioning a Hive or HyperShift cluster
 // and importing a cluster. Additionally, cluster upgrade and destroy operations are supported as well.
@@ -5,8 +5,11 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"os"
 
 	clustercuratorv1 "github.com/stolostron/cluster-curator-controller/pkg/api/v1beta1"
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/rbac"
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/secrets"
 	"github.com/stolostron/cluster-curator-controller/pkg/jobs/utils"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -36,11 +39,27 @@ const DeleteClusterDeployment = "destroy-cluster"
 const MonitorDestroy = "monitor-destroy"
 const DeleteClusterNamespace = "delete-cluster-namespace"
 
+/* The curator job runs its pod again after a transient API error, the steps are checkpointed and skip
+ * their completed work. The curator exits with RetryExitCode for those errors, the other failures fail the
+ * job at once. An evicted pod, or a pod lost with its node, is not counted.
+ */
+const CuratorJobBackoffLimit = 3
+const RetryExitCode = 8
+
+// Label of the curator jobs and their pods, the controller watches them for an abnormal termination
+const CuratorJobLabel = "open-cluster-management"
+const CuratorJobLabelValue = "curator-job"
+
+// Label of the curator jobs with their curation type, the running jobs of a type are capped on the hub
+const CurationTypeLabel = "cluster.open-cluster-management.io/curation-type"
+
 type Launcher struct {
 	client         client.Client
 	kubeset        kubernetes.Interface
 	imageURI       string
 	clusterCurator clustercuratorv1.ClusterCurator
+	jobDefaults    *clustercuratorv1.JobTemplate
+	proxy          *ProxyConfig
 }
 
 func NewLauncher(
@@ -57,32 +76,123 @@ func NewLauncher(
 	}
 }
 
+// WithJobTemplateDefaults - The default jobTemplate of the controller, the jobTemplate of the ClusterCurator is applied over it
+func (I *Launcher) WithJobTemplateDefaults(defaults *clustercuratorv1.JobTemplate) *Launcher {
+	I.jobDefaults = defaults
+	return I
+}
+
+// WithProxy - The proxy and trusted CA bundle of the hub, added to every container of the job
+func (I *Launcher) WithProxy(proxy *ProxyConfig) *Launcher {
+	I.proxy = proxy
+	return I
+}
+
+func getResourceSettings() corev1.ResourceRequirements {
+	return corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("0.3m"),
+			corev1.ResourceMemory: resource.MustParse("30Mi"),
+		},
+		Limits: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("2m"),
+			corev1.ResourceMemory: resource.MustParse("45Mi"),
+		},
+	}
+}
+
+func getPodFailurePolicy() *batchv1.PodFailurePolicy {
+	return &batchv1.PodFailurePolicy{
+		Rules: []batchv1.PodFailurePolicyRule{
+			{
+				Action: batchv1.PodFailurePolicyActionIgnore,
+				OnPodConditions: []batchv1.PodFailurePolicyOnPodConditionsPattern{
+					{Type: corev1.DisruptionTarget, Status: corev1.ConditionTrue},
+				},
+			},
+			{
+				Action: batchv1.PodFailurePolicyActionFailJob,
+				OnExitCodes: &batchv1.PodFailurePolicyOnExitCodesRequirement{
+					Operator: batchv1.PodFailurePolicyOnExitCodesOpNotIn,
+					Values:   []int32{RetryExitCode},
+				},
+			},
+		},
+	}
+}
+
+// Runs the applycloudprovider step of the provider first, so the Hive and Ansible Tower secrets exist for the other steps
+func addApplyCloudProvider(newJob *batchv1.Job, provider string, clusterName string, imageURI string) {
+	step := secrets.ApplyCloudProvider + "-" + provider
+
+	annotations := newJob.GetAnnotations()
+	annotations[step] = "Apply the " + provider + " provider credential to the cluster namespace"
+
+	newJob.Spec.Template.Spec.InitContainers = append([]corev1.Container{
+		corev1.Container{
+			Name:            step,
+			Image:           imageURI,
+			Command:         []string{CurCmd, step, clusterName},
+			ImagePullPolicy: corev1.PullAlways,
+			Resources:       getResourceSettings(),
+		},
+	}, newJob.Spec.Template.Spec.InitContainers...)
+}
+
+/* The Vault settings of the controller, passed to the curator job to read the vault:// credentials.
+ * VAULT_TOKEN is not passed, it would be readable in the Job, the job logs in with its service account.
+ */
+var secretStoreEnv = []string{
+	secrets.VaultAddrEnv,
+	secrets.VaultNamespaceEnv,
+	secrets.VaultRoleEnv,
+	secrets.VaultAuthPathEnv,
+	secrets.VaultKVVersionEnv,
+}
+
+func addSecretStoreEnv(newJob *batchv1.Job) {
+	env := []corev1.EnvVar{}
+	for _, name := range secretStoreEnv {
+		if value := os.Getenv(name); value != "" {
+			env = append(env, corev1.EnvVar{Name: name, Value: value})
+		}
+	}
+	if len(env) == 0 {
+		return
+	}
+
+	podSpec := &newJob.Spec.Template.Spec
+	for i := range podSpec.InitContainers {
+		podSpec.InitContainers[i].Env = append(podSpec.InitContainers[i].Env, env...)
+	}
+	for i := range podSpec.Containers {
+		podSpec.Containers[i].Env = append(podSpec.Containers[i].Env, env...)
+	}
+}
+
 func getBatchJob(
 	clusterName string,
 	clusterNamespace string,
 	imageURI string,
-	curator clustercuratorv1.ClusterCurator) *batchv1.Job {
+	curator clustercuratorv1.ClusterCurator) (*batchv1.Job, error) {
 
 	var ttlf int32 = 3600
+	var backoffLimit int32 = CuratorJobBackoffLimit
 
+	// An operation runs one phase of its curation
+	if op := utils.CurrentOperation(&curator); op != nil {
+		return getRerunJob(clusterName, clusterNamespace, imageURI, curator, op)
+	}
 	desiredCuration := curator.Spec.DesiredCuration
-	if curator.Operation != nil && curator.Operation.RetryPosthook != "" {
-		desiredCuration = curator.Operation.RetryPosthook
+	serviceAccount, err := rbac.CurationServiceAccount(rbac.CurationType(desiredCuration))
+	if err != nil {
+		return nil, err
 	}
 
 	isPrehook := false
 	isPosthook := false
 
-	var resourceSettings = corev1.ResourceRequirements{
-		Requests: corev1.ResourceList{
-			corev1.ResourceCPU:    resource.MustParse("0.3m"),
-			corev1.ResourceMemory: resource.MustParse("30Mi"),
-		},
-		Limits: corev1.ResourceList{
-			corev1.ResourceCPU:    resource.MustParse("2m"),
-			corev1.ResourceMemory: resource.MustParse("45Mi"),
-		},
-	}
+	var resourceSettings = getResourceSettings()
 	var newJob = &batchv1.Job{}
 	switch desiredCuration {
 	case "install":
@@ -106,11 +216,12 @@ func getBatchJob(
 				},
 			},
 			Spec: batchv1.JobSpec{
-				BackoffLimit:            new(int32),
+				BackoffLimit:            &backoffLimit,
+				PodFailurePolicy:        getPodFailurePolicy(),
 				TTLSecondsAfterFinished: &ttlf,
 				Template: corev1.PodTemplateSpec{
 					Spec: corev1.PodSpec{
-						ServiceAccountName: "cluster-installer",
+						ServiceAccountName: serviceAccount,
 						RestartPolicy:      corev1.RestartPolicyNever,
 						InitContainers: []corev1.Container{
 							corev1.Container{
@@ -225,11 +336,12 @@ func getBatchJob(
 				Annotations: annotations,
 			},
 			Spec: batchv1.JobSpec{
-				BackoffLimit:            new(int32),
+				BackoffLimit:            &backoffLimit,
+				PodFailurePolicy:        getPodFailurePolicy(),
 				TTLSecondsAfterFinished: &ttlf,
 				Template: corev1.PodTemplateSpec{
 					Spec: corev1.PodSpec{
-						ServiceAccountName: "cluster-installer",
+						ServiceAccountName: serviceAccount,
 						RestartPolicy:      corev1.RestartPolicyNever,
 						InitContainers:     jobInitContainers,
 						Containers: []corev1.Container{
@@ -264,11 +376,12 @@ func getBatchJob(
 				},
 			},
 			Spec: batchv1.JobSpec{
-				BackoffLimit:            new(int32),
+				BackoffLimit:            &backoffLimit,
+				PodFailurePolicy:        getPodFailurePolicy(),
 				TTLSecondsAfterFinished: &ttlf,
 				Template: corev1.PodTemplateSpec{
 					Spec: corev1.PodSpec{
-						ServiceAccountName: "cluster-installer",
+						ServiceAccountName: serviceAccount,
 						RestartPolicy:      corev1.RestartPolicyNever,
 						InitContainers: []corev1.Container{
 							corev1.Container{
@@ -297,60 +410,11 @@ func getBatchJob(
 				},
 			},
 		}
-	case "installPosthook", "upgradePosthook":
-		if (desiredCuration == "installPosthook" && curator.Spec.Install.Posthook != nil) || (desiredCuration == "upgradePosthook" && curator.Spec.Upgrade.Posthook != nil) {
-			isPosthook = true
-		}
-		newJob = &batchv1.Job{
-			ObjectMeta: v1.ObjectMeta{
-				GenerateName: "curator-job-",
-				Namespace:    clusterNamespace,
-				Labels: map[string]string{
-					"open-cluster-management": "curator-job",
-				},
-				Annotations: map[string]string{
-					PostAJob:     "Retry a posthook job",
-					DoneDoneDone: "Cluster Curator job has completed",
-				},
-			},
-			Spec: batchv1.JobSpec{
-				BackoffLimit:            new(int32),
-				TTLSecondsAfterFinished: &ttlf,
-				Template: corev1.PodTemplateSpec{
-					Spec: corev1.PodSpec{
-						ServiceAccountName: "cluster-installer",
-						RestartPolicy:      corev1.RestartPolicyNever,
-						InitContainers:     []corev1.Container{},
-						Containers: []corev1.Container{
-							corev1.Container{
-								Name:    DoneDoneDone,
-								Image:   imageURI,
-								Command: append([]string{CurCmd, DoneDoneDone, clusterName}),
-							},
-						},
-					},
-				},
-			},
-		}
 	}
 	if isPrehook {
 		annotations := newJob.GetAnnotations()
 		annotations[PreAJob] = "Running pre-" + desiredCuration + " AnsibleJob"
-		initContainers := []corev1.Container{
-			corev1.Container{
-				Name:            PreAJob,
-				Image:           imageURI,
-				Command:         append([]string{CurCmd, PreAJob, clusterName}),
-				ImagePullPolicy: corev1.PullIfNotPresent,
-				Env: []corev1.EnvVar{
-					corev1.EnvVar{
-						Name:  "JOB_TYPE",
-						Value: "prehook",
-					},
-				},
-				Resources: resourceSettings,
-			},
-		}
+		initContainers := []corev1.Container{hookContainer(PreAJob, utils.PrehookPhase, clusterName, imageURI)}
 
 		for _, containers := range newJob.Spec.Template.Spec.InitContainers {
 			initContainers = append(initContainers, containers)
@@ -361,35 +425,216 @@ func getBatchJob(
 		annotations := newJob.GetAnnotations()
 		annotations[PostAJob] = "Running post-" + desiredCuration + " AnsibleJob"
 
-		newJob.Spec.Template.Spec.InitContainers = append(newJob.Spec.Template.Spec.InitContainers, corev1.Container{
-			Name:            PostAJob,
-			Image:           imageURI,
-			Command:         append([]string{CurCmd, PostAJob, clusterName}),
-			ImagePullPolicy: corev1.PullIfNotPresent,
-			Env: []corev1.EnvVar{
-				corev1.EnvVar{
-					Name:  "JOB_TYPE",
-					Value: "posthook",
+		newJob.Spec.Template.Spec.InitContainers = append(newJob.Spec.Template.Spec.InitContainers,
+			hookContainer(PostAJob, utils.PosthookPhase, clusterName, imageURI))
+	}
+	newJob.Spec.Template.Labels = curator.Labels
+	return newJob, nil
+
+}
+
+// The step running the prehooks or posthooks of the curation, JOB_TYPE is the phase
+func hookContainer(name string, phase string, clusterName string, imageURI string) corev1.Container {
+	return corev1.Container{
+		Name:            name,
+		Image:           imageURI,
+		Command:         append([]string{CurCmd, name, clusterName}),
+		ImagePullPolicy: corev1.PullIfNotPresent,
+		Env: []corev1.EnvVar{
+			corev1.EnvVar{
+				Name:  "JOB_TYPE",
+				Value: phase,
+			},
+		},
+		Resources: getResourceSettings(),
+	}
+}
+
+/* getRerunJob - The curator job of an operation, it runs the step of the phase then done. A posthook runs
+ * with the posthook service account, the other phases with the service account of their curation.
+ */
+func getRerunJob(
+	clusterName string,
+	clusterNamespace string,
+	imageURI string,
+	curator clustercuratorv1.ClusterCurator,
+	op *clustercuratorv1.Operation) (*batchv1.Job, error) {
+
+	var ttlf int32 = 3600
+	var backoffLimit int32 = CuratorJobBackoffLimit
+
+	annotations := map[string]string{DoneDoneDone: "Cluster Curator job has completed"}
+	initContainers := []corev1.Container{}
+	if step, err := rerunStep(clusterName, clusterNamespace, imageURI, curator, op); err == nil {
+		annotations[step.Name] = "Re-run the " + op.Phase + " phase of the " + op.Curation + " curation"
+		initContainers = append(initContainers, *step)
+	}
+	serviceAccount, err := rbac.CurationServiceAccount(rbac.PhaseCurationType(op.Curation, op.Phase))
+	if err != nil {
+		return nil, err
+	}
+
+	return &batchv1.Job{
+		ObjectMeta: v1.ObjectMeta{
+			GenerateName: "curator-job-",
+			Namespace:    clusterNamespace,
+			Labels: map[string]string{
+				"open-cluster-management": "curator-job",
+			},
+			Annotations: annotations,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            &backoffLimit,
+			PodFailurePolicy:        getPodFailurePolicy(),
+			TTLSecondsAfterFinished: &ttlf,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: v1.ObjectMeta{Labels: curator.Labels},
+				Spec: corev1.PodSpec{
+					ServiceAccountName: serviceAccount,
+					RestartPolicy:      corev1.RestartPolicyNever,
+					InitContainers:     initContainers,
+					Containers: []corev1.Container{
+						corev1.Container{
+							Name:    DoneDoneDone,
+							Image:   imageURI,
+							Command: append([]string{CurCmd, DoneDoneDone, clusterName}),
+						},
+					},
 				},
 			},
-			Resources: resourceSettings,
+		},
+	}, nil
+}
+
+/* rerunStep - The step of the phase of an operation. The hooks run when the curation has some, the other
+ * phases are the steps of the curator job of the curation.
+ */
+func rerunStep(
+	clusterName string,
+	clusterNamespace string,
+	imageURI string,
+	curator clustercuratorv1.ClusterCurator,
+	op *clustercuratorv1.Operation) (*corev1.Container, error) {
+
+	if op.Action != clustercuratorv1.OperationRerun {
+		return nil, utils.NewError(utils.ReasonInvalidSpec, "The operation action %q is not supported", op.Action)
+	}
+
+	var hooks clustercuratorv1.Hooks
+	switch op.Curation {
+	case "install":
+		hooks = curator.Spec.Install
+	case "scale":
+		hooks = curator.Spec.Scale
+	case "destroy":
+		hooks = curator.Spec.Destroy
+	case "upgrade":
+		hooks = clustercuratorv1.Hooks{Prehook: curator.Spec.Upgrade.Prehook, Posthook: curator.Spec.Upgrade.Posthook}
+	default:
+		return nil, utils.NewError(utils.ReasonInvalidSpec, "The operation curation %q is not supported", op.Curation)
+	}
+
+	var step corev1.Container
+	switch op.Phase {
+	case utils.PrehookPhase:
+		if len(hooks.Prehook) == 0 {
+			return nil, utils.NewError(utils.ReasonInvalidSpec, "The %v curation has no prehook", op.Curation)
+		}
+		step = hookContainer(PreAJob, utils.PrehookPhase, clusterName, imageURI)
+	case utils.PosthookPhase:
+		if len(hooks.Posthook) == 0 {
+			return nil, utils.NewError(utils.ReasonInvalidSpec, "The %v curation has no posthook", op.Curation)
+		}
+		step = hookContainer(PostAJob, utils.PosthookPhase, clusterName, imageURI)
+	default:
+		original := *curator.DeepCopy()
+		original.Operation = nil
+		original.Spec.DesiredCuration = op.Curation
+		found := false
+		originalJob, err := getBatchJob(clusterName, clusterNamespace, imageURI, original)
+		if err != nil {
+			return nil, err
+		}
+		for _, container := range originalJob.Spec.Template.Spec.InitContainers {
+			if container.Name == op.Phase {
+				step, found = container, true
+			}
+		}
+		if !found {
+			return nil, utils.NewError(utils.ReasonInvalidSpec, "The %v curation has no %v phase", op.Curation,
+				op.Phase)
+		}
+	}
+	return &step, nil
+}
+
+// ValidateOperation - The phase of the current operation can run again
+func ValidateOperation(curator *clustercuratorv1.ClusterCurator) error {
+	op := utils.CurrentOperation(curator)
+	if op == nil {
+		return nil
+	}
+	_, err := rerunStep(curator.Name, curator.Namespace, "", *curator, op)
+	return err
+}
+
+/* Labels the job and its pod, an overrideJob included, the labels of the ClusterCurator are not changed.
+ * The job is labeled with its ClusterCurator and owned by it, it is removed with it.
+ */
+func addCuratorJobLabel(newJob *batchv1.Job, curator *clustercuratorv1.ClusterCurator) {
+	labels := map[string]string{CuratorJobLabel: CuratorJobLabelValue}
+	newJob.Labels = mergeMaps(newJob.Labels, mergeMaps(labels, map[string]string{
+		utils.ClusterCuratorLabel: curator.Name,
+		CurationTypeLabel:         CurationType(curator),
+	}))
+	newJob.Spec.Template.Labels = mergeMaps(newJob.Spec.Template.Labels, labels)
+
+	if curator.UID != "" {
+		newJob.OwnerReferences = append(newJob.OwnerReferences, v1.OwnerReference{
+			APIVersion: clustercuratorv1.GroupVersion.String(),
+			Kind:       "ClusterCurator",
+			Name:       curator.Name,
+			UID:        curator.UID,
 		})
 	}
-	newJob.Spec.Template.Labels = curator.Labels
-	return newJob
+}
 
+// The curation type of the curator job, an operation has the type of its curation
+func CurationType(curator *clustercuratorv1.ClusterCurator) string {
+	if op := utils.CurrentOperation(curator); op != nil {
+		return op.Curation
+	}
+	return curator.Spec.DesiredCuration
 }
 
-func (I *Launcher) CreateJob() error {
+func (I *Launcher) CreateJob(ctx context.Context) error {
 	kubeset := I.kubeset
 	clusterName := I.clusterCurator.Name
 	clusterNamespace := I.clusterCurator.Namespace
 
-	newJob := getBatchJob(clusterName, clusterNamespace, I.imageURI, I.clusterCurator)
+	newJob, err := getBatchJob(clusterName, clusterNamespace, I.imageURI, I.clusterCurator)
+	if err != nil {
+		return err
+	}
+
+	// Hive installs with a Provider credential get their secrets from it
+	if I.clusterCurator.Spec.DesiredCuration == "install" && I.clusterCurator.Spec.ProviderCredentialPath != "" &&
+		utils.CurrentOperation(&I.clusterCurator) == nil {
+
+		provider, err := secrets.GetClusterDeploymentProvider(ctx, I.client, clusterName)
+		if err != nil {
+			klog.V(2).Infof("No provider credential step added, the ClusterDeployment was not read: %v", err)
+		} else if provider != "" {
+			klog.V(2).Infof("Adding the %v-%v step for the %v platform", secrets.ApplyCloudProvider, provider, provider)
+			addApplyCloudProvider(newJob, provider, clusterName, I.imageURI)
+		}
+	}
+
+	addSecretStoreEnv(newJob)
+	applyJobTemplate(newJob, MergeJobTemplate(I.jobDefaults, I.clusterCurator.Spec.JobTemplate))
 
 	// Allow us to override the job in the Cluster Curator
 	klog.V(0).Info("Creating Curator job curator-job in namespace " + clusterNamespace)
-	var err error
 	if I.clusterCurator.Spec.Install.OverrideJob != nil {
 		klog.V(0).Info(" Overriding the Curator job with overrideJob from the " + clusterName + " ClusterCurator resource")
 		newJob = &batchv1.Job{}
@@ -409,7 +654,9 @@ func (I *Launcher) CreateJob() error {
 		}
 	}
 	if err == nil {
-		curatorJob, err := kubeset.BatchV1().Jobs(clusterNamespace).Create(context.TODO(), newJob, v1.CreateOptions{})
+		addProxy(newJob, I.proxy)
+		addCuratorJobLabel(newJob, &I.clusterCurator)
+		curatorJob, err := kubeset.BatchV1().Jobs(clusterNamespace).Create(ctx, newJob, v1.CreateOptions{})
 		if err == nil {
 			klog.V(0).Infof(" Created Curator job  ✓ (%v)", curatorJob.Name)
 			err = utils.RecordCuratorJobName(I.client, clusterName, clusterNamespace, curatorJob.Name)
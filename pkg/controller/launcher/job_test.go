@@ -7,10 +7,16 @@ import (
 	"log"
 	"testing"
 
+	hivev1 "github.com/openshift/hive/apis/hive/v1"
+	"github.com/openshift/hive/apis/hive/v1/openstack"
 	clustercuratorv1 "github.com/stolostron/cluster-curator-controller/pkg/api/v1beta1"
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/rbac"
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/secrets"
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/utils"
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v3"
 	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/fake"
@@ -47,7 +53,8 @@ func TestGetBatchJobImageSHA(t *testing.T) {
 			},
 		},
 	}
-	batchJobObj := getBatchJob(clusterName, clusterName, imageURI, clusterCurator)
+	batchJobObj, err := getBatchJob(clusterName, clusterName, imageURI, clusterCurator)
+	assert.Nil(t, err)
 
 	t.Log("Test count initContainers in job")
 	foundInitContainers := len(batchJobObj.Spec.Template.Spec.InitContainers)
@@ -185,7 +192,9 @@ func TestGetBatchJobImageDefault(t *testing.T) {
 	}
 
 	for _, c := range testcases {
-		c.verify(getBatchJob(clusterName, clusterName, imageURI, c.clusterCurator))
+		batchJobObj, err := getBatchJob(clusterName, clusterName, imageURI, c.clusterCurator)
+		assert.Nil(t, err)
+		c.verify(batchJobObj)
 	}
 }
 
@@ -213,7 +222,8 @@ func TestGetBatchJobRetryInstallPosthook(t *testing.T) {
 		},
 	}
 
-	batchJobObj := getBatchJob(clusterName, clusterName, imageURI, clusterCurator)
+	batchJobObj, err := getBatchJob(clusterName, clusterName, imageURI, clusterCurator)
+	assert.Nil(t, err)
 
 	t.Log("Test count initContainers in job")
 	foundInitContainers := len(batchJobObj.Spec.Template.Spec.InitContainers)
@@ -257,7 +267,8 @@ func TestGetBatchJobRetryUpgradePosthook(t *testing.T) {
 		},
 	}
 
-	batchJobObj := getBatchJob(clusterName, clusterName, imageURI, clusterCurator)
+	batchJobObj, err := getBatchJob(clusterName, clusterName, imageURI, clusterCurator)
+	assert.Nil(t, err)
 
 	t.Log("Test count initContainers in job")
 	foundInitContainers := len(batchJobObj.Spec.Template.Spec.InitContainers)
@@ -277,6 +288,36 @@ func TestGetBatchJobRetryUpgradePosthook(t *testing.T) {
 	}
 }
 
+func TestGetBatchJobRerunPhase(t *testing.T) {
+	clusterCurator := clustercuratorv1.ClusterCurator{
+		ObjectMeta: v1.ObjectMeta{Name: clusterName, Namespace: clusterName},
+		Operation: &clustercuratorv1.Operation{Action: clustercuratorv1.OperationRerun, Phase: MonImport,
+			Curation: "install", Nonce: "1"},
+		Spec: clustercuratorv1.ClusterCuratorSpec{DesiredCuration: "upgrade",
+			Upgrade: clustercuratorv1.UpgradeHooks{DesiredUpdate: "4.14.16"}},
+	}
+
+	batchJobObj, err := getBatchJob(clusterName, clusterName, imageURI, clusterCurator)
+	assert.Nil(t, err)
+	podSpec := batchJobObj.Spec.Template.Spec
+	if assert.Len(t, podSpec.InitContainers, 1) {
+		assert.Equal(t, MonImport, podSpec.InitContainers[0].Name)
+	}
+	assert.Equal(t, DoneDoneDone, podSpec.Containers[0].Name)
+	assert.Equal(t, "curator-install", podSpec.ServiceAccountName)
+	assert.Contains(t, batchJobObj.Annotations[MonImport], "monitor-import phase of the install curation")
+	assert.Equal(t, "install", CurationType(&clusterCurator))
+	assert.Nil(t, ValidateOperation(&clusterCurator))
+
+	clusterCurator.Operation.Phase = utils.PosthookPhase
+	err = ValidateOperation(&clusterCurator)
+	assert.NotNil(t, err, "the install curation has no posthook")
+	assert.Equal(t, utils.ReasonInvalidSpec, utils.ReasonForError(err))
+
+	clusterCurator.Operation.Phase = MonUpgrade
+	assert.NotNil(t, ValidateOperation(&clusterCurator), "the install curation has no monitor-upgrade phase")
+}
+
 // Test the launcher to create a job.batchv1 object
 func TestCreateLauncher(t *testing.T) {
 
@@ -296,14 +337,14 @@ func TestCreateLauncher(t *testing.T) {
 	}
 
 	s.AddKnownTypes(clustercuratorv1.SchemeBuilder.GroupVersion, &clustercuratorv1.ClusterCurator{})
-	client := clientfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(clusterCurator).Build()
+	client := clientfake.NewClientBuilder().WithScheme(s).WithStatusSubresource(&clustercuratorv1.ClusterCurator{}).WithRuntimeObjects(clusterCurator).Build()
 	kubeset := fake.NewSimpleClientset()
 
 	testLauncher := NewLauncher(client, kubeset, imageURI, *clusterCurator)
 
 	assert.NotNil(t, testLauncher, "launcher is not nil")
 
-	err := testLauncher.CreateJob()
+	err := testLauncher.CreateJob(context.TODO())
 
 	assert.Nil(t, err, "error is nil")
 
@@ -351,7 +392,7 @@ func TestCreateLauncherBadClusterCurator(t *testing.T) {
 
 	assert.NotNil(t, testLauncher, "launcher is not nil")
 
-	err := testLauncher.CreateJob()
+	err := testLauncher.CreateJob(context.TODO())
 
 	assert.NotNil(t, err, "Invalid ClusterCurator detected")
 	t.Log(err)
@@ -392,7 +433,7 @@ func TestCreateLauncherOverrideJob(t *testing.T) {
 
 	assert.NotNil(t, testLauncher, "launcher is not nil")
 
-	err = testLauncher.CreateJob()
+	err = testLauncher.CreateJob(context.TODO())
 
 	t.Log("SKIP: Test is failing")
 	assert.NotNil(t, err, "test is currently failing with fake client")
@@ -420,7 +461,7 @@ func TestCreateLauncherInvalidOverrideJob(t *testing.T) {
 
 	assert.NotNil(t, testLauncher, "launcher is not nil")
 
-	err := testLauncher.CreateJob()
+	err := testLauncher.CreateJob(context.TODO())
 
 	assert.NotNil(t, err, "CreateJob err is not nil")
 	t.Log(err)
@@ -443,7 +484,8 @@ func TestGetBatchJobEUSUpgrade(t *testing.T) {
 		},
 	}
 
-	batchJobObj := getBatchJob(clusterName, clusterName, imageURI, clusterCurator)
+	batchJobObj, err := getBatchJob(clusterName, clusterName, imageURI, clusterCurator)
+	assert.Nil(t, err)
 
 	t.Log("Test count initContainers in job")
 	foundInitContainers := len(batchJobObj.Spec.Template.Spec.InitContainers)
@@ -478,3 +520,163 @@ func TestGetBatchJobEUSUpgrade(t *testing.T) {
 		t.Fatalf("The ClusterCurator job was not corrctly populated, missing final-monitor-upgrade initContainer")
 	}
 }
+
+func TestCreateLauncherAddsApplyCloudProvider(t *testing.T) {
+
+	clusterCurator := &clustercuratorv1.ClusterCurator{
+		ObjectMeta: v1.ObjectMeta{Name: clusterName, Namespace: clusterName},
+		Spec: clustercuratorv1.ClusterCuratorSpec{
+			ProviderCredentialPath: "default/provider-secret",
+			DesiredCuration:        "install",
+			Install: clustercuratorv1.Hooks{
+				Prehook: []clustercuratorv1.Hook{
+					{
+						Name: "prehook job",
+					},
+				},
+			},
+		},
+	}
+
+	hiveScheme := runtime.NewScheme()
+	assert.Nil(t, clustercuratorv1.AddToScheme(hiveScheme))
+	assert.Nil(t, hivev1.AddToScheme(hiveScheme))
+	client := clientfake.NewClientBuilder().WithScheme(hiveScheme).WithStatusSubresource(&clustercuratorv1.ClusterCurator{}).WithObjects(
+		clusterCurator,
+		&hivev1.ClusterDeployment{
+			ObjectMeta: v1.ObjectMeta{Name: clusterName, Namespace: clusterName},
+			Spec: hivev1.ClusterDeploymentSpec{
+				Platform: hivev1.Platform{
+					OpenStack: &openstack.Platform{Cloud: "openstack"},
+				},
+			},
+		}).Build()
+	kubeset := fake.NewSimpleClientset()
+
+	assert.Nil(t, NewLauncher(client, kubeset, imageURI, *clusterCurator).CreateJob(context.TODO()))
+
+	job, err := kubeset.BatchV1().Jobs(clusterName).Get(context.TODO(), "", v1.GetOptions{})
+	assert.Nil(t, err)
+
+	initContainers := job.Spec.Template.Spec.InitContainers
+	assert.Equal(t, "applycloudprovider-openstack", initContainers[0].Name,
+		"the provider credential is applied before the prehook")
+	assert.Equal(t, []string{CurCmd, "applycloudprovider-openstack", clusterName}, initContainers[0].Command)
+	assert.Equal(t, corev1.PullAlways, initContainers[0].ImagePullPolicy, "the step pulls the curator image")
+	assert.Equal(t, PreAJob, initContainers[1].Name)
+	assert.Contains(t, job.Annotations, "applycloudprovider-openstack")
+}
+
+func TestCreateLauncherNoClusterDeployment(t *testing.T) {
+
+	clusterCurator := &clustercuratorv1.ClusterCurator{
+		ObjectMeta: v1.ObjectMeta{Name: clusterName, Namespace: clusterName},
+		Spec: clustercuratorv1.ClusterCuratorSpec{
+			ProviderCredentialPath: "default/provider-secret",
+			DesiredCuration:        "install",
+		},
+	}
+
+	hiveScheme := runtime.NewScheme()
+	assert.Nil(t, clustercuratorv1.AddToScheme(hiveScheme))
+	assert.Nil(t, hivev1.AddToScheme(hiveScheme))
+	client := clientfake.NewClientBuilder().WithScheme(hiveScheme).WithStatusSubresource(&clustercuratorv1.ClusterCurator{}).WithObjects(clusterCurator).Build()
+	kubeset := fake.NewSimpleClientset()
+
+	assert.Nil(t, NewLauncher(client, kubeset, imageURI, *clusterCurator).CreateJob(context.TODO()),
+		"err is nil, when the cluster is not a Hive cluster")
+
+	job, err := kubeset.BatchV1().Jobs(clusterName).Get(context.TODO(), "", v1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, ActivateAndMonitor, job.Spec.Template.Spec.InitContainers[0].Name)
+}
+
+func TestCreateLauncherSecretStoreEnv(t *testing.T) {
+	t.Setenv(secrets.VaultAddrEnv, "https://vault.example.com")
+	t.Setenv(secrets.VaultRoleEnv, "cluster-curator")
+	t.Setenv(secrets.VaultTokenEnv, "s.controller-token")
+
+	clusterCurator := &clustercuratorv1.ClusterCurator{
+		ObjectMeta: v1.ObjectMeta{Name: clusterName, Namespace: clusterName},
+		Spec: clustercuratorv1.ClusterCuratorSpec{
+			DesiredCuration: "install",
+			Install: clustercuratorv1.Hooks{
+				TowerAuthSecret: "vault://secret/tower",
+				Prehook:         []clustercuratorv1.Hook{{Name: "prehook job"}},
+			},
+		},
+	}
+
+	client := clientfake.NewClientBuilder().WithScheme(s).WithStatusSubresource(&clustercuratorv1.ClusterCurator{}).WithObjects(clusterCurator).Build()
+	kubeset := fake.NewSimpleClientset()
+
+	assert.Nil(t, NewLauncher(client, kubeset, imageURI, *clusterCurator).CreateJob(context.TODO()))
+
+	job, err := kubeset.BatchV1().Jobs(clusterName).Get(context.TODO(), "", v1.GetOptions{})
+	assert.Nil(t, err)
+
+	prehook := job.Spec.Template.Spec.InitContainers[0]
+	assert.Contains(t, prehook.Env, corev1.EnvVar{Name: "JOB_TYPE", Value: "prehook"})
+	assert.Contains(t, prehook.Env, corev1.EnvVar{Name: secrets.VaultAddrEnv, Value: "https://vault.example.com"})
+	assert.Contains(t, prehook.Env, corev1.EnvVar{Name: secrets.VaultRoleEnv, Value: "cluster-curator"})
+	for _, env := range prehook.Env {
+		assert.NotEqual(t, secrets.VaultTokenEnv, env.Name, "the Vault token is not written in the Job")
+	}
+	assert.Contains(t, job.Spec.Template.Spec.Containers[0].Env,
+		corev1.EnvVar{Name: secrets.VaultAddrEnv, Value: "https://vault.example.com"})
+}
+
+func TestGetBatchJobCurationServiceAccount(t *testing.T) {
+	hooks := clustercuratorv1.Hooks{
+		Prehook:  []clustercuratorv1.Hook{{Name: "prehook job"}},
+		Posthook: []clustercuratorv1.Hook{{Name: "posthook job"}},
+	}
+	curators := map[string]clustercuratorv1.ClusterCurator{
+		"curator-install": {Spec: clustercuratorv1.ClusterCuratorSpec{DesiredCuration: "install", Install: hooks}},
+		"curator-upgrade": {Spec: clustercuratorv1.ClusterCuratorSpec{DesiredCuration: "upgrade",
+			Upgrade: clustercuratorv1.UpgradeHooks{IntermediateUpdate: "4.13.37", DesiredUpdate: "4.14.16",
+				Prehook: hooks.Prehook, Posthook: hooks.Posthook}}},
+		"curator-destroy": {Spec: clustercuratorv1.ClusterCuratorSpec{DesiredCuration: "destroy", Destroy: hooks}},
+		"curator-posthook": {Spec: clustercuratorv1.ClusterCuratorSpec{DesiredCuration: "install", Install: hooks},
+			Operation: &clustercuratorv1.Operation{RetryPosthook: "installPosthook"}},
+	}
+
+	for serviceAccount, curator := range curators {
+		batchJobObj, err := getBatchJob(clusterName, clusterName, imageURI, curator)
+		assert.Nil(t, err)
+		addApplyCloudProvider(batchJobObj, "aws", clusterName, imageURI)
+
+		podSpec := batchJobObj.Spec.Template.Spec
+		assert.Equal(t, serviceAccount, podSpec.ServiceAccountName)
+		for _, container := range append(podSpec.InitContainers, podSpec.Containers...) {
+			assert.True(t, rbac.HasStepRules(container.Command[1]),
+				"the %v service account has the rules of step %v", serviceAccount, container.Command[1])
+		}
+	}
+	_, err := getBatchJob(clusterName, clusterName, imageURI, clustercuratorv1.ClusterCurator{
+		Spec: clustercuratorv1.ClusterCuratorSpec{DesiredCuration: "unknown"}})
+	assert.Equal(t, utils.ReasonInvalidSpec, utils.ReasonForError(err), "no curator- service account")
+}
+
+func TestGetBatchJobRetriesTransientErrors(t *testing.T) {
+	curators := []clustercuratorv1.ClusterCurator{
+		{Spec: clustercuratorv1.ClusterCuratorSpec{DesiredCuration: "install"}},
+		{Spec: clustercuratorv1.ClusterCuratorSpec{DesiredCuration: "upgrade",
+			Upgrade: clustercuratorv1.UpgradeHooks{DesiredUpdate: "4.14.16"}}},
+		{Spec: clustercuratorv1.ClusterCuratorSpec{DesiredCuration: "install"},
+			Operation: &clustercuratorv1.Operation{RetryPosthook: "installPosthook"}},
+	}
+
+	for _, curator := range curators {
+		batchJobObj, err := getBatchJob(clusterName, clusterName, imageURI, curator)
+		assert.Nil(t, err)
+		jobSpec := batchJobObj.Spec
+		assert.Equal(t, int32(CuratorJobBackoffLimit), *jobSpec.BackoffLimit)
+
+		rules := jobSpec.PodFailurePolicy.Rules
+		assert.Equal(t, batchv1.PodFailurePolicyActionIgnore, rules[0].Action, "an evicted pod is not counted")
+		assert.Equal(t, corev1.DisruptionTarget, rules[0].OnPodConditions[0].Type)
+		assert.Equal(t, batchv1.PodFailurePolicyActionFailJob, rules[1].Action, "a failed step is not retried")
+		assert.Equal(t, []int32{RetryExitCode}, rules[1].OnExitCodes.Values)
+	}
+}
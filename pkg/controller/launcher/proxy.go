@@ -0,0 +1,235 @@
+// Copyright Contributors to the Open Cluster Management project.
+package launcher
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/utils"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Proxy environment of the controller, passed to the curator jobs
+const (
+	HTTPProxyEnv  = "HTTP_PROXY"
+	HTTPSProxyEnv = "HTTPS_PROXY"
+	NoProxyEnv    = "NO_PROXY"
+)
+
+// NAMESPACE/NAME of the ConfigMap with the trusted CA bundle of the curator jobs, in its ca-bundle.crt key
+const TrustedCABundleEnv = "TRUSTED_CA_BUNDLE"
+
+// Key of the trusted CA bundle ConfigMaps, the key the OpenShift trusted CA injection uses
+const TrustedCABundleKey = "ca-bundle.crt"
+
+// ConfigMap of the cluster namespace with the trusted CA bundle, mounted in the curator jobs
+const TrustedCABundleConfigMap = "curator-trusted-ca-bundle"
+
+// The trusted CA bundle is read from SSL_CERT_FILE, the CAs of the image are still read from its certificate directories
+const (
+	trustedCABundleVolume = "trusted-ca-bundle"
+	trustedCABundleDir    = "/etc/pki/ca-trust/curator"
+)
+
+// Namespace of the ConfigMap of the OpenShift cluster proxy trustedCA
+const openshiftConfigNamespace = "openshift-config"
+
+// The cluster-wide proxy of OpenShift
+var proxyGVK = schema.GroupVersionKind{Group: "config.openshift.io", Version: "v1", Kind: "Proxy"}
+
+// ProxyConfig is the proxy and trusted CA bundle the curator jobs need on a proxied or disconnected hub
+type ProxyConfig struct {
+	HTTPProxy  string
+	HTTPSProxy string
+	NoProxy    string
+	// PEM bundle of the trusted CAs, empty when there is none
+	TrustedCABundle string
+}
+
+func (p *ProxyConfig) isEmpty() bool {
+	return p == nil || (p.HTTPProxy == "" && p.HTTPSProxy == "" && p.TrustedCABundle == "")
+}
+
+/* GetProxyConfig - Discovers the proxy and the trusted CA bundle of the hub. The environment of the
+ * controller is used first, the OLM sets it from the cluster proxy. Otherwise the status and trustedCA
+ * of the OpenShift cluster Proxy are used, there is none on other distributions.
+ */
+func GetProxyConfig(ctx context.Context, c client.Client, kubeset kubernetes.Interface) (*ProxyConfig, error) {
+	proxy := &ProxyConfig{
+		HTTPProxy:  os.Getenv(HTTPProxyEnv),
+		HTTPSProxy: os.Getenv(HTTPSProxyEnv),
+		NoProxy:    os.Getenv(NoProxyEnv),
+	}
+	trustedCAPath := os.Getenv(TrustedCABundleEnv)
+
+	if proxy.HTTPProxy == "" && proxy.HTTPSProxy == "" {
+		clusterProxy := &unstructured.Unstructured{}
+		clusterProxy.SetGroupVersionKind(proxyGVK)
+		err := c.Get(ctx, client.ObjectKey{Name: "cluster"}, clusterProxy)
+		switch {
+		case err == nil:
+			proxy.HTTPProxy, _, _ = unstructured.NestedString(clusterProxy.Object, "status", "httpProxy")
+			proxy.HTTPSProxy, _, _ = unstructured.NestedString(clusterProxy.Object, "status", "httpsProxy")
+			proxy.NoProxy, _, _ = unstructured.NestedString(clusterProxy.Object, "status", "noProxy")
+			if name, _, _ := unstructured.NestedString(clusterProxy.Object, "spec", "trustedCA", "name"); name != "" &&
+				trustedCAPath == "" {
+				trustedCAPath = openshiftConfigNamespace + "/" + name
+			}
+		case k8serrors.IsNotFound(err) || meta.IsNoMatchError(err):
+			klog.V(4).Info("There is no cluster proxy")
+		default:
+			return nil, err
+		}
+	}
+
+	// The hub API is not reached through the proxy
+	if host := os.Getenv("KUBERNETES_SERVICE_HOST"); host != "" && (proxy.HTTPProxy != "" || proxy.HTTPSProxy != "") &&
+		!containsValue(strings.Split(proxy.NoProxy, ","), host) {
+		proxy.NoProxy = strings.Trim(proxy.NoProxy+","+host, ",")
+	}
+
+	if namespace, name, ok := strings.Cut(trustedCAPath, "/"); ok {
+		configMap, err := kubeset.CoreV1().ConfigMaps(namespace).Get(ctx, name, v1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		proxy.TrustedCABundle = configMap.Data[TrustedCABundleKey]
+		if proxy.TrustedCABundle == "" {
+			return nil, utils.NewError(utils.ReasonInvalidSpec,
+				"The trusted CA bundle ConfigMap %v has no %v key", trustedCAPath, TrustedCABundleKey)
+		}
+	}
+	return proxy, nil
+}
+
+func containsValue(values []string, value string) bool {
+	for _, v := range values {
+		if strings.TrimSpace(v) == value {
+			return true
+		}
+	}
+	return false
+}
+
+/* ApplyTrustedCABundle - Creates or updates the ConfigMap of the cluster namespace with the trusted CA
+ * bundle, it is mounted in the curator jobs of the namespace.
+ */
+func ApplyTrustedCABundle(ctx context.Context, kubeset kubernetes.Interface, namespace string, proxy *ProxyConfig) error {
+	if proxy == nil || proxy.TrustedCABundle == "" {
+		return nil
+	}
+
+	configMap, err := kubeset.CoreV1().ConfigMaps(namespace).Get(ctx, TrustedCABundleConfigMap, v1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		_, err = kubeset.CoreV1().ConfigMaps(namespace).Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: v1.ObjectMeta{Name: TrustedCABundleConfigMap, Namespace: namespace},
+			Data:       map[string]string{TrustedCABundleKey: proxy.TrustedCABundle},
+		}, v1.CreateOptions{})
+		if err == nil {
+			klog.V(2).Infof("Created the trusted CA bundle ConfigMap in namespace %v", namespace)
+		}
+		return err
+	} else if err != nil {
+		return err
+	}
+
+	if configMap.Data[TrustedCABundleKey] == proxy.TrustedCABundle {
+		return nil
+	}
+	if configMap.Data == nil {
+		configMap.Data = map[string]string{}
+	}
+	configMap.Data[TrustedCABundleKey] = proxy.TrustedCABundle
+	klog.V(2).Infof("Updating the trusted CA bundle ConfigMap in namespace %v", namespace)
+	_, err = kubeset.CoreV1().ConfigMaps(namespace).Update(ctx, configMap, v1.UpdateOptions{})
+	return err
+}
+
+/* addProxy - Adds the proxy environment and mounts the trusted CA bundle in every container of the job,
+ * an overrideJob included. The values the containers already set are kept.
+ */
+func addProxy(newJob *batchv1.Job, proxy *ProxyConfig) {
+	if proxy.isEmpty() {
+		return
+	}
+
+	env := []corev1.EnvVar{}
+	for _, e := range []corev1.EnvVar{
+		{Name: HTTPProxyEnv, Value: proxy.HTTPProxy},
+		{Name: HTTPSProxyEnv, Value: proxy.HTTPSProxy},
+		{Name: NoProxyEnv, Value: proxy.NoProxy},
+	} {
+		if e.Value != "" {
+			env = append(env, e)
+		}
+	}
+
+	podSpec := &newJob.Spec.Template.Spec
+	if proxy.TrustedCABundle != "" {
+		env = append(env, corev1.EnvVar{Name: "SSL_CERT_FILE", Value: trustedCABundleDir + "/" + TrustedCABundleKey})
+		if !hasVolume(podSpec.Volumes, trustedCABundleVolume) {
+			podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+				Name: trustedCABundleVolume,
+				VolumeSource: corev1.VolumeSource{
+					ConfigMap: &corev1.ConfigMapVolumeSource{
+						LocalObjectReference: corev1.LocalObjectReference{Name: TrustedCABundleConfigMap},
+					},
+				},
+			})
+		}
+	}
+
+	for _, containers := range [][]corev1.Container{podSpec.InitContainers, podSpec.Containers} {
+		for i := range containers {
+			for _, e := range env {
+				if !hasEnv(containers[i].Env, e.Name) {
+					containers[i].Env = append(containers[i].Env, e)
+				}
+			}
+			if proxy.TrustedCABundle != "" && !hasVolumeMount(containers[i].VolumeMounts, trustedCABundleVolume) {
+				containers[i].VolumeMounts = append(containers[i].VolumeMounts, corev1.VolumeMount{
+					Name:      trustedCABundleVolume,
+					MountPath: trustedCABundleDir,
+					ReadOnly:  true,
+				})
+			}
+		}
+	}
+}
+
+func hasEnv(env []corev1.EnvVar, name string) bool {
+	for _, e := range env {
+		if e.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func hasVolume(volumes []corev1.Volume, name string) bool {
+	for _, v := range volumes {
+		if v.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func hasVolumeMount(mounts []corev1.VolumeMount, name string) bool {
+	for _, m := range mounts {
+		if m.Name == name {
+			return true
+		}
+	}
+	return false
+}
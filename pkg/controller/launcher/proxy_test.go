@@ -0,0 +1,187 @@
+// Copyright Contributors to the Open Cluster Management project.
+package launcher
+
+import (
+	"context"
+	"testing"
+
+	clustercuratorv1 "github.com/stolostron/cluster-curator-controller/pkg/api/v1beta1"
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/utils"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const caBundle = "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n"
+
+func unsetProxyEnv(t *testing.T) {
+	for _, name := range []string{HTTPProxyEnv, HTTPSProxyEnv, NoProxyEnv, TrustedCABundleEnv, "KUBERNETES_SERVICE_HOST"} {
+		t.Setenv(name, "")
+	}
+}
+
+func getClusterProxy() *unstructured.Unstructured {
+	clusterProxy := &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "cluster"},
+		"spec": map[string]interface{}{
+			"trustedCA": map[string]interface{}{"name": "user-ca-bundle"},
+		},
+		"status": map[string]interface{}{
+			"httpProxy":  "http://proxy.example.com:3128",
+			"httpsProxy": "http://proxy.example.com:3128",
+			"noProxy":    ".cluster.local,.svc,172.30.0.1",
+		},
+	}}
+	clusterProxy.SetGroupVersionKind(proxyGVK)
+	return clusterProxy
+}
+
+func TestGetProxyConfigFromEnv(t *testing.T) {
+	unsetProxyEnv(t)
+	t.Setenv(HTTPSProxyEnv, "http://proxy.example.com:3128")
+	t.Setenv(NoProxyEnv, ".svc")
+	t.Setenv("KUBERNETES_SERVICE_HOST", "172.30.0.1")
+	t.Setenv(TrustedCABundleEnv, "open-cluster-management/trusted-ca")
+
+	kubeset := fake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: v1.ObjectMeta{Name: "trusted-ca", Namespace: "open-cluster-management"},
+		Data:       map[string]string{TrustedCABundleKey: caBundle},
+	})
+
+	proxy, err := GetProxyConfig(context.TODO(), clientfake.NewClientBuilder().Build(), kubeset)
+	assert.Nil(t, err)
+	assert.Equal(t, &ProxyConfig{
+		HTTPSProxy:      "http://proxy.example.com:3128",
+		NoProxy:         ".svc,172.30.0.1",
+		TrustedCABundle: caBundle,
+	}, proxy, "the hub API is not proxied")
+}
+
+func TestGetProxyConfigFromClusterProxy(t *testing.T) {
+	unsetProxyEnv(t)
+
+	proxyScheme := runtime.NewScheme()
+	proxyScheme.AddKnownTypeWithName(proxyGVK, &unstructured.Unstructured{})
+	client := clientfake.NewClientBuilder().WithScheme(proxyScheme).WithObjects(getClusterProxy()).Build()
+	kubeset := fake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: v1.ObjectMeta{Name: "user-ca-bundle", Namespace: "openshift-config"},
+		Data:       map[string]string{TrustedCABundleKey: caBundle},
+	})
+
+	proxy, err := GetProxyConfig(context.TODO(), client, kubeset)
+	assert.Nil(t, err)
+	assert.Equal(t, &ProxyConfig{
+		HTTPProxy:       "http://proxy.example.com:3128",
+		HTTPSProxy:      "http://proxy.example.com:3128",
+		NoProxy:         ".cluster.local,.svc,172.30.0.1",
+		TrustedCABundle: caBundle,
+	}, proxy)
+}
+
+func TestGetProxyConfigNoProxy(t *testing.T) {
+	unsetProxyEnv(t)
+
+	proxy, err := GetProxyConfig(context.TODO(), clientfake.NewClientBuilder().Build(), fake.NewSimpleClientset())
+	assert.Nil(t, err, "there is no cluster Proxy kind outside of OpenShift")
+	assert.True(t, proxy.isEmpty())
+}
+
+func TestGetProxyConfigInvalidTrustedCA(t *testing.T) {
+	unsetProxyEnv(t)
+	t.Setenv(TrustedCABundleEnv, "open-cluster-management/trusted-ca")
+
+	kubeset := fake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: v1.ObjectMeta{Name: "trusted-ca", Namespace: "open-cluster-management"},
+		Data:       map[string]string{"ca.crt": caBundle},
+	})
+
+	_, err := GetProxyConfig(context.TODO(), clientfake.NewClientBuilder().Build(), kubeset)
+	assert.NotNil(t, err)
+	assert.Equal(t, utils.ReasonInvalidSpec, utils.ReasonForError(err))
+}
+
+func TestApplyTrustedCABundle(t *testing.T) {
+	kubeset := fake.NewSimpleClientset()
+
+	assert.Nil(t, ApplyTrustedCABundle(context.TODO(), kubeset, clusterName, &ProxyConfig{}))
+	_, err := kubeset.CoreV1().ConfigMaps(clusterName).Get(context.TODO(), TrustedCABundleConfigMap, v1.GetOptions{})
+	assert.NotNil(t, err, "there is no ConfigMap without a trusted CA bundle")
+
+	assert.Nil(t, ApplyTrustedCABundle(context.TODO(), kubeset, clusterName, &ProxyConfig{TrustedCABundle: caBundle}))
+	assert.Nil(t, ApplyTrustedCABundle(context.TODO(), kubeset, clusterName, &ProxyConfig{TrustedCABundle: caBundle + caBundle}))
+
+	configMap, err := kubeset.CoreV1().ConfigMaps(clusterName).Get(context.TODO(), TrustedCABundleConfigMap, v1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, caBundle+caBundle, configMap.Data[TrustedCABundleKey], "the rotated bundle is updated")
+}
+
+func assertProxy(t *testing.T, podSpec corev1.PodSpec) {
+	assert.Equal(t, TrustedCABundleConfigMap, podSpec.Volumes[0].ConfigMap.Name)
+	for _, container := range append(podSpec.InitContainers, podSpec.Containers...) {
+		env := map[string]string{}
+		for _, e := range container.Env {
+			env[e.Name] = e.Value
+		}
+		assert.Equal(t, "http://proxy.example.com:3128", env[HTTPSProxyEnv], container.Name)
+		assert.Equal(t, ".svc", env[NoProxyEnv], container.Name)
+		assert.Equal(t, "/etc/pki/ca-trust/curator/ca-bundle.crt", env["SSL_CERT_FILE"], container.Name)
+		assert.NotContains(t, env, HTTPProxyEnv, "an empty proxy is not set")
+		assert.Equal(t, []corev1.VolumeMount{{Name: "trusted-ca-bundle", MountPath: "/etc/pki/ca-trust/curator",
+			ReadOnly: true}}, container.VolumeMounts, container.Name)
+	}
+}
+
+func TestCreateJobWithProxy(t *testing.T) {
+	proxy := &ProxyConfig{HTTPSProxy: "http://proxy.example.com:3128", NoProxy: ".svc", TrustedCABundle: caBundle}
+	clusterCurator := &clustercuratorv1.ClusterCurator{
+		ObjectMeta: v1.ObjectMeta{Name: clusterName, Namespace: clusterName},
+		Spec: clustercuratorv1.ClusterCuratorSpec{
+			DesiredCuration: "install",
+			Install: clustercuratorv1.Hooks{
+				Prehook: []clustercuratorv1.Hook{{Name: "prehook job"}},
+			},
+		},
+	}
+	client := clientfake.NewClientBuilder().WithStatusSubresource(&clustercuratorv1.ClusterCurator{}).WithRuntimeObjects(clusterCurator).Build()
+	kubeset := fake.NewSimpleClientset()
+
+	assert.Nil(t, NewLauncher(client, kubeset, imageURI, *clusterCurator).WithProxy(proxy).CreateJob(context.TODO()))
+
+	job, err := kubeset.BatchV1().Jobs(clusterName).Get(context.TODO(), "", v1.GetOptions{})
+	assert.Nil(t, err)
+	assertProxy(t, job.Spec.Template.Spec)
+}
+
+func TestCreateOverrideJobWithProxy(t *testing.T) {
+	proxy := &ProxyConfig{HTTPSProxy: "http://proxy.example.com:3128", NoProxy: ".svc", TrustedCABundle: caBundle}
+	clusterCurator := &clustercuratorv1.ClusterCurator{
+		ObjectMeta: v1.ObjectMeta{Name: clusterName, Namespace: clusterName, UID: "curator-uid"},
+		Spec: clustercuratorv1.ClusterCuratorSpec{
+			DesiredCuration: "install",
+			Install: clustercuratorv1.Hooks{
+				OverrideJob: &runtime.RawExtension{Raw: []byte(`{"spec":{"template":{"spec":{"containers":[` +
+					`{"name":"custom","image":"custom","env":[{"name":"NO_PROXY","value":".svc"}]}]}}}}`)},
+			},
+		},
+	}
+	client := clientfake.NewClientBuilder().WithStatusSubresource(&clustercuratorv1.ClusterCurator{}).WithRuntimeObjects(clusterCurator).Build()
+	kubeset := fake.NewSimpleClientset()
+
+	assert.Nil(t, NewLauncher(client, kubeset, imageURI, *clusterCurator).WithProxy(proxy).CreateJob(context.TODO()))
+
+	job, err := kubeset.BatchV1().Jobs(clusterName).Get(context.TODO(), "", v1.GetOptions{})
+	assert.Nil(t, err)
+	assertProxy(t, job.Spec.Template.Spec)
+	assert.Len(t, job.Spec.Template.Spec.Containers[0].Env, 3, "the NO_PROXY of the overrideJob is kept")
+	assert.Equal(t, CuratorJobLabelValue, job.Labels[CuratorJobLabel], "the overrideJob is watched")
+	assert.Equal(t, CuratorJobLabelValue, job.Spec.Template.Labels[CuratorJobLabel], "the overrideJob pod is watched")
+	assert.Equal(t, clusterName, job.Labels[utils.ClusterCuratorLabel], "the overrideJob is removed with its ClusterCurator")
+	assert.Equal(t, "install", job.Labels[CurationTypeLabel], "the overrideJob is capped with the install curations")
+	assert.Len(t, job.OwnerReferences, 1)
+	assert.Equal(t, types.UID("curator-uid"), job.OwnerReferences[0].UID, "the overrideJob is owned by its ClusterCurator")
+}
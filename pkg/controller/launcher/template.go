@@ -0,0 +1,172 @@
+// Copyright Contributors to the Open Cluster Management project.
+package launcher
+
+import (
+	"context"
+	"strings"
+
+	clustercuratorv1 "github.com/stolostron/cluster-curator-controller/pkg/api/v1beta1"
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/utils"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+// Name of the ConfigMap, in the namespace of the controller, with the default jobTemplate of the curator Jobs
+const JobTemplateConfigMap = "cluster-curator-job-template"
+
+// Key of the ConfigMap with the jobTemplate YAML, it has the fields of spec.jobTemplate
+const JobTemplateKey = "jobTemplate"
+
+/* GetJobTemplateDefaults - Reads the default jobTemplate of the controller from the ConfigMap at
+ * NAMESPACE/NAME. There are no defaults when the path is empty or the ConfigMap does not exist.
+ */
+func GetJobTemplateDefaults(
+	ctx context.Context,
+	kubeset kubernetes.Interface,
+	configMapPath string) (*clustercuratorv1.JobTemplate, error) {
+
+	namespace, name, ok := strings.Cut(configMapPath, "/")
+	if !ok {
+		return nil, nil
+	}
+
+	configMap, err := kubeset.CoreV1().ConfigMaps(namespace).Get(ctx, name, v1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		klog.V(4).Infof("No curator job defaults, the ConfigMap %v does not exist", configMapPath)
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	defaults := &clustercuratorv1.JobTemplate{}
+	if err = yaml.UnmarshalStrict([]byte(configMap.Data[JobTemplateKey]), defaults); err != nil {
+		return nil, utils.NewError(utils.ReasonInvalidSpec,
+			"The %v key of ConfigMap %v is not a valid jobTemplate: %v", JobTemplateKey, configMapPath, err)
+	}
+	return defaults, nil
+}
+
+/* MergeJobTemplate - The jobTemplate of the ClusterCurator over the defaults of the controller. The fields
+ * set in the template replace the defaults, the labels, annotations and nodeSelector are merged.
+ */
+func MergeJobTemplate(defaults *clustercuratorv1.JobTemplate, template *clustercuratorv1.JobTemplate) *clustercuratorv1.JobTemplate {
+	if defaults == nil {
+		return template.DeepCopy()
+	}
+	merged := defaults.DeepCopy()
+	if template == nil {
+		return merged
+	}
+	template = template.DeepCopy()
+
+	merged.Labels = mergeMaps(merged.Labels, template.Labels)
+	merged.Annotations = mergeMaps(merged.Annotations, template.Annotations)
+	merged.NodeSelector = mergeMaps(merged.NodeSelector, template.NodeSelector)
+	if template.ServiceAccountName != "" {
+		merged.ServiceAccountName = template.ServiceAccountName
+	}
+	if template.ImagePullPolicy != "" {
+		merged.ImagePullPolicy = template.ImagePullPolicy
+	}
+	if template.ImagePullSecrets != nil {
+		merged.ImagePullSecrets = template.ImagePullSecrets
+	}
+	if template.Resources != nil {
+		merged.Resources = template.Resources
+	}
+	if template.Tolerations != nil {
+		merged.Tolerations = template.Tolerations
+	}
+	if template.Affinity != nil {
+		merged.Affinity = template.Affinity
+	}
+	if template.PriorityClassName != "" {
+		merged.PriorityClassName = template.PriorityClassName
+	}
+	if template.SecurityContext != nil {
+		merged.SecurityContext = template.SecurityContext
+	}
+	if template.ContainerSecurityContext != nil {
+		merged.ContainerSecurityContext = template.ContainerSecurityContext
+	}
+	if template.TTLSecondsAfterFinished != nil {
+		merged.TTLSecondsAfterFinished = template.TTLSecondsAfterFinished
+	}
+	if template.ActiveDeadlineSeconds != nil {
+		merged.ActiveDeadlineSeconds = template.ActiveDeadlineSeconds
+	}
+	return merged
+}
+
+func mergeMaps(values map[string]string, overrides map[string]string) map[string]string {
+	if len(overrides) == 0 {
+		return values
+	}
+	merged := map[string]string{}
+	for key, value := range values {
+		merged[key] = value
+	}
+	for key, value := range overrides {
+		merged[key] = value
+	}
+	return merged
+}
+
+// Applies the jobTemplate to the pod of the curator Job, the container settings to every step
+func applyJobTemplate(newJob *batchv1.Job, template *clustercuratorv1.JobTemplate) {
+	if template == nil {
+		return
+	}
+
+	podTemplate := &newJob.Spec.Template
+	// The pod labels are the labels of the ClusterCurator, they are not changed in place
+	podTemplate.Labels = mergeMaps(podTemplate.Labels, template.Labels)
+	podTemplate.Annotations = mergeMaps(podTemplate.Annotations, template.Annotations)
+
+	podSpec := &podTemplate.Spec
+	if template.ServiceAccountName != "" {
+		podSpec.ServiceAccountName = template.ServiceAccountName
+	}
+	if template.ImagePullSecrets != nil {
+		podSpec.ImagePullSecrets = template.ImagePullSecrets
+	}
+	podSpec.NodeSelector = mergeMaps(podSpec.NodeSelector, template.NodeSelector)
+	if template.Tolerations != nil {
+		podSpec.Tolerations = template.Tolerations
+	}
+	if template.Affinity != nil {
+		podSpec.Affinity = template.Affinity
+	}
+	if template.PriorityClassName != "" {
+		podSpec.PriorityClassName = template.PriorityClassName
+	}
+	if template.SecurityContext != nil {
+		podSpec.SecurityContext = template.SecurityContext
+	}
+
+	for _, containers := range [][]corev1.Container{podSpec.InitContainers, podSpec.Containers} {
+		for i := range containers {
+			if template.ImagePullPolicy != "" {
+				containers[i].ImagePullPolicy = template.ImagePullPolicy
+			}
+			if template.Resources != nil {
+				containers[i].Resources = *template.Resources.DeepCopy()
+			}
+			if template.ContainerSecurityContext != nil {
+				containers[i].SecurityContext = template.ContainerSecurityContext.DeepCopy()
+			}
+		}
+	}
+
+	if template.TTLSecondsAfterFinished != nil {
+		newJob.Spec.TTLSecondsAfterFinished = template.TTLSecondsAfterFinished
+	}
+	if template.ActiveDeadlineSeconds != nil {
+		newJob.Spec.ActiveDeadlineSeconds = template.ActiveDeadlineSeconds
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright Contributors to the Open Cluster Management project.
+package launcher
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	clustercuratorv1 "github.com/stolostron/cluster-curator-controller/pkg/api/v1beta1"
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/utils"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+)
+
+const controllerNamespace = "open-cluster-management"
+const jobTemplatePath = controllerNamespace + "/" + JobTemplateConfigMap
+
+func getJobTemplateConfigMap(jobTemplate string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: v1.ObjectMeta{Name: JobTemplateConfigMap, Namespace: controllerNamespace},
+		Data:       map[string]string{JobTemplateKey: jobTemplate},
+	}
+}
+
+func TestGetJobTemplateDefaults(t *testing.T) {
+	kubeset := fake.NewSimpleClientset(getJobTemplateConfigMap(
+		"nodeSelector:\n  node-role.kubernetes.io/infra: \"\"\n" +
+			"resources:\n  limits:\n    memory: 256Mi\n" +
+			"ttlSecondsAfterFinished: 600\n"))
+
+	defaults, err := GetJobTemplateDefaults(context.TODO(), kubeset, jobTemplatePath)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"node-role.kubernetes.io/infra": ""}, defaults.NodeSelector)
+	assert.Equal(t, resource.MustParse("256Mi"), defaults.Resources.Limits[corev1.ResourceMemory])
+	assert.Equal(t, int32(600), *defaults.TTLSecondsAfterFinished)
+}
+
+func TestGetJobTemplateDefaultsMissing(t *testing.T) {
+	kubeset := fake.NewSimpleClientset()
+
+	defaults, err := GetJobTemplateDefaults(context.TODO(), kubeset, jobTemplatePath)
+	assert.Nil(t, err, "there are no defaults when the ConfigMap does not exist")
+	assert.Nil(t, defaults)
+
+	defaults, err = GetJobTemplateDefaults(context.TODO(), kubeset, "")
+	assert.Nil(t, err, "there are no defaults without a ConfigMap")
+	assert.Nil(t, defaults)
+}
+
+func TestGetJobTemplateDefaultsInvalid(t *testing.T) {
+	kubeset := fake.NewSimpleClientset(getJobTemplateConfigMap("nodeSelectors:\n  infra: \"\"\n"))
+
+	_, err := GetJobTemplateDefaults(context.TODO(), kubeset, jobTemplatePath)
+	assert.NotNil(t, err, "an unknown field is an error")
+	assert.Equal(t, utils.ReasonInvalidSpec, utils.ReasonForError(err))
+}
+
+func TestJobTemplateSample(t *testing.T) {
+	data, err := os.ReadFile("../../../deploy/samples/sample-job-template.yaml")
+	assert.Nil(t, err)
+
+	configMap := &corev1.ConfigMap{}
+	assert.Nil(t, yaml.Unmarshal(data, configMap))
+	configMap.Namespace = controllerNamespace
+
+	defaults, err := GetJobTemplateDefaults(context.TODO(), fake.NewSimpleClientset(configMap), jobTemplatePath)
+	assert.Nil(t, err, "the sample is a valid jobTemplate")
+	assert.NotEmpty(t, defaults.Tolerations)
+}
+
+func TestMergeJobTemplate(t *testing.T) {
+	var ttl int32 = 600
+	defaults := &clustercuratorv1.JobTemplate{
+		NodeSelector:            map[string]string{"node-role.kubernetes.io/infra": ""},
+		Labels:                  map[string]string{"team": "platform"},
+		ImagePullPolicy:         corev1.PullIfNotPresent,
+		TTLSecondsAfterFinished: &ttl,
+		Tolerations:             []corev1.Toleration{{Key: "infra", Operator: corev1.TolerationOpExists}},
+	}
+	template := &clustercuratorv1.JobTemplate{
+		NodeSelector: map[string]string{"zone": "a"},
+		Labels:       map[string]string{"team": "apps"},
+		Tolerations:  []corev1.Toleration{},
+	}
+
+	merged := MergeJobTemplate(defaults, template)
+	assert.Equal(t, map[string]string{"node-role.kubernetes.io/infra": "", "zone": "a"}, merged.NodeSelector)
+	assert.Equal(t, map[string]string{"team": "apps"}, merged.Labels, "the ClusterCurator value wins")
+	assert.Equal(t, corev1.PullIfNotPresent, merged.ImagePullPolicy, "an unset field keeps the default")
+	assert.Equal(t, int32(600), *merged.TTLSecondsAfterFinished)
+	assert.Empty(t, merged.Tolerations, "an empty list replaces the default")
+	assert.Len(t, defaults.Tolerations, 1, "the defaults are not changed")
+
+	assert.Equal(t, defaults, MergeJobTemplate(defaults, nil))
+	assert.Equal(t, template, MergeJobTemplate(nil, template))
+	assert.Nil(t, MergeJobTemplate(nil, nil))
+}
+
+func TestCreateJobWithJobTemplate(t *testing.T) {
+	var ttl int32 = 86400
+	var deadline int64 = 7200
+	clusterCurator := &clustercuratorv1.ClusterCurator{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      clusterName,
+			Namespace: clusterName,
+			Labels:    map[string]string{"env": "prod"},
+		},
+		Spec: clustercuratorv1.ClusterCuratorSpec{
+			DesiredCuration: "install",
+			Install: clustercuratorv1.Hooks{
+				Prehook: []clustercuratorv1.Hook{{Name: "prehook job"}},
+			},
+			JobTemplate: &clustercuratorv1.JobTemplate{
+				Labels:           map[string]string{"team": "apps"},
+				ImagePullSecrets: []corev1.LocalObjectReference{{Name: "private-registry"}},
+				Resources: &corev1.ResourceRequirements{
+					Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("512Mi")},
+				},
+				ActiveDeadlineSeconds: &deadline,
+			},
+		},
+	}
+	defaults := &clustercuratorv1.JobTemplate{
+		NodeSelector:             map[string]string{"node-role.kubernetes.io/infra": ""},
+		Tolerations:              []corev1.Toleration{{Key: "node-role.kubernetes.io/infra", Operator: corev1.TolerationOpExists}},
+		ImagePullPolicy:          corev1.PullIfNotPresent,
+		ContainerSecurityContext: &corev1.SecurityContext{AllowPrivilegeEscalation: new(bool)},
+		TTLSecondsAfterFinished:  &ttl,
+	}
+
+	client := clientfake.NewClientBuilder().WithStatusSubresource(&clustercuratorv1.ClusterCurator{}).WithRuntimeObjects(clusterCurator).Build()
+	kubeset := fake.NewSimpleClientset()
+
+	assert.Nil(t, NewLauncher(client, kubeset, imageURI, *clusterCurator).WithJobTemplateDefaults(defaults).CreateJob(context.TODO()))
+
+	job, err := kubeset.BatchV1().Jobs(clusterName).Get(context.TODO(), "", v1.GetOptions{})
+	assert.Nil(t, err)
+
+	podTemplate := job.Spec.Template
+	assert.Equal(t, map[string]string{"env": "prod", "team": "apps", CuratorJobLabel: CuratorJobLabelValue}, podTemplate.Labels)
+	assert.Equal(t, map[string]string{"env": "prod"}, clusterCurator.Labels, "the ClusterCurator labels are not changed")
+	assert.Equal(t, "curator-install", podTemplate.Spec.ServiceAccountName, "the service account is not overridden")
+	assert.Equal(t, defaults.NodeSelector, podTemplate.Spec.NodeSelector)
+	assert.Equal(t, defaults.Tolerations, podTemplate.Spec.Tolerations)
+	assert.Equal(t, []corev1.LocalObjectReference{{Name: "private-registry"}}, podTemplate.Spec.ImagePullSecrets)
+	assert.Equal(t, int32(86400), *job.Spec.TTLSecondsAfterFinished)
+	assert.Equal(t, int64(7200), *job.Spec.ActiveDeadlineSeconds)
+
+	containers := append(podTemplate.Spec.InitContainers, podTemplate.Spec.Containers...)
+	assert.Len(t, containers, 4)
+	for _, container := range containers {
+		assert.Equal(t, corev1.PullIfNotPresent, container.ImagePullPolicy, container.Name)
+		assert.Equal(t, resource.MustParse("512Mi"), container.Resources.Limits[corev1.ResourceMemory], container.Name)
+		assert.False(t, *container.SecurityContext.AllowPrivilegeEscalation, container.Name)
+	}
+}
+
+func TestCreateJobWithoutJobTemplate(t *testing.T) {
+	clusterCurator := &clustercuratorv1.ClusterCurator{
+		ObjectMeta: v1.ObjectMeta{Name: clusterName, Namespace: clusterName},
+		Spec: clustercuratorv1.ClusterCuratorSpec{
+			DesiredCuration: "destroy",
+		},
+	}
+	client := clientfake.NewClientBuilder().WithStatusSubresource(&clustercuratorv1.ClusterCurator{}).WithRuntimeObjects(clusterCurator).Build()
+	kubeset := fake.NewSimpleClientset()
+
+	assert.Nil(t, NewLauncher(client, kubeset, imageURI, *clusterCurator).CreateJob(context.TODO()))
+
+	job, err := kubeset.BatchV1().Jobs(clusterName).Get(context.TODO(), "", v1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(3600), *job.Spec.TTLSecondsAfterFinished, "the default TTL is kept")
+	assert.Equal(t, getResourceSettings(), job.Spec.Template.Spec.InitContainers[0].Resources)
+	assert.Nil(t, job.Spec.Template.Spec.NodeSelector)
+}
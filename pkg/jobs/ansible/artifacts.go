@@ -0,0 +1,158 @@
+// Copyright Contributors to the Open Cluster Management project.
+package ansible
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	clustercuratorv1 "github.com/stolostron/cluster-curator-controller/pkg/api/v1beta1"
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/utils"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const ARTIFACTS_SUFFIX = "-artifacts"
+const ARTIFACTS_LABEL = "curator-ansiblejob-artifacts"
+
+// Keys written to the artifacts ConfigMap
+const ARTIFACTS_RESULT_KEY = "result.json"
+const ARTIFACTS_CONDITIONS_KEY = "conditions.json"
+const ARTIFACTS_STDOUT_KEY = "stdout"
+
+// The tail of the AnsibleJob runner pod log is bounded, so the ConfigMap stays well below the 1MiB object limit
+var LogTailLines int64 = 500
+var LogLimitBytes int64 = 256 * 1024
+
+// Name of the ConfigMap that holds the logs and artifacts of an AnsibleJob
+func GetArtifactsConfigMapName(ansibleJobName string) string {
+	return ansibleJobName + ARTIFACTS_SUFFIX
+}
+
+/* CollectAnsibleJobArtifacts - Stores the AnsibleJob result, its conditions and a bounded tail of the
+ * runner (k8sJob) pod log in a ConfigMap owned by the ClusterCurator. The ConfigMap name is returned,
+ * so it can be referenced from the ClusterCurator status.
+ *  kubeset          # used to read the runner pod log, when nil only the result and conditions are stored
+ *  jobResource      # the AnsibleJob, as last read by MonitorAnsibleJob
+ */
+func CollectAnsibleJobArtifacts(
+	ctx context.Context,
+	client client.Client,
+	kubeset kubernetes.Interface,
+	curator *clustercuratorv1.ClusterCurator,
+	jobResource *unstructured.Unstructured) (string, error) {
+
+	cmName := GetArtifactsConfigMapName(jobResource.GetName())
+	klog.V(2).Info("Collecting logs and artifacts of AnsibleJob " + jobResource.GetNamespace() + "/" +
+		jobResource.GetName() + " into ConfigMap " + cmName)
+
+	data := map[string]string{}
+
+	if result, found, _ := unstructured.NestedMap(jobResource.Object, "status", "ansibleJobResult"); found {
+		b, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		data[ARTIFACTS_RESULT_KEY] = string(b)
+	}
+
+	if conditions, found, _ := unstructured.NestedSlice(jobResource.Object, "status", "conditions"); found {
+		b, err := json.MarshalIndent(conditions, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		data[ARTIFACTS_CONDITIONS_KEY] = string(b)
+	}
+
+	k8sJob, _, _ := unstructured.NestedString(jobResource.Object, "status", "k8sJob", "namespacedName")
+	if kubeset != nil && k8sJob != "" {
+		stdout, err := getRunnerLogTail(ctx, kubeset, k8sJob)
+		if err != nil {
+			// The runner pod may already be gone, keep what we have
+			klog.Warningf("Could not retrieve the log of AnsibleJob runner %v: %v", k8sJob, err)
+		} else {
+			data[ARTIFACTS_STDOUT_KEY] = stdout
+		}
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      cmName,
+			Namespace: curator.Namespace,
+			Labels: map[string]string{
+				"open-cluster-management": ARTIFACTS_LABEL,
+			},
+			Annotations: map[string]string{
+				"ansiblejob": jobResource.GetName(),
+				"jobtype":    jobResource.GetAnnotations()["jobtype"],
+			},
+			OwnerReferences: []v1.OwnerReference{
+				*v1.NewControllerRef(curator, clustercuratorv1.GroupVersion.WithKind("ClusterCurator")),
+			},
+		},
+		Data: data,
+	}
+
+	utils.SetCurationMetadata(configMap, curator)
+
+	if err := client.Create(ctx, configMap); err != nil {
+		if !k8serrors.IsAlreadyExists(err) {
+			return "", err
+		}
+		existing := &corev1.ConfigMap{}
+		if err := client.Get(ctx, types.NamespacedName{
+			Namespace: curator.Namespace,
+			Name:      cmName,
+		}, existing); err != nil {
+			return "", err
+		}
+		existing.Data = data
+		if err := client.Update(ctx, existing); err != nil {
+			return "", err
+		}
+	}
+
+	klog.V(0).Info("Stored AnsibleJob logs and artifacts in ConfigMap " + curator.Namespace + "/" + cmName + " ✓")
+	return cmName, nil
+}
+
+// Returns the bounded tail of the newest pod log created by the AnsibleJob runner Job NAMESPACE/JOB_NAME
+func getRunnerLogTail(ctx context.Context, kubeset kubernetes.Interface, k8sJob string) (string, error) {
+	namespace, jobName, err := utils.PathSplitterFromEnv(k8sJob)
+	if err != nil {
+		return "", err
+	}
+
+	pods, err := kubeset.CoreV1().Pods(namespace).List(ctx, v1.ListOptions{
+		LabelSelector: "job-name=" + jobName,
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(pods.Items) == 0 {
+		return "", k8serrors.NewNotFound(corev1.Resource("pods"), "job-name="+jobName)
+	}
+
+	// Newest pod last
+	sort.Slice(pods.Items, func(i, j int) bool {
+		return pods.Items[i].CreationTimestamp.Before(&pods.Items[j].CreationTimestamp)
+	})
+	pod := pods.Items[len(pods.Items)-1]
+
+	logs, err := kubeset.CoreV1().Pods(namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+		TailLines:  &LogTailLines,
+		LimitBytes: &LogLimitBytes,
+	}).DoRaw(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.ToValidUTF8(string(logs), ""), nil
+}
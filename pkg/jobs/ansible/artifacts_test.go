@@ -0,0 +1,117 @@
+// Copyright Contributors to the Open Cluster Management project.
+package ansible
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	ajv1 "github.com/stolostron/cluster-curator-controller/pkg/api/v1alpha1"
+	clustercuratorv1 "github.com/stolostron/cluster-curator-controller/pkg/api/v1beta1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func getRunnerPod() *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      AnsibleJobName + "-runner-abcde",
+			Namespace: ClusterName,
+			Labels: map[string]string{
+				"job-name": AnsibleJobName + "-runner",
+			},
+		},
+	}
+}
+
+func TestCollectAnsibleJobArtifacts(t *testing.T) {
+
+	cc := getClusterCurator()
+	aj := buildAnsibleJob("error", ClusterName+"/"+AnsibleJobName+"-runner")
+
+	s.AddKnownTypes(ajv1.SchemeBuilder.GroupVersion, &ajv1.AnsibleJob{})
+	s.AddKnownTypes(clustercuratorv1.SchemeBuilder.GroupVersion, &clustercuratorv1.ClusterCurator{})
+	s.AddKnownTypes(corev1.SchemeGroupVersion, &corev1.ConfigMap{})
+	client := clientfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(cc).Build()
+	kubeset := fake.NewSimpleClientset(getRunnerPod())
+
+	mapAJ, _ := runtime.DefaultUnstructuredConverter.ToUnstructured(aj)
+	unstructAJ := &unstructured.Unstructured{Object: mapAJ}
+
+	cmName, err := CollectAnsibleJobArtifacts(context.TODO(), client, kubeset, cc, unstructAJ)
+	assert.Nil(t, err, "err nil, when artifacts are collected")
+	assert.Equal(t, AnsibleJobName+ARTIFACTS_SUFFIX, cmName)
+
+	configMap := &corev1.ConfigMap{}
+	assert.Nil(t, client.Get(context.TODO(), types.NamespacedName{Namespace: ClusterName, Name: cmName}, configMap))
+	assert.Equal(t, ARTIFACTS_LABEL, configMap.Labels["open-cluster-management"])
+	assert.Equal(t, "ClusterCurator", configMap.OwnerReferences[0].Kind)
+	assert.Equal(t, ClusterName, configMap.OwnerReferences[0].Name)
+	assert.Contains(t, configMap.Data[ARTIFACTS_RESULT_KEY], "\"status\": \"error\"")
+	assert.Contains(t, configMap.Data[ARTIFACTS_CONDITIONS_KEY], "The job failed from condition")
+	// The fake clientset always returns "fake logs" for a pod log request
+	assert.Equal(t, "fake logs", configMap.Data[ARTIFACTS_STDOUT_KEY])
+
+	// A second collection for the same AnsibleJob refreshes the ConfigMap
+	delete(unstructAJ.Object, "status")
+	_, err = CollectAnsibleJobArtifacts(context.TODO(), client, nil, cc, unstructAJ)
+	assert.Nil(t, err, "err nil, when the ConfigMap already exists")
+	assert.Nil(t, client.Get(context.TODO(), types.NamespacedName{Namespace: ClusterName, Name: cmName}, configMap))
+	assert.Empty(t, configMap.Data)
+}
+
+func TestCollectAnsibleJobArtifactsNoRunnerPod(t *testing.T) {
+
+	cc := getClusterCurator()
+	aj := buildAnsibleJob("error", ClusterName+"/"+AnsibleJobName+"-runner")
+
+	s.AddKnownTypes(corev1.SchemeGroupVersion, &corev1.ConfigMap{})
+	client := clientfake.NewClientBuilder().WithScheme(s).WithStatusSubresource(&clustercuratorv1.ClusterCurator{}).WithRuntimeObjects(cc).Build()
+
+	mapAJ, _ := runtime.DefaultUnstructuredConverter.ToUnstructured(aj)
+	unstructAJ := &unstructured.Unstructured{Object: mapAJ}
+
+	cmName, err := CollectAnsibleJobArtifacts(context.TODO(), client, fake.NewSimpleClientset(), cc, unstructAJ)
+	assert.Nil(t, err, "err nil, when the runner pod is gone")
+
+	configMap := &corev1.ConfigMap{}
+	assert.Nil(t, client.Get(context.TODO(), types.NamespacedName{Namespace: ClusterName, Name: cmName}, configMap))
+	assert.NotContains(t, configMap.Data, ARTIFACTS_STDOUT_KEY)
+	assert.Contains(t, configMap.Data, ARTIFACTS_RESULT_KEY)
+}
+
+func TestMonitorAnsibleJobRecordsArtifacts(t *testing.T) {
+
+	cc := getClusterCurator()
+	aj := buildAnsibleJob("error", ClusterName+"/"+AnsibleJobName+"-runner")
+
+	os.Setenv(EnvJobType, PREHOOK)
+
+	s.AddKnownTypes(ajv1.SchemeBuilder.GroupVersion, &ajv1.AnsibleJob{})
+	s.AddKnownTypes(clustercuratorv1.SchemeBuilder.GroupVersion, &clustercuratorv1.ClusterCurator{})
+	s.AddKnownTypes(corev1.SchemeGroupVersion, &corev1.ConfigMap{})
+	client := clientfake.NewClientBuilder().WithScheme(s).WithStatusSubresource(&clustercuratorv1.ClusterCurator{}).WithRuntimeObjects(aj, cc).Build()
+
+	mapAJ, _ := runtime.DefaultUnstructuredConverter.ToUnstructured(aj)
+	unstructAJ := &unstructured.Unstructured{Object: mapAJ}
+
+	err := MonitorAnsibleJob(context.TODO(), client, fake.NewSimpleClientset(getRunnerPod()), unstructAJ, cc)
+	assert.NotNil(t, err, "err not nil, when the AnsibleJob failed")
+	assert.True(t, strings.HasSuffix(err.Error(), ClusterName+"/"+AnsibleJobName+ARTIFACTS_SUFFIX),
+		"error points to the artifacts ConfigMap")
+
+	curator := &clustercuratorv1.ClusterCurator{}
+	assert.Nil(t, client.Get(context.TODO(), types.NamespacedName{Namespace: ClusterName, Name: ClusterName}, curator))
+	condition := meta.FindStatusCondition(curator.Status.Conditions, AnsibleJobName+ARTIFACTS_SUFFIX)
+	assert.NotNil(t, condition, "the ClusterCurator status points to the artifacts ConfigMap")
+	assert.Equal(t, "ansiblejob_artifacts", condition.Reason)
+	assert.Equal(t, ClusterName+"/"+AnsibleJobName+ARTIFACTS_SUFFIX, condition.Message)
+}
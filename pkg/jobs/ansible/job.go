@@ -4,23 +4,26 @@ package ansible
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"os"
-	"time"
+	"sort"
 
 	hivev1 "github.com/openshift/hive/apis/hive/v1"
 	clustercuratorv1 "github.com/stolostron/cluster-curator-controller/pkg/api/v1beta1"
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/secrets"
 	"github.com/stolostron/cluster-curator-controller/pkg/jobs/utils"
 	managedclusterinfov1beta1 "github.com/stolostron/cluster-lifecycle-api/clusterinfo/v1beta1"
 	"gopkg.in/yaml.v2"
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/klog/v2"
+	managedclusterv1 "open-cluster-management.io/api/cluster/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -31,13 +34,33 @@ const ICSUFFIX = "-install-config"
 const JOB_TEMPLATE_NAME_KEY = "job_template_name"
 const WORKFLOW_TEMPLATE_NAME_KEY = "workflow_template_name"
 
+// extra_vars keys the curator adds to every AnsibleJob
+const CLUSTER_DEPLOYMENT_KEY = "cluster_deployment"
+const INSTALL_CONFIG_KEY = "install_config"
+const CLUSTER_INFO_KEY = "cluster_info"
+const HOSTED_CLUSTER_KEY = "hosted_cluster"
+const NODE_POOLS_KEY = "node_pools"
+const CURATION_CONTEXT_KEY = "curation_context"
+
+// Label of the phase of the AnsibleJobs created by a curator job, a retried job adopts the AnsibleJobs of its phase
+const CuratorPhaseLabel = "cluster.open-cluster-management.io/curator-phase"
+
 var ansibleJobGVR = schema.GroupVersionResource{
 	Group: "tower.ansible.com", Version: "v1alpha1", Resource: "ansiblejobs"}
 
-func Job(client client.Client, curator *clustercuratorv1.ClusterCurator) error {
+func Job(
+	ctx context.Context,
+	client client.Client,
+	kubeset kubernetes.Interface,
+	curator *clustercuratorv1.ClusterCurator) error {
+
 	jobType := os.Getenv("JOB_TYPE")
 	if jobType != PREHOOK && jobType != POSTHOOK {
-		return errors.New("Missing JOB_TYPE environment parameter, use \"prehook\" or \"posthook\"")
+		return utils.NewError(utils.ReasonInvalidSpec,
+			"Missing JOB_TYPE environment parameter, use \"prehook\" or \"posthook\"")
+	}
+	if curator == nil {
+		return utils.NewError(utils.ReasonNotFound, "No ClusterCurator found for the %v AnsibleJob", jobType)
 	}
 
 	// var hooks clustercuratorv1.Hooks
@@ -45,9 +68,14 @@ func Job(client client.Client, curator *clustercuratorv1.ClusterCurator) error {
 	var posthook []clustercuratorv1.Hook
 	var towerauthsecret string
 
+	// An operation runs the hooks of its phase only, with the hooks of its curation
 	desiredCuration := curator.Spec.DesiredCuration
-	if curator.Operation != nil && curator.Operation.RetryPosthook != "" {
-		desiredCuration = curator.Operation.RetryPosthook
+	if op := utils.CurrentOperation(curator); op != nil {
+		if op.Phase != jobType {
+			klog.V(0).Infof("The operation re-runs the %v phase, the %v hooks are skipped", op.Phase, jobType)
+			return nil
+		}
+		desiredCuration = op.Curation
 	}
 
 	switch desiredCuration {
@@ -63,19 +91,13 @@ func Job(client client.Client, curator *clustercuratorv1.ClusterCurator) error {
 		prehook = curator.Spec.Destroy.Prehook
 		posthook = curator.Spec.Destroy.Posthook
 		towerauthsecret = curator.Spec.Destroy.TowerAuthSecret
-		/*	case "scale":
-				hooks = curator.Spec.Scale
-			case "upgrade":
-				hooks = curator.Spec.Upgrade
-		*/
-	case "installPosthook":
-		posthook = curator.Spec.Install.Posthook
-		towerauthsecret = curator.Spec.Install.TowerAuthSecret
-	case "upgradePosthook":
-		posthook = curator.Spec.Upgrade.Posthook
-		towerauthsecret = curator.Spec.Upgrade.TowerAuthSecret
+	case "scale":
+		prehook = curator.Spec.Scale.Prehook
+		posthook = curator.Spec.Scale.Posthook
+		towerauthsecret = curator.Spec.Scale.TowerAuthSecret
 	default:
-		return errors.New("The Spec.DesiredCuration value is not supported: " + curator.Spec.DesiredCuration)
+		return utils.NewError(utils.ReasonInvalidSpec,
+			"The Spec.DesiredCuration value is not supported: %s", curator.Spec.DesiredCuration)
 	}
 
 	// Extract the prehooks or posthooks
@@ -90,19 +112,51 @@ func Job(client client.Client, curator *clustercuratorv1.ClusterCurator) error {
 		return nil
 	}
 
-	for _, ttn := range hooksToRun {
+	// The AnsibleJob references a Secret of its namespace, created from the external store
+	towerSecrets := map[string]string{}
+
+	// The hooks run one after the other, the AnsibleJobs of an earlier pod are in the order of the hooks
+	adopted, err := getCuratorJobAnsibleJobs(ctx, client, curator, jobType)
+	if err != nil {
+		return err
+	}
+
+	for i, ttn := range hooksToRun {
 		klog.V(3).Info("Tower Job name: " + ttn.Name + " type:" + string(ttn.Type))
-		jobResource, err := RunAnsibleJob(client, curator, jobType, ttn, towerauthsecret)
-		if err != nil {
-			return err
+
+		var jobResource *unstructured.Unstructured
+		if i < len(adopted) {
+			jobResource = &adopted[i]
+			klog.V(0).Infof("Adopting AnsibleJob %v, created by an earlier pod of the curator job", jobResource.GetName())
+		} else {
+			// A hook can run its template on another Ansible Tower
+			secretRef := towerauthsecret
+			if ttn.TowerAuthSecret != "" {
+				secretRef = ttn.TowerAuthSecret
+			}
+			if secrets.IsExternalPath(secretRef) {
+				if _, ok := towerSecrets[secretRef]; !ok {
+					secretName, err := secrets.ApplyExternalTowerSecret(ctx, kubeset, secretRef, curator.Namespace)
+					if err != nil {
+						return err
+					}
+					towerSecrets[secretRef] = secretName
+				}
+				secretRef = towerSecrets[secretRef]
+			}
+
+			jobResource, err = RunAnsibleJob(ctx, client, curator, jobType, ttn, secretRef)
+			if err != nil {
+				return err
+			}
 		}
 
 		klog.V(0).Infof("Monitor AnsibleJob: %v", jobResource.GetName())
 		if jobResource.GetName() == "" {
-			return errors.New("Name was not generated")
+			return utils.NewError(utils.ReasonAnsibleJobFailed, "Name was not generated")
 		}
 		klog.V(4).Infof("AnsibleJob: %v", jobResource)
-		err = MonitorAnsibleJob(client, jobResource, curator)
+		err = MonitorAnsibleJob(ctx, client, kubeset, jobResource, curator)
 		if err != nil {
 			return err
 		}
@@ -119,18 +173,7 @@ func getAnsibleJob(jobtype string, // pre or post
 	ansibleJobName string,
 	clusterName string,
 	jobTags string,
-	skipTags string) *unstructured.Unstructured {
-
-	/*mapExtraVars := map[string]interface{}{}
-	if extraVars != nil {
-
-		klog.V(4).Infof("Converting extra_vars to map: %v",extraVars)
-		marshalledExtraVars, err := json.Marshal(extraVars)
-		utils.CheckError(err)
-
-		err = json.Unmarshal(marshalledExtraVars, &mapExtraVars)
-		utils.CheckError(err)
-	}*/
+	skipTags string) (*unstructured.Unstructured, error) {
 
 	templateNameKey := JOB_TEMPLATE_NAME_KEY
 	if hooktype == string(clustercuratorv1.HookTypeWorkflow) {
@@ -161,8 +204,10 @@ func getAnsibleJob(jobtype string, // pre or post
 
 	if extraVars != nil {
 
-		err := json.Unmarshal(extraVars.Raw, &mapExtraVars)
-		utils.CheckError(err)
+		if err := json.Unmarshal(extraVars.Raw, &mapExtraVars); err != nil {
+			return nil, utils.NewError(utils.ReasonInvalidSpec,
+				"The extra_vars of %v are not a valid object: %w", ansibleTemplateName, err)
+		}
 	}
 
 	ansibleJob.Object["spec"].(map[string]interface{})["extra_vars"] = mapExtraVars
@@ -177,14 +222,17 @@ func getAnsibleJob(jobtype string, // pre or post
 		}
 	}
 
-	return ansibleJob
+	return ansibleJob, nil
 }
 
 // Retreive the cluster deployment for use in the extra_vars
-func getClusterDeployment(client client.Client, clusterName string) (map[string]interface{}, error) {
+func getClusterDeployment(
+	ctx context.Context,
+	client client.Client,
+	clusterName string) (map[string]interface{}, error) {
 	cd := hivev1.ClusterDeployment{}
 
-	if err := client.Get(context.Background(), types.NamespacedName{
+	if err := client.Get(ctx, types.NamespacedName{
 		Namespace: clusterName,
 		Name:      clusterName,
 	}, &cd); err != nil {
@@ -209,10 +257,10 @@ func getClusterDeployment(client client.Client, clusterName string) (map[string]
 // }
 
 // Extract the control, compute and networking keys from the install config. This skips sensitive values
-func getInstallConfig(client client.Client, clusterName string) (map[string]interface{}, error) {
+func getInstallConfig(ctx context.Context, client client.Client, clusterName string) (map[string]interface{}, error) {
 	ic := corev1.Secret{}
 
-	if err := client.Get(context.Background(), types.NamespacedName{
+	if err := client.Get(ctx, types.NamespacedName{
 		Namespace: clusterName,
 		Name:      clusterName + ICSUFFIX,
 	}, &ic); err != nil {
@@ -239,9 +287,12 @@ func getInstallConfig(client client.Client, clusterName string) (map[string]inte
 	return subset, nil
 }
 
-func getManagedClusterInfo(client client.Client, clusterName string) (map[string]interface{}, error) {
+func getManagedClusterInfo(
+	ctx context.Context,
+	client client.Client,
+	clusterName string) (map[string]interface{}, error) {
 	managedClusterInfo := managedclusterinfov1beta1.ManagedClusterInfo{}
-	if err := client.Get(context.TODO(), types.NamespacedName{
+	if err := client.Get(ctx, types.NamespacedName{
 		Namespace: clusterName,
 		Name:      clusterName,
 	}, &managedClusterInfo); err != nil {
@@ -273,6 +324,94 @@ func getManagedClusterInfo(client client.Client, clusterName string) (map[string
 	return clusterInfo, nil
 }
 
+// Retreive the ManagedCluster labels and ClusterClaims for use in the extra_vars
+func getManagedCluster(ctx context.Context, client client.Client, clusterName string) (map[string]interface{}, error) {
+	managedCluster := managedclusterv1.ManagedCluster{}
+	if err := client.Get(ctx, types.NamespacedName{Name: clusterName}, &managedCluster); err != nil {
+		return nil, err
+	}
+
+	labels := map[string]interface{}{}
+	for key, value := range managedCluster.GetLabels() {
+		labels[key] = value
+	}
+
+	clusterClaims := map[string]interface{}{}
+	for _, claim := range managedCluster.Status.ClusterClaims {
+		clusterClaims[claim.Name] = claim.Value
+	}
+
+	return map[string]interface{}{
+		"labels":        labels,
+		"clusterClaims": clusterClaims,
+	}, nil
+}
+
+// Retreive the HostedCluster spec for use in the extra_vars
+func getHostedCluster(
+	ctx context.Context,
+	client client.Client,
+	clusterName string,
+	namespace string) (map[string]interface{}, error) {
+	hostedCluster := &unstructured.Unstructured{}
+	hostedCluster.SetGroupVersionKind(schema.GroupVersionKind{
+		Group: utils.HCGVR.Group, Version: utils.HCGVR.Version, Kind: "HostedCluster"})
+
+	if err := client.Get(ctx, types.NamespacedName{
+		Namespace: namespace,
+		Name:      clusterName,
+	}, hostedCluster); err != nil {
+		return nil, err
+	}
+
+	spec, _, err := unstructured.NestedMap(hostedCluster.Object, "spec")
+	return spec, err
+}
+
+// Retreive the NodePools belonging to the HostedCluster for use in the extra_vars
+func getNodePools(
+	ctx context.Context,
+	hubClient client.Client,
+	clusterName string,
+	namespace string) ([]interface{}, error) {
+	nodePools := &unstructured.UnstructuredList{}
+	nodePools.SetGroupVersionKind(schema.GroupVersionKind{
+		Group: utils.NPGVR.Group, Version: utils.NPGVR.Version, Kind: "NodePoolList"})
+
+	if err := hubClient.List(ctx, nodePools, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+
+	pools := []interface{}{}
+	for _, np := range nodePools.Items {
+		if npClusterName, _, _ := unstructured.NestedString(np.Object, "spec", "clusterName"); npClusterName != clusterName {
+			continue
+		}
+		spec, _, err := unstructured.NestedMap(np.Object, "spec")
+		if err != nil {
+			return nil, err
+		}
+		pools = append(pools, map[string]interface{}{
+			"name": np.GetName(),
+			"spec": spec,
+		})
+	}
+	return pools, nil
+}
+
+// A missing kind (e.g. HyperShift is not installed on the hub) is treated the same as a missing resource
+func isMissing(err error) bool {
+	return k8serrors.IsNotFound(err) || meta.IsNoMatchError(err)
+}
+
+// The curation being run, an operation runs as part of its curation
+func getCurationType(curator *clustercuratorv1.ClusterCurator) string {
+	if op := utils.CurrentOperation(curator); op != nil {
+		return op.Curation
+	}
+	return curator.Spec.DesiredCuration
+}
+
 // Not currently used, represents an OPT-IN approach
 // func parsePlatform(m interface{}) interface{} {
 //
@@ -330,6 +469,7 @@ func getManagedClusterInfo(client client.Client, clusterName string) (map[string
  *  secretRef		 # The secret to connect to Tower in the cluster namespace, ie. toweraccess
  */
 func RunAnsibleJob(
+	ctx context.Context,
 	client client.Client,
 	curator *clustercuratorv1.ClusterCurator,
 	jobtype string,
@@ -341,7 +481,7 @@ func RunAnsibleJob(
 	namespace := curator.Namespace
 	klog.V(4).Infof("hookToRun: %v", hookToRun)
 
-	ansibleJob := getAnsibleJob(
+	ansibleJob, err := getAnsibleJob(
 		jobtype,
 		string(hookToRun.Type),
 		hookToRun.Name,
@@ -351,8 +491,20 @@ func RunAnsibleJob(
 		namespace,
 		hookToRun.JobTags,
 		hookToRun.SkipTags)
+	if err != nil {
+		return nil, err
+	}
+
+	extraVars := ansibleJob.Object["spec"].(map[string]interface{})["extra_vars"].(map[string]interface{})
+	curationContext := map[string]interface{}{
+		"clusterName":      curator.Name,
+		"clusterNamespace": namespace,
+		"curation":         getCurationType(curator),
+		"phase":            jobtype,
+		"clusterType":      utils.StandaloneClusterType,
+	}
 
-	cd, err := getClusterDeployment(client, namespace)
+	cd, err := getClusterDeployment(ctx, client, namespace)
 	if err != nil {
 		if k8serrors.IsNotFound(err) {
 			klog.Warning("Did not find clusterDeployment")
@@ -360,10 +512,10 @@ func RunAnsibleJob(
 			return nil, err
 		}
 	} else {
-		ansibleJob.Object["spec"].(map[string]interface{})["extra_vars"].(map[string]interface{})["cluster_deployment"] = cd["spec"]
+		extraVars[CLUSTER_DEPLOYMENT_KEY] = cd["spec"]
 	}
 
-	mp, err := getInstallConfig(client, namespace)
+	mp, err := getInstallConfig(ctx, client, namespace)
 	if err != nil {
 		if k8serrors.IsNotFound(err) {
 			klog.Warning("Did not find install-config")
@@ -371,29 +523,72 @@ func RunAnsibleJob(
 			return nil, err
 		}
 	} else {
-		ansibleJob.Object["spec"].(map[string]interface{})["extra_vars"].(map[string]interface{})["install_config"] = mp
+		extraVars[INSTALL_CONFIG_KEY] = mp
 	}
 
-	if curator.Spec.DesiredCuration == "upgrade" {
-		mcl, err := getManagedClusterInfo(client, namespace)
+	// HyperShift clusters have no ClusterDeployment, expose the HostedCluster and its NodePools instead
+	if cd == nil {
+		hc, err := getHostedCluster(ctx, client, curator.Name, namespace)
 		if err != nil {
-			if k8serrors.IsNotFound(err) {
-				klog.Warning("Did not find managedClusterInfo")
+			if isMissing(err) {
+				klog.V(2).Info("Did not find hostedCluster")
 			} else {
 				return nil, err
 			}
 		} else {
-			ansibleJob.Object["spec"].(map[string]interface{})["extra_vars"].(map[string]interface{})["cluster_info"] = mcl
+			extraVars[HOSTED_CLUSTER_KEY] = hc
+			curationContext["clusterType"] = utils.HypershiftClusterType
+
+			nps, err := getNodePools(ctx, client, curator.Name, namespace)
+			if err != nil && !isMissing(err) {
+				return nil, err
+			}
+			extraVars[NODE_POOLS_KEY] = nps
 		}
 	}
 
+	mcl, err := getManagedClusterInfo(ctx, client, curator.Name)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			klog.Warning("Did not find managedClusterInfo")
+		} else {
+			return nil, err
+		}
+	} else {
+		extraVars[CLUSTER_INFO_KEY] = mcl
+	}
+
+	mc, err := getManagedCluster(ctx, client, curator.Name)
+	if err != nil {
+		if isMissing(err) {
+			klog.Warning("Did not find managedCluster")
+		} else if k8serrors.IsForbidden(err) {
+			// The cluster-installer of an overrideJob curation can not read the cluster-scoped ManagedCluster
+			klog.Warning("Can not read the managedCluster, its labels and ClusterClaims are not set")
+		} else {
+			return nil, err
+		}
+	} else {
+		curationContext["labels"] = mc["labels"]
+		curationContext["clusterClaims"] = mc["clusterClaims"]
+	}
+
+	extraVars[CURATION_CONTEXT_KEY] = curationContext
+
 	if curator.Spec.Inventory != "" {
-		ansibleJob.Object["spec"].(map[string]interface{})["extra_vars"].(map[string]interface{})["inventory"] = curator.Spec.Inventory
+		extraVars["inventory"] = curator.Spec.Inventory
+	}
+
+	utils.SetCurationMetadata(ansibleJob, curator)
+	if utils.CuratingJob(curator) != "" {
+		labels := ansibleJob.GetLabels()
+		labels[CuratorPhaseLabel] = jobtype
+		ansibleJob.SetLabels(labels)
 	}
 
 	klog.V(0).Info("Creating AnsibleJob " + ansibleJob.GetName() + " in namespace " + namespace)
 	klog.V(4).Infof("ansibleJob: %v", ansibleJob)
-	err = client.Create(context.Background(), ansibleJob)
+	err = client.Create(ctx, ansibleJob)
 
 	if err != nil {
 		return nil, err
@@ -404,8 +599,40 @@ func RunAnsibleJob(
 	return ansibleJob, nil
 }
 
+// The AnsibleJobs of the phase created by the curator job, oldest first
+func getCuratorJobAnsibleJobs(
+	ctx context.Context,
+	c client.Client,
+	curator *clustercuratorv1.ClusterCurator,
+	jobType string) ([]unstructured.Unstructured, error) {
+
+	if utils.CuratingJob(curator) == "" {
+		return nil, nil
+	}
+
+	ansibleJobs := &unstructured.UnstructuredList{}
+	ansibleJobs.SetGroupVersionKind(ansibleJobGVR.GroupVersion().WithKind("AnsibleJobList"))
+	if err := c.List(ctx, ansibleJobs, client.InNamespace(curator.Namespace), client.MatchingLabels{
+		utils.CurationRunLabel: utils.CuratingJob(curator),
+		CuratorPhaseLabel:      jobType,
+	}); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(ansibleJobs.Items, func(i, j int) bool {
+		ti, tj := ansibleJobs.Items[i].GetCreationTimestamp(), ansibleJobs.Items[j].GetCreationTimestamp()
+		if ti.Equal(&tj) {
+			return ansibleJobs.Items[i].GetName() < ansibleJobs.Items[j].GetName()
+		}
+		return ti.Before(&tj)
+	})
+	return ansibleJobs.Items, nil
+}
+
 func MonitorAnsibleJob(
+	ctx context.Context,
 	client client.Client,
+	kubeset kubernetes.Interface,
 	jobResource *unstructured.Unstructured,
 	curator *clustercuratorv1.ClusterCurator) error {
 
@@ -413,25 +640,30 @@ func MonitorAnsibleJob(
 	ansibleJobName := jobResource.GetName()
 	klog.V(0).Info("* Monitoring AnsibleJob " + namespace + "/" + jobResource.GetName())
 
-	utils.CheckError(utils.RecordCurrentStatusCondition(
+	if err := utils.RecordCurrentStatusCondition(
 		client,
 		curator.Name,
 		curator.Namespace,
 		"current-ansiblejob",
 		v1.ConditionFalse,
-		jobResource.GetName()))
+		jobResource.GetName()); err != nil {
+		return err
+	}
 
-	// Monitor the AnsibeJob resource
+	ansibleJobList := &unstructured.UnstructuredList{}
+	ansibleJobList.SetGroupVersionKind(ansibleJobGVR.GroupVersion().WithKind("AnsibleJobList"))
+
+	// Monitor the AnsibeJob resource, re-evaluated on every change
 	foundUrlOnce := false
-	for {
+	return utils.WaitForCondition(ctx, utils.PauseFiveSeconds, func(ctx context.Context) (bool, error) {
 
-		err := client.Get(context.Background(), types.NamespacedName{
+		err := client.Get(ctx, types.NamespacedName{
 			Namespace: namespace,
 			Name:      ansibleJobName,
 		}, jobResource)
 
 		if err != nil {
-			return err
+			return false, err
 		}
 
 		klog.V(4).Infof("ansibleJob: %v", jobResource)
@@ -441,8 +673,7 @@ func MonitorAnsibleJob(
 			jobResource.Object["status"].(map[string]interface{})["conditions"] == nil {
 
 			klog.V(2).Infof("AnsibleJob %v/%v is initializing", namespace, ansibleJobName)
-			time.Sleep(utils.PauseFiveSeconds)
-			continue
+			return false, nil
 		}
 
 		jos := jobResource.Object["status"]
@@ -452,13 +683,15 @@ func MonitorAnsibleJob(
 			klog.V(2).Infof("Found result url %v", jobStatusUrl)
 
 			if !foundUrlOnce && jobStatusUrl != nil {
-				utils.CheckError(utils.RecordAnsibleJobStatusUrlCondition(
+				if err := utils.RecordAnsibleJobStatusUrlCondition(
 					client,
 					curator.Name,
 					curator.Namespace,
 					jobResource.GetName(),
 					v1.ConditionTrue,
-					jobStatusUrl.(string)))
+					jobStatusUrl.(string)); err != nil {
+					return false, err
+				}
 				foundUrlOnce = true
 			}
 
@@ -469,18 +702,22 @@ func MonitorAnsibleJob(
 
 				klog.V(2).Infof("AnsibleJob %v/%v finished successfully ✓", namespace, ansibleJobName)
 
-				utils.CheckError(utils.RecordCurrentStatusCondition(
+				if err := utils.RecordCurrentStatusCondition(
 					client,
 					curator.Name,
 					curator.Namespace,
 					"current-ansiblejob",
 					v1.ConditionTrue,
-					jobResource.GetName()))
+					jobResource.GetName()); err != nil {
+					return false, err
+				}
 
-				break
+				recordArtifacts(ctx, client, kubeset, curator, jobResource)
+				return true, nil
 			} else if jobStatus == "error" {
 
-				return errors.New("AnsibleJob " + namespace + "/" + ansibleJobName + " exited with an error")
+				return false, utils.NewError(utils.ReasonAnsibleJobFailed, "AnsibleJob %s/%s exited with an error%s",
+					namespace, ansibleJobName, recordArtifacts(ctx, client, kubeset, curator, jobResource))
 			}
 		}
 
@@ -493,13 +730,14 @@ func MonitorAnsibleJob(
 		for _, condition := range jobResource.Object["status"].(map[string]interface{})["conditions"].([]interface{}) {
 
 			if condition.(map[string]interface{})["reason"] == "Failed" {
-				return errors.New(condition.(map[string]interface{})["message"].(string))
+				return false, utils.NewError(utils.ReasonAnsibleJobFailed, "%v%s",
+					condition.(map[string]interface{})["message"],
+					recordArtifacts(ctx, client, kubeset, curator, jobResource))
 			}
 		}
 		klog.V(2).Infof("AnsibleJob %v/%v is still running", namespace, ansibleJobName)
-		time.Sleep(utils.PauseFiveSeconds)
-	}
-	return nil
+		return false, nil
+	}, utils.WatchObject(client, ansibleJobList, namespace, ansibleJobName))
 }
 
 type AnsibleJob struct {
@@ -512,7 +750,7 @@ func FindAnsibleTemplateNamefromCurator(
 	jobType string) ([]clustercuratorv1.Hook, error) {
 
 	if hooks == nil {
-		utils.CheckError(errors.New("No Ansible job hooks found"))
+		return nil, utils.NewError(utils.ReasonInvalidSpec, "No Ansible job hooks found")
 	}
 	hooksToRun := hooks.Prehook
 	if jobType == POSTHOOK {
@@ -520,7 +758,36 @@ func FindAnsibleTemplateNamefromCurator(
 	}
 
 	if len(hooksToRun) == 0 {
-		return nil, errors.New("Missing " + jobType + " in curator kind ")
+		return nil, utils.NewError(utils.ReasonInvalidSpec, "Missing %s in curator kind ", jobType)
 	}
 	return hooksToRun, nil
 }
+
+// Collects the AnsibleJob logs and artifacts and points the ClusterCurator status at them. Failures are only
+// logged, so they never hide the result of the AnsibleJob. Returns a hint to append to error messages.
+func recordArtifacts(
+	ctx context.Context,
+	client client.Client,
+	kubeset kubernetes.Interface,
+	curator *clustercuratorv1.ClusterCurator,
+	jobResource *unstructured.Unstructured) string {
+
+	cmName, err := CollectAnsibleJobArtifacts(ctx, client, kubeset, curator, jobResource)
+	if err != nil {
+		klog.Warningf("Could not store the logs and artifacts of AnsibleJob %v/%v: %v",
+			jobResource.GetNamespace(), jobResource.GetName(), err)
+		return ""
+	}
+
+	if err := utils.RecordAnsibleJobArtifactsCondition(
+		client,
+		curator.Name,
+		curator.Namespace,
+		cmName,
+		v1.ConditionTrue,
+		curator.Namespace+"/"+cmName); err != nil {
+		klog.Warningf("Could not record the artifacts ConfigMap %v on the ClusterCurator: %v", cmName, err)
+	}
+
+	return ", logs and artifacts are stored in ConfigMap " + curator.Namespace + "/" + cmName
+}
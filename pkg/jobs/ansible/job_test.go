@@ -3,6 +3,7 @@ package ansible
 
 import (
 	"context"
+	"errors"
 	"os"
 	"testing"
 	"time"
@@ -14,13 +15,17 @@ import (
 	managedclusterinfov1beta1 "github.com/stolostron/cluster-lifecycle-api/clusterinfo/v1beta1"
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"open-cluster-management.io/api/client/cluster/clientset/versioned/scheme"
+	managedclusterv1 "open-cluster-management.io/api/cluster/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 )
 
 const EnvJobType = "JOB_TYPE"
@@ -29,7 +34,7 @@ const AnsibleJobName = "my-ansiblejob-12345"
 const SecretRef = "toweraccess"
 const AnsibleJobTemplateName = "Ansible Tower Template to run as a job"
 
-var ansibleJob = getAnsibleJob(PREHOOK, "", AnsibleJobTemplateName, SecretRef, nil, AnsibleJobName, ClusterName, "", "")
+var ansibleJob, _ = getAnsibleJob(PREHOOK, "", AnsibleJobTemplateName, SecretRef, nil, AnsibleJobName, ClusterName, "", "")
 var s = scheme.Scheme
 
 func getClusterCurator() *clustercuratorv1.ClusterCurator {
@@ -245,7 +250,7 @@ func TestJobNoEnvVar(t *testing.T) {
 
 	t.Log("No JOB_TYPE variable configured")
 
-	assert.NotNil(t, Job(nil, nil), "err not nil, when no os.env JOB_TYPE")
+	assert.NotNil(t, Job(context.TODO(), nil, nil, nil), "err not nil, when no os.env JOB_TYPE")
 }
 
 func TestJobInvalidDesiredCuration(t *testing.T) {
@@ -256,19 +261,28 @@ func TestJobInvalidDesiredCuration(t *testing.T) {
 
 	cc.Spec.DesiredCuration = "INVALID CHOICE"
 
-	assert.NotNil(t, Job(nil, cc), "err not nil, DesiredCuration value is not VALID")
+	assert.NotNil(t, Job(context.TODO(), nil, nil, cc), "err not nil, DesiredCuration value is not VALID")
 }
 
 func TestJobNoClusterCurator(t *testing.T) {
 
-	// We should never get in this situation, but if it happens then send a panic
+	// We should never get in this situation, but if it happens then return an error
 	t.Log(PREHOOK)
 	os.Setenv(EnvJobType, PREHOOK)
-	assert.Panics(t, func() { Job(nil, nil) }, "Panics when no ClusterCurator is present")
+	err := Job(context.TODO(), nil, nil, nil)
+	assert.Equal(t, utils.ReasonNotFound, utils.ReasonForError(err), "NotFound, when no ClusterCurator is present")
 
 	t.Log(POSTHOOK)
 	os.Setenv(EnvJobType, POSTHOOK)
-	assert.Panics(t, func() { Job(nil, nil) }, "Panics when no ClusterCurator is present")
+	err = Job(context.TODO(), nil, nil, nil)
+	assert.Equal(t, utils.ReasonNotFound, utils.ReasonForError(err), "NotFound, when no ClusterCurator is present")
+}
+
+func TestGetAnsibleJobInvalidExtraVars(t *testing.T) {
+
+	_, err := getAnsibleJob(PREHOOK, "", AnsibleJobTemplateName, SecretRef,
+		&runtime.RawExtension{Raw: []byte(`["not", "an", "object"]`)}, AnsibleJobName, ClusterName, "", "")
+	assert.Equal(t, utils.ReasonInvalidSpec, utils.ReasonForError(err), "InvalidSpec, when extra_vars is not an object")
 }
 
 func TestJobNoClusterCuratorData(t *testing.T) {
@@ -276,10 +290,10 @@ func TestJobNoClusterCuratorData(t *testing.T) {
 	// If prehook or posthook is not defined in the ClusterCurator skip
 	t.Logf("Test %v", PREHOOK)
 	os.Setenv(EnvJobType, PREHOOK)
-	assert.Nil(t, Job(nil, getClusterCuratorEmpty()), "err nil, when no Ansible posthooks")
+	assert.Nil(t, Job(context.TODO(), nil, nil, getClusterCuratorEmpty()), "err nil, when no Ansible posthooks")
 
 	t.Logf("Test %v", POSTHOOK)
-	assert.Nil(t, Job(nil, getClusterCuratorEmpty()), "err nil, when no Ansible prehooks")
+	assert.Nil(t, Job(context.TODO(), nil, nil, getClusterCuratorEmpty()), "err nil, when no Ansible prehooks")
 }
 
 func TestJobInstallUpgradeRetryposthook(t *testing.T) {
@@ -291,14 +305,14 @@ func TestJobInstallUpgradeRetryposthook(t *testing.T) {
 		RetryPosthook: "installPosthook",
 	}
 	cc.Operation = &operationInstall
-	assert.Nil(t, Job(nil, cc), "Test installPosthook case statement only")
+	assert.Nil(t, Job(context.TODO(), nil, nil, cc), "Test installPosthook case statement only")
 
 	// Upgrade posthook retry
 	operationUpgrade := clustercuratorv1.Operation{
 		RetryPosthook: "upgradePosthook",
 	}
 	cc.Operation = &operationUpgrade
-	assert.Nil(t, Job(nil, cc), "Test upgradePosthook case statement only")
+	assert.Nil(t, Job(context.TODO(), nil, nil, cc), "Test upgradePosthook case statement only")
 }
 
 func TestFindAnsibleTemplateNamefromClusterCurator(t *testing.T) {
@@ -327,7 +341,8 @@ func TestJob(t *testing.T) {
 	s.AddKnownTypes(clustercuratorv1.SchemeBuilder.GroupVersion, &clustercuratorv1.ClusterCurator{})
 	s.AddKnownTypes(hivev1.SchemeBuilder.GroupVersion, &hivev1.ClusterDeployment{}, &hivev1.MachinePool{})
 	s.AddKnownTypes(corev1.SchemeGroupVersion, &corev1.Secret{})
-	client := clientfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(
+	s.AddKnownTypes(managedclusterinfov1beta1.SchemeGroupVersion, &managedclusterinfov1beta1.ManagedClusterInfo{})
+	client := clientfake.NewClientBuilder().WithScheme(s).WithStatusSubresource(&clustercuratorv1.ClusterCurator{}).WithRuntimeObjects(
 		getClusterCurator(), genClusterDeployment(), genInstallConfigSecret(), genMachinePool()).Build()
 
 	t.Logf("client has been initialized")
@@ -359,7 +374,7 @@ func TestJob(t *testing.T) {
 			"err is nil, when ansibleJob resource is created")
 		t.Logf("AnsibleJob %v marked successful", jobName)
 	}()
-	err := Job(client, cc)
+	err := Job(context.TODO(), client, nil, cc)
 
 	assert.Nil(t, err,
 		"err nil, when Ansible Job created and monitored with AnsibleJobStatus successful")
@@ -375,7 +390,7 @@ func TestJobPosthook(t *testing.T) {
 	s.AddKnownTypes(ajv1.SchemeBuilder.GroupVersion, &ajv1.AnsibleJob{})
 	s.AddKnownTypes(clustercuratorv1.SchemeBuilder.GroupVersion, &clustercuratorv1.ClusterCurator{})
 	s.AddKnownTypes(hivev1.SchemeBuilder.GroupVersion, &hivev1.ClusterDeployment{}, &hivev1.MachinePool{})
-	client := clientfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(
+	client := clientfake.NewClientBuilder().WithScheme(s).WithStatusSubresource(&clustercuratorv1.ClusterCurator{}).WithRuntimeObjects(
 		getClusterCurator(), genClusterDeployment(), genMachinePool()).Build()
 
 	// buildAnsibleJob("successful", AnsibleJobTemplateName),
@@ -406,7 +421,7 @@ func TestJobPosthook(t *testing.T) {
 			"err is nil, when ansibleJob resource is created")
 		t.Logf("AnsibleJob %v marked successful", jobName)
 	}()
-	err := Job(client, cc)
+	err := Job(context.TODO(), client, nil, cc)
 
 	assert.Nil(t, err,
 		"err nil, when Ansible Job created and monitored with AnsibleJobStatus successful")
@@ -421,14 +436,16 @@ func TestMonitorAnsibleJobAnsibleJobStatusSuccessfulPreHook(t *testing.T) {
 
 	s.AddKnownTypes(ajv1.SchemeBuilder.GroupVersion, &ajv1.AnsibleJob{})
 	s.AddKnownTypes(clustercuratorv1.SchemeBuilder.GroupVersion, &clustercuratorv1.ClusterCurator{})
-	client := clientfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(
+	client := clientfake.NewClientBuilder().WithScheme(s).WithStatusSubresource(&clustercuratorv1.ClusterCurator{}).WithRuntimeObjects(
 		aj, cc).Build()
 
 	mapAJ, _ := runtime.DefaultUnstructuredConverter.ToUnstructured(&aj)
 	unstructAJ := &unstructured.Unstructured{Object: mapAJ}
 
 	assert.Nil(t, MonitorAnsibleJob(
+		context.TODO(),
 		client,
+		nil,
 		unstructAJ,
 		cc), "err nil, when successful")
 }
@@ -449,7 +466,7 @@ func TestMonitorAnsibleJobAnsibleJobStatusError(t *testing.T) {
 	mapAJ, _ := runtime.DefaultUnstructuredConverter.ToUnstructured(&aj)
 	unstructAJ := &unstructured.Unstructured{Object: mapAJ}
 
-	assert.NotNil(t, MonitorAnsibleJob(client, unstructAJ, cc), "err nil, when successful")
+	assert.NotNil(t, MonitorAnsibleJob(context.TODO(), client, nil, unstructAJ, cc), "err nil, when successful")
 }
 
 func TestMonitorAnsibleJobK8sJob(t *testing.T) {
@@ -462,13 +479,15 @@ func TestMonitorAnsibleJobK8sJob(t *testing.T) {
 
 	s.AddKnownTypes(ajv1.SchemeBuilder.GroupVersion, &ajv1.AnsibleJob{})
 	s.AddKnownTypes(clustercuratorv1.SchemeBuilder.GroupVersion, &clustercuratorv1.ClusterCurator{})
-	client := clientfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(
+	client := clientfake.NewClientBuilder().WithScheme(s).WithStatusSubresource(&clustercuratorv1.ClusterCurator{}).WithRuntimeObjects(
 		aj, cc).Build()
 
 	mapAJ, _ := runtime.DefaultUnstructuredConverter.ToUnstructured(&aj)
 	unstructAJ := &unstructured.Unstructured{Object: mapAJ}
 
-	assert.NotNil(t, MonitorAnsibleJob(client, unstructAJ, cc), "err not nil, when condition.reason = Failed")
+	err := MonitorAnsibleJob(context.TODO(), client, nil, unstructAJ, cc)
+	assert.NotNil(t, err, "err not nil, when condition.reason = Failed")
+	assert.Equal(t, utils.ReasonAnsibleJobFailed, utils.ReasonForError(err))
 
 	// Todo: Come back and figure out why ClusterCurator is not returning from dynamic fake.
 	curator := &clustercuratorv1.ClusterCurator{}
@@ -483,6 +502,52 @@ func TestMonitorAnsibleJobK8sJob(t *testing.T) {
 		"ClusterCurator Ansible Job object name correct")
 }
 
+func TestJobAdoptsAnsibleJob(t *testing.T) {
+
+	cc := getClusterCurator()
+	cc.Spec.CuratingJob = "curator-job-d9pwh"
+
+	os.Setenv(EnvJobType, PREHOOK)
+
+	s.AddKnownTypes(ajv1.SchemeBuilder.GroupVersion, &ajv1.AnsibleJob{}, &ajv1.AnsibleJobList{})
+	s.AddKnownTypes(clustercuratorv1.SchemeBuilder.GroupVersion, &clustercuratorv1.ClusterCurator{})
+	s.AddKnownTypes(hivev1.SchemeBuilder.GroupVersion, &hivev1.ClusterDeployment{}, &hivev1.MachinePool{})
+
+	// Launched by the pod of the curator job that hit a transient error
+	launched := buildAnsibleJob("successful", AnsibleJobTemplateName)
+	launched.SetLabels(map[string]string{utils.CurationRunLabel: cc.Spec.CuratingJob, CuratorPhaseLabel: PREHOOK})
+	client := clientfake.NewClientBuilder().WithScheme(s).WithStatusSubresource(&clustercuratorv1.ClusterCurator{}).WithRuntimeObjects(
+		cc, genClusterDeployment(), genMachinePool(), launched).Build()
+
+	assert.Nil(t, Job(context.TODO(), client, nil, cc), "err nil, when the adopted AnsibleJob is successful")
+
+	ansibleJobs := &ajv1.AnsibleJobList{}
+	assert.Nil(t, client.List(context.TODO(), ansibleJobs))
+	assert.Len(t, ansibleJobs.Items, 1, "the Ansible Tower job is not launched again")
+}
+
+func TestRunAnsibleJobCuratorJobLabels(t *testing.T) {
+
+	cc := getClusterCurator()
+	cc.UID = "curator-uid"
+	cc.Spec.CuratingJob = "curator-job-d9pwh"
+
+	s.AddKnownTypes(ajv1.SchemeBuilder.GroupVersion, &ajv1.AnsibleJob{})
+	s.AddKnownTypes(clustercuratorv1.SchemeBuilder.GroupVersion, &clustercuratorv1.ClusterCurator{})
+	s.AddKnownTypes(hivev1.SchemeBuilder.GroupVersion, &hivev1.ClusterDeployment{}, &hivev1.MachinePool{})
+	s.AddKnownTypes(corev1.SchemeGroupVersion, &corev1.Secret{})
+	s.AddKnownTypes(managedclusterinfov1beta1.SchemeGroupVersion, &managedclusterinfov1beta1.ManagedClusterInfo{})
+	client := clientfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(
+		cc, genClusterDeployment(), genMachinePool(), genInstallConfigSecret()).Build()
+
+	aJob, err := RunAnsibleJob(context.TODO(), client, cc, PREHOOK, cc.Spec.Install.Prehook[0], SecretRef)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{utils.CurationRunLabel: "curator-job-d9pwh", CuratorPhaseLabel: PREHOOK,
+		utils.ClusterCuratorLabel: ClusterName}, aJob.GetLabels())
+	assert.Equal(t, []v1.OwnerReference{{APIVersion: clustercuratorv1.GroupVersion.String(), Kind: "ClusterCurator",
+		Name: ClusterName, UID: "curator-uid"}}, aJob.GetOwnerReferences(), "the AnsibleJob is garbage collected")
+}
+
 func TestRunAnsibleJob(t *testing.T) {
 
 	cc := getClusterCurator()
@@ -494,10 +559,11 @@ func TestRunAnsibleJob(t *testing.T) {
 	s.AddKnownTypes(clustercuratorv1.SchemeBuilder.GroupVersion, &clustercuratorv1.ClusterCurator{})
 	s.AddKnownTypes(hivev1.SchemeBuilder.GroupVersion, &hivev1.ClusterDeployment{}, &hivev1.MachinePool{})
 	s.AddKnownTypes(corev1.SchemeGroupVersion, &corev1.Secret{})
+	s.AddKnownTypes(managedclusterinfov1beta1.SchemeGroupVersion, &managedclusterinfov1beta1.ManagedClusterInfo{})
 	client := clientfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(
 		cc, genClusterDeployment(), genMachinePool(), genInstallConfigSecret()).Build()
 
-	aJob, err := RunAnsibleJob(client, cc, POSTHOOK, cc.Spec.Install.Posthook[0], "toweraccess")
+	aJob, err := RunAnsibleJob(context.TODO(), client, cc, POSTHOOK, cc.Spec.Install.Posthook[0], "toweraccess")
 	assert.Nil(t, err, "err is nil when job is started")
 	t.Logf("Fake ansibleJob launched with name: %v", aJob.GetName())
 }
@@ -513,10 +579,11 @@ func TestAnsibleJobExtraVars(t *testing.T) {
 	s.AddKnownTypes(clustercuratorv1.SchemeBuilder.GroupVersion, &clustercuratorv1.ClusterCurator{})
 	s.AddKnownTypes(hivev1.SchemeBuilder.GroupVersion, &hivev1.ClusterDeployment{}, &hivev1.MachinePool{})
 	s.AddKnownTypes(corev1.SchemeGroupVersion, &corev1.Secret{})
+	s.AddKnownTypes(managedclusterinfov1beta1.SchemeGroupVersion, &managedclusterinfov1beta1.ManagedClusterInfo{})
 	client := clientfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(
 		cc, genClusterDeployment(), genMachinePool(), genInstallConfigSecret()).Build()
 
-	aJob, err := RunAnsibleJob(client, cc, POSTHOOK, cc.Spec.Install.Posthook[0], "toweraccess")
+	aJob, err := RunAnsibleJob(context.TODO(), client, cc, POSTHOOK, cc.Spec.Install.Posthook[0], "toweraccess")
 	assert.Nil(t, err, "err is nil when job is started")
 	t.Logf("Fake ansibleJob launched with name: %v", aJob.GetName())
 
@@ -587,7 +654,7 @@ func TestMonitorAnsibleRetryForLoop(t *testing.T) {
 	mapAJ, _ := runtime.DefaultUnstructuredConverter.ToUnstructured(&aj)
 	unstructAJ := &unstructured.Unstructured{Object: mapAJ}
 
-	assert.Panics(t, func() { MonitorAnsibleJob(client, unstructAJ, cc) }, "Panics when For loop times out")
+	assert.Panics(t, func() { MonitorAnsibleJob(context.TODO(), client, nil, unstructAJ, cc) }, "Panics when For loop times out")
 }*/
 
 /*
@@ -609,7 +676,7 @@ func TestMonitorAnsibleRetryForLoopJobResourceObjectNil(t *testing.T) {
 	}
 	dynclient := dynfake.NewSimpleDynamicClient(s, aj)
 
-	assert.Panics(t, func() { MonitorAnsibleJob(dynclient, ansibleJob, nil) }, "Panics when For loop times out, no condition status")
+	assert.Panics(t, func() { MonitorAnsibleJob(context.TODO(), dynclient, nil, ansibleJob, nil) }, "Panics when For loop times out, no condition status")
 }*/
 
 func getUpgradeClusterCurator() *clustercuratorv1.ClusterCurator {
@@ -711,7 +778,7 @@ func TestUpgradeAnsibleJobExtraVars(t *testing.T) {
 			curation:               "install",
 			clusterCurator:         getClusterCurator(),
 			managedClusterInfo:     genManagedClusterInfo(),
-			expectedClusterInfoVar: false,
+			expectedClusterInfoVar: true,
 		},
 	}
 
@@ -741,7 +808,7 @@ func TestUpgradeAnsibleJobExtraVars(t *testing.T) {
 			case "upgrade":
 				hook = test.clusterCurator.Spec.Upgrade.Posthook[0]
 			}
-			aJob, err := RunAnsibleJob(fakeClient, test.clusterCurator, POSTHOOK, hook, "toweraccess")
+			aJob, err := RunAnsibleJob(context.TODO(), fakeClient, test.clusterCurator, POSTHOOK, hook, "toweraccess")
 			assert.Nil(t, err, "err is nil when job is started")
 
 			extraVars := aJob.Object["spec"].(map[string]interface{})["extra_vars"].(map[string]interface{})
@@ -767,6 +834,171 @@ func TestUpgradeAnsibleJobExtraVars(t *testing.T) {
 	}
 }
 
+func genManagedCluster() *managedclusterv1.ManagedCluster {
+	return &managedclusterv1.ManagedCluster{
+		ObjectMeta: v1.ObjectMeta{
+			Name: ClusterName,
+			Labels: map[string]string{
+				"cloud":  "Amazon",
+				"region": "us-east-1",
+			},
+		},
+		Status: managedclusterv1.ManagedClusterStatus{
+			ClusterClaims: []managedclusterv1.ManagedClusterClaim{
+				{Name: "id.k8s.io", Value: "abc"},
+				{Name: "platform.open-cluster-management.io", Value: "AWS"},
+			},
+		},
+	}
+}
+
+func genHostedCluster(namespace string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "hypershift.openshift.io/v1beta1",
+			"kind":       "HostedCluster",
+			"metadata": map[string]interface{}{
+				"name":      ClusterName,
+				"namespace": namespace,
+			},
+			"spec": map[string]interface{}{
+				"platform": map[string]interface{}{
+					"type": "KubeVirt",
+				},
+			},
+		},
+	}
+}
+
+func genNodePool(name string, namespace string, clusterName string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "hypershift.openshift.io/v1beta1",
+			"kind":       "NodePool",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": namespace,
+			},
+			"spec": map[string]interface{}{
+				"clusterName": clusterName,
+				"replicas":    int64(2),
+			},
+		},
+	}
+}
+
+func TestCurationContextExtraVars(t *testing.T) {
+
+	os.Setenv(EnvJobType, PREHOOK)
+
+	s.AddKnownTypes(ajv1.SchemeBuilder.GroupVersion, &ajv1.AnsibleJob{})
+	s.AddKnownTypes(clustercuratorv1.SchemeBuilder.GroupVersion, &clustercuratorv1.ClusterCurator{})
+	s.AddKnownTypes(hivev1.SchemeBuilder.GroupVersion, &hivev1.ClusterDeployment{}, &hivev1.MachinePool{})
+	s.AddKnownTypes(corev1.SchemeGroupVersion, &corev1.Secret{})
+	s.AddKnownTypes(managedclusterinfov1beta1.SchemeGroupVersion, &managedclusterinfov1beta1.ManagedClusterInfo{})
+
+	t.Run("hive destroy", func(t *testing.T) {
+		cc := getClusterCurator()
+		cc.Spec.DesiredCuration = "destroy"
+		cc.Spec.Destroy.Prehook = cc.Spec.Install.Prehook
+
+		client := clientfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(
+			cc, genClusterDeployment(), genManagedClusterInfo(), genManagedCluster()).Build()
+
+		aJob, err := RunAnsibleJob(context.TODO(), client, cc, PREHOOK, cc.Spec.Destroy.Prehook[0], "toweraccess")
+		assert.Nil(t, err, "err is nil when job is started")
+
+		extraVars := aJob.Object["spec"].(map[string]interface{})["extra_vars"].(map[string]interface{})
+		assert.NotNil(t, extraVars[CLUSTER_DEPLOYMENT_KEY], "cluster_deployment is set for hive clusters")
+		assert.NotNil(t, extraVars[CLUSTER_INFO_KEY], "cluster_info is set for every curation")
+		assert.Nil(t, extraVars[HOSTED_CLUSTER_KEY], "hosted_cluster is not set for hive clusters")
+		assert.Equal(t, "1", extraVars["variable1"], "user extra_vars are kept")
+
+		curationContext := extraVars[CURATION_CONTEXT_KEY].(map[string]interface{})
+		assert.Equal(t, ClusterName, curationContext["clusterName"])
+		assert.Equal(t, ClusterName, curationContext["clusterNamespace"])
+		assert.Equal(t, "destroy", curationContext["curation"])
+		assert.Equal(t, PREHOOK, curationContext["phase"])
+		assert.Equal(t, utils.StandaloneClusterType, curationContext["clusterType"])
+		assert.Equal(t, "us-east-1", curationContext["labels"].(map[string]interface{})["region"])
+		assert.Equal(t, "AWS",
+			curationContext["clusterClaims"].(map[string]interface{})["platform.open-cluster-management.io"])
+	})
+
+	t.Run("hypershift install", func(t *testing.T) {
+		hcNamespace := "clusters"
+		s.AddKnownTypeWithName(schema.GroupVersionKind{
+			Group: "hypershift.openshift.io", Version: "v1beta1", Kind: "HostedCluster"}, &unstructured.Unstructured{})
+		s.AddKnownTypeWithName(schema.GroupVersionKind{
+			Group: "hypershift.openshift.io", Version: "v1beta1", Kind: "NodePool"}, &unstructured.Unstructured{})
+		s.AddKnownTypeWithName(schema.GroupVersionKind{
+			Group: "hypershift.openshift.io", Version: "v1beta1", Kind: "NodePoolList"}, &unstructured.UnstructuredList{})
+
+		cc := getClusterCurator()
+		cc.Namespace = hcNamespace
+
+		client := clientfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(
+			cc, genHostedCluster(hcNamespace),
+			genNodePool(ClusterName+"-us-east-1a", hcNamespace, ClusterName),
+			genNodePool("other-cluster-us-east-1a", hcNamespace, "other-cluster")).Build()
+
+		aJob, err := RunAnsibleJob(context.TODO(), client, cc, POSTHOOK, cc.Spec.Install.Posthook[0], "toweraccess")
+		assert.Nil(t, err, "err is nil when job is started")
+
+		extraVars := aJob.Object["spec"].(map[string]interface{})["extra_vars"].(map[string]interface{})
+		assert.Nil(t, extraVars[CLUSTER_DEPLOYMENT_KEY], "cluster_deployment is not set for hosted clusters")
+		assert.Equal(t, "KubeVirt",
+			extraVars[HOSTED_CLUSTER_KEY].(map[string]interface{})["platform"].(map[string]interface{})["type"])
+
+		nodePools := extraVars[NODE_POOLS_KEY].([]interface{})
+		assert.Len(t, nodePools, 1, "only the NodePools of this HostedCluster are included")
+		assert.Equal(t, ClusterName+"-us-east-1a", nodePools[0].(map[string]interface{})["name"])
+
+		curationContext := extraVars[CURATION_CONTEXT_KEY].(map[string]interface{})
+		assert.Equal(t, hcNamespace, curationContext["clusterNamespace"])
+		assert.Equal(t, "install", curationContext["curation"])
+		assert.Equal(t, POSTHOOK, curationContext["phase"])
+		assert.Equal(t, utils.HypershiftClusterType, curationContext["clusterType"])
+		assert.Nil(t, curationContext["labels"], "no labels without a ManagedCluster")
+	})
+
+	t.Run("managedcluster forbidden", func(t *testing.T) {
+		cc := getClusterCurator()
+
+		client := clientfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(
+			cc, genClusterDeployment(), genManagedCluster()).WithInterceptorFuncs(interceptor.Funcs{
+			Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object,
+				opts ...client.GetOption) error {
+				if _, ok := obj.(*managedclusterv1.ManagedCluster); ok {
+					return k8serrors.NewForbidden(managedclusterv1.Resource("managedclusters"), key.Name,
+						errors.New("cluster-installer can not get managedclusters"))
+				}
+				return c.Get(ctx, key, obj, opts...)
+			},
+		}).Build()
+
+		aJob, err := RunAnsibleJob(context.TODO(), client, cc, PREHOOK, cc.Spec.Install.Prehook[0], "toweraccess")
+		assert.Nil(t, err, "a ManagedCluster the service account can not read does not fail the hook")
+
+		extraVars := aJob.Object["spec"].(map[string]interface{})["extra_vars"].(map[string]interface{})
+		assert.Nil(t, extraVars[CURATION_CONTEXT_KEY].(map[string]interface{})["labels"])
+	})
+
+	t.Run("posthook retry", func(t *testing.T) {
+		cc := getClusterCurator()
+		cc.Spec.DesiredCuration = ""
+		cc.Operation = &clustercuratorv1.Operation{RetryPosthook: "installPosthook"}
+
+		client := clientfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(cc).Build()
+
+		aJob, err := RunAnsibleJob(context.TODO(), client, cc, POSTHOOK, cc.Spec.Install.Posthook[0], "toweraccess")
+		assert.Nil(t, err, "err is nil when job is started")
+
+		extraVars := aJob.Object["spec"].(map[string]interface{})["extra_vars"].(map[string]interface{})
+		assert.Equal(t, "install", extraVars[CURATION_CONTEXT_KEY].(map[string]interface{})["curation"])
+	})
+}
+
 func TestInventory(t *testing.T) {
 	tests := []struct {
 		name                 string
@@ -793,6 +1025,7 @@ func TestInventory(t *testing.T) {
 	s.AddKnownTypes(clustercuratorv1.SchemeBuilder.GroupVersion, &clustercuratorv1.ClusterCurator{})
 	s.AddKnownTypes(hivev1.SchemeBuilder.GroupVersion, &hivev1.ClusterDeployment{}, &hivev1.MachinePool{})
 	s.AddKnownTypes(corev1.SchemeGroupVersion, &corev1.Secret{})
+	s.AddKnownTypes(managedclusterinfov1beta1.SchemeGroupVersion, &managedclusterinfov1beta1.ManagedClusterInfo{})
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -804,7 +1037,7 @@ func TestInventory(t *testing.T) {
 			client := clientfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(
 				cc, genClusterDeployment(), genMachinePool(), genInstallConfigSecret()).Build()
 
-			aJob, err := RunAnsibleJob(client, cc, POSTHOOK, cc.Spec.Install.Posthook[0], "toweraccess")
+			aJob, err := RunAnsibleJob(context.TODO(), client, cc, POSTHOOK, cc.Spec.Install.Posthook[0], "toweraccess")
 			assert.Nil(t, err, "err is nil when job is started")
 
 			extraVars := aJob.Object["spec"].(map[string]interface{})["extra_vars"].(map[string]interface{})
@@ -847,7 +1080,7 @@ func Test_getAnsibleJobHooktype(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			aJob := getAnsibleJob(PREHOOK, test.hooktype, AnsibleJobTemplateName, SecretRef, nil, AnsibleJobName, ClusterName, "", "")
+			aJob, _ := getAnsibleJob(PREHOOK, test.hooktype, AnsibleJobTemplateName, SecretRef, nil, AnsibleJobName, ClusterName, "", "")
 			_, ok := aJob.Object["spec"].(map[string]interface{})[test.expectedTemplateNameKey]
 			assert.True(t, ok, "template name key is not %s", test.expectedTemplateNameKey)
 			_, ok = aJob.Object["spec"].(map[string]interface{})[test.expectedNotTemplateNameKey]
@@ -892,9 +1125,9 @@ func Test_getAnsibleJob_Tags(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			var aJob *unstructured.Unstructured
 			if test.isWorkflow {
-				aJob = getAnsibleJob(PREHOOK, string(clustercuratorv1.HookTypeWorkflow), AnsibleJobTemplateName, SecretRef, nil, AnsibleJobName, ClusterName, test.jobTags, test.skipTags)
+				aJob, _ = getAnsibleJob(PREHOOK, string(clustercuratorv1.HookTypeWorkflow), AnsibleJobTemplateName, SecretRef, nil, AnsibleJobName, ClusterName, test.jobTags, test.skipTags)
 			} else {
-				aJob = getAnsibleJob(PREHOOK, string(clustercuratorv1.HookTypeJob), AnsibleJobTemplateName, SecretRef, nil, AnsibleJobName, ClusterName, test.jobTags, test.skipTags)
+				aJob, _ = getAnsibleJob(PREHOOK, string(clustercuratorv1.HookTypeJob), AnsibleJobTemplateName, SecretRef, nil, AnsibleJobName, ClusterName, test.jobTags, test.skipTags)
 			}
 			if test.expectEmptyFields {
 				assert.Nil(t, aJob.Object["spec"].(map[string]interface{})["job_tags"])
@@ -5,7 +5,6 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"log"
 	"strconv"
 	"strings"
@@ -20,6 +19,7 @@ import (
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 
 	"github.com/blang/semver/v4"
@@ -37,15 +37,15 @@ const ForceUpgradeAnnotation = "cluster.open-cluster-management.io/upgrade-allow
 const UpgradeClusterversionBackoffLimit = "cluster.open-cluster-management.io/upgrade-clusterversion-backoff-limit"
 const HiveReconcilePauseAnnotation = "hive.openshift.io/reconcile-pause"
 
-var getErr = errors.New("Failed to get remote clusterversion")
+var getErr = utils.NewError(utils.ReasonUpgradeFailed, "Failed to get remote clusterversion")
 
-func ActivateDeploy(hiveset clientv1.Client, clusterName string) error {
+func ActivateDeploy(ctx context.Context, hiveset clientv1.Client, clusterName string) error {
 	klog.V(0).Info("* Initiate Provisioning")
 	klog.V(2).Info("Looking up cluster " + clusterName)
 
 	err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
 		cluster := &hivev1.ClusterDeployment{}
-		err := hiveset.Get(context.TODO(), types.NamespacedName{
+		err := hiveset.Get(ctx, types.NamespacedName{
 			Name:      clusterName,
 			Namespace: clusterName,
 		}, cluster)
@@ -63,26 +63,31 @@ func ActivateDeploy(hiveset clientv1.Client, clusterName string) error {
 		// Update the pause annotation
 		delete(annotations, HiveReconcilePauseAnnotation)
 		cluster.SetAnnotations(annotations)
-		return hiveset.Update(context.TODO(), cluster)
+		return hiveset.Update(ctx, cluster)
 	})
 	return err
 }
 
 func MonitorClusterStatus(
-	config *rest.Config, clusterName string, jobType string, curator *clustercuratorv1.ClusterCurator) error {
+	ctx context.Context,
+	config *rest.Config,
+	clusterName string,
+	jobType string,
+	curator *clustercuratorv1.ClusterCurator) error {
+
 	client, err := utils.GetClient()
 	if err = utils.LogError(err); err != nil {
 		return err
 	}
 
-	return monitorClusterStatus(client, clusterName, jobType, utils.GetMonitorAttempts(jobType, curator))
+	return monitorClusterStatus(ctx, client, clusterName, jobType, utils.GetMonitorAttempts(jobType, curator))
 }
 
-func DestroyClusterDeployment(hiveset clientv1.Client, clusterName string) error {
+func DestroyClusterDeployment(ctx context.Context, hiveset clientv1.Client, clusterName string) error {
 	klog.V(0).Infof("Deleting Cluster Deployment for %v\n", clusterName)
 
 	cluster := &hivev1.ClusterDeployment{}
-	err := hiveset.Get(context.TODO(), types.NamespacedName{
+	err := hiveset.Get(ctx, types.NamespacedName{
 		Name:      clusterName,
 		Namespace: clusterName,
 	}, cluster)
@@ -94,7 +99,7 @@ func DestroyClusterDeployment(hiveset clientv1.Client, clusterName string) error
 		return err
 	}
 
-	err = hiveset.Delete(context.TODO(), &hivev1.ClusterDeployment{
+	err = hiveset.Delete(ctx, &hivev1.ClusterDeployment{
 		ObjectMeta: v1.ObjectMeta{
 			Name:      cluster.Name,
 			Namespace: cluster.Namespace,
@@ -108,168 +113,217 @@ func DestroyClusterDeployment(hiveset clientv1.Client, clusterName string) error
 	return nil
 }
 
-func monitorClusterStatus(client clientv1.Client, clusterName string, jobType string, monitorAttempts int) error {
+// Reason reported when the Hive job of jobType fails
+func failureReason(jobType string) utils.Reason {
+	if jobType == utils.Destroying {
+		return utils.ReasonDestroyFailed
+	}
+	return utils.ReasonProvisionFailed
+}
+
+func monitorClusterStatus(
+	ctx context.Context,
+	client clientv1.Client,
+	clusterName string,
+	jobType string,
+	monitorAttempts int) error {
+
 	klog.V(0).Info("Waiting up to " + strconv.Itoa(monitorAttempts*5) + "s for Hive Provisioning job")
 	jobName := ""
 	var cluster *hivev1.ClusterDeployment
 
-	for i := 1; i <= monitorAttempts; i++ {
+	// Time spent while a Hive job runs does not count against the monitor timeout
+	deadline := time.Now().Add(time.Duration(monitorAttempts) * utils.PauseFiveSeconds)
 
-		// Refresh the clusterDeployment resource
-		cluster := &hivev1.ClusterDeployment{}
-		err := client.Get(context.TODO(), types.NamespacedName{
-			Name:      clusterName,
-			Namespace: clusterName,
-		}, cluster)
+	for {
+		foundJob := false
 
-		if err = utils.LogError(err); err != nil {
+		waitCtx, cancel := context.WithDeadline(ctx, deadline)
+		// The ClusterDeployment and the Hive jobs in the cluster namespace drive the wait
+		err := utils.WaitForCondition(waitCtx, utils.PauseTenSeconds, func(ctx context.Context) (bool, error) {
 
-			// If the cluster deployment is already gone
-			if jobType == utils.Destroying && k8serrors.IsNotFound(err) {
-				klog.Warning("No cluster deployment for " + clusterName + " was found")
-				return nil
-			}
-			return err
-		}
+			// Refresh the clusterDeployment resource
+			cluster = &hivev1.ClusterDeployment{}
+			err := client.Get(ctx, types.NamespacedName{
+				Name:      clusterName,
+				Namespace: clusterName,
+			}, cluster)
+
+			if err = utils.LogError(err); err != nil {
 
-		if jobType == utils.Installing && cluster.Status.WebConsoleURL != "" {
-			klog.V(2).Info("Provisioning succeeded ✓")
-
-			if jobName != "" {
-				utils.CheckError(utils.RecordCurrentStatusCondition(
-					client,
-					clusterName,
-					clusterName,
-					"hive-provisioning-job",
-					v1.ConditionTrue,
-					jobName))
+				// If the cluster deployment is already gone
+				if jobType == utils.Destroying && k8serrors.IsNotFound(err) {
+					klog.Warning("No cluster deployment for " + clusterName + " was found")
+					return true, nil
+				}
+				return false, err
 			}
 
-			return nil
+			if jobType == utils.Installing && cluster.Status.WebConsoleURL != "" {
+				klog.V(2).Info("Provisioning succeeded ✓")
+
+				if jobName != "" {
+					if err := utils.RecordCurrentStatusCondition(
+						client,
+						clusterName,
+						clusterName,
+						"hive-provisioning-job",
+						v1.ConditionTrue,
+						jobName); err != nil {
+						return false, err
+					}
+				}
 
-		} else if (cluster.Status.ProvisionRef != nil &&
-			cluster.Status.ProvisionRef.Name != "") || jobType == utils.Destroying {
+				return true, nil
 
-			klog.V(2).Info("Found ClusterDeployment status details ✓")
+			} else if (cluster.Status.ProvisionRef != nil &&
+				cluster.Status.ProvisionRef.Name != "") || jobType == utils.Destroying {
 
-			if jobType == utils.Destroying {
-				jobName = clusterName + "-" + utils.Destroying
-			} else {
-				jobName = cluster.Status.ProvisionRef.Name + "-provision"
-			}
+				klog.V(2).Info("Found ClusterDeployment status details ✓")
+
+				if jobType == utils.Destroying {
+					jobName = clusterName + "-" + utils.Destroying
+				} else {
+					jobName = cluster.Status.ProvisionRef.Name + "-provision"
+				}
 
-			jobPath := clusterName + "/" + jobName
+				klog.V(2).Info("Checking for " + jobType + "ing job " + clusterName + "/" + jobName)
+				newJob := &batchv1.Job{}
+				err := client.Get(ctx, types.NamespacedName{Namespace: clusterName, Name: jobName}, newJob)
 
-			klog.V(2).Info("Checking for " + jobType + "ing job " + jobPath)
-			newJob := &batchv1.Job{}
-			err := client.Get(context.Background(), types.NamespacedName{Namespace: clusterName, Name: jobName}, newJob)
+				// If the job is missing, keep waiting
+				if err != nil && k8serrors.IsNotFound(err) {
+					klog.Warningf("Could not retrieve job: %v", err)
+					return false, nil
+				}
 
-			// If the job is missing, follow the main loop
-			if err != nil && k8serrors.IsNotFound(err) {
-				klog.Warningf("Could not retrieve job: %v", err)
-				time.Sleep(utils.PauseFiveSeconds) // 10s
-				continue
+				if err = utils.LogError(err); err != nil {
+					return false, err
+				}
+
+				// A provisioning job that already succeeded leaves us waiting for the webConsoleURL
+				if jobType == utils.Installing && newJob.Status.Active == 0 && newJob.Status.Succeeded > 0 {
+					return false, nil
+				}
+
+				foundJob = true
+				return true, nil
+
+				// Detect that we've failed
+			} else {
+
+				klog.V(0).Infof("Waiting for ClusterDeployment %v to report a provision", clusterName)
+
+				for _, condition := range cluster.Status.Conditions {
+					// the cluster provisioning will be treated as failed if the ClusterDeployment has any of
+					// the following conditions:
+					// 1) ProvisionStoppedCondition is True, it indicates that at least one provision attempt was
+					//    made, but there will be no further retries;
+					// 2) RequirementsMetCondition is False , it indicates that some pre-provision requirement has not
+					//    been met;
+					//
+					// Check ProvisionStoppedCondition instead of ProvisionFailedCondition because ProvisionFailedCondition
+					// is transient. If it's True, hive might still be trying; and if the provisioning subsequently succeed
+					// it can be set back to False. Whereas once ProvisionStoppedCondition is True, it means hive has given
+					// up completely and won't try anymore.
+					if (condition.Status == "True" && condition.Type == hivev1.ProvisionStoppedCondition) ||
+						(condition.Type == hivev1.RequirementsMetCondition && condition.Status == "False") {
+						klog.Warning(cluster.Status.Conditions)
+						return false, utils.NewError(failureReason(jobType), "Failure detected")
+					}
+				}
 			}
+			return false, nil
+		},
+			utils.WatchObject(client, &hivev1.ClusterDeploymentList{}, clusterName, clusterName),
+			utils.WatchObject(client, &batchv1.JobList{}, clusterName, ""))
+		cancel()
 
-			if err = utils.LogError(err); err != nil {
-				return err
+		if errors.Is(err, utils.ErrWaitTimeout) {
+			if cluster != nil {
+				klog.Warning(cluster.Status.Conditions)
 			}
+			return utils.NewError(utils.ReasonTimeout, "Timed out waiting for job")
+		} else if err != nil || !foundJob {
+			return err
+		}
 
-			klog.V(2).Info("Found job " + jobPath + " ✓ Start monitoring: ")
-			elapsedTime := 0
+		jobPath := clusterName + "/" + jobName
+		klog.V(2).Info("Found job " + jobPath + " ✓ Start monitoring: ")
 
-			// Wait while the job is running
-			klog.V(0).Info("Wait for the " + jobType + "ing job from Hive to complete")
+		// Wait while the job is running
+		klog.V(0).Info("Wait for the " + jobType + "ing job from Hive to complete")
 
-			utils.CheckError(utils.RecordCurrentStatusCondition(
+		if err := utils.RecordCurrentStatusCondition(
+			client,
+			clusterName,
+			clusterName,
+			"hive-"+jobType+"ing-job",
+			v1.ConditionFalse,
+			jobName); err != nil {
+			return err
+		}
+
+		jobStart := time.Now()
+		newJob, err := waitForJob(ctx, client, clusterName, jobName)
+		if err != nil && (jobType != utils.Destroying || !k8serrors.IsNotFound(err)) {
+			return err
+		}
+		deadline = deadline.Add(time.Since(jobStart))
+
+		// When Destroying, by this point the job finished
+		if jobType == utils.Destroying {
+			klog.V(0).Info("Uninstall job complete")
+			return utils.RecordCurrentStatusCondition(
 				client,
 				clusterName,
 				clusterName,
 				"hive-"+jobType+"ing-job",
-				v1.ConditionFalse,
-				jobName))
-
-			for newJob.Status.Active == 1 {
-				if elapsedTime%6 == 0 {
-					klog.V(0).Info("Job: " + jobPath + " - " + strconv.Itoa(elapsedTime/6) + "min")
-				}
-				time.Sleep(utils.PauseTenSeconds) // 10s
-				elapsedTime++
-
-				// Reset the job, so we make sure we're getting clean data (not cached)
-				newJob = &batchv1.Job{}
-				err = client.Get(
-					context.Background(),
-					types.NamespacedName{Namespace: clusterName, Name: jobName},
-					newJob)
-
-				if jobType == utils.Destroying && err != nil && k8serrors.IsNotFound(err) {
-					break
-				}
+				v1.ConditionTrue,
+				jobName)
+		}
 
-				utils.CheckError(err)
-			}
+		// If succeeded = 0 then we did not finish
+		if newJob.Status.Succeeded == 0 {
+			cluster = &hivev1.ClusterDeployment{}
+			_ = client.Get(ctx, types.NamespacedName{
+				Name:      clusterName,
+				Namespace: clusterName,
+			}, cluster)
 
-			// When Destroying, by this point the job finished
-			if jobType == utils.Destroying {
-				klog.V(0).Info("Uninstall job complete")
-				utils.CheckError(utils.RecordCurrentStatusCondition(
-					client,
-					clusterName,
-					clusterName,
-					"hive-"+jobType+"ing-job",
-					v1.ConditionTrue,
-					jobName))
-				return nil
-			}
+			klog.Warning(cluster.Status.Conditions)
+			return utils.NewError(failureReason(jobType), "%sing job \"%s\" failed", jobType, jobPath)
+		}
 
-			// If succeeded = 0 then we did not finish
-			if newJob.Status.Succeeded == 0 {
-				cluster = &hivev1.ClusterDeployment{}
-				_ = client.Get(context.TODO(), types.NamespacedName{
-					Name:      clusterName,
-					Namespace: clusterName,
-				}, cluster)
+		klog.V(0).Info("The " + jobType + "ing job from Hive completed ✓")
+	}
+}
 
-				klog.Warning(cluster.Status.Conditions)
-				return errors.New(jobType + "ing job \"" + jobPath + "\" failed")
-			}
+// Waits while the Hive job NAMESPACE/JOB_NAME is active and returns its last state
+func waitForJob(ctx context.Context, client clientv1.Client, namespace string, jobName string) (*batchv1.Job, error) {
+	jobPath := namespace + "/" + jobName
+	start := time.Now()
+	newJob := &batchv1.Job{}
 
-			klog.V(0).Info("The " + jobType + "ing job from Hive completed ✓")
+	err := utils.WaitForCondition(ctx, utils.PauseFiveSeconds, func(ctx context.Context) (bool, error) {
 
-			// Detect that we've failed
-		} else {
+		// Reset the job, so we make sure we're getting clean data (not cached)
+		newJob = &batchv1.Job{}
+		if err := client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: jobName}, newJob); err != nil {
+			return false, err
+		}
 
-			klog.V(0).Infof("Attempt: "+strconv.Itoa(i)+"/%v, pause %v", monitorAttempts, utils.PauseFiveSeconds)
-			time.Sleep(utils.PauseFiveSeconds)
-
-			for _, condition := range cluster.Status.Conditions {
-				// the cluster provisioning will be treated as failed if the ClusterDeployment has any of
-				// the following conditions:
-				// 1) ProvisionStoppedCondition is True, it indicates that at least one provision attempt was
-				//    made, but there will be no further retries;
-				// 2) RequirementsMetCondition is False , it indicates that some pre-provision requirement has not
-				//    been met;
-				//
-				// Check ProvisionStoppedCondition instead of ProvisionFailedCondition because ProvisionFailedCondition
-				// is transient. If it's True, hive might still be trying; and if the provisioning subsequently succeed
-				// it can be set back to False. Whereas once ProvisionStoppedCondition is True, it means hive has given
-				// up completely and won't try anymore.
-				if (condition.Status == "True" && condition.Type == hivev1.ProvisionStoppedCondition) ||
-					(condition.Type == hivev1.RequirementsMetCondition && condition.Status == "False") {
-					klog.Warning(cluster.Status.Conditions)
-					return errors.New("Failure detected")
-				}
-			}
+		if newJob.Status.Active == 1 {
+			klog.V(0).Info("Job: " + jobPath + " - " + strconv.Itoa(int(time.Since(start).Minutes())) + "min")
+			return false, nil
 		}
-	}
-	if cluster != nil {
-		klog.Warning(cluster.Status.Conditions)
-	}
-	return errors.New("Timed out waiting for job")
+		return true, nil
+	}, utils.WatchObject(client, &batchv1.JobList{}, namespace, jobName))
+
+	return newJob, err
 }
 
-func UpgradeCluster(client clientv1.Client, clusterName string, curator *clustercuratorv1.ClusterCurator) error {
+func UpgradeCluster(ctx context.Context, client clientv1.Client, clusterName string, curator *clustercuratorv1.ClusterCurator) error {
 	klog.V(0).Info("* Initiate Upgrade")
 	klog.V(2).Info("Looking up managedclusterinfo " + clusterName)
 
@@ -291,7 +345,7 @@ func UpgradeCluster(client clientv1.Client, clusterName string, curator *cluster
 
 	desiredUpdate := curator.Spec.Upgrade.DesiredUpdate
 
-	if err := validateUpgradeVersion(client, clusterName, curator); err != nil {
+	if err := validateUpgradeVersion(ctx, client, clusterName, curator); err != nil {
 		return err
 	}
 
@@ -301,10 +355,13 @@ func UpgradeCluster(client clientv1.Client, clusterName string, curator *cluster
 	for i := 1; i <= retries && !successful; i++ {
 		klog.V(2).Info("Update clusterversion attempt " + strconv.Itoa(i))
 
-		mcaStatus, err := retreiveAndUpdateClusterVersion(client, clusterName, curator, desiredUpdate)
+		mcaStatus, err := retreiveAndUpdateClusterVersion(ctx, client, clusterName, curator, desiredUpdate)
 		if err != nil {
 			return err
 		}
+		if mcaStatus == nil {
+			return nil
+		}
 
 		if mcaStatus.Status.Conditions != nil {
 			for _, condition := range mcaStatus.Status.Conditions {
@@ -312,7 +369,7 @@ func UpgradeCluster(client clientv1.Client, clusterName string, curator *cluster
 					klog.Warning("ManagedClusterAction failed to update remote clusterversion", mcaStatus.Status.Conditions)
 					if i == retries {
 						klog.Warning("Max attempts reached updating clusterversion")
-						return errors.New("Remote clusterversion update failed")
+						return utils.NewError(utils.ReasonUpgradeFailed, "Remote clusterversion update failed")
 					} else {
 						klog.V(2).Info("Retrying clusterversion update")
 					}
@@ -324,10 +381,10 @@ func UpgradeCluster(client clientv1.Client, clusterName string, curator *cluster
 				}
 			}
 		} else if i == retries {
-			return errors.New("Remote clusterversion update failed")
+			return utils.NewError(utils.ReasonUpgradeFailed, "Remote clusterversion update failed")
 		}
 
-		if err := client.Delete(context.TODO(), &mcaStatus); err != nil {
+		if err := client.Delete(ctx, mcaStatus); err != nil {
 			return err
 		}
 	}
@@ -335,7 +392,12 @@ func UpgradeCluster(client clientv1.Client, clusterName string, curator *cluster
 	return nil
 }
 
-func EUSUpgradeCluster(client clientv1.Client, clusterName string, curator *clustercuratorv1.ClusterCurator, isInterVersion bool) error {
+func EUSUpgradeCluster(
+	ctx context.Context,
+	client clientv1.Client,
+	clusterName string,
+	curator *clustercuratorv1.ClusterCurator,
+	isInterVersion bool) error {
 	updateVersion := curator.Spec.Upgrade.IntermediateUpdate
 	if !isInterVersion {
 		updateVersion = curator.Spec.Upgrade.DesiredUpdate
@@ -347,7 +409,7 @@ func EUSUpgradeCluster(client clientv1.Client, clusterName string, curator *clus
 		klog.V(0).Info("* Initiate EUS to EUS Final Upgrade to " + updateVersion)
 	}
 
-	if err := validateEUSUpgradeVersion(client, clusterName, curator, isInterVersion); err != nil {
+	if err := validateEUSUpgradeVersion(ctx, client, clusterName, curator, isInterVersion); err != nil {
 		return err
 	}
 
@@ -374,22 +436,23 @@ func EUSUpgradeCluster(client clientv1.Client, clusterName string, curator *clus
 	}
 
 	ocpConfigView := managedclusterviewv1beta1.ManagedClusterView{}
-	if err := client.Get(context.TODO(), types.NamespacedName{
+	if err := client.Get(ctx, types.NamespacedName{
 		Name:      clusterName + "admack",
 		Namespace: clusterName,
 	}, &ocpConfigView); err != nil && k8serrors.IsNotFound(err) {
 		// check if mcv exists before creating
 		klog.V(2).Info("Create managedclusterview " + clusterName + "admack")
-		if err := client.Create(context.TODO(), ocpConfigMCV); err != nil {
+		utils.SetCurationMetadata(ocpConfigMCV, curator)
+		if err := client.Create(ctx, ocpConfigMCV); err != nil {
 			return err
 		}
 	} else if err != nil {
 		return err
 	}
 
-	ocpConfigGetErr := errors.New("Failed to get remote admin-ack configmap")
+	ocpConfigGetErr := utils.NewError(utils.ReasonUpgradeFailed, "Failed to get remote admin-ack configmap")
 	resultOCPConfigMCV := managedclusterviewv1beta1.ManagedClusterView{}
-	if err := waitForMCV(client, clusterName+"admack", clusterName, &resultOCPConfigMCV, ocpConfigGetErr); err != nil {
+	if err := waitForMCV(ctx, client, clusterName+"admack", clusterName, &resultOCPConfigMCV, ocpConfigGetErr); err != nil {
 		return err
 	}
 
@@ -398,7 +461,9 @@ func EUSUpgradeCluster(client clientv1.Client, clusterName string, curator *clus
 
 	if resultConfigMap.Raw != nil {
 		err := json.Unmarshal(resultConfigMap.Raw, &configMap)
-		utils.CheckError(err)
+		if err != nil {
+			return err
+		}
 	} else {
 		return ocpConfigGetErr
 	}
@@ -424,12 +489,13 @@ func EUSUpgradeCluster(client clientv1.Client, clusterName string, curator *clus
 	}
 
 	mcview := managedclusterviewv1beta1.ManagedClusterView{}
-	if err := client.Get(context.TODO(), types.NamespacedName{
+	if err := client.Get(ctx, types.NamespacedName{
 		Namespace: clusterName,
 		Name:      clusterName,
 	}, &mcview); err != nil && k8serrors.IsNotFound(err) {
 		klog.V(2).Info("Create managedclusterview " + clusterName)
-		if err := client.Create(context.TODO(), managedclusterview); err != nil {
+		utils.SetCurationMetadata(managedclusterview, curator)
+		if err := client.Create(ctx, managedclusterview); err != nil {
 			return err
 		}
 	} else if err != nil {
@@ -437,7 +503,7 @@ func EUSUpgradeCluster(client clientv1.Client, clusterName string, curator *clus
 	}
 
 	resultmcview := managedclusterviewv1beta1.ManagedClusterView{}
-	if err := waitForMCV(client, clusterName, clusterName, &resultmcview, getErr); err != nil {
+	if err := waitForMCV(ctx, client, clusterName, clusterName, &resultmcview, getErr); err != nil {
 		return err
 	}
 
@@ -446,7 +512,9 @@ func EUSUpgradeCluster(client clientv1.Client, clusterName string, curator *clus
 
 	if resultClusterVersion.Raw != nil {
 		err := json.Unmarshal(resultClusterVersion.Raw, &clusterVersion)
-		utils.CheckError(err)
+		if err != nil {
+			return err
+		}
 	} else {
 		return getErr
 	}
@@ -475,7 +543,9 @@ func EUSUpgradeCluster(client clientv1.Client, clusterName string, curator *clus
 	var updateConfigMap runtime.RawExtension
 	if configMap != nil {
 		b, err := json.Marshal(configMap)
-		utils.CheckError(err)
+		if err != nil {
+			return err
+		}
 		updateConfigMap.Raw = b
 	}
 	klog.V(2).Info("Create managedclusteraction to update configmap " + clusterName + "admack")
@@ -483,6 +553,9 @@ func EUSUpgradeCluster(client clientv1.Client, clusterName string, curator *clus
 		ObjectMeta: v1.ObjectMeta{
 			Name:      clusterName + "admack",
 			Namespace: clusterName,
+			Labels: map[string]string{
+				MCVUpgradeLabel: clusterName,
+			},
 		},
 		Spec: managedclusteractionv1beta1.ActionSpec{
 			ActionType: managedclusteractionv1beta1.UpdateActionType,
@@ -494,15 +567,18 @@ func EUSUpgradeCluster(client clientv1.Client, clusterName string, curator *clus
 			},
 		},
 	}
-	if err := client.Create(context.TODO(), ocpConfigMCA); err != nil {
+	utils.SetCurationMetadata(ocpConfigMCA, curator)
+	if err := client.Create(ctx, ocpConfigMCA); err != nil {
 		return err
 	}
 
 	// wait for managedclusteraction results
 	ocpConfigMCAStatus := managedclusteractionv1beta1.ManagedClusterAction{}
 	for i := 1; i <= 5; i++ {
-		time.Sleep(utils.PauseFiveSeconds)
-		if err := client.Get(context.TODO(), types.NamespacedName{
+		if err := utils.Sleep(ctx, utils.PauseFiveSeconds); err != nil {
+			return err
+		}
+		if err := client.Get(ctx, types.NamespacedName{
 			Name:      clusterName + "admack",
 			Namespace: clusterName,
 		}, &ocpConfigMCAStatus); err != nil {
@@ -524,20 +600,22 @@ func EUSUpgradeCluster(client clientv1.Client, clusterName string, curator *clus
 				klog.V(2).Info("Remote configmap updated successfully " + clusterName + "admack")
 			} else if condition.Status == v1.ConditionFalse {
 				klog.Warning("ManagedClusterAction failed to update remote clusterversion", ocpConfigMCAStatus.Status.Conditions)
-				return errors.New("Remote confimap update failed")
+				return utils.NewError(utils.ReasonUpgradeFailed, "Remote confimap update failed")
 			}
 		}
 	} else {
-		return errors.New("Remote configmap update failed")
+		return utils.NewError(utils.ReasonUpgradeFailed, "Remote configmap update failed")
 	}
 
-	if err := client.Delete(context.TODO(), &ocpConfigMCAStatus); err != nil {
+	if err := client.Delete(ctx, &ocpConfigMCAStatus); err != nil {
 		return err
 	}
 
 	// After updating the OCP ack configmap it takes some time for clusterversion to pick up the change
 	klog.V(0).Info("Pause 60 seconds for clusterversion to update")
-	time.Sleep(utils.PauseSixtySeconds)
+	if err := utils.Sleep(ctx, utils.PauseSixtySeconds); err != nil {
+		return err
+	}
 
 	var retries = 1
 	curatorAnnotations := curator.GetAnnotations()
@@ -559,11 +637,14 @@ func EUSUpgradeCluster(client clientv1.Client, clusterName string, curator *clus
 	for i := 1; i <= retries && !successful; i++ {
 		klog.V(2).Info("Update clusterversion attempt " + strconv.Itoa(i))
 
-		mcaStatus, err := eusRetreiveAndUpdateClusterVersion(client,
-			clusterName, updateVersion, managedclusterview, isInterVersion)
+		mcaStatus, err := eusRetreiveAndUpdateClusterVersion(ctx, client,
+			clusterName, curator, updateVersion, managedclusterview, isInterVersion)
 		if err != nil {
 			return err
 		}
+		if mcaStatus == nil {
+			return nil
+		}
 
 		if mcaStatus.Status.Conditions != nil {
 			condition := meta.FindStatusCondition(mcaStatus.Status.Conditions, managedclusteractionv1beta1.ConditionActionCompleted)
@@ -576,17 +657,17 @@ func EUSUpgradeCluster(client clientv1.Client, clusterName string, curator *clus
 					klog.Warning("ManagedClusterAction failed to update remote clusterversion", mcaStatus.Status.Conditions)
 					if i == retries {
 						klog.Warning("Max attempts reached updating clusterversion")
-						return errors.New("Remote clusterversion update failed")
+						return utils.NewError(utils.ReasonUpgradeFailed, "Remote clusterversion update failed")
 					} else {
 						klog.V(2).Info("Retrying clusterversion update")
 					}
 				}
 			}
 		} else if i == retries {
-			return errors.New("Remote clusterversion update failed")
+			return utils.NewError(utils.ReasonUpgradeFailed, "Remote clusterversion update failed")
 		}
 
-		if err := client.Delete(context.TODO(), &mcaStatus); err != nil {
+		if err := client.Delete(ctx, mcaStatus); err != nil {
 			return err
 		}
 	}
@@ -594,7 +675,12 @@ func EUSUpgradeCluster(client clientv1.Client, clusterName string, curator *clus
 	return nil
 }
 
-func MonitorUpgradeStatus(client clientv1.Client, clusterName string, curator *clustercuratorv1.ClusterCurator, isInterUpdate bool) error {
+func MonitorUpgradeStatus(
+	ctx context.Context,
+	client clientv1.Client,
+	clusterName string,
+	curator *clustercuratorv1.ClusterCurator,
+	isInterUpdate bool) error {
 	desiredUpdate := curator.Spec.Upgrade.DesiredUpdate
 	if isInterUpdate {
 		desiredUpdate = curator.Spec.Upgrade.IntermediateUpdate
@@ -605,82 +691,89 @@ func MonitorUpgradeStatus(client clientv1.Client, clusterName string, curator *c
 
 	upgradeAttempts := utils.GetRetryTimes(curator.Spec.Upgrade.MonitorTimeout, 120, utils.PauseSixtySeconds)
 
+	waitCtx, cancel := context.WithTimeout(ctx, time.Duration(upgradeAttempts)*utils.PauseSixtySeconds)
+	defer cancel()
+
 	var getErr, timeoutErr error
-	isChannelUpstreamUpdate := false
-	for i := 0; i < upgradeAttempts; i++ {
+	var cvConditions []interface{}
+
+	// The ManagedClusterView result is refreshed from the managed cluster, every refresh is evaluated
+	waitErr := utils.WaitForCondition(waitCtx, utils.PauseSixtySeconds, func(ctx context.Context) (bool, error) {
 
-		if getErr = client.Get(context.TODO(), types.NamespacedName{
+		if getErr = client.Get(ctx, types.NamespacedName{
 			Namespace: clusterName,
 			Name:      clusterName,
 		}, &resultmcview); getErr != nil {
-			// sleep and keep on retrying
-			time.Sleep(utils.PauseSixtySeconds)
-			continue
+			// keep on retrying
+			return false, nil
 		}
 
 		labels := resultmcview.ObjectMeta.GetLabels()
-		if len(labels) == 0 {
-			return errors.New("Failed to get managedclusterview")
-		}
 		if _, ok := labels[MCVUpgradeLabel]; !ok {
-			return errors.New("Failed to get managedclusterview")
+			return false, utils.NewError(utils.ReasonUpgradeFailed, "Failed to get managedclusterview")
 		}
 		resultClusterVersion := resultmcview.Status.Result
 
 		clusterVersion := map[string]interface{}{}
 
 		if resultClusterVersion.Raw != nil {
-			err := json.Unmarshal(resultClusterVersion.Raw, &clusterVersion)
-			utils.CheckError(err)
+			if err := json.Unmarshal(resultClusterVersion.Raw, &clusterVersion); err != nil {
+				return false, err
+			}
 		}
 		if desiredUpdate != "" {
-			if cvConditions, ok := clusterVersion["status"].(map[string]interface{})["conditions"].([]interface{}); ok {
+			// The result is empty until the managed cluster reported the clusterversion
+			if conditions, found, _ := unstructured.NestedSlice(clusterVersion, "status", "conditions"); found {
+				cvConditions = conditions
 				for _, condition := range cvConditions {
 					if condition.(map[string]interface{})["type"] == "Available" && condition.(map[string]interface{})["status"] == "True" {
 						if strings.Contains(condition.(map[string]interface{})["message"].(string), desiredUpdate) {
 							klog.V(2).Info("Upgrade succeeded ✓")
-							i = upgradeAttempts
-							break
+							return true, nil
 						}
-					} else if i == (upgradeAttempts - 1) {
-						klog.Warning(cvConditions)
-						klog.V(2).Info("Timed out waiting for monitor upgrade job")
-						timeoutErr = errors.New("Timed out waiting for monitor upgrade job")
-						break
 					} else if condition.(map[string]interface{})["type"] == "Progressing" && condition.(map[string]interface{})["status"] == "True" {
 						klog.V(2).Info(" Upgrade status " + condition.(map[string]interface{})["message"].(string))
 						// update curator status to show upgrade %
 						strMessage := "Upgrade status - " + condition.(map[string]interface{})["message"].(string)
-						utils.CheckError(utils.RecordCurrentStatusCondition(
+						if err := utils.RecordCurrentStatusCondition(
 							client,
 							clusterName,
 							clusterName,
 							"monitor-upgrade",
 							v1.ConditionFalse,
-							strMessage))
+							strMessage); err != nil {
+							return false, err
+						}
 					}
 				}
 			}
 		}
 		if desiredUpdate == "" && channel != "" {
-			if clusterVersion["spec"].(map[string]interface{})["channel"] == channel {
+			if cvChannel, _, _ := unstructured.NestedString(clusterVersion, "spec", "channel"); cvChannel == channel {
 				klog.V(2).Info("Updated channel successfully ✓")
-				isChannelUpstreamUpdate = true
+				return true, nil
 			}
 		}
 		if desiredUpdate == "" && upstream != "" {
-			if clusterVersion["spec"].(map[string]interface{})["upstream"] == upstream {
+			if cvUpstream, _, _ := unstructured.NestedString(clusterVersion, "spec", "upstream"); cvUpstream == upstream {
 				klog.V(2).Info("Updated upstream successfully ✓")
-				isChannelUpstreamUpdate = true
+				return true, nil
 			}
 		}
-		if isChannelUpstreamUpdate {
-			break
+		return false, nil
+	}, utils.WatchObject(client, &managedclusterviewv1beta1.ManagedClusterViewList{}, clusterName, clusterName))
+
+	if errors.Is(waitErr, utils.ErrWaitTimeout) {
+		if desiredUpdate != "" && getErr == nil {
+			klog.Warning(cvConditions)
+			klog.V(2).Info("Timed out waiting for monitor upgrade job")
+			timeoutErr = utils.NewError(utils.ReasonTimeout, "Timed out waiting for monitor upgrade job")
 		}
-		time.Sleep(utils.PauseSixtySeconds)
+	} else if waitErr != nil {
+		return waitErr
 	}
 
-	if err := client.Delete(context.TODO(), &resultmcview); err != nil {
+	if err := client.Delete(ctx, &resultmcview); err != nil {
 		return err
 	}
 
@@ -691,14 +784,14 @@ func MonitorUpgradeStatus(client clientv1.Client, clusterName string, curator *c
 	return timeoutErr
 }
 
-func validateUpgradeVersion(client clientv1.Client, clusterName string, curator *clustercuratorv1.ClusterCurator) error {
+func validateUpgradeVersion(ctx context.Context, client clientv1.Client, clusterName string, curator *clustercuratorv1.ClusterCurator) error {
 
 	desiredUpdate := curator.Spec.Upgrade.DesiredUpdate
 	channel := curator.Spec.Upgrade.Channel
 	upstream := curator.Spec.Upgrade.Upstream
 
 	managedClusterInfo := managedclusterinfov1beta1.ManagedClusterInfo{}
-	if err := client.Get(context.TODO(), types.NamespacedName{
+	if err := client.Get(ctx, types.NamespacedName{
 		Namespace: clusterName,
 		Name:      clusterName,
 	}, &managedClusterInfo); err != nil {
@@ -708,11 +801,11 @@ func validateUpgradeVersion(client clientv1.Client, clusterName string, curator
 	klog.V(2).Info("kubevendor: ", managedClusterInfo.Status.KubeVendor)
 
 	if managedClusterInfo.Status.KubeVendor != "OpenShift" && managedClusterInfo.Status.KubeVendor != "OpenShiftDedicated" {
-		return errors.New("Can not upgrade non openshift cluster")
+		return utils.NewError(utils.ReasonInvalidSpec, "Can not upgrade non openshift cluster")
 	}
 
 	if desiredUpdate == "" && channel == "" && upstream == "" {
-		return errors.New("Provide valid upgrade version or channel or upstream")
+		return utils.NewError(utils.ReasonInvalidSpec, "Provide valid upgrade version or channel or upstream")
 	}
 
 	curatorAnnotations := curator.GetAnnotations()
@@ -732,7 +825,7 @@ func validateUpgradeVersion(client clientv1.Client, clusterName string, curator
 		}
 	}
 	if desiredUpdate != "" && !isValidVersion {
-		return errors.New("Provided version is not valid")
+		return utils.NewError(utils.ReasonInvalidSpec, "Provided version is not valid")
 	}
 
 	isValidChannel := false
@@ -746,16 +839,24 @@ func validateUpgradeVersion(client clientv1.Client, clusterName string, curator
 		}
 	}
 	if channel != "" && !isValidChannel {
-		return errors.New("Provided channel is not valid")
+		return utils.NewError(utils.ReasonInvalidSpec, "Provided channel is not valid")
 	}
 
 	return nil
 }
 
-func waitForMCV(client clientv1.Client, clusterName string, clusterNamespace string, mcv *managedclusterviewv1beta1.ManagedClusterView, err error) error {
+func waitForMCV(
+	ctx context.Context,
+	client clientv1.Client,
+	clusterName string,
+	clusterNamespace string,
+	mcv *managedclusterviewv1beta1.ManagedClusterView,
+	err error) error {
 	for i := 1; i <= 5; i++ {
-		time.Sleep(utils.PauseFiveSeconds)
-		if clientGetErr := client.Get(context.TODO(), types.NamespacedName{
+		if err := utils.Sleep(ctx, utils.PauseFiveSeconds); err != nil {
+			return err
+		}
+		if clientGetErr := client.Get(ctx, types.NamespacedName{
 			Name:      clusterName,
 			Namespace: clusterNamespace,
 		}, mcv); clientGetErr != nil {
@@ -782,22 +883,27 @@ func waitForMCV(client clientv1.Client, clusterName string, clusterNamespace str
 	return nil
 }
 
-func validateEUSUpgradeVersion(client clientv1.Client, clusterName string, curator *clustercuratorv1.ClusterCurator, isInterVersion bool) error {
+func validateEUSUpgradeVersion(
+	ctx context.Context,
+	client clientv1.Client,
+	clusterName string,
+	curator *clustercuratorv1.ClusterCurator,
+	isInterVersion bool) error {
 	if curator.Spec.Upgrade.DesiredUpdate == "" {
-		return errors.New(fmt.Sprintf("DesiredUpdate is required to run EUS to EUS upgrade for Curator %q", curator.Name))
+		return utils.NewError(utils.ReasonInvalidSpec, "DesiredUpdate is required to run EUS to EUS upgrade for Curator %q", curator.Name)
 	}
 	desiredVersion, err := semver.Make(curator.Spec.Upgrade.DesiredUpdate)
 	if err != nil {
-		return err
+		return utils.WrapError(utils.ReasonInvalidSpec, err)
 	}
 
 	intermediateVersion, err := semver.Make(curator.Spec.Upgrade.IntermediateUpdate)
 	if err != nil {
-		return err
+		return utils.WrapError(utils.ReasonInvalidSpec, err)
 	}
 
 	managedClusterInfo := managedclusterinfov1beta1.ManagedClusterInfo{}
-	if err := client.Get(context.TODO(), types.NamespacedName{
+	if err := client.Get(ctx, types.NamespacedName{
 		Namespace: clusterName,
 		Name:      clusterName,
 	}, &managedClusterInfo); err != nil {
@@ -807,7 +913,7 @@ func validateEUSUpgradeVersion(client clientv1.Client, clusterName string, curat
 	klog.V(2).Info("kubevendor: ", managedClusterInfo.Status.KubeVendor)
 
 	if managedClusterInfo.Status.KubeVendor != "OpenShift" && managedClusterInfo.Status.KubeVendor != "OpenShiftDedicated" {
-		return errors.New("can not upgrade non openshift cluster")
+		return utils.NewError(utils.ReasonInvalidSpec, "can not upgrade non openshift cluster")
 	}
 
 	currentVersion, err := semver.Make(managedClusterInfo.Status.DistributionInfo.OCP.Version)
@@ -816,34 +922,35 @@ func validateEUSUpgradeVersion(client clientv1.Client, clusterName string, curat
 	}
 
 	if isInterVersion && (intermediateVersion.Compare(currentVersion) == 0 || intermediateVersion.Compare(currentVersion) == -1) {
-		return errors.New(fmt.Sprintf("IntermediateUpdate %s must be greater than current version %s to run EUS to EUS upgrade for Curator %q",
-			intermediateVersion, currentVersion, curator.Name))
+		return utils.NewError(utils.ReasonInvalidSpec, "IntermediateUpdate %s must be greater than current version %s to run EUS to EUS upgrade for Curator %q",
+			intermediateVersion, currentVersion, curator.Name)
 	}
 
 	// desiredVersion == targeted final EUS version
 	if desiredVersion.Compare(intermediateVersion) == 0 || desiredVersion.Compare(intermediateVersion) == -1 {
-		return errors.New(fmt.Sprintf("DesiredUpdate %s must be greater than IntermediateUpdate %s to run EUS to EUS upgrade for Curator %q",
-			desiredVersion, intermediateVersion, curator.Name))
+		return utils.NewError(utils.ReasonInvalidSpec, "DesiredUpdate %s must be greater than IntermediateUpdate %s to run EUS to EUS upgrade for Curator %q",
+			desiredVersion, intermediateVersion, curator.Name)
 	}
 
 	if intermediateVersion.Major != currentVersion.Major || desiredVersion.Major != currentVersion.Major {
-		return errors.New(fmt.Sprintf("Major version EUS to EUS upgrade in not supported for Curator %q", curator.Name))
+		return utils.NewError(utils.ReasonInvalidSpec, "Major version EUS to EUS upgrade in not supported for Curator %q", curator.Name)
 	}
 
 	if isInterVersion && (intermediateVersion.Minor != (currentVersion.Minor+1) || desiredVersion.Minor != (currentVersion.Minor+2)) {
-		return errors.New(fmt.Sprintf("Minor version EUS to EUS upgrade must be continuous for Curator %q", curator.Name))
+		return utils.NewError(utils.ReasonInvalidSpec, "Minor version EUS to EUS upgrade must be continuous for Curator %q", curator.Name)
 	}
 
 	return nil
 }
 
 func retreiveAndUpdateClusterVersion(
+	ctx context.Context,
 	client clientv1.Client,
 	clusterName string,
 	curator *clustercuratorv1.ClusterCurator,
-	desiredUpdate string) (managedclusteractionv1beta1.ManagedClusterAction, error) {
+	desiredUpdate string) (*managedclusteractionv1beta1.ManagedClusterAction, error) {
 
-	mcaStatus := managedclusteractionv1beta1.ManagedClusterAction{}
+	mcaStatus := &managedclusteractionv1beta1.ManagedClusterAction{}
 	managedclusterview := &managedclusterviewv1beta1.ManagedClusterView{
 		ObjectMeta: v1.ObjectMeta{
 			Name:      clusterName,
@@ -864,22 +971,24 @@ func retreiveAndUpdateClusterVersion(
 	}
 
 	mcview := managedclusterviewv1beta1.ManagedClusterView{}
-	if err := client.Get(context.TODO(), types.NamespacedName{
+	if err := client.Get(ctx, types.NamespacedName{
 		Namespace: clusterName,
 		Name:      clusterName,
 	}, &mcview); err != nil {
 
 		klog.V(2).Info("Create managedclusterview " + clusterName)
-		if err := client.Create(context.TODO(), managedclusterview); err != nil {
+		utils.SetCurationMetadata(managedclusterview, curator)
+		if err := client.Create(ctx, managedclusterview); err != nil {
 			return mcaStatus, err
 		}
 	}
 
-	getErr := errors.New("Failed to get remote clusterversion")
 	resultmcview := managedclusterviewv1beta1.ManagedClusterView{}
 	for i := 1; i <= 5; i++ {
-		time.Sleep(utils.PauseFiveSeconds)
-		if err := client.Get(context.TODO(), types.NamespacedName{
+		if err := utils.Sleep(ctx, utils.PauseFiveSeconds); err != nil {
+			return mcaStatus, err
+		}
+		if err := client.Get(ctx, types.NamespacedName{
 			Namespace: clusterName,
 			Name:      clusterName,
 		}, &resultmcview); err != nil {
@@ -909,11 +1018,19 @@ func retreiveAndUpdateClusterVersion(
 
 	if resultClusterVersion.Raw != nil {
 		err := json.Unmarshal(resultClusterVersion.Raw, &clusterVersion)
-		utils.CheckError(err)
+		if err != nil {
+			return mcaStatus, err
+		}
 	} else {
 		return mcaStatus, getErr
 	}
 
+	// A retried curator job does not update the clusterversion again
+	if desiredUpdateApplied(clusterVersion, desiredUpdate, curator.Spec.Upgrade.Channel, curator.Spec.Upgrade.Upstream) {
+		klog.V(0).Infof("The clusterversion of %v already has the desiredUpdate %v", clusterName, desiredUpdate)
+		return nil, nil
+	}
+
 	curatorAnnotations := curator.GetAnnotations()
 
 	if curatorAnnotations != nil && curatorAnnotations[ForceUpgradeAnnotation] == "true" {
@@ -956,7 +1073,9 @@ func retreiveAndUpdateClusterVersion(
 	var updateClusterVersion runtime.RawExtension
 	if clusterVersion != nil {
 		b, err := json.Marshal(clusterVersion)
-		utils.CheckError(err)
+		if err != nil {
+			return mcaStatus, err
+		}
 		updateClusterVersion.Raw = b
 	}
 	klog.V(2).Info("Create managedclusteraction to update clusterversion " + clusterName)
@@ -964,6 +1083,9 @@ func retreiveAndUpdateClusterVersion(
 		ObjectMeta: v1.ObjectMeta{
 			Name:      clusterName,
 			Namespace: clusterName,
+			Labels: map[string]string{
+				MCVUpgradeLabel: clusterName,
+			},
 		},
 		Spec: managedclusteractionv1beta1.ActionSpec{
 			ActionType: managedclusteractionv1beta1.UpdateActionType,
@@ -975,16 +1097,18 @@ func retreiveAndUpdateClusterVersion(
 			},
 		},
 	}
-	if err := client.Create(context.TODO(), managedclusteraction); err != nil {
+	if err := createManagedClusterAction(ctx, client, curator, managedclusteraction); err != nil {
 		return mcaStatus, err
 	}
 
 	for i := 1; i <= 5; i++ {
-		time.Sleep(utils.PauseFiveSeconds)
-		if err := client.Get(context.TODO(), types.NamespacedName{
+		if err := utils.Sleep(ctx, utils.PauseFiveSeconds); err != nil {
+			return mcaStatus, err
+		}
+		if err := client.Get(ctx, types.NamespacedName{
 			Namespace: clusterName,
 			Name:      clusterName,
-		}, &mcaStatus); err != nil {
+		}, mcaStatus); err != nil {
 			if i == 5 {
 				return mcaStatus, err
 			}
@@ -1000,42 +1124,63 @@ func retreiveAndUpdateClusterVersion(
 }
 
 func eusRetreiveAndUpdateClusterVersion(
+	ctx context.Context,
 	client clientv1.Client,
 	clusterName string,
+	curator *clustercuratorv1.ClusterCurator,
 	updateVersion string,
 	managedclusterview *managedclusterviewv1beta1.ManagedClusterView,
-	isInterVersion bool) (managedclusteractionv1beta1.ManagedClusterAction, error) {
+	isInterVersion bool) (*managedclusteractionv1beta1.ManagedClusterAction, error) {
 
 	// Get latest clusterversion or else the object might be stale
 	mcview := managedclusterviewv1beta1.ManagedClusterView{}
-	mcaStatus := managedclusteractionv1beta1.ManagedClusterAction{}
+	mcaStatus := &managedclusteractionv1beta1.ManagedClusterAction{}
 	resultmcview := managedclusterviewv1beta1.ManagedClusterView{}
 	clusterVersion := map[string]interface{}{}
 
-	if err := client.Get(context.TODO(), types.NamespacedName{
+	if err := client.Get(ctx, types.NamespacedName{
 		Namespace: clusterName,
 		Name:      clusterName,
 	}, &mcview); err != nil && k8serrors.IsNotFound(err) {
 		klog.V(2).Info("Create managedclusterview " + clusterName)
-		if err := client.Create(context.TODO(), managedclusterview); err != nil {
+		utils.SetCurationMetadata(managedclusterview, curator)
+		if err := client.Create(ctx, managedclusterview); err != nil {
 			return mcaStatus, err
 		}
 	} else if err != nil {
 		return mcaStatus, err
 	}
 
-	if err := waitForMCV(client, clusterName, clusterName, &resultmcview, getErr); err != nil {
+	if err := waitForMCV(ctx, client, clusterName, clusterName, &resultmcview, getErr); err != nil {
 		return mcaStatus, err
 	}
 	resultClusterVersion := resultmcview.Status.Result
 
 	if resultClusterVersion.Raw != nil {
 		err := json.Unmarshal(resultClusterVersion.Raw, &clusterVersion)
-		utils.CheckError(err)
+		if err != nil {
+			return mcaStatus, err
+		}
 	} else {
 		return mcaStatus, getErr
 	}
 
+	// The channel is set when upgrading to the final EUS version
+	channel := ""
+	if !isInterVersion {
+		finalSemVer, err := semver.Make(updateVersion)
+		if err != nil {
+			return mcaStatus, err
+		}
+		channel = "stable-" + strconv.Itoa(int(finalSemVer.Major)) + "." + strconv.Itoa(int(finalSemVer.Minor))
+	}
+
+	// A retried curator job does not update the clusterversion again
+	if desiredUpdateApplied(clusterVersion, updateVersion, channel, "") {
+		klog.V(0).Infof("The clusterversion of %v already has the desiredUpdate %v", clusterName, updateVersion)
+		return nil, nil
+	}
+
 	cvDesiredUpdate := clusterVersion["spec"].(map[string]interface{})["desiredUpdate"]
 
 	// Always use 'force' option since we cannot get the image hash for EUS to EUS upgrade
@@ -1054,20 +1199,16 @@ func eusRetreiveAndUpdateClusterVersion(
 		}
 	}
 
-	// set channel when upgrading to final EUS version
-	if !isInterVersion {
-		finalSemVer, err := semver.Make(updateVersion)
-		if err != nil {
-			return mcaStatus, err
-		}
-		clusterVersion["spec"].(map[string]interface{})["channel"] = "stable-" + strconv.Itoa(int(finalSemVer.Major)) +
-			"." + strconv.Itoa(int(finalSemVer.Minor))
+	if channel != "" {
+		clusterVersion["spec"].(map[string]interface{})["channel"] = channel
 	}
 
 	var updateClusterVersion runtime.RawExtension
 	if clusterVersion != nil {
 		b, err := json.Marshal(clusterVersion)
-		utils.CheckError(err)
+		if err != nil {
+			return mcaStatus, err
+		}
 		updateClusterVersion.Raw = b
 	}
 	klog.V(2).Info("Create managedclusteraction to update clusterversion " + clusterName)
@@ -1075,6 +1216,9 @@ func eusRetreiveAndUpdateClusterVersion(
 		ObjectMeta: v1.ObjectMeta{
 			Name:      clusterName,
 			Namespace: clusterName,
+			Labels: map[string]string{
+				MCVUpgradeLabel: clusterName,
+			},
 		},
 		Spec: managedclusteractionv1beta1.ActionSpec{
 			ActionType: managedclusteractionv1beta1.UpdateActionType,
@@ -1086,16 +1230,18 @@ func eusRetreiveAndUpdateClusterVersion(
 			},
 		},
 	}
-	if err := client.Create(context.TODO(), managedclusteraction); err != nil {
+	if err := createManagedClusterAction(ctx, client, curator, managedclusteraction); err != nil {
 		return mcaStatus, err
 	}
 
 	for i := 1; i <= 5; i++ {
-		time.Sleep(utils.PauseFiveSeconds)
-		if err := client.Get(context.TODO(), types.NamespacedName{
+		if err := utils.Sleep(ctx, utils.PauseFiveSeconds); err != nil {
+			return mcaStatus, err
+		}
+		if err := client.Get(ctx, types.NamespacedName{
 			Namespace: clusterName,
 			Name:      clusterName,
-		}, &mcaStatus); err != nil {
+		}, mcaStatus); err != nil {
 			if i == 5 {
 				return mcaStatus, err
 			}
@@ -1109,3 +1255,38 @@ func eusRetreiveAndUpdateClusterVersion(
 
 	return mcaStatus, nil
 }
+
+/* desiredUpdateApplied - The clusterversion already has the desiredUpdate version, and the channel and
+ * upstream when they are set, the update was applied by a previous run of the step.
+ */
+func desiredUpdateApplied(clusterVersion map[string]interface{}, version string, channel string, upstream string) bool {
+	spec, ok := clusterVersion["spec"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	desiredUpdate, ok := spec["desiredUpdate"].(map[string]interface{})
+	if !ok || desiredUpdate["version"] != version {
+		return false
+	}
+	return (channel == "" || spec["channel"] == channel) && (upstream == "" || spec["upstream"] == upstream)
+}
+
+// Creates the ManagedClusterAction, the action left by an interrupted run of the step is replaced
+func createManagedClusterAction(
+	ctx context.Context,
+	client clientv1.Client,
+	curator *clustercuratorv1.ClusterCurator,
+	managedclusteraction *managedclusteractionv1beta1.ManagedClusterAction) error {
+
+	utils.SetCurationMetadata(managedclusteraction, curator)
+	err := client.Create(ctx, managedclusteraction)
+	if !k8serrors.IsAlreadyExists(err) {
+		return err
+	}
+
+	klog.V(2).Info("Replace the managedclusteraction " + managedclusteraction.Name)
+	if err := client.Delete(ctx, managedclusteraction.DeepCopy()); err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+	return client.Create(ctx, managedclusteraction)
+}
@@ -4,7 +4,6 @@ package hive
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"testing"
 	"time"
 
@@ -130,7 +129,7 @@ func TestActivateDeployNoCD(t *testing.T) {
 	hiveset := clientfake.NewClientBuilder().WithScheme(s).Build()
 
 	t.Log("No ClusterDeployment")
-	assert.NotNil(t, ActivateDeploy(hiveset, ClusterName),
+	assert.NotNil(t, ActivateDeploy(context.TODO(), hiveset, ClusterName),
 		"err NotNil when ClusterDeployment kind does not exist")
 }
 
@@ -149,7 +148,7 @@ func TestActivateDeployNoPauseAnnotation(t *testing.T) {
 	}).WithScheme(s).Build()
 
 	t.Log("ClusterDeployment with no Pause annotation")
-	assert.Nil(t, ActivateDeploy(hiveset, ClusterName),
+	assert.Nil(t, ActivateDeploy(context.TODO(), hiveset, ClusterName),
 		"err Nil when ClusterDeployment has no Pause annotation")
 }
 
@@ -167,7 +166,7 @@ func TestActivateDeployNoneTruePauseAnnotation(t *testing.T) {
 		},
 	}).WithScheme(s).Build()
 
-	assert.Nil(t, ActivateDeploy(hiveset, ClusterName),
+	assert.Nil(t, ActivateDeploy(context.TODO(), hiveset, ClusterName),
 		"err Nil when ClusterDeployment with non true pause annotation")
 }
 
@@ -186,7 +185,7 @@ func TestActivateDeploy(t *testing.T) {
 	}).WithScheme(s).Build()
 
 	t.Log("ClusterDeployment with true pause annotation")
-	assert.Nil(t, ActivateDeploy(hiveset, ClusterName),
+	assert.Nil(t, ActivateDeploy(context.TODO(), hiveset, ClusterName),
 		"err Nil when ClusterDeployment with true pause annotation")
 }
 
@@ -241,7 +240,7 @@ func TestMonitorDeployStatusNoClusterDeployment(t *testing.T) {
 
 	hiveset := clientfake.NewClientBuilder().Build()
 
-	assert.NotNil(t, monitorClusterStatus(hiveset, ClusterName, utils.Installing, testTimeout),
+	assert.NotNil(t, monitorClusterStatus(context.TODO(), hiveset, ClusterName, utils.Installing, testTimeout),
 		"err is not nil, when cluster provisioning has a condition")
 }
 
@@ -257,8 +256,9 @@ func TestMonitorDeployStatusProvisionStoppedCondition(t *testing.T) {
 
 	hiveset := clientfake.NewClientBuilder().WithRuntimeObjects(cd).Build()
 
-	assert.NotNil(t, monitorClusterStatus(hiveset, ClusterName, utils.Installing, testTimeout),
-		"err is not nil, when cluster provisioning has a condition")
+	err := monitorClusterStatus(context.TODO(), hiveset, ClusterName, utils.Installing, testTimeout)
+	assert.NotNil(t, err, "err is not nil, when cluster provisioning has a condition")
+	assert.Equal(t, utils.ReasonProvisionFailed, utils.ReasonForError(err))
 }
 
 func TestMonitorDeployStatusRequirementsMetCondition(t *testing.T) {
@@ -273,7 +273,7 @@ func TestMonitorDeployStatusRequirementsMetCondition(t *testing.T) {
 
 	hiveset := clientfake.NewClientBuilder().WithRuntimeObjects(cd).Build()
 
-	assert.NotNil(t, monitorClusterStatus(hiveset, ClusterName, utils.Installing, testTimeout),
+	assert.NotNil(t, monitorClusterStatus(context.TODO(), hiveset, ClusterName, utils.Installing, testTimeout),
 		"err is not nil, when cluster provisioning has a condition")
 }
 
@@ -283,8 +283,21 @@ func TestMonitorDeployNoJobTimeout(t *testing.T) {
 
 	hiveset := clientfake.NewClientBuilder().WithRuntimeObjects(cd).Build()
 
-	assert.NotNil(t, monitorClusterStatus(hiveset, ClusterName, utils.Installing, testTimeout),
-		"err is not nil, when cluster provisioning has no job created")
+	err := monitorClusterStatus(context.TODO(), hiveset, ClusterName, utils.Installing, testTimeout)
+	assert.NotNil(t, err, "err is not nil, when cluster provisioning has no job created")
+	assert.Equal(t, utils.ReasonTimeout, utils.ReasonForError(err))
+}
+
+func TestMonitorDeployCanceled(t *testing.T) {
+
+	cd := getClusterDeployment()
+
+	hiveset := clientfake.NewClientBuilder().WithRuntimeObjects(cd).Build()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := monitorClusterStatus(ctx, hiveset, ClusterName, utils.Installing, testTimeout)
+	assert.Equal(t, utils.ReasonCanceled, utils.ReasonForError(err), "Canceled, when the context is canceled")
 }
 func TestMonitorDeployStatusJobFailed(t *testing.T) {
 
@@ -304,7 +317,7 @@ func TestMonitorDeployStatusJobFailed(t *testing.T) {
 
 	client := clientfake.NewClientBuilder().WithRuntimeObjects(cd, cc, job).WithScheme(s).Build()
 
-	assert.NotNil(t, monitorClusterStatus(client, ClusterName, utils.Installing, testTimeout),
+	assert.NotNil(t, monitorClusterStatus(context.TODO(), client, ClusterName, utils.Installing, testTimeout),
 		"err is not nil, when cluster provisioning has a condition")
 }
 
@@ -326,7 +339,7 @@ func TestMonitorDeployStatusJobCompletedWithSuccess(t *testing.T) {
 	hivev1.AddToScheme(s)
 	s.AddKnownTypes(clustercuratorv1.SchemeBuilder.GroupVersion, &clustercuratorv1.ClusterCurator{})
 
-	client := clientfake.NewClientBuilder().WithRuntimeObjects(cd, cc).WithScheme(s).Build()
+	client := clientfake.NewClientBuilder().WithStatusSubresource(&clustercuratorv1.ClusterCurator{}).WithRuntimeObjects(cd, cc).WithScheme(s).Build()
 
 	// Put a delay to complete the ClusterDeployment to test the wait loop
 	go func() {
@@ -338,7 +351,7 @@ func TestMonitorDeployStatusJobCompletedWithSuccess(t *testing.T) {
 	}()
 
 	assert.Equal(t, len(cc.Status.Conditions), 0, "Should be emtpy")
-	assert.Nil(t, monitorClusterStatus(client, ClusterName, utils.Installing, testTimeout),
+	assert.Nil(t, monitorClusterStatus(context.TODO(), client, ClusterName, utils.Installing, testTimeout),
 		"err is nil, when cluster provisioning is successful")
 
 	err := client.Get(context.Background(), types.NamespacedName{Namespace: ClusterName, Name: ClusterName}, cc)
@@ -363,7 +376,7 @@ func TestMonitorDeployStatusJobComplete(t *testing.T) {
 	hivev1.AddToScheme(s)
 	s.AddKnownTypes(clustercuratorv1.SchemeBuilder.GroupVersion, &clustercuratorv1.ClusterCurator{})
 
-	client := clientfake.NewClientBuilder().WithRuntimeObjects(cd, cc).WithScheme(s).Build()
+	client := clientfake.NewClientBuilder().WithStatusSubresource(&clustercuratorv1.ClusterCurator{}).WithRuntimeObjects(cd, cc).WithScheme(s).Build()
 
 	// Put a delay to complete the job to test the wait loop
 	go func() {
@@ -381,7 +394,7 @@ func TestMonitorDeployStatusJobComplete(t *testing.T) {
 		client.Update(context.TODO(), cd)
 	}()
 
-	assert.Nil(t, monitorClusterStatus(client, ClusterName, utils.Installing, testTimeout),
+	assert.Nil(t, monitorClusterStatus(context.TODO(), client, ClusterName, utils.Installing, testTimeout),
 		"err is not nil, when cluster provisioning is successful")
 }
 
@@ -396,7 +409,7 @@ func TestMonitorDeployStatusJobDelayedComplete(t *testing.T) {
 	hivev1.AddToScheme(s)
 	s.AddKnownTypes(clustercuratorv1.SchemeBuilder.GroupVersion, &clustercuratorv1.ClusterCurator{})
 
-	client := clientfake.NewClientBuilder().WithRuntimeObjects(cd, getClusterCurator()).WithScheme(s).Build()
+	client := clientfake.NewClientBuilder().WithStatusSubresource(&clustercuratorv1.ClusterCurator{}).WithRuntimeObjects(cd, getClusterCurator()).WithScheme(s).Build()
 
 	// Put a delay to complete the job to test the wait loop
 	go func() {
@@ -413,7 +426,7 @@ func TestMonitorDeployStatusJobDelayedComplete(t *testing.T) {
 		client.Update(context.TODO(), cd)
 	}()
 
-	assert.Nil(t, monitorClusterStatus(client, ClusterName, utils.Installing, testTimeout),
+	assert.Nil(t, monitorClusterStatus(context.TODO(), client, ClusterName, utils.Installing, testTimeout),
 		"err is nil, when cluster provisioning is successful")
 }
 
@@ -428,7 +441,7 @@ func TestMonitorDestroyStatusJobDelayedComplete(t *testing.T) {
 	hivev1.AddToScheme(s)
 	s.AddKnownTypes(clustercuratorv1.SchemeBuilder.GroupVersion, &clustercuratorv1.ClusterCurator{})
 
-	client := clientfake.NewClientBuilder().WithRuntimeObjects(cd, getClusterCurator()).WithScheme(s).Build()
+	client := clientfake.NewClientBuilder().WithStatusSubresource(&clustercuratorv1.ClusterCurator{}).WithRuntimeObjects(cd, getClusterCurator()).WithScheme(s).Build()
 
 	// Put a delay to complete the job to test the wait loop
 	go func() {
@@ -447,7 +460,7 @@ func TestMonitorDestroyStatusJobDelayedComplete(t *testing.T) {
 		// client.Delete(context.Background(), uninstallJob)
 	}()
 
-	assert.Nil(t, monitorClusterStatus(client, ClusterName, utils.Destroying, testTimeout),
+	assert.Nil(t, monitorClusterStatus(context.TODO(), client, ClusterName, utils.Destroying, testTimeout),
 		"err is nil, when cluster uninstall is successful")
 }
 
@@ -460,7 +473,7 @@ func TestMonitorDestroyClusterDeployementMissing(t *testing.T) {
 	client := clientfake.NewClientBuilder().WithRuntimeObjects(cd).WithScheme(s).Build()
 
 	// Should not return an error, just logs a warning
-	assert.Nil(t, DestroyClusterDeployment(client, cd.GetName()))
+	assert.Nil(t, DestroyClusterDeployment(context.TODO(), client, cd.GetName()))
 }
 
 func TestMonitorDestroyClusterDeployement(t *testing.T) {
@@ -470,7 +483,7 @@ func TestMonitorDestroyClusterDeployement(t *testing.T) {
 
 	client := clientfake.NewClientBuilder().WithRuntimeObjects(cd).WithScheme(s).Build()
 	// Should not return an error, just logs a warning
-	assert.Nil(t, DestroyClusterDeployment(client, cd.GetName()))
+	assert.Nil(t, DestroyClusterDeployment(context.TODO(), client, cd.GetName()))
 
 	err := client.Get(context.TODO(), types.NamespacedName{
 		Name:      cd.GetName(),
@@ -490,7 +503,7 @@ func TestMonitorDestroyStatusJobDelayedDeleteOnFinish(t *testing.T) {
 	hivev1.AddToScheme(s)
 	s.AddKnownTypes(clustercuratorv1.SchemeBuilder.GroupVersion, &clustercuratorv1.ClusterCurator{})
 
-	client := clientfake.NewClientBuilder().WithRuntimeObjects(cd, getClusterCurator()).WithScheme(s).Build()
+	client := clientfake.NewClientBuilder().WithStatusSubresource(&clustercuratorv1.ClusterCurator{}).WithRuntimeObjects(cd, getClusterCurator()).WithScheme(s).Build()
 
 	// Put a delay to complete the job to test the wait loop
 	go func() {
@@ -511,7 +524,7 @@ func TestMonitorDestroyStatusJobDelayedDeleteOnFinish(t *testing.T) {
 		client.Delete(context.Background(), uninstallJob)
 	}()
 
-	assert.Nil(t, monitorClusterStatus(client, ClusterName, utils.Destroying, testTimeout),
+	assert.Nil(t, monitorClusterStatus(context.TODO(), client, ClusterName, utils.Destroying, testTimeout),
 		"err is nil, when cluster uninstall is successful")
 }
 
@@ -537,8 +550,8 @@ func TestUpgradeClusterNonOpenshift(t *testing.T) {
 
 	client := clientfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(getUpgradeClusterCurator(), managedclusterinfo).Build()
 
-	assert.Equal(t, UpgradeCluster(client, ClusterName, getUpgradeClusterCurator()),
-		errors.New("Can not upgrade non openshift cluster"))
+	assert.EqualError(t, UpgradeCluster(context.TODO(), client, ClusterName, getUpgradeClusterCurator()),
+		"Can not upgrade non openshift cluster")
 }
 
 func TestUpgradeClusterNoDesiredUpdate(t *testing.T) {
@@ -560,8 +573,8 @@ func TestUpgradeClusterNoDesiredUpdate(t *testing.T) {
 
 	client := clientfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(clustercurator, getManagedClusterInfo()).Build()
 
-	assert.Equal(t, UpgradeCluster(client, ClusterName, clustercurator),
-		errors.New("Provide valid upgrade version or channel or upstream"))
+	assert.EqualError(t, UpgradeCluster(context.TODO(), client, ClusterName, clustercurator),
+		"Provide valid upgrade version or channel or upstream")
 }
 
 func TestUpgradeClusterInValidVersion(t *testing.T) {
@@ -585,8 +598,9 @@ func TestUpgradeClusterInValidVersion(t *testing.T) {
 
 	client := clientfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(clustercurator, getManagedClusterInfo()).Build()
 
-	assert.Equal(t, UpgradeCluster(client, ClusterName, clustercurator),
-		errors.New("Provided version is not valid"), "Invalid Version")
+	err := UpgradeCluster(context.TODO(), client, ClusterName, clustercurator)
+	assert.EqualError(t, err, "Provided version is not valid", "Invalid Version")
+	assert.Equal(t, utils.ReasonInvalidSpec, utils.ReasonForError(err))
 }
 
 func TestUpgradeClusterInValidChannel(t *testing.T) {
@@ -611,8 +625,8 @@ func TestUpgradeClusterInValidChannel(t *testing.T) {
 
 	client := clientfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(clustercurator, getManagedClusterInfo()).Build()
 
-	assert.Equal(t, UpgradeCluster(client, ClusterName, clustercurator),
-		errors.New("Provided channel is not valid"), "Invalid Channel")
+	assert.EqualError(t, UpgradeCluster(context.TODO(), client, ClusterName, clustercurator),
+		"Provided channel is not valid", "Invalid Channel")
 }
 
 func getManagedClusterView() *managedclusterviewv1beta1.ManagedClusterView {
@@ -667,7 +681,7 @@ func TestUpgradeClusterMCVExists(t *testing.T) {
 
 	client := clientfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(clustercurator, getManagedClusterInfo(), managedclusterview).Build()
 
-	assert.NotNil(t, UpgradeCluster(client, ClusterName, clustercurator), "err not nil when managedclusterview already exists")
+	assert.NotNil(t, UpgradeCluster(context.TODO(), client, ClusterName, clustercurator), "err not nil when managedclusterview already exists")
 }
 
 func TestUpgradeCluster(t *testing.T) {
@@ -777,7 +791,7 @@ func TestUpgradeCluster(t *testing.T) {
 		}
 	}()
 
-	assert.Nil(t, UpgradeCluster(client, ClusterName, clustercurator), "Upgrade started successfully")
+	assert.Nil(t, UpgradeCluster(context.TODO(), client, ClusterName, clustercurator), "Upgrade started successfully")
 }
 
 func TestUpgradeClusterWithChannelUpstream(t *testing.T) {
@@ -889,7 +903,7 @@ func TestUpgradeClusterWithChannelUpstream(t *testing.T) {
 		}
 	}()
 
-	assert.Nil(t, UpgradeCluster(client, ClusterName, clustercurator), "Upgrade started successfully")
+	assert.Nil(t, UpgradeCluster(context.TODO(), client, ClusterName, clustercurator), "Upgrade started successfully")
 }
 
 func TestMonitorUpgradeStatusJobComplete(t *testing.T) {
@@ -985,8 +999,9 @@ func TestMonitorUpgradeStatusJobComplete(t *testing.T) {
 
 	s := scheme.Scheme
 	s.AddKnownTypes(clustercuratorv1.SchemeBuilder.GroupVersion, &clustercuratorv1.ClusterCurator{})
-	s.AddKnownTypes(managedclusterviewv1beta1.SchemeGroupVersion, &managedclusterviewv1beta1.ManagedClusterView{})
-	client := clientfake.NewClientBuilder().WithRuntimeObjects([]runtime.Object{cc, managedclusterview}...).WithScheme(s).Build()
+	s.AddKnownTypes(managedclusterviewv1beta1.SchemeGroupVersion,
+		&managedclusterviewv1beta1.ManagedClusterView{}, &managedclusterviewv1beta1.ManagedClusterViewList{})
+	client := clientfake.NewClientBuilder().WithStatusSubresource(&clustercuratorv1.ClusterCurator{}).WithRuntimeObjects([]runtime.Object{cc, managedclusterview}...).WithScheme(s).Build()
 
 	// Put a delay to complete the job to test the wait loop
 	go func() {
@@ -999,7 +1014,7 @@ func TestMonitorUpgradeStatusJobComplete(t *testing.T) {
 		client.Update(context.TODO(), &resultmcview)
 	}()
 
-	assert.Nil(t, MonitorUpgradeStatus(client, ClusterName, cc, false), "err is nil, when cluster upgrade is successful")
+	assert.Nil(t, MonitorUpgradeStatus(context.TODO(), client, ClusterName, cc, false), "err is nil, when cluster upgrade is successful")
 }
 
 func TestUpgradeClusterForceUpgradeCSVHasDesiredUpdate(t *testing.T) {
@@ -1116,7 +1131,7 @@ func TestUpgradeClusterForceUpgradeCSVHasDesiredUpdate(t *testing.T) {
 		}
 	}()
 
-	assert.Nil(t, UpgradeCluster(client, ClusterName, clustercurator), "Upgrade started successfully to non-recommended version")
+	assert.Nil(t, UpgradeCluster(context.TODO(), client, ClusterName, clustercurator), "Upgrade started successfully to non-recommended version")
 }
 
 func TestUpgradeClusterForceUpgradeCSVNoDesiredUpdate(t *testing.T) {
@@ -1229,7 +1244,7 @@ func TestUpgradeClusterForceUpgradeCSVNoDesiredUpdate(t *testing.T) {
 		}
 	}()
 
-	assert.Nil(t, UpgradeCluster(client, ClusterName, clustercurator), "Upgrade started successfully to non-recommended version")
+	assert.Nil(t, UpgradeCluster(context.TODO(), client, ClusterName, clustercurator), "Upgrade started successfully to non-recommended version")
 }
 
 func TestEUSIntermediateUpgrade(t *testing.T) {
@@ -1447,7 +1462,7 @@ func TestEUSIntermediateUpgrade(t *testing.T) {
 
 	assert.Nil(
 		t,
-		EUSUpgradeCluster(client, ClusterName, clustercurator, true),
+		EUSUpgradeCluster(context.TODO(), client, ClusterName, clustercurator, true),
 		"EUS Intermediate Upgrade started successfully")
 }
 
@@ -1666,6 +1681,49 @@ func TestEUSFinalUpgrade(t *testing.T) {
 
 	assert.Nil(
 		t,
-		EUSUpgradeCluster(client, ClusterName, clustercurator, false),
+		EUSUpgradeCluster(context.TODO(), client, ClusterName, clustercurator, false),
 		"EUS Final Upgrade started successfully")
 }
+
+func TestDesiredUpdateApplied(t *testing.T) {
+	clusterVersion := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"channel":       "stable-4.14",
+			"desiredUpdate": map[string]interface{}{"version": "4.14.16", "force": false},
+		},
+	}
+
+	assert.True(t, desiredUpdateApplied(clusterVersion, "4.14.16", "", ""), "a retried step does not update again")
+	assert.True(t, desiredUpdateApplied(clusterVersion, "4.14.16", "stable-4.14", ""))
+	assert.False(t, desiredUpdateApplied(clusterVersion, "4.14.17", "", ""))
+	assert.False(t, desiredUpdateApplied(clusterVersion, "4.14.16", "fast-4.14", ""), "the channel is updated")
+	assert.False(t, desiredUpdateApplied(clusterVersion, "4.14.16", "", "https://upstream.example.com"))
+	assert.False(t, desiredUpdateApplied(map[string]interface{}{"spec": map[string]interface{}{}}, "4.14.16", "", ""))
+}
+
+func TestCreateManagedClusterActionReplacesStaleAction(t *testing.T) {
+	s := runtime.NewScheme()
+	s.AddKnownTypes(managedclusteractionv1beta1.SchemeGroupVersion, &managedclusteractionv1beta1.ManagedClusterAction{})
+	stale := &managedclusteractionv1beta1.ManagedClusterAction{
+		ObjectMeta: v1.ObjectMeta{Name: ClusterName, Namespace: ClusterName},
+		Status: managedclusteractionv1beta1.ActionStatus{Conditions: []v1.Condition{{
+			Type: managedclusteractionv1beta1.ConditionActionCompleted, Status: v1.ConditionFalse}}},
+	}
+	client := clientfake.NewClientBuilder().WithScheme(s).WithObjects(stale).Build()
+	curator := getUpgradeClusterCurator()
+	curator.UID = "curator-uid"
+	curator.Spec.CuratingJob = "curator-job-d9pwh"
+
+	assert.Nil(t, createManagedClusterAction(context.TODO(), client, curator, &managedclusteractionv1beta1.ManagedClusterAction{
+		ObjectMeta: v1.ObjectMeta{Name: ClusterName, Namespace: ClusterName},
+		Spec:       managedclusteractionv1beta1.ActionSpec{ActionType: managedclusteractionv1beta1.UpdateActionType},
+	}), "the action of an interrupted run of the step is replaced")
+
+	mca := &managedclusteractionv1beta1.ManagedClusterAction{}
+	assert.Nil(t, client.Get(context.TODO(), types.NamespacedName{Namespace: ClusterName, Name: ClusterName}, mca))
+	assert.Equal(t, managedclusteractionv1beta1.UpdateActionType, mca.Spec.ActionType)
+	assert.Empty(t, mca.Status.Conditions)
+	assert.Equal(t, "curator-job-d9pwh", mca.Labels[utils.CurationRunLabel])
+	assert.Equal(t, ClusterName, mca.Labels[utils.ClusterCuratorLabel])
+	assert.Len(t, mca.OwnerReferences, 1, "the action is garbage collected with the ClusterCurator")
+}
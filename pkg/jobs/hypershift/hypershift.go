@@ -30,26 +30,26 @@ We must use dynamic types here unfortunately because the Hypershift API requires
 an older version of sigs.k8s.io/controller-runtime/pkg/client(v0.13.1) which is
 not compatible with the version	that the Hive API requires and results in compile issues.
 */
-func ActivateDeploy(dc dynamic.Interface, clusterName string, namespace string) error {
+func ActivateDeploy(ctx context.Context, dc dynamic.Interface, clusterName string, namespace string) error {
 	klog.V(0).Info("* Initiate Hypershift Provisioning")
 
 	// Update HostedCluster
-	err := removePausedUntil(dc, clusterName, namespace, utils.HCGVR)
-	utils.CheckError(err)
+	if err := removePausedUntil(ctx, dc, clusterName, namespace, utils.HCGVR); err != nil {
+		return err
+	}
 
 	// Update NodePool
 	// Need to account for 0 or multiple NodePools
-	nodePools, err := dc.Resource(utils.NPGVR).Namespace(namespace).List(context.TODO(), v1.ListOptions{})
-	utils.CheckError(err)
+	nodePools, err := dc.Resource(utils.NPGVR).Namespace(namespace).List(ctx, v1.ListOptions{})
+	if err != nil {
+		return err
+	}
 
 	for _, np := range nodePools.Items {
-		spec := np.Object["spec"].(map[string]interface{})
-		if spec["clusterName"] != nil {
-			npClusterName := spec["clusterName"].(string)
-			if npClusterName == clusterName {
-				npName := np.Object["metadata"].(map[string]interface{})["name"].(string)
-				err = removePausedUntil(dc, npName, namespace, utils.NPGVR)
-				utils.CheckError(err)
+		npClusterName, _, _ := unstructured.NestedString(np.Object, "spec", "clusterName")
+		if npClusterName == clusterName {
+			if err := removePausedUntil(ctx, dc, np.GetName(), namespace, utils.NPGVR); err != nil {
+				return err
 			}
 		}
 	}
@@ -58,6 +58,7 @@ func ActivateDeploy(dc dynamic.Interface, clusterName string, namespace string)
 }
 
 func removePausedUntil(
+	ctx context.Context,
 	dc dynamic.Interface,
 	clusterName string,
 	namespace string,
@@ -68,7 +69,7 @@ func removePausedUntil(
 		var resource *unstructured.Unstructured
 		var err error
 
-		resource, err = dc.Resource(resourceType).Namespace(namespace).Get(context.TODO(), clusterName, v1.GetOptions{})
+		resource, err = dc.Resource(resourceType).Namespace(namespace).Get(ctx, clusterName, v1.GetOptions{})
 		if err != nil {
 			return err
 		}
@@ -81,7 +82,8 @@ func removePausedUntil(
 			resourceType.Resource, clusterName, metadata["namespace"].(string))
 
 		spec := resource.Object["spec"].(map[string]interface{})
-		if spec["pausedUntil"] != nil && spec["pausedUntil"].(string) != "true" {
+		// Not paused until the curator starts it, or already unpaused by a previous run of the step
+		if pausedUntil, ok := spec["pausedUntil"].(string); !ok || pausedUntil != "true" {
 			klog.V(2).Info("Handle " + resourceType.Resource + " directly")
 			return nil
 		}
@@ -100,7 +102,7 @@ func removePausedUntil(
 		klog.V(2).Infof("Patching %v %v in namespace %v ✓",
 			resourceType.Resource, clusterName, metadata["namespace"].(string))
 		_, err = dc.Resource(resourceType).Namespace(namespace).Patch(
-			context.TODO(), clusterName, types.JSONPatchType, patchInBytes, v1.PatchOptions{})
+			ctx, clusterName, types.JSONPatchType, patchInBytes, v1.PatchOptions{})
 		if err != nil {
 			return err
 		}
@@ -112,6 +114,7 @@ func removePausedUntil(
 }
 
 func MonitorClusterStatus(
+	ctx context.Context,
 	dc dynamic.Interface,
 	client clientv1.Client,
 	clusterName string,
@@ -120,119 +123,100 @@ func MonitorClusterStatus(
 	monitorAttempts int) error {
 	klog.V(0).Info("Waiting up to " + strconv.Itoa(monitorAttempts*5) + "s for Hypershift Provisioning job")
 	jobName := ""
-	var hostedCluster *unstructured.Unstructured
-
-	for i := 1; i <= monitorAttempts; i++ {
 
-		// Refresh the hostedCluster resource
-		hostedCluster, err := dc.Resource(utils.HCGVR).Namespace(namespace).Get(
-			context.TODO(), clusterName, v1.GetOptions{})
+	// Refresh the hostedCluster resource
+	hostedCluster, err := dc.Resource(utils.HCGVR).Namespace(namespace).Get(ctx, clusterName, v1.GetOptions{})
 
-		// Destroy path
-		if err = utils.LogError(err); err != nil {
-			// If the hostedCluster is already gone
-			if jobType == utils.Destroying && k8serrors.IsNotFound(err) {
-				klog.Warning("No hosted cluster for " + clusterName + " was found")
-				return nil
-			}
-			return err
+	// Destroy path
+	if err = utils.LogError(err); err != nil {
+		// If the hostedCluster is already gone
+		if jobType == utils.Destroying && k8serrors.IsNotFound(err) {
+			klog.Warning("No hosted cluster for " + clusterName + " was found")
+			return nil
 		}
+		return err
+	}
 
-		// Install path
-		if jobType == utils.Installing && isHostedReady(hostedCluster, false) {
-			klog.V(2).Info("Provisioning succeeded ✓")
-
-			if jobName != "" {
-				utils.CheckError(utils.RecordCurrentStatusCondition(
-					client,
-					clusterName,
-					namespace,
-					"hypershift-provisioning-job",
-					v1.ConditionTrue,
-					jobName))
-			}
-
-			return nil
-		} else if !isHostedReady(hostedCluster, false) || jobType == utils.Destroying {
-			klog.V(2).Info("Found HostedCluster status details ✓")
-
-			if jobType == utils.Destroying {
-				jobName = clusterName + "-" + utils.Destroying
-			} else {
-				// No ProvisionRef in HostedCluster, we use infra-id instead
-				metadata := hostedCluster.Object["metadata"].(map[string]interface{})
-				if metadata["labels"] != nil {
-					labels := metadata["labels"].(map[string]interface{})
-					jobName = labels["hypershift.openshift.io/auto-created-for-infra"].(string) + "-provision"
-				} else {
-					// For HC types without the auto-created-for-infra label
-					jobName = clusterName + "-provision"
-				}
-			}
+	// Install path
+	if jobType == utils.Installing && isHostedReady(hostedCluster, false) {
+		klog.V(2).Info("Provisioning succeeded ✓")
+		return nil
+	}
 
-			jobPath := namespace + "/" + jobName
+	klog.V(2).Info("Found HostedCluster status details ✓")
 
-			klog.V(2).Info("Found job " + jobPath + " ✓ Start monitoring: ")
-			elapsedTime := 0
+	if jobType == utils.Destroying {
+		jobName = clusterName + "-" + utils.Destroying
+	} else {
+		// No ProvisionRef in HostedCluster, we use infra-id instead
+		if infraID := hostedCluster.GetLabels()["hypershift.openshift.io/auto-created-for-infra"]; infraID != "" {
+			jobName = infraID + "-provision"
+		} else {
+			// For HC types without the auto-created-for-infra label
+			jobName = clusterName + "-provision"
+		}
+	}
 
-			// Wait while the job is running
-			klog.V(0).Info("Wait for the " + jobType + "ing job from Hypershift to complete")
+	jobPath := namespace + "/" + jobName
 
-			utils.CheckError(utils.RecordCurrentStatusCondition(
-				client,
-				clusterName,
-				namespace,
-				"hypershift-"+jobType+"ing-job",
-				v1.ConditionFalse,
-				jobName))
+	klog.V(2).Info("Found job " + jobPath + " ✓ Start monitoring: ")
 
-			for !isHostedReady(hostedCluster, false) {
-				if elapsedTime%6 == 0 {
-					klog.V(0).Info("Job: " + jobPath + " - " + strconv.Itoa(elapsedTime/6) + "min")
-				}
-				time.Sleep(utils.PauseTenSeconds) // 10s
-				elapsedTime++
+	// Wait while the job is running
+	klog.V(0).Info("Wait for the " + jobType + "ing job from Hypershift to complete")
 
-				// Reset hostedCluster, so we make sure we're getting clean data (not cached)
-				hostedCluster, err = dc.Resource(utils.HCGVR).Namespace(namespace).Get(
-					context.TODO(), clusterName, v1.GetOptions{})
+	if err := utils.RecordCurrentStatusCondition(
+		client,
+		clusterName,
+		namespace,
+		"hypershift-"+jobType+"ing-job",
+		v1.ConditionFalse,
+		jobName); err != nil {
+		return err
+	}
 
-				if jobType == utils.Destroying && err != nil && k8serrors.IsNotFound(err) {
-					break
-				}
+	/*
+		  There's no Hypershift equivalent of ProvisionStoppedCondition, so the wait is not bound by a timeout.
+			The problem with trying to detect failure for hypershift is that there's no total failure state
+			where the operator will give up trying. Users can always fix an issue ie. WebIdentity error to
+			allow the provision to continue.
+	*/
+	start := time.Now()
+	err = utils.WaitForCondition(ctx, utils.PauseTenSeconds, func(ctx context.Context) (bool, error) {
 
-				utils.CheckError(err)
-			}
+		// Reset hostedCluster, so we make sure we're getting clean data (not cached)
+		hostedCluster, err := dc.Resource(utils.HCGVR).Namespace(namespace).Get(ctx, clusterName, v1.GetOptions{})
 
-			// When Destroying, by this point the job finished
-			if jobType == utils.Destroying {
-				klog.V(0).Info("Uninstall job complete")
-				utils.CheckError(utils.RecordCurrentStatusCondition(
-					client,
-					clusterName,
-					namespace,
-					"hypershift-"+jobType+"ing-job",
-					v1.ConditionTrue,
-					jobName))
-				return nil
-			}
+		if jobType == utils.Destroying && err != nil && k8serrors.IsNotFound(err) {
+			return true, nil
+		} else if err != nil {
+			return false, err
+		}
 
-			klog.V(0).Info("The " + jobType + "ing job from Hypershift completed ✓")
-		} else {
-			klog.V(0).Info("The " + jobType + "ing job from Hypershift failed ✓")
-			/*
-				  Detect that we've failed but there's no Hypershift equivalent of ProvisionStoppedCondition.
-					The problem with trying to detect failure for hypershift is that there's no total failure state
-					where the operator will give up trying. Users can always fix an issue ie. WebIdentity error to
-					allow the provision to continue.
-			*/
+		if isHostedReady(hostedCluster, false) {
+			return true, nil
 		}
+		klog.V(0).Info("Job: " + jobPath + " - " + strconv.Itoa(int(time.Since(start).Minutes())) + "min")
+		return false, nil
+	}, utils.WatchDynamic(dc, utils.HCGVR, namespace, clusterName))
+	if err != nil {
+		return err
 	}
 
-	if hostedCluster != nil && hostedCluster.Object["status"] != nil {
-		klog.Warning(hostedCluster.Object["status"].(map[string]interface{})["conditions"])
+	// When Destroying, by this point the job finished
+	if jobType == utils.Destroying {
+		klog.V(0).Info("Uninstall job complete")
+	} else {
+		klog.V(0).Info("The " + jobType + "ing job from Hypershift completed ✓")
+		klog.V(2).Info("Provisioning succeeded ✓")
 	}
-	return errors.New("Timed out waiting for job")
+
+	return utils.RecordCurrentStatusCondition(
+		client,
+		clusterName,
+		namespace,
+		"hypershift-"+jobType+"ing-job",
+		v1.ConditionTrue,
+		jobName)
 }
 
 /*
@@ -360,6 +344,7 @@ func parseConditionStatus(conditionStatus string) v1.ConditionStatus {
 }
 
 func UpgradeCluster(
+	ctx context.Context,
 	client clientv1.Client,
 	dc dynamic.Interface,
 	clusterName string,
@@ -369,7 +354,7 @@ func UpgradeCluster(
 
 	desiredUpdate := curator.Spec.Upgrade.DesiredUpdate
 
-	if err := validateUpgradeVersion(client, clusterName, curator, desiredUpdate); err != nil {
+	if err := validateUpgradeVersion(ctx, client, clusterName, curator, desiredUpdate); err != nil {
 		return err
 	}
 
@@ -377,20 +362,22 @@ func UpgradeCluster(
 	image := "quay.io/openshift-release-dev/ocp-release:" + desiredUpdate + "-multi"
 
 	// Patch HostedCluster with new image
-	err := patchUpgradeVersion(dc, clusterName, curator.Namespace, utils.HCGVR, image)
-	utils.CheckError(err)
+	if err := patchUpgradeVersion(ctx, dc, clusterName, curator.Namespace, utils.HCGVR, image); err != nil {
+		return err
+	}
 
 	// Need to account for 0 or multiple NodePools
-	nodePools, err := dc.Resource(utils.NPGVR).Namespace(curator.Namespace).List(context.TODO(), v1.ListOptions{})
-	utils.CheckError(err)
+	nodePools, err := dc.Resource(utils.NPGVR).Namespace(curator.Namespace).List(ctx, v1.ListOptions{})
+	if err != nil {
+		return err
+	}
 
 	for _, np := range nodePools.Items {
-		spec := np.Object["spec"].(map[string]interface{})
-		npClusterName := spec["clusterName"].(string)
+		npClusterName, _, _ := unstructured.NestedString(np.Object, "spec", "clusterName")
 		if npClusterName == clusterName {
-			npName := np.Object["metadata"].(map[string]interface{})["name"].(string)
-			err = patchUpgradeVersion(dc, npName, curator.Namespace, utils.NPGVR, image)
-			utils.CheckError(err)
+			if err := patchUpgradeVersion(ctx, dc, np.GetName(), curator.Namespace, utils.NPGVR, image); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -398,61 +385,65 @@ func UpgradeCluster(
 }
 
 func MonitorUpgradeStatus(
+	ctx context.Context,
 	dc dynamic.Interface,
 	client clientv1.Client,
 	clusterName string,
 	curator *clustercuratorv1.ClusterCurator) error {
 	upgradeAttempts := utils.GetRetryTimes(curator.Spec.Upgrade.MonitorTimeout, 120, utils.PauseSixtySeconds)
-	klog.V(0).Info("Monitoring up to " + strconv.Itoa(upgradeAttempts) + " attempts for Hypershift Upgrade job")
-	elapsedTime := 0
+	klog.V(0).Info("Monitoring up to " + strconv.Itoa(upgradeAttempts) + " minutes for Hypershift Upgrade job")
 
 	// Refresh the hostedCluster resource
-	hostedCluster, err := dc.Resource(utils.HCGVR).Namespace(curator.Namespace).Get(
-		context.TODO(), clusterName, v1.GetOptions{})
+	hostedCluster, err := dc.Resource(utils.HCGVR).Namespace(curator.Namespace).Get(ctx, clusterName, v1.GetOptions{})
 	if err != nil {
 		return err
 	}
 
-	for i := 0; i < upgradeAttempts; i++ {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(upgradeAttempts)*utils.PauseSixtySeconds)
+	defer cancel()
+
+	start := time.Now()
+	err = utils.WaitForCondition(ctx, utils.PauseTenSeconds, func(ctx context.Context) (bool, error) {
 		if isHostedReady(hostedCluster, true) {
-			klog.V(2).Info("Upgrade succeeded ✓")
-			utils.CheckError(utils.RecordCurrentStatusCondition(
-				client,
-				clusterName,
-				curator.Namespace,
-				"hypershift-upgrade-job",
-				v1.ConditionTrue,
-				"upgrade-job"))
+			return true, nil
+		}
+		klog.V(0).Info("Upgrade Job:  - " + strconv.Itoa(int(time.Since(start).Minutes())) + "min")
 
-			return nil
-		} else {
-			for !isHostedReady(hostedCluster, true) {
-				if elapsedTime%6 == 0 {
-					klog.V(0).Info("Upgrade Job:  - " + strconv.Itoa(elapsedTime/6) + "min")
-				}
-				time.Sleep(utils.PauseTenSeconds) // 10s
-				elapsedTime++
-
-				// Reset hostedCluster, so we make sure we're getting clean data (not cached)
-				hostedCluster, err = dc.Resource(utils.HCGVR).Namespace(curator.Namespace).Get(
-					context.TODO(), clusterName, v1.GetOptions{})
-
-				if err != nil && k8serrors.IsNotFound(err) {
-					break
-				}
-
-				utils.CheckError(err)
-			}
+		// Reset hostedCluster, so we make sure we're getting clean data (not cached)
+		hc, err := dc.Resource(utils.HCGVR).Namespace(curator.Namespace).Get(ctx, clusterName, v1.GetOptions{})
+		if err != nil && k8serrors.IsNotFound(err) {
+			// Keep the last known state, the HostedCluster may come back
+			klog.Warning("Could not retreive hosted cluster " + clusterName)
+			return false, nil
+		} else if err != nil {
+			return false, err
 		}
-	}
+		hostedCluster = hc
 
-	if hostedCluster != nil {
-		klog.Warning(hostedCluster.Object["status"].(map[string]interface{})["conditions"])
+		return isHostedReady(hostedCluster, true), nil
+	}, utils.WatchDynamic(dc, utils.HCGVR, curator.Namespace, clusterName))
+
+	if errors.Is(err, utils.ErrWaitTimeout) {
+		if hostedCluster.Object["status"] != nil {
+			klog.Warning(hostedCluster.Object["status"].(map[string]interface{})["conditions"])
+		}
+		return utils.NewError(utils.ReasonTimeout, "Timed out waiting for job")
+	} else if err != nil {
+		return err
 	}
-	return errors.New("Timed out waiting for job")
+
+	klog.V(2).Info("Upgrade succeeded ✓")
+	return utils.RecordCurrentStatusCondition(
+		client,
+		clusterName,
+		curator.Namespace,
+		"hypershift-upgrade-job",
+		v1.ConditionTrue,
+		"upgrade-job")
 }
 
 func patchUpgradeVersion(
+	ctx context.Context,
 	dc dynamic.Interface,
 	clusterName string,
 	namespace string,
@@ -470,7 +461,7 @@ func patchUpgradeVersion(
 
 		klog.V(2).Infof("Patching %v %v in namespace %v ✓", resourceType.Resource, clusterName, namespace)
 		_, err := dc.Resource(resourceType).Namespace(namespace).Patch(
-			context.TODO(), clusterName, types.JSONPatchType, patchInBytes, v1.PatchOptions{})
+			ctx, clusterName, types.JSONPatchType, patchInBytes, v1.PatchOptions{})
 		if err != nil {
 			return err
 		}
@@ -482,12 +473,13 @@ func patchUpgradeVersion(
 }
 
 func validateUpgradeVersion(
+	ctx context.Context,
 	client clientv1.Client,
 	clusterName string,
 	curator *clustercuratorv1.ClusterCurator,
 	desiredUpdate string) error {
 	managedClusterInfo := managedclusterinfov1beta1.ManagedClusterInfo{}
-	if err := client.Get(context.TODO(), types.NamespacedName{
+	if err := client.Get(ctx, types.NamespacedName{
 		Namespace: clusterName,
 		Name:      clusterName,
 	}, &managedClusterInfo); err != nil {
@@ -496,23 +488,23 @@ func validateUpgradeVersion(
 
 	desiredSemver, err := semver.Make(desiredUpdate)
 	if err != nil {
-		return err
+		return utils.WrapError(utils.ReasonInvalidSpec, err)
 	}
 	currentSemver, err := semver.Make(managedClusterInfo.Status.DistributionInfo.OCP.Version)
 	if err != nil {
 		return err
 	}
 	if desiredSemver.Equals(currentSemver) {
-		return errors.New("Cannot upgrade to the same version")
+		return utils.NewError(utils.ReasonInvalidSpec, "Cannot upgrade to the same version")
 	}
 
 	return nil
 }
 
-func DestroyHostedCluster(dc dynamic.Interface, clusterName string, namespace string) error {
+func DestroyHostedCluster(ctx context.Context, dc dynamic.Interface, clusterName string, namespace string) error {
 	klog.V(0).Infof("Deleting Hosted Cluster for %v in namespace %v\n", clusterName, namespace)
 
-	_, err := dc.Resource(utils.HCGVR).Namespace(namespace).Get(context.TODO(), clusterName, v1.GetOptions{})
+	_, err := dc.Resource(utils.HCGVR).Namespace(namespace).Get(ctx, clusterName, v1.GetOptions{})
 
 	if err != nil {
 		if k8serrors.IsNotFound(err) {
@@ -522,7 +514,7 @@ func DestroyHostedCluster(dc dynamic.Interface, clusterName string, namespace st
 		return err
 	}
 
-	err = dc.Resource(utils.HCGVR).Namespace(namespace).Delete(context.TODO(), clusterName, v1.DeleteOptions{})
+	err = dc.Resource(utils.HCGVR).Namespace(namespace).Delete(ctx, clusterName, v1.DeleteOptions{})
 	if err != nil {
 		return err
 	}
@@ -530,7 +522,12 @@ func DestroyHostedCluster(dc dynamic.Interface, clusterName string, namespace st
 	return nil
 }
 
-func DetachAndMonitor(dc dynamic.Interface, clusterName string, curator *clustercuratorv1.ClusterCurator) error {
+func DetachAndMonitor(
+	ctx context.Context,
+	dc dynamic.Interface,
+	clusterName string,
+	curator *clustercuratorv1.ClusterCurator) error {
+
 	var mcGVR = schema.GroupVersionResource{
 		Group:    "cluster.open-cluster-management.io",
 		Version:  "v1",
@@ -538,28 +535,27 @@ func DetachAndMonitor(dc dynamic.Interface, clusterName string, curator *cluster
 	}
 	klog.V(0).Info("=> Monitoring ManagedCluster detach of " + clusterName)
 
-	hostedCluster, err := dc.Resource(utils.HCGVR).Namespace(curator.Namespace).Get(
-		context.TODO(), clusterName, v1.GetOptions{})
+	hostedCluster, err := dc.Resource(utils.HCGVR).Namespace(curator.Namespace).Get(ctx, clusterName, v1.GetOptions{})
 	if err != nil {
 		if k8serrors.IsNotFound(err) {
 			klog.Warning("Could not retreive hosted cluster " + clusterName + " may have been deleted")
 		} else {
 			return err
 		}
-	}
-
-	spec := hostedCluster.Object["spec"].(map[string]interface{})
-	if spec["platform"] == nil {
-		return errors.New("Not able to find HostedCluster platform type")
-	}
-	hcType := spec["platform"].(map[string]interface{})["type"].(string)
+	} else {
+		hcType, found, _ := unstructured.NestedString(hostedCluster.Object, "spec", "platform", "type")
+		if !found {
+			return utils.NewError(utils.ReasonInvalidSpec, "Not able to find HostedCluster platform type")
+		}
 
-	if hcType != "KubeVirt" && hcType != "Agent" {
-		return errors.New("Destroying HosterCluster type " + hcType + " is not supported. Use the HostedCluster CLI.")
+		if hcType != "KubeVirt" && hcType != "Agent" {
+			return utils.NewError(utils.ReasonInvalidSpec,
+				"Destroying HosterCluster type %s is not supported. Use the HostedCluster CLI.", hcType)
+		}
 	}
 
 	retryCount := utils.GetRetryTimes(curator.Spec.Destroy.JobMonitorTimeout, 5, utils.PauseTwoSeconds)
-	_, err = dc.Resource(mcGVR).Get(context.TODO(), clusterName, v1.GetOptions{})
+	_, err = dc.Resource(mcGVR).Get(ctx, clusterName, v1.GetOptions{})
 
 	if err != nil && k8serrors.IsNotFound(err) {
 		klog.Warning("Could not retreive managed cluster " + clusterName + " may have been deleted")
@@ -570,23 +566,27 @@ func DetachAndMonitor(dc dynamic.Interface, clusterName string, curator *cluster
 
 	klog.V(0).Info("Deleting ManagedCluster " + clusterName)
 	// Delete will hang until resource is delete, no need to monitor
-	err = dc.Resource(mcGVR).Delete(context.TODO(), clusterName, v1.DeleteOptions{})
+	err = dc.Resource(mcGVR).Delete(ctx, clusterName, v1.DeleteOptions{})
 	if err != nil {
 		return err
 	}
 
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(retryCount)*utils.PauseTenSeconds*3)
+	defer cancel()
+
 	// Monitor managed cluster delete
-	for i := 1; i <= retryCount; i++ {
-		_, err := dc.Resource(mcGVR).Get(context.TODO(), clusterName, v1.GetOptions{})
+	err = utils.WaitForCondition(ctx, utils.PauseTenSeconds*3, func(ctx context.Context) (bool, error) {
+		_, err := dc.Resource(mcGVR).Get(ctx, clusterName, v1.GetOptions{})
 
 		if err != nil && k8serrors.IsNotFound(err) {
 			klog.Warning("Could not retreive managed cluster " + clusterName + " may have been deleted")
-			return nil
-		} else if err != nil {
-			return err
+			return true, nil
 		}
+		return false, err
+	}, utils.WatchDynamic(dc, mcGVR, "", clusterName))
 
-		time.Sleep(utils.PauseTenSeconds * 3)
+	if errors.Is(err, utils.ErrWaitTimeout) {
+		return utils.NewError(utils.ReasonTimeout, "Time out waiting for hosted cluster to detach")
 	}
-	return errors.New("Time out waiting for hosted cluster to detach")
+	return err
 }
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	clustercuratorv1 "github.com/stolostron/cluster-curator-controller/pkg/api/v1beta1"
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/rbac"
 	"github.com/stolostron/cluster-curator-controller/pkg/jobs/utils"
 	managedclusterinfov1beta1 "github.com/stolostron/cluster-lifecycle-api/clusterinfo/v1beta1"
 	"github.com/stretchr/testify/assert"
@@ -16,6 +17,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	dynfake "k8s.io/client-go/dynamic/fake"
 	"k8s.io/client-go/kubernetes/scheme"
+	clienttesting "k8s.io/client-go/testing"
 	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
@@ -159,14 +161,10 @@ func getManagedCluster() *unstructured.Unstructured {
 }
 
 func TestActivateDeployNoHC(t *testing.T) {
-	defer func() { // recover from not having a hub config object
-		if r := recover(); r == nil {
-			t.Fatal("expected recover, but failed")
-		}
-	}()
 	dynfake := dynfake.NewSimpleDynamicClient(runtime.NewScheme())
-	assert.NotNil(t, ActivateDeploy(
-		dynfake, ClusterName, ClusterNamespace), "err not nil, when HostedCluster resource is not present")
+	err := ActivateDeploy(context.TODO(), dynfake, ClusterName, ClusterNamespace)
+	assert.NotNil(t, err, "err not nil, when HostedCluster resource is not present")
+	assert.Equal(t, utils.ReasonNotFound, utils.ReasonForError(err))
 }
 
 func TestActivateDeployAvailable(t *testing.T) {
@@ -176,7 +174,21 @@ func TestActivateDeployAvailable(t *testing.T) {
 		getNodepool(NodepoolName, ClusterNamespace, ClusterName),
 		getNodepool("other-cluster-us-east-2", ClusterNamespace, "other-cluster"))
 	assert.Nil(t, ActivateDeploy(
-		dynfake, ClusterName, ClusterNamespace), "err nil, when HostedCluster is available")
+		context.TODO(), dynfake, ClusterName, ClusterNamespace), "err nil, when HostedCluster is available")
+}
+
+func TestActivateDeployAlreadyUnpaused(t *testing.T) {
+	hostedCluster := getHostedCluster("AWS", []interface{}{})
+	unstructured.RemoveNestedField(hostedCluster.Object, "spec", "pausedUntil")
+	nodepool := getNodepool(NodepoolName, ClusterNamespace, ClusterName)
+	unstructured.RemoveNestedField(nodepool.Object, "spec", "pausedUntil")
+	dynfake := dynfake.NewSimpleDynamicClient(runtime.NewScheme(), hostedCluster, nodepool)
+
+	assert.Nil(t, ActivateDeploy(context.TODO(), dynfake, ClusterName, ClusterNamespace),
+		"err nil, when a previous run of the step unpaused the HostedCluster")
+	for _, action := range dynfake.Actions() {
+		assert.NotEqual(t, "patch", action.GetVerb(), "the unpaused resources are not patched")
+	}
 }
 
 func TestMonitorClusterStatusInstallNoHC(t *testing.T) {
@@ -187,7 +199,7 @@ func TestMonitorClusterStatusInstallNoHC(t *testing.T) {
 
 	assert.NotNil(
 		t,
-		MonitorClusterStatus(dynfake, client, ClusterName, ClusterNamespace, utils.Installing, testTimeout),
+		MonitorClusterStatus(context.TODO(), dynfake, client, ClusterName, ClusterNamespace, utils.Installing, testTimeout),
 		"err is not nil, when HostedCluster does not exist",
 	)
 }
@@ -200,7 +212,7 @@ func TestMonitorClusterStatusDestroyComplete(t *testing.T) {
 
 	assert.Nil(
 		t,
-		MonitorClusterStatus(dynfake, client, ClusterName, ClusterNamespace, utils.Destroying, testTimeout),
+		MonitorClusterStatus(context.TODO(), dynfake, client, ClusterName, ClusterNamespace, utils.Destroying, testTimeout),
 		"err is nil, when HostedCluster is deleted",
 	)
 }
@@ -237,7 +249,7 @@ func TestMonitorClusterStatusInstallComplete(t *testing.T) {
 
 	assert.Nil(
 		t,
-		MonitorClusterStatus(dynfake, client, ClusterName, ClusterNamespace, utils.Installing, testTimeout),
+		MonitorClusterStatus(context.TODO(), dynfake, client, ClusterName, ClusterNamespace, utils.Installing, testTimeout),
 		"err is nil, when HostedCluster install is complete",
 	)
 }
@@ -270,7 +282,7 @@ func TestMonitorClusterStatusInstallCompleteHCNoLabel(t *testing.T) {
 		},
 		))
 	s.AddKnownTypes(clustercuratorv1.SchemeBuilder.GroupVersion, &clustercuratorv1.ClusterCurator{})
-	client := clientfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(clusterCurator).Build()
+	client := clientfake.NewClientBuilder().WithScheme(s).WithStatusSubresource(&clustercuratorv1.ClusterCurator{}).WithRuntimeObjects(clusterCurator).Build()
 
 	go func() {
 		time.Sleep(utils.PauseTenSeconds)
@@ -303,7 +315,7 @@ func TestMonitorClusterStatusInstallCompleteHCNoLabel(t *testing.T) {
 
 	assert.Nil(
 		t,
-		MonitorClusterStatus(dynfake, client, ClusterName, ClusterNamespace, utils.Installing, testTimeout),
+		MonitorClusterStatus(context.TODO(), dynfake, client, ClusterName, ClusterNamespace, utils.Installing, testTimeout),
 		"err is nil, when HostedCluster install is complete",
 	)
 }
@@ -336,7 +348,7 @@ func TestMonitorClusterStatusWaitForDestroyToComplete(t *testing.T) {
 		},
 		))
 	s.AddKnownTypes(clustercuratorv1.SchemeBuilder.GroupVersion, &clustercuratorv1.ClusterCurator{})
-	client := clientfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(clusterCurator).Build()
+	client := clientfake.NewClientBuilder().WithScheme(s).WithStatusSubresource(&clustercuratorv1.ClusterCurator{}).WithRuntimeObjects(clusterCurator).Build()
 
 	go func() {
 		time.Sleep(utils.PauseTenSeconds)
@@ -347,7 +359,7 @@ func TestMonitorClusterStatusWaitForDestroyToComplete(t *testing.T) {
 
 	assert.Nil(
 		t,
-		MonitorClusterStatus(dynfake, client, ClusterName, ClusterNamespace, utils.Destroying, testTimeout),
+		MonitorClusterStatus(context.TODO(), dynfake, client, ClusterName, ClusterNamespace, utils.Destroying, testTimeout),
 		"err is nil, when HostedCluster is deleted successfully",
 	)
 }
@@ -380,7 +392,7 @@ func TestMonitorClusterStatusWaitForInstallToComplete(t *testing.T) {
 		},
 		))
 	s.AddKnownTypes(clustercuratorv1.SchemeBuilder.GroupVersion, &clustercuratorv1.ClusterCurator{})
-	client := clientfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(clusterCurator).Build()
+	client := clientfake.NewClientBuilder().WithScheme(s).WithStatusSubresource(&clustercuratorv1.ClusterCurator{}).WithRuntimeObjects(clusterCurator).Build()
 
 	go func() {
 		time.Sleep(utils.PauseTenSeconds)
@@ -413,7 +425,7 @@ func TestMonitorClusterStatusWaitForInstallToComplete(t *testing.T) {
 
 	assert.Nil(
 		t,
-		MonitorClusterStatus(dynfake, client, ClusterName, ClusterNamespace, utils.Installing, testTimeout),
+		MonitorClusterStatus(context.TODO(), dynfake, client, ClusterName, ClusterNamespace, utils.Installing, testTimeout),
 		"err is nil, when HostedCluster is deleted successfully",
 	)
 }
@@ -458,7 +470,7 @@ func TestUpgradeClusterCompleted(t *testing.T) {
 
 	assert.Nil(
 		t,
-		UpgradeCluster(client, dynfake, ClusterName, clusterCurator),
+		UpgradeCluster(context.TODO(), client, dynfake, ClusterName, clusterCurator),
 		"err is nil, when HC and NPs are patched successfully for upgrade",
 	)
 }
@@ -503,7 +515,7 @@ func TestUpgradeClusterSameVersion(t *testing.T) {
 
 	assert.NotNil(
 		t,
-		UpgradeCluster(client, dynfake, ClusterName, clusterCurator),
+		UpgradeCluster(context.TODO(), client, dynfake, ClusterName, clusterCurator),
 		"err is not nil, when HC and NPs are upgrading to the same version",
 	)
 }
@@ -544,11 +556,11 @@ func TestMonitorUpgradeStatusCompleted(t *testing.T) {
 	)
 	s.AddKnownTypes(clustercuratorv1.SchemeBuilder.GroupVersion, &clustercuratorv1.ClusterCurator{})
 	s.AddKnownTypes(managedclusterinfov1beta1.SchemeGroupVersion, &managedclusterinfov1beta1.ManagedClusterInfo{})
-	client := clientfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(clusterCurator, managedClusterInfo).Build()
+	client := clientfake.NewClientBuilder().WithScheme(s).WithStatusSubresource(&clustercuratorv1.ClusterCurator{}).WithRuntimeObjects(clusterCurator, managedClusterInfo).Build()
 
 	assert.Nil(
 		t,
-		MonitorUpgradeStatus(dynfake, client, ClusterName, clusterCurator),
+		MonitorUpgradeStatus(context.TODO(), dynfake, client, ClusterName, clusterCurator),
 		"err is nil, when HC and NPs are upgraded sucessfully",
 	)
 }
@@ -589,7 +601,7 @@ func TestMonitorUpgradeStatusWaitForUpdate(t *testing.T) {
 	)
 	s.AddKnownTypes(clustercuratorv1.SchemeBuilder.GroupVersion, &clustercuratorv1.ClusterCurator{})
 	s.AddKnownTypes(managedclusterinfov1beta1.SchemeGroupVersion, &managedclusterinfov1beta1.ManagedClusterInfo{})
-	client := clientfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(clusterCurator, managedClusterInfo).Build()
+	client := clientfake.NewClientBuilder().WithScheme(s).WithStatusSubresource(&clustercuratorv1.ClusterCurator{}).WithRuntimeObjects(clusterCurator, managedClusterInfo).Build()
 
 	go func() {
 		time.Sleep(utils.PauseTenSeconds)
@@ -627,7 +639,7 @@ func TestMonitorUpgradeStatusWaitForUpdate(t *testing.T) {
 
 	assert.Nil(
 		t,
-		MonitorUpgradeStatus(dynfake, client, ClusterName, clusterCurator),
+		MonitorUpgradeStatus(context.TODO(), dynfake, client, ClusterName, clusterCurator),
 		"err is nil, when HC and NPs are upgraded sucessfully",
 	)
 }
@@ -666,7 +678,7 @@ func TestDestroyHostedCluster(t *testing.T) {
 
 	assert.Nil(
 		t,
-		DestroyHostedCluster(dynfake, ClusterName, ClusterNamespace),
+		DestroyHostedCluster(context.TODO(), dynfake, ClusterName, ClusterNamespace),
 		"err is nil, when HC and NPs are upgraded sucessfully",
 	)
 }
@@ -707,7 +719,7 @@ func TestDetachAndMonitorAWS(t *testing.T) {
 
 	assert.NotNil(
 		t,
-		DetachAndMonitor(dynfake, ClusterName, clusterCurator),
+		DetachAndMonitor(context.TODO(), dynfake, ClusterName, clusterCurator),
 		"err is not nil, when user is destroying AWS HC",
 	)
 }
@@ -748,7 +760,55 @@ func TestDetachAndMonitorKubeVirt(t *testing.T) {
 
 	assert.Nil(
 		t,
-		DetachAndMonitor(dynfake, ClusterName, clusterCurator),
+		DetachAndMonitor(context.TODO(), dynfake, ClusterName, clusterCurator),
 		"err is nil, when user is destroying KubeVirt HC",
 	)
 }
+
+// The API calls of the step are allowed by the Role of the step
+func assertStepRulesAllow(t *testing.T, step string, actions []clienttesting.Action) {
+	rules := rbac.StepRules(step)
+	for _, action := range actions {
+		resource := action.GetResource().Resource
+		if action.GetSubresource() != "" {
+			resource += "/" + action.GetSubresource()
+		}
+		assert.True(t, rbac.RulesAllow(rules, action.GetVerb(), action.GetResource().Group, resource, ClusterName),
+			"step %v can %v %v", step, action.GetVerb(), resource)
+	}
+}
+
+func TestActivateDeployStepRules(t *testing.T) {
+	dynfake := dynfake.NewSimpleDynamicClient(
+		runtime.NewScheme(),
+		getHostedCluster("AWS", []interface{}{}),
+		getNodepool(NodepoolName, ClusterNamespace, ClusterName))
+
+	assert.Nil(t, ActivateDeploy(context.TODO(), dynfake, ClusterName, ClusterNamespace))
+	assert.NotEmpty(t, dynfake.Actions())
+	assertStepRulesAllow(t, "activate-and-monitor", dynfake.Actions())
+}
+
+func TestDestroyHostedClusterStepRules(t *testing.T) {
+	dynfake := dynfake.NewSimpleDynamicClient(runtime.NewScheme(), getHostedCluster("AWS", []interface{}{}))
+
+	assert.Nil(t, DestroyHostedCluster(context.TODO(), dynfake, ClusterName, ClusterNamespace))
+	assert.NotEmpty(t, dynfake.Actions())
+	assertStepRulesAllow(t, "destroy-cluster", dynfake.Actions())
+}
+
+func TestUpgradeClusterStepRules(t *testing.T) {
+	clusterCurator := getUpgradeClusterCurator("4.13.7")
+	dynfake := dynfake.NewSimpleDynamicClient(
+		runtime.NewScheme(),
+		getHostedCluster("AWS", []interface{}{}),
+		getNodepool(NodepoolName, ClusterNamespace, ClusterName))
+	s.AddKnownTypes(clustercuratorv1.SchemeBuilder.GroupVersion, &clustercuratorv1.ClusterCurator{})
+	s.AddKnownTypes(managedclusterinfov1beta1.SchemeGroupVersion, &managedclusterinfov1beta1.ManagedClusterInfo{})
+	client := clientfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(
+		clusterCurator, getManagedClusterInfo()).Build()
+
+	assert.Nil(t, UpgradeCluster(context.TODO(), client, dynfake, ClusterName, clusterCurator))
+	assert.NotEmpty(t, dynfake.Actions())
+	assertStepRulesAllow(t, "upgrade-cluster", dynfake.Actions())
+}
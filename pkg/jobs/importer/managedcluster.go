@@ -11,19 +11,21 @@ import (
 	"github.com/stolostron/cluster-curator-controller/pkg/jobs/utils"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/klog/v2"
 	managedclusterclient "open-cluster-management.io/api/client/cluster/clientset/versioned"
 	managedclusterv1 "open-cluster-management.io/api/cluster/v1"
 )
 
-func MonitorImport(mcset managedclusterclient.Interface, clusterName string) error {
+func MonitorImport(ctx context.Context, mcset managedclusterclient.Interface, clusterName string) error {
 
 	klog.V(0).Info("=> Monitoring ManagedCluster import of \"" + clusterName +
 		"\" using Override Template \"" + clusterName + "\"")
-	managedCluster, err := mcset.ClusterV1().ManagedClusters().Get(context.TODO(), clusterName, v1.GetOptions{})
-	if err != nil {
+	if _, err := mcset.ClusterV1().ManagedClusters().Get(ctx, clusterName, v1.GetOptions{}); err != nil {
 		return err
 	}
 
@@ -34,17 +36,21 @@ func MonitorImport(mcset managedclusterclient.Interface, clusterName string) err
 	 * Order is important. We expect the default for a few tries, then ManagedCluster joined
 	 * and finally exit when available
 	 */
-	for {
+	return utils.WaitForCondition(ctx, utils.PauseTenSeconds, func(ctx context.Context) (bool, error) {
+		managedCluster, err := mcset.ClusterV1().ManagedClusters().Get(ctx, clusterName, v1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
 		if managedCluster.Status.Conditions != nil {
 			for _, condition := range managedCluster.Status.Conditions {
 				switch condition.Type {
 
 				case managedclusterv1.ManagedClusterConditionHubDenied:
-					return errors.New("ManagedCluster join denied")
+					return false, utils.NewError(utils.ReasonImportFailed, "ManagedCluster join denied")
 
 				case managedclusterv1.ManagedClusterConditionAvailable:
 					klog.V(0).Info("ManagedCluster available")
-					return nil
+					return true, nil
 
 				case managedclusterv1.ManagedClusterConditionJoined:
 					klog.V(2).Info("ManagedCluster joined but not avaialble")
@@ -54,11 +60,19 @@ func MonitorImport(mcset managedclusterclient.Interface, clusterName string) err
 				}
 			}
 		}
-		time.Sleep(utils.PauseTenSeconds)
-	}
+		return false, nil
+	}, func(ctx context.Context) (watch.Interface, error) {
+		return mcset.ClusterV1().ManagedClusters().Watch(ctx, v1.ListOptions{
+			FieldSelector: fields.OneTermEqualSelector("metadata.name", clusterName).String(),
+		})
+	})
 }
 
-func MonitorMCInfoImport(mcset dynamic.Interface, clusterName string, curator *clustercuratorv1.ClusterCurator) error {
+func MonitorMCInfoImport(
+	ctx context.Context,
+	mcset dynamic.Interface,
+	clusterName string,
+	curator *clustercuratorv1.ClusterCurator) error {
 
 	var mciGVR = schema.GroupVersionResource{
 		Group: "internal.open-cluster-management.io", Version: "v1beta1", Resource: "managedclusterinfos"}
@@ -67,6 +81,9 @@ func MonitorMCInfoImport(mcset dynamic.Interface, clusterName string, curator *c
 
 	retryCount := utils.GetRetryTimes(curator.Spec.Install.JobMonitorTimeout, 5, utils.PauseTwoSeconds)
 
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(retryCount)*utils.PauseTwoSeconds)
+	defer cancel()
+
 	/* Two levels of status.conditions:
 	 * managedClusterAvailable
 	 * ManagedClusterJoined
@@ -74,46 +91,51 @@ func MonitorMCInfoImport(mcset dynamic.Interface, clusterName string, curator *c
 	 * Order is important. We expect the default for a few tries, then ManagedCluster joined
 	 * and finally exit when available
 	 */
-	for i := 1; i <= retryCount; i++ {
-		managedCluster, err := mcset.Resource(mciGVR).Namespace(clusterName).Get(context.TODO(), clusterName, v1.GetOptions{})
+	err := utils.WaitForCondition(ctx, utils.PauseTwoSeconds, func(ctx context.Context) (bool, error) {
+		managedCluster, err := mcset.Resource(mciGVR).Namespace(clusterName).Get(ctx, clusterName, v1.GetOptions{})
 		if err != nil {
-			return err
+			return false, err
 		}
-		if managedCluster.Object["status"].(map[string]interface{})["conditions"] != nil {
-			for _, condition := range managedCluster.Object["status"].(map[string]interface{})["conditions"].([]interface{}) {
+		conditions, _, _ := unstructured.NestedSlice(managedCluster.Object, "status", "conditions")
+		if conditions != nil {
+			for _, condition := range conditions {
 				switch condition.(map[string]interface{})["type"] {
 
 				case managedclusterv1.ManagedClusterConditionHubDenied:
-					return errors.New("ManagedCluster join denied")
+					return false, utils.NewError(utils.ReasonImportFailed, "ManagedCluster join denied")
 
 				case managedclusterv1.ManagedClusterConditionAvailable:
 					klog.V(2).Info("ManagedCluster available")
-					return nil
+					return true, nil
 
 				case managedclusterv1.ManagedClusterConditionJoined:
-					klog.V(2).Infof("ManagedCluster joined but not avaialble (%v/%v)", i, retryCount)
+					klog.V(2).Info("ManagedCluster joined but not avaialble")
 
 				default:
-					klog.V(2).Infof("Waiting for ManagedCluster to join %v (%v/%v)",
-						condition.(map[string]interface{})["message"], i, retryCount)
+					klog.V(2).Infof("Waiting for ManagedCluster to join %v",
+						condition.(map[string]interface{})["message"])
 				}
 			}
 		} else {
-			klog.V(2).Infof("Waiting for %v ManagedCluster to report conditions (%v/%v)", clusterName, i, retryCount)
+			klog.V(2).Infof("Waiting for %v ManagedCluster to report conditions", clusterName)
 		}
-		time.Sleep(utils.PauseTwoSeconds)
+		return false, nil
+	}, utils.WatchDynamic(mcset, mciGVR, clusterName, clusterName))
+
+	if errors.Is(err, utils.ErrWaitTimeout) {
+		return utils.NewError(utils.ReasonTimeout, "Time out waiting for cluster to import")
 	}
-	return errors.New("Time out waiting for cluster to import")
+	return err
 }
 
-func DetachCluster(mcset dynamic.Interface, clusterName string) error {
+func DetachCluster(ctx context.Context, mcset dynamic.Interface, clusterName string) error {
 
 	var mcGVR = schema.GroupVersionResource{
 		Group: "cluster.open-cluster-management.io", Version: "v1", Resource: "managedclusters"}
 
 	klog.V(0).Info("=> Detaching ManagedCluster \"" + clusterName)
 
-	_, err := mcset.Resource(mcGVR).Get(context.TODO(), clusterName, v1.GetOptions{})
+	_, err := mcset.Resource(mcGVR).Get(ctx, clusterName, v1.GetOptions{})
 
 	if err != nil {
 		if k8serrors.IsNotFound(err) {
@@ -125,7 +147,7 @@ func DetachCluster(mcset dynamic.Interface, clusterName string) error {
 	}
 
 	err = mcset.Resource(mcGVR).Delete(
-		context.Background(),
+		ctx,
 		clusterName,
 		v1.DeleteOptions{})
 
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	clustercuratorv1 "github.com/stolostron/cluster-curator-controller/pkg/api/v1beta1"
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/rbac"
 	"github.com/stolostron/cluster-curator-controller/pkg/jobs/utils"
 	"github.com/stretchr/testify/assert"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -22,7 +23,7 @@ const ClusterName = "my-cluster"
 
 func TestMonitorManagedClusterMissing(t *testing.T) {
 	mcset := fake.NewSimpleClientset()
-	assert.NotNil(t, MonitorImport(mcset, ClusterName), "err not nil, when no ManagedCluster object is present")
+	assert.NotNil(t, MonitorImport(context.TODO(), mcset, ClusterName), "err not nil, when no ManagedCluster object is present")
 }
 
 // Uses all three stages, otherwise it loops infinitely
@@ -51,7 +52,7 @@ func TestMonitorManagedClusterConditionAvailable(t *testing.T) {
 		},
 	})
 
-	assert.Nil(t, MonitorImport(mcset, ClusterName), "err nil, when ManagedCluster is available")
+	assert.Nil(t, MonitorImport(context.TODO(), mcset, ClusterName), "err nil, when ManagedCluster is available")
 }
 
 func TestMonitorManagedClusterConditionDenied(t *testing.T) {
@@ -73,7 +74,7 @@ func TestMonitorManagedClusterConditionDenied(t *testing.T) {
 		},
 	})
 
-	assert.NotNil(t, MonitorImport(mcset, ClusterName), "err not nil, when ManagedCluster join condition is denied")
+	assert.NotNil(t, MonitorImport(context.TODO(), mcset, ClusterName), "err not nil, when ManagedCluster join condition is denied")
 }
 
 func getManagedClusterInfos(conditionType string, conditionMessage string) *unstructured.Unstructured {
@@ -100,18 +101,30 @@ func getManagedClusterInfos(conditionType string, conditionMessage string) *unst
 func TestMonitorMCInfoConditionMissingManagedClusterInfos(t *testing.T) {
 
 	dynfake := dynfake.NewSimpleDynamicClient(runtime.NewScheme())
-	assert.NotNil(t, MonitorMCInfoImport(dynfake, ClusterName, &clustercuratorv1.ClusterCurator{}), "err not nil, when ManagedClusterInfo resource is not present")
+	assert.NotNil(t, MonitorMCInfoImport(context.TODO(), dynfake, ClusterName, &clustercuratorv1.ClusterCurator{}), "err not nil, when ManagedClusterInfo resource is not present")
 }
 func TestMonitorMCInfoConditionAvailable(t *testing.T) {
 
 	dynfake := dynfake.NewSimpleDynamicClient(runtime.NewScheme(), getManagedClusterInfos(managedclusterv1.ManagedClusterConditionAvailable, "All good"))
-	assert.Nil(t, MonitorMCInfoImport(dynfake, ClusterName, &clustercuratorv1.ClusterCurator{}), "err nil, when ManagedClusterInfos is available")
+	assert.Nil(t, MonitorMCInfoImport(context.TODO(), dynfake, ClusterName, &clustercuratorv1.ClusterCurator{}), "err nil, when ManagedClusterInfos is available")
 }
 
 func TestMonitorMCInfoConditionDenied(t *testing.T) {
 
 	dynfake := dynfake.NewSimpleDynamicClient(runtime.NewScheme(), getManagedClusterInfos(managedclusterv1.ManagedClusterConditionHubDenied, "Not Allowed"))
-	assert.NotNil(t, MonitorMCInfoImport(dynfake, ClusterName, &clustercuratorv1.ClusterCurator{}), "err not nil, when ManagedClusterInfos is denied")
+	err := MonitorMCInfoImport(context.TODO(), dynfake, ClusterName, &clustercuratorv1.ClusterCurator{})
+	assert.NotNil(t, err, "err not nil, when ManagedClusterInfos is denied")
+	assert.Equal(t, utils.ReasonImportFailed, utils.ReasonForError(err))
+}
+
+func TestMonitorMCInfoImportCanceled(t *testing.T) {
+
+	dynfake := dynfake.NewSimpleDynamicClient(runtime.NewScheme(), getManagedClusterInfos("", ""))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := MonitorMCInfoImport(ctx, dynfake, ClusterName, &clustercuratorv1.ClusterCurator{})
+	assert.Equal(t, utils.ReasonCanceled, utils.ReasonForError(err), "Canceled, when the context is canceled")
 }
 
 var mciGVR = schema.GroupVersionResource{
@@ -130,7 +143,7 @@ func TestMonitorMCInfoConditionFullFlowAvailable(t *testing.T) {
 		_, err = dynfake.Resource(mciGVR).Namespace(ClusterName).Update(context.TODO(), getManagedClusterInfos(managedclusterv1.ManagedClusterConditionAvailable, "connected"), v1.UpdateOptions{})
 		assert.Nil(t, err, "err is nill, when ManagedClusterConditionAvailable condition is updated")
 	}()
-	assert.Nil(t, MonitorMCInfoImport(dynfake, ClusterName, &clustercuratorv1.ClusterCurator{}), "err nil, when ManagedCluster is available")
+	assert.Nil(t, MonitorMCInfoImport(context.TODO(), dynfake, ClusterName, &clustercuratorv1.ClusterCurator{}), "err nil, when ManagedCluster is available")
 }
 
 // Includes a test for no-initial conditions
@@ -150,5 +163,29 @@ func TestMonitorMCInfoConditionFullFlowDenied(t *testing.T) {
 		_, err = dynfake.Resource(mciGVR).Namespace(ClusterName).Update(context.TODO(), getManagedClusterInfos(managedclusterv1.ManagedClusterConditionHubDenied, "connected"), v1.UpdateOptions{})
 		assert.Nil(t, err, "err is nill, when ManagedClusterConditionAvailable condition is updated")
 	}()
-	assert.NotNil(t, MonitorMCInfoImport(dynfake, ClusterName, &clustercuratorv1.ClusterCurator{}), "err not nil, when ManagedCluster is denied")
+	assert.NotNil(t, MonitorMCInfoImport(context.TODO(), dynfake, ClusterName, &clustercuratorv1.ClusterCurator{}), "err not nil, when ManagedCluster is denied")
+}
+
+func TestDetachClusterStepRules(t *testing.T) {
+	dynfake := dynfake.NewSimpleDynamicClient(runtime.NewScheme(), &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "cluster.open-cluster-management.io/v1",
+			"kind":       "ManagedCluster",
+			"metadata": map[string]interface{}{
+				"name": ClusterName,
+			},
+		},
+	})
+
+	assert.Nil(t, DetachCluster(context.TODO(), dynfake, ClusterName))
+
+	rules := rbac.StepClusterRules("detach-nowait", ClusterName)
+	assert.Len(t, dynfake.Actions(), 2, "the ManagedCluster is read and deleted")
+	for _, action := range dynfake.Actions() {
+		name := action.(interface{ GetName() string }).GetName()
+		assert.True(t, rbac.RulesAllow(rules, action.GetVerb(), action.GetResource().Group,
+			action.GetResource().Resource, name), "detach-nowait can %v the ManagedCluster", action.GetVerb())
+	}
+	assert.False(t, rbac.RulesAllow(rules, "delete", "cluster.open-cluster-management.io", "managedclusters",
+		"other-cluster"), "only the ManagedCluster of the cluster can be detached")
 }
@@ -0,0 +1,626 @@
+// Copyright Contributors to the Open Cluster Management project.
+package rbac
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/utils"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Curation types, each curator Job runs with the service account and Roles of its type
+const (
+	CurationInstall  = "install"
+	CurationUpgrade  = "upgrade"
+	CurationDestroy  = "destroy"
+	CurationScale    = "scale"
+	CurationPosthook = "posthook"
+)
+
+// Label of the Roles and bindings generated for one cluster, they are removed with its ClusterCurator
+const ClusterLabel = "cluster.open-cluster-management.io/curator-cluster"
+
+// Step name prefix of the applycloudprovider[-PROVIDER] steps
+const applyCloudProvider = "applycloudprovider"
+
+// API groups of the resources the curator steps use
+const (
+	groupCore       = ""
+	groupBatch      = "batch"
+	groupHive       = "hive.openshift.io"
+	groupHypershift = "hypershift.openshift.io"
+	groupCluster    = "cluster.open-cluster-management.io"
+	groupInternal   = "internal.open-cluster-management.io"
+	groupView       = "view.open-cluster-management.io"
+	groupAction     = "action.open-cluster-management.io"
+	groupTower      = "tower.ansible.com"
+)
+
+func rule(group string, resource string, verbs ...string) rbacv1.PolicyRule {
+	return rbacv1.PolicyRule{APIGroups: []string{group}, Resources: []string{resource}, Verbs: verbs}
+}
+
+// Every step records its conditions on the ClusterCurator
+var commonRules = []rbacv1.PolicyRule{
+	rule(groupCluster, "clustercurators", "get", "update", "patch"),
+	rule(groupCluster, "clustercurators/status", "update", "patch"),
+	// The curator job, a retried pod skips the steps completed by the earlier pods
+	rule(groupBatch, "jobs", "get"),
+}
+
+// Steps that share the rules of the upgrade of a standalone cluster
+var upgradeRules = []rbacv1.PolicyRule{
+	rule(groupHive, "clusterdeployments", "get"),
+	rule(groupInternal, "managedclusterinfos", "get"),
+	rule(groupView, "managedclusterviews", "get", "list", "watch", "create", "update", "delete"),
+	rule(groupAction, "managedclusteractions", "get", "create", "delete"),
+}
+
+var hookRules = []rbacv1.PolicyRule{
+	rule(groupTower, "ansiblejobs", "get", "list", "watch", "create"),
+	// The extra vars, and the install-config of the cluster
+	rule(groupHive, "clusterdeployments", "get"),
+	rule(groupInternal, "managedclusterinfos", "get"),
+	rule(groupHypershift, "hostedclusters", "get"),
+	rule(groupHypershift, "nodepools", "list"),
+	// The AnsibleJob artifacts and runner logs
+	rule(groupCore, "configmaps", "get", "create", "update"),
+	rule(groupCore, "pods", "list"),
+	rule(groupCore, "pods/log", "get"),
+}
+
+/* The rules each step needs in the ClusterCurator namespace and, for hosted clusters, in the cluster
+ * namespace. Keep them in sync with the API calls of the step, the tests of each step check them.
+ */
+var stepRules = map[string][]rbacv1.PolicyRule{
+	applyCloudProvider: {
+		rule(groupHive, "clusterdeployments", "get"),
+		rule(groupHypershift, "hostedclusters", "get"),
+		rule(groupCore, "secrets", "create"),
+	},
+	"prehook-ansiblejob":  hookRules,
+	"posthook-ansiblejob": hookRules,
+	"activate-and-monitor": {
+		rule(groupHive, "clusterdeployments", "get", "list", "watch", "update"),
+		rule(groupBatch, "jobs", "get", "list", "watch"),
+		rule(groupHypershift, "hostedclusters", "get", "list", "watch", "patch"),
+		rule(groupHypershift, "nodepools", "get", "list", "patch"),
+	},
+	"monitor-import": {
+		rule(groupInternal, "managedclusterinfos", "get", "list", "watch"),
+	},
+	"upgrade-cluster": append([]rbacv1.PolicyRule{
+		rule(groupHypershift, "hostedclusters", "get", "patch"),
+		rule(groupHypershift, "nodepools", "list", "patch"),
+	}, upgradeRules...),
+	"monitor-upgrade": append([]rbacv1.PolicyRule{
+		rule(groupHypershift, "hostedclusters", "get", "list", "watch"),
+	}, upgradeRules...),
+	"intermediate-upgrade-cluster": upgradeRules,
+	"intermediate-monitor-upgrade": upgradeRules,
+	"final-upgrade-cluster":        upgradeRules,
+	"destroy-cluster": {
+		rule(groupHive, "clusterdeployments", "get", "delete"),
+		rule(groupHypershift, "hostedclusters", "get", "delete"),
+	},
+	"monitor-destroy": {
+		rule(groupHive, "clusterdeployments", "get", "list", "watch"),
+		rule(groupBatch, "jobs", "get", "list", "watch"),
+		rule(groupHypershift, "hostedclusters", "get", "list", "watch"),
+	},
+	"detach-nowait":            {},
+	"delete-cluster-namespace": {},
+	"done":                     {},
+}
+
+/* The verbs each step needs on the secrets of the cluster, limited to their names: the secrets generated
+ * from the Provider credential, the Tower secrets of the towerAuthSecrets of an external store and the
+ * install-config. A create can not be limited to a name, it is part of stepRules: the Tower secrets are
+ * reserved by the controller, the hooks only update them.
+ */
+var stepSecretVerbs = map[string][]string{
+	applyCloudProvider:    {"get", "update", "patch"},
+	"prehook-ansiblejob":  {"get", "update", "patch"},
+	"posthook-ansiblejob": {"get", "update", "patch"},
+	// The generated secrets are deleted with the cluster
+	"monitor-destroy": {"get", "delete"},
+}
+
+/* The rules each step needs on the ManagedCluster of the cluster, it is not namespaced. A list can not be
+ * limited to a name, the ManagedCluster is watched with a metadata.name field selector.
+ */
+var stepClusterRules = map[string][]rbacv1.PolicyRule{
+	"prehook-ansiblejob":  {rule(groupCluster, "managedclusters", "get")},
+	"posthook-ansiblejob": {rule(groupCluster, "managedclusters", "get")},
+	"destroy-cluster":     {rule(groupCluster, "managedclusters", "get", "watch", "delete")},
+	"detach-nowait":       {rule(groupCluster, "managedclusters", "get", "delete")},
+}
+
+// The steps of the curator Job of each curation type, the layouts of getBatchJob
+var curationSteps = map[string][]string{
+	CurationInstall: {applyCloudProvider, "prehook-ansiblejob", "activate-and-monitor", "monitor-import",
+		"posthook-ansiblejob", "done"},
+	CurationUpgrade: {"prehook-ansiblejob", "upgrade-cluster", "monitor-upgrade", "intermediate-upgrade-cluster",
+		"intermediate-monitor-upgrade", "final-upgrade-cluster", "posthook-ansiblejob", "done"},
+	CurationDestroy:  {"prehook-ansiblejob", "destroy-cluster", "monitor-destroy", "posthook-ansiblejob", "done"},
+	CurationScale:    {"prehook-ansiblejob", "posthook-ansiblejob", "done"},
+	CurationPosthook: {"posthook-ansiblejob", "done"},
+}
+
+// CurationType - The curation type of a desiredCuration or retryPosthook, "" when it does not run a Job
+func CurationType(desiredCuration string) string {
+	switch desiredCuration {
+	case CurationInstall, CurationUpgrade, CurationDestroy, CurationScale:
+		return desiredCuration
+	case "installPosthook", "upgradePosthook":
+		return CurationPosthook
+	}
+	return ""
+}
+
+// PhaseCurationType - The curation type of a curator Job running one phase of a curation, for an operation
+func PhaseCurationType(curation string, phase string) string {
+	if phase == "posthook" {
+		return CurationPosthook
+	}
+	return CurationType(curation)
+}
+
+// CurationServiceAccount - Name of the service account, Role and RoleBinding of the curation type
+func CurationServiceAccount(curationType string) (string, error) {
+	if _, ok := curationSteps[curationType]; !ok {
+		return "", utils.NewError(utils.ReasonInvalidSpec, "No curator service account for curation type %q",
+			curationType)
+	}
+	return "curator-" + curationType, nil
+}
+
+// Name of the ClusterRole, and the credential Role, of the curation type for one cluster
+func curationClusterRoleName(serviceAccount string, clusterName string) string {
+	return serviceAccount + "-" + clusterName
+}
+
+// Name of the Role of the curation type on the secrets of one cluster
+func curationSecretsRoleName(serviceAccount string, clusterName string) string {
+	return curationClusterRoleName(serviceAccount, clusterName) + "-secrets"
+}
+
+// InstallConfigSecret - Name of the install-config secret of the cluster, read by the hooks
+func InstallConfigSecret(clusterName string) string {
+	return clusterName + "-install-config"
+}
+
+func stepKey(step string) string {
+	if strings.HasPrefix(step, applyCloudProvider) {
+		return applyCloudProvider
+	}
+	return step
+}
+
+// HasStepRules - The step has its rules, the steps of a curator Job without rules can not run
+func HasStepRules(step string) bool {
+	_, ok := stepRules[stepKey(step)]
+	return ok
+}
+
+// StepRules - The rules of the step in the ClusterCurator and cluster namespaces, with the common rules
+func StepRules(step string) []rbacv1.PolicyRule {
+	return mergeRules(commonRules, stepRules[stepKey(step)])
+}
+
+// StepClusterRules - The rules of the step on the ManagedCluster clusterName
+func StepClusterRules(step string, clusterName string) []rbacv1.PolicyRule {
+	rules := mergeRules(stepClusterRules[stepKey(step)])
+	for i := range rules {
+		rules[i].ResourceNames = []string{clusterName}
+	}
+	return rules
+}
+
+// StepSecretRules - The rules of the step on the secrets secretNames of the cluster
+func StepSecretRules(step string, secretNames []string) []rbacv1.PolicyRule {
+	verbs := stepSecretVerbs[stepKey(step)]
+	if len(verbs) == 0 {
+		return []rbacv1.PolicyRule{}
+	}
+	return namedRules(mergeRules([]rbacv1.PolicyRule{rule(groupCore, "secrets", verbs...)}), secretNames)
+}
+
+// GetCurationRules - The rules of the Role of the curation type, for every step of its Job
+func GetCurationRules(curationType string) []rbacv1.PolicyRule {
+	rules := [][]rbacv1.PolicyRule{commonRules}
+	for _, step := range curationSteps[curationType] {
+		rules = append(rules, stepRules[step])
+	}
+	return mergeRules(rules...)
+}
+
+// GetCurationClusterRules - The rules of the ClusterRole of the curation type, on the ManagedCluster clusterName
+func GetCurationClusterRules(curationType string, clusterName string) []rbacv1.PolicyRule {
+	rules := [][]rbacv1.PolicyRule{}
+	for _, step := range curationSteps[curationType] {
+		rules = append(rules, stepClusterRules[step])
+	}
+	merged := mergeRules(rules...)
+	for i := range merged {
+		merged[i].ResourceNames = []string{clusterName}
+	}
+	return merged
+}
+
+// GetCurationSecretRules - The rules of the secrets Role of the curation type, on the secrets secretNames
+func GetCurationSecretRules(curationType string, secretNames []string) []rbacv1.PolicyRule {
+	rules := [][]rbacv1.PolicyRule{}
+	for _, step := range curationSteps[curationType] {
+		if verbs := stepSecretVerbs[step]; len(verbs) > 0 {
+			rules = append(rules, []rbacv1.PolicyRule{rule(groupCore, "secrets", verbs...)})
+		}
+	}
+	return namedRules(mergeRules(rules...), secretNames)
+}
+
+// Limits the rules to the sorted names
+func namedRules(rules []rbacv1.PolicyRule, names []string) []rbacv1.PolicyRule {
+	sorted := append([]string{}, names...)
+	sort.Strings(sorted)
+	for i := range rules {
+		rules[i].ResourceNames = sorted
+	}
+	return rules
+}
+
+// GetCredentialRules - The rules of the Role that reads the Provider credential in its namespace
+func GetCredentialRules(secretName string) []rbacv1.PolicyRule {
+	return []rbacv1.PolicyRule{{
+		APIGroups:     []string{groupCore},
+		Resources:     []string{"secrets"},
+		Verbs:         []string{"get"},
+		ResourceNames: []string{secretName},
+	}}
+}
+
+/* mergeRules - One rule per API group and resource, with the verbs of every rule. The rules are sorted,
+ * so the generated Roles do not change between reconciles.
+ */
+func mergeRules(rules ...[]rbacv1.PolicyRule) []rbacv1.PolicyRule {
+	verbs := map[[2]string]map[string]bool{}
+	for _, list := range rules {
+		for _, r := range list {
+			for _, group := range r.APIGroups {
+				for _, resource := range r.Resources {
+					key := [2]string{group, resource}
+					if verbs[key] == nil {
+						verbs[key] = map[string]bool{}
+					}
+					for _, verb := range r.Verbs {
+						verbs[key][verb] = true
+					}
+				}
+			}
+		}
+	}
+
+	merged := []rbacv1.PolicyRule{}
+	for key, set := range verbs {
+		r := rbacv1.PolicyRule{APIGroups: []string{key[0]}, Resources: []string{key[1]}}
+		for verb := range set {
+			r.Verbs = append(r.Verbs, verb)
+		}
+		sort.Strings(r.Verbs)
+		merged = append(merged, r)
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		if merged[i].APIGroups[0] != merged[j].APIGroups[0] {
+			return merged[i].APIGroups[0] < merged[j].APIGroups[0]
+		}
+		return merged[i].Resources[0] < merged[j].Resources[0]
+	})
+	return merged
+}
+
+/* RulesAllow - The rules allow the verb on the resource, named name, of the API group. The subresource
+ * is part of the resource, pods/log. An empty name is a request on the collection, list or create.
+ */
+func RulesAllow(rules []rbacv1.PolicyRule, verb string, group string, resource string, name string) bool {
+	for _, r := range rules {
+		if matches(r.Verbs, verb) && matches(r.APIGroups, group) && matches(r.Resources, resource) &&
+			(len(r.ResourceNames) == 0 || (name != "" && contains(r.ResourceNames, name))) {
+			return true
+		}
+	}
+	return false
+}
+
+func matches(values []string, value string) bool {
+	return contains(values, rbacv1.VerbAll) || contains(values, value)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+/* ApplyCurationRBAC - Reconciles the service account of the curation type in the ClusterCurator namespace,
+ * with the least privileged RBAC its steps need:
+ *  - the curator-TYPE Role and RoleBinding of the ClusterCurator namespace, shared by its clusters
+ *  - the same Role and RoleBinding in the cluster namespace, of a hosted cluster
+ *  - the curator-TYPE-CLUSTER ClusterRole and ClusterRoleBinding, on the ManagedCluster only
+ *  - the curator-TYPE-CLUSTER Role and RoleBinding, on the Provider credential secret only
+ *  - the curator-TYPE-CLUSTER-secrets Role and RoleBinding, on the secrets of the cluster only, see
+ *    stepSecretVerbs, in the ClusterCurator namespace and in the cluster namespace of a hosted cluster
+ * The credentialPath is the NAMESPACE/SECRET_NAME of the Provider credential, or "". The secretNames are the
+ * secrets generated for the cluster, the install-config is added. Returns the drifted objects.
+ */
+func ApplyCurationRBAC(
+	ctx context.Context,
+	kubeset kubernetes.Interface,
+	curationType string,
+	clusterName string,
+	namespace string,
+	credentialPath string,
+	secretNames []string) ([]string, error) {
+
+	drifted := []string{}
+	name, err := CurationServiceAccount(curationType)
+	if err != nil {
+		return drifted, err
+	}
+	subjects := []rbacv1.Subject{{Kind: "ServiceAccount", Name: name, Namespace: namespace}}
+
+	klog.V(2).Infof("Check if serviceAccount %v exists", name)
+	if _, err := kubeset.CoreV1().ServiceAccounts(namespace).Get(
+		ctx, name, v1.GetOptions{}); k8serrors.IsNotFound(err) {
+
+		_, err = kubeset.CoreV1().ServiceAccounts(namespace).Create(ctx, &corev1.ServiceAccount{
+			ObjectMeta: v1.ObjectMeta{Name: name},
+		}, v1.CreateOptions{})
+		if err != nil {
+			return drifted, err
+		}
+		klog.V(0).Infof(" Created serviceAccount %v ✓", name)
+	} else if err != nil {
+		return drifted, err
+	}
+
+	namespaces := []string{namespace}
+	if clusterName != namespace {
+		namespaces = append(namespaces, clusterName)
+	}
+	for _, ns := range namespaces {
+		labels := map[string]string{}
+		if ns != namespace {
+			// The cluster namespace of a hosted cluster is only used by its ClusterCurator
+			labels[ClusterLabel] = clusterName
+		}
+		if err := applyNamespacedRole(ctx, kubeset, ns, name, labels, GetCurationRules(curationType), subjects,
+			&drifted); err != nil {
+			return drifted, err
+		}
+	}
+
+	clusterLabels := map[string]string{ClusterLabel: clusterName}
+	perCluster := curationClusterRoleName(name, clusterName)
+
+	secretRules := GetCurationSecretRules(curationType,
+		append(append([]string{}, secretNames...), InstallConfigSecret(clusterName)))
+	if len(secretRules) > 0 {
+		for _, ns := range namespaces {
+			if err := applyNamespacedRole(ctx, kubeset, ns, curationSecretsRoleName(name, clusterName), clusterLabels,
+				secretRules, subjects, &drifted); err != nil {
+				return drifted, err
+			}
+		}
+	}
+
+	if rules := GetCurationClusterRules(curationType, clusterName); len(rules) > 0 {
+		if err := applyClusterRole(ctx, kubeset, perCluster, clusterLabels, rules, subjects, &drifted); err != nil {
+			return drifted, err
+		}
+	}
+
+	if credentialPath != "" && !strings.Contains(credentialPath, "://") && curationType == CurationInstall {
+		if credentialNamespace, secretName, ok := strings.Cut(credentialPath, "/"); ok {
+			if err := applyNamespacedRole(ctx, kubeset, credentialNamespace, perCluster, clusterLabels,
+				GetCredentialRules(secretName), subjects, &drifted); err != nil {
+				return drifted, err
+			}
+		}
+	}
+	return drifted, nil
+}
+
+// Creates or updates the Role and its RoleBinding, the drifted objects are added to drifted
+func applyNamespacedRole(
+	ctx context.Context,
+	kubeset kubernetes.Interface,
+	namespace string,
+	name string,
+	labels map[string]string,
+	rules []rbacv1.PolicyRule,
+	subjects []rbacv1.Subject,
+	drifted *[]string) error {
+
+	role, err := kubeset.RbacV1().Roles(namespace).Get(ctx, name, v1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		klog.V(2).Infof(" Creating Role %v in namespace %v", name, namespace)
+		_, err = kubeset.RbacV1().Roles(namespace).Create(ctx, &rbacv1.Role{
+			ObjectMeta: v1.ObjectMeta{Name: name, Labels: labels},
+			Rules:      rules,
+		}, v1.CreateOptions{})
+		if err != nil {
+			return err
+		}
+		klog.V(0).Infof(" Created Role %v ✓", name)
+	} else if err != nil {
+		return err
+	} else if !equality.Semantic.DeepEqual(role.Rules, rules) {
+		klog.Warningf("The rules of Role %v/%v drifted, updating them", namespace, name)
+		role.Rules = rules
+		if _, err = kubeset.RbacV1().Roles(namespace).Update(ctx, role, v1.UpdateOptions{}); err != nil {
+			return err
+		}
+		*drifted = append(*drifted, "Role/"+namespace+"/"+name)
+	}
+
+	updated, err := applyRoleBinding(ctx, kubeset, namespace, &rbacv1.RoleBinding{
+		ObjectMeta: v1.ObjectMeta{Name: name, Labels: labels},
+		Subjects:   subjects,
+		RoleRef:    rbacv1.RoleRef{Kind: "Role", Name: name, APIGroup: rbacv1.GroupName},
+	})
+	if updated {
+		*drifted = append(*drifted, "RoleBinding/"+namespace+"/"+name)
+	}
+	return err
+}
+
+// Creates or updates the ClusterRole and its ClusterRoleBinding, the drifted objects are added to drifted
+func applyClusterRole(
+	ctx context.Context,
+	kubeset kubernetes.Interface,
+	name string,
+	labels map[string]string,
+	rules []rbacv1.PolicyRule,
+	subjects []rbacv1.Subject,
+	drifted *[]string) error {
+
+	clusterRole, err := kubeset.RbacV1().ClusterRoles().Get(ctx, name, v1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		klog.V(2).Infof(" Creating ClusterRole %v", name)
+		_, err = kubeset.RbacV1().ClusterRoles().Create(ctx, &rbacv1.ClusterRole{
+			ObjectMeta: v1.ObjectMeta{Name: name, Labels: labels},
+			Rules:      rules,
+		}, v1.CreateOptions{})
+		if err != nil {
+			return err
+		}
+		klog.V(0).Infof(" Created ClusterRole %v ✓", name)
+	} else if err != nil {
+		return err
+	} else if !equality.Semantic.DeepEqual(clusterRole.Rules, rules) {
+		klog.Warningf("The rules of ClusterRole %v drifted, updating them", name)
+		clusterRole.Rules = rules
+		if _, err = kubeset.RbacV1().ClusterRoles().Update(ctx, clusterRole, v1.UpdateOptions{}); err != nil {
+			return err
+		}
+		*drifted = append(*drifted, "ClusterRole/"+name)
+	}
+
+	desired := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: v1.ObjectMeta{Name: name, Labels: labels},
+		Subjects:   subjects,
+		RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", Name: name, APIGroup: rbacv1.GroupName},
+	}
+	crb, err := kubeset.RbacV1().ClusterRoleBindings().Get(ctx, name, v1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		_, err = kubeset.RbacV1().ClusterRoleBindings().Create(ctx, desired, v1.CreateOptions{})
+		return err
+	} else if err != nil {
+		return err
+	}
+	if crb.RoleRef == desired.RoleRef && equality.Semantic.DeepEqual(crb.Subjects, desired.Subjects) {
+		return nil
+	}
+
+	klog.Warningf("ClusterRoleBinding %v drifted, updating it", name)
+	if crb.RoleRef != desired.RoleRef {
+		err = recreateClusterRoleBinding(ctx, kubeset, desired)
+	} else {
+		crb.Subjects = desired.Subjects
+		_, err = kubeset.RbacV1().ClusterRoleBindings().Update(ctx, crb, v1.UpdateOptions{})
+	}
+	if err == nil {
+		*drifted = append(*drifted, "ClusterRoleBinding/"+name)
+	}
+	return err
+}
+
+// RemoveCurationRBAC - Removes the Roles and bindings generated for the cluster, with its ClusterCurator
+func RemoveCurationRBAC(ctx context.Context, kubeset kubernetes.Interface, clusterName string) error {
+	selector := v1.ListOptions{LabelSelector: ClusterLabel + "=" + clusterName}
+
+	crbs, err := kubeset.RbacV1().ClusterRoleBindings().List(ctx, selector)
+	if err != nil {
+		return err
+	}
+	for _, crb := range crbs.Items {
+		if err = ignoreNotFound(kubeset.RbacV1().ClusterRoleBindings().Delete(
+			ctx, crb.Name, v1.DeleteOptions{})); err != nil {
+			return err
+		}
+	}
+
+	clusterRoles, err := kubeset.RbacV1().ClusterRoles().List(ctx, selector)
+	if err != nil {
+		return err
+	}
+	for _, clusterRole := range clusterRoles.Items {
+		if err = ignoreNotFound(kubeset.RbacV1().ClusterRoles().Delete(
+			ctx, clusterRole.Name, v1.DeleteOptions{})); err != nil {
+			return err
+		}
+	}
+
+	roleBindings, err := kubeset.RbacV1().RoleBindings("").List(ctx, selector)
+	if err != nil {
+		return err
+	}
+	for _, roleBinding := range roleBindings.Items {
+		if err = ignoreNotFound(kubeset.RbacV1().RoleBindings(roleBinding.Namespace).Delete(
+			ctx, roleBinding.Name, v1.DeleteOptions{})); err != nil {
+			return err
+		}
+	}
+
+	roles, err := kubeset.RbacV1().Roles("").List(ctx, selector)
+	if err != nil {
+		return err
+	}
+	for _, role := range roles.Items {
+		if err = ignoreNotFound(kubeset.RbacV1().Roles(role.Namespace).Delete(
+			ctx, role.Name, v1.DeleteOptions{})); err != nil {
+			return err
+		}
+	}
+	klog.V(2).Infof("Removed the curator RBAC of cluster %v", clusterName)
+	return nil
+}
+
+// Removes the curation types RBAC of the namespace, once it has no ClusterCurator left
+func removeNamespaceCurationRBAC(ctx context.Context, kubeset kubernetes.Interface, namespace string) error {
+	for curationType := range curationSteps {
+		name, _ := CurationServiceAccount(curationType)
+		if err := ignoreNotFound(kubeset.RbacV1().RoleBindings(namespace).Delete(
+			ctx, name, v1.DeleteOptions{})); err != nil {
+			return err
+		}
+		if err := ignoreNotFound(kubeset.RbacV1().Roles(namespace).Delete(
+			ctx, name, v1.DeleteOptions{})); err != nil {
+			return err
+		}
+		if err := ignoreNotFound(kubeset.CoreV1().ServiceAccounts(namespace).Delete(
+			ctx, name, v1.DeleteOptions{})); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ignoreNotFound(err error) error {
+	if k8serrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
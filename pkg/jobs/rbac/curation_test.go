@@ -0,0 +1,371 @@
+// Copyright Contributors to the Open Cluster Management project.
+package rbac
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/yaml"
+)
+
+const HostedClusterName = "my-hosted-cluster"
+
+func TestCurationType(t *testing.T) {
+	assert.Equal(t, CurationInstall, CurationType("install"))
+	assert.Equal(t, CurationUpgrade, CurationType("upgrade"))
+	assert.Equal(t, CurationDestroy, CurationType("destroy"))
+	assert.Equal(t, CurationScale, CurationType("scale"))
+	assert.Equal(t, CurationPosthook, CurationType("installPosthook"))
+	assert.Equal(t, CurationPosthook, CurationType("upgradePosthook"))
+	assert.Equal(t, "", CurationType("unknown"))
+	serviceAccount, err := CurationServiceAccount(CurationInstall)
+	assert.Nil(t, err)
+	assert.Equal(t, "curator-install", serviceAccount)
+	_, err = CurationServiceAccount(CurationType("unknown"))
+	assert.NotNil(t, err, "no service account without a curation type")
+}
+
+func curationName(curationType string) string {
+	name, _ := CurationServiceAccount(curationType)
+	return name
+}
+
+func TestRulesAllow(t *testing.T) {
+	rules := []rbacv1.PolicyRule{
+		rule(groupHive, "clusterdeployments", "get", "update"),
+		rule(groupCore, "pods/log", "get"),
+		{APIGroups: []string{groupCluster}, Resources: []string{"managedclusters"}, Verbs: []string{"get"},
+			ResourceNames: []string{ClusterName}},
+		rule(groupBatch, rbacv1.ResourceAll, rbacv1.VerbAll),
+	}
+
+	assert.True(t, RulesAllow(rules, "get", groupHive, "clusterdeployments", ClusterName))
+	assert.True(t, RulesAllow(rules, "update", groupHive, "clusterdeployments", ClusterName))
+	assert.False(t, RulesAllow(rules, "delete", groupHive, "clusterdeployments", ClusterName))
+	assert.False(t, RulesAllow(rules, "get", groupHypershift, "clusterdeployments", ClusterName))
+	assert.True(t, RulesAllow(rules, "get", groupCore, "pods/log", "pod"))
+	assert.False(t, RulesAllow(rules, "get", groupCore, "pods", "pod"), "the subresource is not the resource")
+	assert.True(t, RulesAllow(rules, "get", groupCluster, "managedclusters", ClusterName))
+	assert.False(t, RulesAllow(rules, "get", groupCluster, "managedclusters", "other-cluster"))
+	assert.False(t, RulesAllow(rules, "list", groupCluster, "managedclusters", ""),
+		"a rule with resourceNames does not allow the collection")
+	assert.True(t, RulesAllow(rules, "delete", groupBatch, "jobs", "job"))
+}
+
+func TestMergeRules(t *testing.T) {
+	merged := mergeRules(
+		[]rbacv1.PolicyRule{rule(groupHive, "clusterdeployments", "get", "update")},
+		[]rbacv1.PolicyRule{
+			rule(groupHive, "clusterdeployments", "get", "delete"),
+			{APIGroups: []string{groupCore, groupBatch}, Resources: []string{"secrets"}, Verbs: []string{"get"}},
+		})
+
+	assert.Equal(t, []rbacv1.PolicyRule{
+		rule(groupCore, "secrets", "get"),
+		rule(groupBatch, "secrets", "get"),
+		rule(groupHive, "clusterdeployments", "delete", "get", "update"),
+	}, merged)
+}
+
+func TestCurationRulesCoverTheSteps(t *testing.T) {
+	for curationType, steps := range curationSteps {
+		rules := GetCurationRules(curationType)
+		clusterRules := GetCurationClusterRules(curationType, ClusterName)
+		secretRules := GetCurationSecretRules(curationType, []string{ClusterName + "-creds"})
+
+		for _, step := range steps {
+			assert.True(t, HasStepRules(step), "step %v of %v has rules", step, curationType)
+			for _, r := range StepRules(step) {
+				for _, verb := range r.Verbs {
+					assert.True(t, RulesAllow(rules, verb, r.APIGroups[0], r.Resources[0], ClusterName),
+						"the %v Role allows %v %v for step %v", curationType, verb, r.Resources[0], step)
+				}
+			}
+			for _, r := range StepSecretRules(step, []string{ClusterName + "-creds"}) {
+				for _, verb := range r.Verbs {
+					assert.True(t, RulesAllow(secretRules, verb, groupCore, "secrets", ClusterName+"-creds"),
+						"the %v secrets Role allows %v for step %v", curationType, verb, step)
+				}
+			}
+			for _, r := range StepClusterRules(step, ClusterName) {
+				for _, verb := range r.Verbs {
+					assert.True(t, RulesAllow(clusterRules, verb, r.APIGroups[0], r.Resources[0], ClusterName),
+						"the %v ClusterRole allows %v %v for step %v", curationType, verb, r.Resources[0], step)
+				}
+			}
+		}
+		for _, r := range clusterRules {
+			assert.Equal(t, []string{ClusterName}, r.ResourceNames, "the ClusterRole is limited to the cluster")
+		}
+		for _, r := range secretRules {
+			assert.Equal(t, []string{ClusterName + "-creds"}, r.ResourceNames, "the secrets Role is limited to them")
+		}
+		for _, verb := range []string{"get", "list", "update", "delete"} {
+			assert.False(t, RulesAllow(rules, verb, groupCore, "secrets", "other-cluster-creds"),
+				"the %v Role can not %v the secrets of the shared namespace", curationType, verb)
+		}
+	}
+	assert.True(t, HasStepRules("applycloudprovider-aws"))
+	assert.False(t, HasStepRules("unknown-step"))
+}
+
+func TestCurationRulesLeastPrivilege(t *testing.T) {
+	install := GetCurationRules(CurationInstall)
+	assert.False(t, RulesAllow(install, "delete", groupHive, "clusterdeployments", ClusterName),
+		"install can not destroy the cluster")
+	assert.False(t, RulesAllow(install, "create", groupAction, "managedclusteractions", ""),
+		"install can not upgrade the cluster")
+
+	destroy := GetCurationRules(CurationDestroy)
+	assert.True(t, RulesAllow(destroy, "delete", groupHive, "clusterdeployments", ClusterName))
+	assert.False(t, RulesAllow(destroy, "update", groupHive, "clusterdeployments", ClusterName))
+
+	posthook := GetCurationRules(CurationPosthook)
+	assert.True(t, RulesAllow(posthook, "create", groupTower, "ansiblejobs", ""))
+	assert.False(t, RulesAllow(posthook, "create", groupCore, "secrets", ""), "the Tower secrets are reserved")
+	assert.False(t, RulesAllow(posthook, "patch", groupHypershift, "hostedclusters", ClusterName))
+
+	assert.False(t, RulesAllow(GetCurationClusterRules(CurationDestroy, ClusterName), "list", groupCluster,
+		"managedclusters", ""), "a list can not be limited to the ManagedCluster")
+	assert.False(t, RulesAllow(GetCurationClusterRules(CurationUpgrade, ClusterName), "delete", groupCluster,
+		"managedclusters", ClusterName), "upgrade can not detach the ManagedCluster")
+	for _, curationType := range []string{CurationInstall, CurationUpgrade, CurationDestroy, CurationScale} {
+		assert.False(t, RulesAllow(GetCurationRules(curationType), "create", "rbac.authorization.k8s.io", "roles", ""))
+		assert.False(t, RulesAllow(GetCurationRules(curationType), "get", groupCore, "serviceaccounts", "curator"))
+		assert.True(t, RulesAllow(GetCurationRules(curationType), "get", groupBatch, "jobs", "curator-job-d9pwh"),
+			"the curator reads the retries of its job")
+	}
+}
+
+// The controller can only grant the rules it holds, its ClusterRole has no escalate or bind verb
+func TestControllerHoldsGrantedRules(t *testing.T) {
+	manifest, err := os.ReadFile("../../../deploy/controller/clusterrole.yaml")
+	assert.Nil(t, err)
+	controller := &rbacv1.ClusterRole{}
+	assert.Nil(t, yaml.Unmarshal(manifest, controller))
+	for _, verb := range []string{"escalate", "bind"} {
+		assert.False(t, RulesAllow(controller.Rules, verb, "rbac.authorization.k8s.io", "roles", ""))
+		assert.False(t, RulesAllow(controller.Rules, verb, "rbac.authorization.k8s.io", "clusterroles", ""))
+	}
+
+	secretNames := []string{ClusterName + "-creds"}
+	granted := [][]rbacv1.PolicyRule{
+		getRole(ClusterName).Rules,
+		getClusterRole(ClusterName).Rules,
+		GetGeneratedSecretsRules(secretNames),
+		GetCredentialRules(ClusterName + "-creds"),
+	}
+	for curationType := range curationSteps {
+		granted = append(granted, GetCurationRules(curationType), GetCurationClusterRules(curationType, ClusterName),
+			GetCurationSecretRules(curationType, secretNames))
+	}
+	for _, rules := range granted {
+		for _, r := range rules {
+			name := ""
+			if len(r.ResourceNames) > 0 {
+				name = r.ResourceNames[0]
+			}
+			for _, group := range r.APIGroups {
+				for _, resource := range r.Resources {
+					for _, verb := range r.Verbs {
+						assert.True(t, RulesAllow(controller.Rules, verb, group, resource, name),
+							"the controller holds %v %v.%v", verb, resource, group)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestApplyCurationRBAC(t *testing.T) {
+	kubeset := fake.NewSimpleClientset()
+
+	secretNames := []string{ClusterName + "-creds", "toweraccess"}
+	drifted, err := ApplyCurationRBAC(context.TODO(), kubeset, CurationInstall, ClusterName, ClusterName,
+		"creds/aws-creds", secretNames)
+	assert.Nil(t, err)
+	assert.Empty(t, drifted, "created objects did not drift")
+
+	name := curationName(CurationInstall)
+	_, err = kubeset.CoreV1().ServiceAccounts(ClusterName).Get(context.TODO(), name, v1.GetOptions{})
+	assert.Nil(t, err, "the service account is created")
+
+	role, err := kubeset.RbacV1().Roles(ClusterName).Get(context.TODO(), name, v1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, GetCurationRules(CurationInstall), role.Rules)
+
+	roleBinding, err := kubeset.RbacV1().RoleBindings(ClusterName).Get(context.TODO(), name, v1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []rbacv1.Subject{{Kind: "ServiceAccount", Name: name, Namespace: ClusterName}},
+		roleBinding.Subjects)
+	assert.Equal(t, rbacv1.RoleRef{Kind: "Role", Name: name, APIGroup: rbacv1.GroupName}, roleBinding.RoleRef)
+
+	perCluster := name + "-" + ClusterName
+	clusterRole, err := kubeset.RbacV1().ClusterRoles().Get(context.TODO(), perCluster, v1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, ClusterName, clusterRole.Labels[ClusterLabel])
+	assert.True(t, RulesAllow(clusterRole.Rules, "get", groupCluster, "managedclusters", ClusterName))
+	assert.False(t, RulesAllow(clusterRole.Rules, "get", groupCluster, "managedclusters", "other-cluster"))
+
+	_, err = kubeset.RbacV1().ClusterRoleBindings().Get(context.TODO(), perCluster, v1.GetOptions{})
+	assert.Nil(t, err)
+
+	credentialRole, err := kubeset.RbacV1().Roles("creds").Get(context.TODO(), perCluster, v1.GetOptions{})
+	assert.Nil(t, err, "the credential namespace has a Role for the Provider credential")
+	assert.True(t, RulesAllow(credentialRole.Rules, "get", groupCore, "secrets", "aws-creds"))
+	assert.False(t, RulesAllow(credentialRole.Rules, "get", groupCore, "secrets", "other-creds"))
+	_, err = kubeset.RbacV1().RoleBindings("creds").Get(context.TODO(), perCluster, v1.GetOptions{})
+	assert.Nil(t, err)
+
+	secretsRole, err := kubeset.RbacV1().Roles(ClusterName).Get(context.TODO(), perCluster+"-secrets", v1.GetOptions{})
+	assert.Nil(t, err, "the secrets of the cluster have their own Role")
+	assert.Equal(t, ClusterName, secretsRole.Labels[ClusterLabel])
+	assert.True(t, RulesAllow(secretsRole.Rules, "update", groupCore, "secrets", ClusterName+"-creds"))
+	assert.True(t, RulesAllow(secretsRole.Rules, "get", groupCore, "secrets", ClusterName+"-install-config"))
+	assert.False(t, RulesAllow(secretsRole.Rules, "get", groupCore, "secrets", "other-cluster-creds"))
+	assert.False(t, RulesAllow(role.Rules, "get", groupCore, "secrets", "other-cluster-creds"),
+		"the shared Role can not read the secrets of the namespace")
+
+	t.Log("Apply again, nothing drifted")
+	drifted, err = ApplyCurationRBAC(context.TODO(), kubeset, CurationInstall, ClusterName, ClusterName,
+		"creds/aws-creds", secretNames)
+	assert.Nil(t, err)
+	assert.Empty(t, drifted)
+}
+
+func TestApplyCurationRBACExternalCredential(t *testing.T) {
+	kubeset := fake.NewSimpleClientset()
+
+	_, err := ApplyCurationRBAC(context.TODO(), kubeset, CurationInstall, ClusterName, ClusterName,
+		"vault://secret/aws", nil)
+	assert.Nil(t, err)
+
+	roles, err := kubeset.RbacV1().Roles("").List(context.TODO(), v1.ListOptions{})
+	assert.Nil(t, err)
+	for _, role := range roles.Items {
+		assert.Equal(t, ClusterName, role.Namespace, "a credential kept in Vault has no Role")
+	}
+}
+
+func TestApplyCurationRBACHostedCluster(t *testing.T) {
+	kubeset := fake.NewSimpleClientset()
+
+	_, err := ApplyCurationRBAC(context.TODO(), kubeset, CurationDestroy, HostedClusterName, ClusterNamespace, "", nil)
+	assert.Nil(t, err)
+
+	name := curationName(CurationDestroy)
+	for _, ns := range []string{ClusterNamespace, HostedClusterName} {
+		role, err := kubeset.RbacV1().Roles(ns).Get(context.TODO(), name, v1.GetOptions{})
+		assert.Nil(t, err, "the Role is created in namespace %v", ns)
+		assert.Equal(t, GetCurationRules(CurationDestroy), role.Rules)
+
+		roleBinding, err := kubeset.RbacV1().RoleBindings(ns).Get(context.TODO(), name, v1.GetOptions{})
+		assert.Nil(t, err)
+		assert.Equal(t, ClusterNamespace, roleBinding.Subjects[0].Namespace,
+			"the service account of the ClusterCurator namespace is bound")
+	}
+
+	clusterRole, err := kubeset.RbacV1().ClusterRoles().Get(
+		context.TODO(), name+"-"+HostedClusterName, v1.GetOptions{})
+	assert.Nil(t, err)
+	assert.True(t, RulesAllow(clusterRole.Rules, "delete", groupCluster, "managedclusters", HostedClusterName))
+}
+
+func TestApplyCurationRBACDrift(t *testing.T) {
+	kubeset := fake.NewSimpleClientset()
+	name := curationName(CurationUpgrade)
+
+	_, err := ApplyCurationRBAC(context.TODO(), kubeset, CurationUpgrade, ClusterName, ClusterName, "", nil)
+	assert.Nil(t, err)
+
+	role, err := kubeset.RbacV1().Roles(ClusterName).Get(context.TODO(), name, v1.GetOptions{})
+	assert.Nil(t, err)
+	role.Rules = append(role.Rules, rule(groupHive, "clusterdeployments", "delete"))
+	_, err = kubeset.RbacV1().Roles(ClusterName).Update(context.TODO(), role, v1.UpdateOptions{})
+	assert.Nil(t, err)
+
+	roleBinding, err := kubeset.RbacV1().RoleBindings(ClusterName).Get(context.TODO(), name, v1.GetOptions{})
+	assert.Nil(t, err)
+	roleBinding.Subjects = append(roleBinding.Subjects,
+		rbacv1.Subject{Kind: "ServiceAccount", Name: "default", Namespace: ClusterName})
+	_, err = kubeset.RbacV1().RoleBindings(ClusterName).Update(context.TODO(), roleBinding, v1.UpdateOptions{})
+	assert.Nil(t, err)
+
+	drifted, err := ApplyCurationRBAC(context.TODO(), kubeset, CurationUpgrade, ClusterName, ClusterName, "", nil)
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{
+		"Role/" + ClusterName + "/" + name,
+		"RoleBinding/" + ClusterName + "/" + name,
+	}, drifted)
+
+	role, err = kubeset.RbacV1().Roles(ClusterName).Get(context.TODO(), name, v1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, GetCurationRules(CurationUpgrade), role.Rules, "the added rule is removed")
+
+	roleBinding, err = kubeset.RbacV1().RoleBindings(ClusterName).Get(context.TODO(), name, v1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, roleBinding.Subjects, 1, "the added subject is removed")
+}
+
+func TestRemoveCurationRBAC(t *testing.T) {
+	kubeset := fake.NewSimpleClientset()
+
+	_, err := ApplyCurationRBAC(context.TODO(), kubeset, CurationInstall, ClusterName, ClusterName, "creds/aws-creds", nil)
+	assert.Nil(t, err)
+	_, err = ApplyCurationRBAC(context.TODO(), kubeset, CurationDestroy, HostedClusterName, ClusterNamespace, "", nil)
+	assert.Nil(t, err)
+
+	assert.Nil(t, RemoveCurationRBAC(context.TODO(), kubeset, HostedClusterName))
+
+	_, err = kubeset.RbacV1().Roles(HostedClusterName).Get(
+		context.TODO(), curationName(CurationDestroy), v1.GetOptions{})
+	assert.True(t, k8serrors.IsNotFound(err), "the Role of the hosted cluster namespace is removed")
+	_, err = kubeset.RbacV1().ClusterRoles().Get(
+		context.TODO(), curationName(CurationDestroy)+"-"+HostedClusterName, v1.GetOptions{})
+	assert.True(t, k8serrors.IsNotFound(err))
+	_, err = kubeset.RbacV1().ClusterRoleBindings().Get(
+		context.TODO(), curationName(CurationDestroy)+"-"+HostedClusterName, v1.GetOptions{})
+	assert.True(t, k8serrors.IsNotFound(err))
+
+	_, err = kubeset.RbacV1().Roles(ClusterNamespace).Get(
+		context.TODO(), curationName(CurationDestroy), v1.GetOptions{})
+	assert.Nil(t, err, "the Role of the ClusterCurator namespace is shared, it is kept")
+
+	_, err = kubeset.RbacV1().ClusterRoles().Get(
+		context.TODO(), curationName(CurationInstall)+"-"+ClusterName, v1.GetOptions{})
+	assert.Nil(t, err, "the RBAC of the other cluster is kept")
+
+	assert.Nil(t, RemoveCurationRBAC(context.TODO(), kubeset, ClusterName))
+	_, err = kubeset.RbacV1().Roles("creds").Get(
+		context.TODO(), curationName(CurationInstall)+"-"+ClusterName, v1.GetOptions{})
+	assert.True(t, k8serrors.IsNotFound(err), "the credential Role is removed")
+}
+
+func TestRemoveRBACRemovesCurationServiceAccounts(t *testing.T) {
+	kubeset := fake.NewSimpleClientset()
+
+	_, err := ApplyCurationRBAC(context.TODO(), kubeset, CurationScale, ClusterName, ClusterName, "", nil)
+	assert.Nil(t, err)
+	_, err = kubeset.CoreV1().ServiceAccounts(ClusterName).Create(context.TODO(), &corev1.ServiceAccount{
+		ObjectMeta: v1.ObjectMeta{Name: "default"},
+	}, v1.CreateOptions{})
+	assert.Nil(t, err)
+
+	assert.Nil(t, RemoveRBAC(context.TODO(), kubeset, ClusterName))
+
+	_, err = kubeset.CoreV1().ServiceAccounts(ClusterName).Get(
+		context.TODO(), curationName(CurationScale), v1.GetOptions{})
+	assert.True(t, k8serrors.IsNotFound(err))
+	_, err = kubeset.RbacV1().Roles(ClusterName).Get(
+		context.TODO(), curationName(CurationScale), v1.GetOptions{})
+	assert.True(t, k8serrors.IsNotFound(err))
+	_, err = kubeset.CoreV1().ServiceAccounts(ClusterName).Get(context.TODO(), "default", v1.GetOptions{})
+	assert.Nil(t, err, "other service accounts are kept")
+}
@@ -4,6 +4,7 @@ package rbac
 import (
 	"context"
 	"errors"
+	"sort"
 	"time"
 
 	"github.com/stolostron/cluster-curator-controller/pkg/jobs/utils"
@@ -11,6 +12,7 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
@@ -35,32 +37,43 @@ func getRole(clusterName string) *rbacv1.Role {
 			rbacv1.PolicyRule{
 				APIGroups: []string{"hypershift.openshift.io"},
 				Resources: []string{"hostedclusters", "nodepools"},
-				Verbs:     []string{"get", "patch", "delete", "update", "list"},
+				Verbs:     []string{"get", "patch", "delete", "update", "list", "watch"},
 			},
 			rbacv1.PolicyRule{
 				APIGroups: []string{"batch", "hive.openshift.io", "tower.ansible.com"},
 				Resources: []string{"jobs", "clusterdeployments", "ansiblejobs", "machinepools"},
-				Verbs:     []string{"get"},
+				Verbs:     []string{"get", "list", "watch"},
 			},
 			rbacv1.PolicyRule{
 				APIGroups: []string{""},
 				Resources: []string{"configmaps"},
-				Verbs:     []string{"update", "get", "patch"},
+				Verbs:     []string{"create", "update", "get", "patch"},
+			},
+			// To collect the AnsibleJob runner logs
+			rbacv1.PolicyRule{
+				APIGroups: []string{""},
+				Resources: []string{"pods", "pods/log"},
+				Verbs:     []string{"get", "list"},
 			},
 			rbacv1.PolicyRule{
 				APIGroups: []string{"internal.open-cluster-management.io"},
 				Resources: []string{"managedclusterinfos"},
-				Verbs:     []string{"get"},
+				Verbs:     []string{"get", "list", "watch"},
 			},
 			rbacv1.PolicyRule{
 				APIGroups: []string{"cluster.open-cluster-management.io"},
 				Resources: []string{"clustercurators", "managedclusters"},
-				Verbs:     []string{"get", "update", "patch", "delete"},
+				Verbs:     []string{"get", "update", "patch", "delete", "list", "watch"},
+			},
+			rbacv1.PolicyRule{
+				APIGroups: []string{"cluster.open-cluster-management.io"},
+				Resources: []string{"clustercurators/status"},
+				Verbs:     []string{"get", "update", "patch"},
 			},
 			rbacv1.PolicyRule{
 				APIGroups: []string{"view.open-cluster-management.io"},
 				Resources: []string{"managedclusterviews"},
-				Verbs:     []string{"get", "create", "update", "delete"},
+				Verbs:     []string{"get", "create", "update", "delete", "list", "watch"},
 			},
 			rbacv1.PolicyRule{
 				APIGroups: []string{"action.open-cluster-management.io"},
@@ -96,32 +109,43 @@ func getClusterRole(clusterName string) *rbacv1.ClusterRole {
 			rbacv1.PolicyRule{
 				APIGroups: []string{"hypershift.openshift.io"},
 				Resources: []string{"hostedclusters", "nodepools"},
-				Verbs:     []string{"get", "patch", "delete", "update", "list"},
+				Verbs:     []string{"get", "patch", "delete", "update", "list", "watch"},
 			},
 			rbacv1.PolicyRule{
 				APIGroups: []string{"batch", "hive.openshift.io", "tower.ansible.com"},
 				Resources: []string{"jobs", "clusterdeployments", "ansiblejobs", "machinepools"},
-				Verbs:     []string{"get"},
+				Verbs:     []string{"get", "list", "watch"},
 			},
 			rbacv1.PolicyRule{
 				APIGroups: []string{""},
 				Resources: []string{"configmaps"},
-				Verbs:     []string{"update", "get", "patch"},
+				Verbs:     []string{"create", "update", "get", "patch"},
+			},
+			// To collect the AnsibleJob runner logs
+			rbacv1.PolicyRule{
+				APIGroups: []string{""},
+				Resources: []string{"pods", "pods/log"},
+				Verbs:     []string{"get", "list"},
 			},
 			rbacv1.PolicyRule{
 				APIGroups: []string{"internal.open-cluster-management.io"},
 				Resources: []string{"managedclusterinfos"},
-				Verbs:     []string{"get"},
+				Verbs:     []string{"get", "list", "watch"},
 			},
 			rbacv1.PolicyRule{
 				APIGroups: []string{"cluster.open-cluster-management.io"},
 				Resources: []string{"clustercurators", "managedclusters"},
-				Verbs:     []string{"get", "update", "patch", "delete"},
+				Verbs:     []string{"get", "update", "patch", "delete", "list", "watch"},
+			},
+			rbacv1.PolicyRule{
+				APIGroups: []string{"cluster.open-cluster-management.io"},
+				Resources: []string{"clustercurators/status"},
+				Verbs:     []string{"get", "update", "patch"},
 			},
 			rbacv1.PolicyRule{
 				APIGroups: []string{"view.open-cluster-management.io"},
 				Resources: []string{"managedclusterviews"},
-				Verbs:     []string{"get", "create", "update", "delete"},
+				Verbs:     []string{"get", "create", "update", "delete", "list", "watch"},
 			},
 			rbacv1.PolicyRule{
 				APIGroups: []string{"action.open-cluster-management.io"},
@@ -164,7 +188,7 @@ func getClusterInstallerRules() []rbacv1.PolicyRule {
 		},
 		rbacv1.PolicyRule{
 			APIGroups: []string{"cluster.open-cluster-management.io"},
-			Resources: []string{"clustercurators"},
+			Resources: []string{"clustercurators", "clustercurators/status"},
 			Verbs:     []string{"get", "update", "patch"},
 		},
 	}
@@ -216,88 +240,307 @@ func getServiceAccount() *corev1.ServiceAccount {
 	return serviceAccount
 }
 
-func ApplyRBAC(kubeset kubernetes.Interface, namespace string) error {
+/* ApplyRBAC - Reconciles the cluster-installer ServiceAccount, the curator ClusterRole and the curator
+ * RoleBinding of the namespace to their desired state. They are created when missing and updated when
+ * they drifted, after an upgrade of the controller added rules for example. Returns the drifted objects.
+ */
+func ApplyRBAC(ctx context.Context, kubeset kubernetes.Interface, namespace string) ([]string, error) {
+	drifted := []string{}
 
 	klog.V(2).Info("Check if serviceAccount cluster-installer exists")
 	if _, err := kubeset.CoreV1().ServiceAccounts(namespace).Get(
-		context.TODO(), "cluster-installer", v1.GetOptions{}); err != nil {
+		ctx, clusterInstaller, v1.GetOptions{}); k8serrors.IsNotFound(err) {
 
 		klog.V(2).Info(" Creating serviceAccount cluster-installer")
 		_, err = kubeset.CoreV1().ServiceAccounts(namespace).Create(
-			context.TODO(), getServiceAccount(), v1.CreateOptions{})
+			ctx, getServiceAccount(), v1.CreateOptions{})
 
 		if err != nil {
-			return err
+			return drifted, err
 		}
 		klog.V(0).Info(" Created serviceAccount ✓")
+	} else if err != nil {
+		return drifted, err
 	}
 
-	klog.V(2).Info("Check if ClusterRole curator exists")
-	if _, err := kubeset.RbacV1().ClusterRoles().Get(context.TODO(), "curator", v1.GetOptions{}); err != nil {
+	klog.V(2).Info("Check if ClusterRole curator is up to date")
+	desiredRole := getClusterRole(namespace)
+	clusterRole, err := kubeset.RbacV1().ClusterRoles().Get(ctx, desiredRole.Name, v1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
 		klog.V(2).Info(" Creating ClusterRole curator")
-		_, err = kubeset.RbacV1().ClusterRoles().Create(context.TODO(), getClusterRole(namespace), v1.CreateOptions{})
-		if err != nil {
-			return err
+		if _, err = kubeset.RbacV1().ClusterRoles().Create(ctx, desiredRole, v1.CreateOptions{}); err != nil {
+			return drifted, err
 		}
 		klog.V(0).Info(" Created ClusterRole ✓")
+	} else if err != nil {
+		return drifted, err
+	} else if !equality.Semantic.DeepEqual(clusterRole.Rules, desiredRole.Rules) {
+		klog.Warningf("The rules of ClusterRole %v drifted, updating them", clusterRole.Name)
+		clusterRole.Rules = desiredRole.Rules
+		if _, err = kubeset.RbacV1().ClusterRoles().Update(ctx, clusterRole, v1.UpdateOptions{}); err != nil {
+			return drifted, err
+		}
+		drifted = append(drifted, "ClusterRole/"+clusterRole.Name)
+		klog.V(0).Info(" Updated ClusterRole ✓")
 	}
 
-	klog.V(2).Info("Check if RoleBinding cluster-installer exists")
-	if _, err := kubeset.RbacV1().RoleBindings(namespace).Get(context.TODO(), "curator", v1.GetOptions{}); err != nil {
-		klog.V(2).Info(" Creating RoleBinding curator")
-		_, err = kubeset.RbacV1().RoleBindings(namespace).Create(context.TODO(), getRoleBinding(namespace), v1.CreateOptions{})
-		if err != nil {
-			return err
+	klog.V(2).Info("Check if RoleBinding curator is up to date")
+	updated, err := applyRoleBinding(ctx, kubeset, namespace, getRoleBinding(namespace))
+	if updated {
+		drifted = append(drifted, "RoleBinding/"+namespace+"/curator")
+	}
+	return drifted, err
+}
+
+// Name of the Role of the cluster-installer on the secrets generated for the cluster
+func generatedSecretsRoleName(clusterName string) string {
+	return "curator-generated-secrets-" + clusterName
+}
+
+// GetGeneratedSecretsRules - The rules to label, re-apply and delete the secrets generated for a cluster
+func GetGeneratedSecretsRules(secretNames []string) []rbacv1.PolicyRule {
+	names := append([]string{}, secretNames...)
+	sort.Strings(names)
+	return []rbacv1.PolicyRule{{
+		APIGroups:     []string{""},
+		Resources:     []string{"secrets"},
+		Verbs:         []string{"get", "update", "patch", "delete"},
+		ResourceNames: names,
+	}}
+}
+
+/* ApplyGeneratedSecretsRBAC - Reconciles the curator-generated-secrets-CLUSTER Role and RoleBinding of the
+ * cluster-installer, on the secrets generated for the cluster only, in the ClusterCurator namespace and in
+ * the cluster namespace of a hosted cluster. They are removed with the ClusterCurator. Returns the drifted
+ * objects.
+ */
+func ApplyGeneratedSecretsRBAC(
+	ctx context.Context,
+	kubeset kubernetes.Interface,
+	clusterName string,
+	namespace string,
+	secretNames []string) ([]string, error) {
+
+	drifted := []string{}
+	name := generatedSecretsRoleName(clusterName)
+	labels := map[string]string{ClusterLabel: clusterName}
+	subjects := []rbacv1.Subject{{Kind: "ServiceAccount", Name: clusterInstaller, Namespace: namespace}}
+
+	namespaces := []string{namespace}
+	if clusterName != namespace {
+		namespaces = append(namespaces, clusterName)
+	}
+	for _, ns := range namespaces {
+		if err := applyNamespacedRole(ctx, kubeset, ns, name, labels, GetGeneratedSecretsRules(secretNames), subjects,
+			&drifted); err != nil {
+			return drifted, err
+		}
+	}
+	return drifted, nil
+}
+
+/* ApplyRBACHypershift - Reconciles the curator RoleBinding of the hosted cluster namespace, for the
+ * cluster-installer of the ClusterCurator namespace, and adds it to the curator-crb ClusterRoleBinding.
+ * The ClusterRoleBinding is shared by the ClusterCurator namespaces, its other subjects are kept.
+ * Returns the drifted objects.
+ */
+func ApplyRBACHypershift(
+	ctx context.Context,
+	kubeset kubernetes.Interface,
+	namespace string,
+	curatorNamespace string) ([]string, error) {
+
+	drifted := []string{}
+
+	klog.V(2).Info("Check if RoleBinding curator is up to date in namespace " + namespace)
+	updated, err := applyRoleBinding(ctx, kubeset, namespace, getRoleBinding(curatorNamespace))
+	if err != nil {
+		return drifted, err
+	}
+	if updated {
+		drifted = append(drifted, "RoleBinding/"+namespace+"/curator")
+	}
+
+	klog.V(2).Info("Check if ClusterRoleBinding curator-crb is up to date")
+	desired := getClusterRoleBinding(curatorNamespace)
+	crb, err := kubeset.RbacV1().ClusterRoleBindings().Get(ctx, desired.Name, v1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		klog.V(2).Info(" Creating ClusterRoleBinding curator-crb")
+		if _, err = kubeset.RbacV1().ClusterRoleBindings().Create(ctx, desired, v1.CreateOptions{}); err != nil {
+			return drifted, err
+		}
+		klog.V(0).Info(" Created ClusterRoleBinding ✓")
+		return drifted, nil
+	} else if err != nil {
+		return drifted, err
+	}
+
+	// The roleRef of a binding is immutable
+	if crb.RoleRef != desired.RoleRef {
+		klog.Warningf("The roleRef of ClusterRoleBinding %v drifted, recreating it", crb.Name)
+		desired.Subjects = mergeSubjects(crb.Subjects, desired.Subjects)
+		if err = recreateClusterRoleBinding(ctx, kubeset, desired); err != nil {
+			return drifted, err
+		}
+		return append(drifted, "ClusterRoleBinding/"+crb.Name), nil
+	}
+	if subjects := mergeSubjects(crb.Subjects, desired.Subjects); len(subjects) != len(crb.Subjects) {
+		klog.V(2).Infof(" Adding the %v service account of %v to ClusterRoleBinding %v", clusterInstaller,
+			curatorNamespace, crb.Name)
+		crb.Subjects = subjects
+		if _, err = kubeset.RbacV1().ClusterRoleBindings().Update(ctx, crb, v1.UpdateOptions{}); err != nil {
+			return drifted, err
+		}
+		klog.V(0).Info(" Updated ClusterRoleBinding ✓")
+	}
+	return drifted, nil
+}
+
+// Creates or updates the RoleBinding to the desired one, returns true when an existing one drifted
+func applyRoleBinding(
+	ctx context.Context,
+	kubeset kubernetes.Interface,
+	namespace string,
+	desired *rbacv1.RoleBinding) (bool, error) {
+
+	roleBinding, err := kubeset.RbacV1().RoleBindings(namespace).Get(ctx, desired.Name, v1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		klog.V(2).Infof(" Creating RoleBinding %v in namespace %v", desired.Name, namespace)
+		if _, err = kubeset.RbacV1().RoleBindings(namespace).Create(ctx, desired, v1.CreateOptions{}); err != nil {
+			return false, err
 		}
 		klog.V(0).Info(" Created RoleBinding ✓")
+		return false, nil
+	} else if err != nil {
+		return false, err
 	}
-	return nil
+
+	if roleBinding.RoleRef == desired.RoleRef && equality.Semantic.DeepEqual(roleBinding.Subjects, desired.Subjects) {
+		return false, nil
+	}
+
+	klog.Warningf("RoleBinding %v/%v drifted, updating it", namespace, roleBinding.Name)
+	if roleBinding.RoleRef != desired.RoleRef {
+		// The roleRef of a binding is immutable
+		err = kubeset.RbacV1().RoleBindings(namespace).Delete(ctx, roleBinding.Name, v1.DeleteOptions{})
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return false, err
+		}
+		_, err = kubeset.RbacV1().RoleBindings(namespace).Create(ctx, desired, v1.CreateOptions{})
+	} else {
+		roleBinding.Subjects = desired.Subjects
+		_, err = kubeset.RbacV1().RoleBindings(namespace).Update(ctx, roleBinding, v1.UpdateOptions{})
+	}
+	if err != nil {
+		return false, err
+	}
+	klog.V(0).Info(" Updated RoleBinding ✓")
+	return true, nil
 }
 
-func ApplyRBACHypershift(kubeset kubernetes.Interface, namespace string, curatorNamespace string) error {
-	klog.V(2).Info("Check if RoleBinding curator exists in namespace " + namespace)
-	var err error
-	if _, err = kubeset.RbacV1().RoleBindings(namespace).Get(
-		context.TODO(), "curator", v1.GetOptions{}); k8serrors.IsNotFound(err) {
-		klog.V(2).Info(" Creating RoleBinding curator in namespace " + namespace)
-		_, err = kubeset.RbacV1().RoleBindings(namespace).Create(
-			context.TODO(), getRoleBinding(curatorNamespace), v1.CreateOptions{})
-		if err != nil {
-			return err
+func recreateClusterRoleBinding(
+	ctx context.Context,
+	kubeset kubernetes.Interface,
+	desired *rbacv1.ClusterRoleBinding) error {
+
+	err := kubeset.RbacV1().ClusterRoleBindings().Delete(ctx, desired.Name, v1.DeleteOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+	_, err = kubeset.RbacV1().ClusterRoleBindings().Create(ctx, desired, v1.CreateOptions{})
+	return err
+}
+
+// The subjects, with the added subjects that are missing
+func mergeSubjects(subjects []rbacv1.Subject, added []rbacv1.Subject) []rbacv1.Subject {
+	merged := append([]rbacv1.Subject{}, subjects...)
+	for _, subject := range added {
+		if indexOfSubject(merged, subject) < 0 {
+			merged = append(merged, subject)
+		}
+	}
+	return merged
+}
+
+func indexOfSubject(subjects []rbacv1.Subject, subject rbacv1.Subject) int {
+	for i, s := range subjects {
+		if s.Kind == subject.Kind && s.Name == subject.Name && s.Namespace == subject.Namespace {
+			return i
 		}
-		klog.V(0).Info(" Created RoleBinding in cluster namespace ✓")
+	}
+	return -1
+}
+
+/* RemoveRBAC - Removes the curator RoleBinding of the namespace and its cluster-installer from the
+ * curator-crb ClusterRoleBinding, once the namespace has no ClusterCurator left, with the service accounts,
+ * Roles and RoleBindings of the curation types. The cluster-installer ServiceAccount and the ClusterRole
+ * are kept, the ServiceAccount can be used by other jobs of the cluster namespace.
+ */
+func RemoveRBAC(ctx context.Context, kubeset kubernetes.Interface, namespace string) error {
+	if err := removeNamespaceCurationRBAC(ctx, kubeset, namespace); err != nil {
+		return err
+	}
+
+	klog.V(2).Info("Removing RoleBinding curator in namespace " + namespace)
+	err := kubeset.RbacV1().RoleBindings(namespace).Delete(ctx, "curator", v1.DeleteOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+
+	crb, err := kubeset.RbacV1().ClusterRoleBindings().Get(ctx, "curator-crb", v1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return nil
 	} else if err != nil {
 		return err
 	}
 
-	klog.V(2).Info("Check if ClusterRole curator-crb exists")
-	if _, err = kubeset.RbacV1().ClusterRoleBindings().Get(
-		context.TODO(), "curator-crb", v1.GetOptions{}); k8serrors.IsNotFound(err) {
-		klog.V(2).Info(" Creating ClusterRoleBinding curator-crb")
-		_, err = kubeset.RbacV1().ClusterRoleBindings().Create(
-			context.TODO(), getClusterRoleBinding(curatorNamespace), v1.CreateOptions{})
-		if err != nil {
-			return err
+	i := indexOfSubject(crb.Subjects, getClusterRoleBinding(namespace).Subjects[0])
+	if i < 0 {
+		return nil
+	}
+	if len(crb.Subjects) == 1 {
+		klog.V(2).Info("Removing ClusterRoleBinding curator-crb, it has no other subject")
+		err = kubeset.RbacV1().ClusterRoleBindings().Delete(ctx, crb.Name, v1.DeleteOptions{})
+		if k8serrors.IsNotFound(err) {
+			return nil
 		}
-		klog.V(0).Info(" Created ClusterRoleBinding ✓")
+		return err
+	}
+	klog.V(2).Infof("Removing the %v service account of %v from ClusterRoleBinding curator-crb", clusterInstaller, namespace)
+	crb.Subjects = append(crb.Subjects[:i], crb.Subjects[i+1:]...)
+	_, err = kubeset.RbacV1().ClusterRoleBindings().Update(ctx, crb, v1.UpdateOptions{})
+	return err
+}
+
+// RemoveRBACHypershift - Removes the curator RoleBinding of the hosted cluster namespace
+func RemoveRBACHypershift(ctx context.Context, kubeset kubernetes.Interface, namespace string) error {
+	klog.V(2).Info("Removing RoleBinding curator in namespace " + namespace)
+	err := kubeset.RbacV1().RoleBindings(namespace).Delete(ctx, "curator", v1.DeleteOptions{})
+	if k8serrors.IsNotFound(err) {
+		return nil
 	}
 	return err
 }
 
-func ExtendClusterInstallerRole(kubeset kubernetes.Interface, namespace string) error {
+func ExtendClusterInstallerRole(ctx context.Context, kubeset kubernetes.Interface, namespace string) error {
 
 	klog.V(0).Infof("Extending the %v role to support curator", clusterInstaller)
 
 	checkCount := 15 // Loop every 2s
 	for i := 1; i <= checkCount; i++ {
-		ciRole, err := kubeset.RbacV1().Roles(namespace).Get(context.TODO(), clusterInstaller, v1.GetOptions{})
+		ciRole, err := kubeset.RbacV1().Roles(namespace).Get(ctx, clusterInstaller, v1.GetOptions{})
 		if err != nil {
 			klog.Warningf("Did not find %v Role in namespace: %v (%v/%v)", clusterInstaller, namespace, i, checkCount)
 			time.Sleep(utils.PauseTwoSeconds)
 		} else {
 			klog.V(2).Infof(" Found %v role ✓", clusterInstaller)
-			ciRole.Rules = append(ciRole.Rules, getClusterInstallerRules()...)
-			_, err = kubeset.RbacV1().Roles(namespace).Update(context.TODO(), ciRole, v1.UpdateOptions{})
+			// Only the missing rules are added, the role can already have been extended
+			for _, rule := range getClusterInstallerRules() {
+				if !hasRule(ciRole.Rules, rule) {
+					ciRole.Rules = append(ciRole.Rules, rule)
+				}
+			}
+			_, err = kubeset.RbacV1().Roles(namespace).Update(ctx, ciRole, v1.UpdateOptions{})
 			if err != nil {
 				return err
 			}
@@ -311,3 +554,12 @@ func ExtendClusterInstallerRole(kubeset kubernetes.Interface, namespace string)
 	}
 	return nil
 }
+
+func hasRule(rules []rbacv1.PolicyRule, rule rbacv1.PolicyRule) bool {
+	for _, r := range rules {
+		if equality.Semantic.DeepEqual(r, rule) {
+			return true
+		}
+	}
+	return false
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/stolostron/cluster-curator-controller/pkg/jobs/utils"
 	"github.com/stretchr/testify/assert"
 	rbacv1 "k8s.io/api/rbac/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/fake"
 )
@@ -31,32 +32,43 @@ func getRules(clusterName string) []rbacv1.PolicyRule {
 		rbacv1.PolicyRule{
 			APIGroups: []string{"hypershift.openshift.io"},
 			Resources: []string{"hostedclusters", "nodepools"},
-			Verbs:     []string{"get", "patch", "delete", "update", "list"},
+			Verbs:     []string{"get", "patch", "delete", "update", "list", "watch"},
 		},
 		rbacv1.PolicyRule{
 			APIGroups: []string{"batch", "hive.openshift.io", "tower.ansible.com"},
 			Resources: []string{"jobs", "clusterdeployments", "ansiblejobs", "machinepools"},
-			Verbs:     []string{"get"},
+			Verbs:     []string{"get", "list", "watch"},
 		},
 		rbacv1.PolicyRule{
 			APIGroups: []string{""},
 			Resources: []string{"configmaps"},
-			Verbs:     []string{"update", "get", "patch"},
+			Verbs:     []string{"create", "update", "get", "patch"},
+		},
+		// To collect the AnsibleJob runner logs
+		rbacv1.PolicyRule{
+			APIGroups: []string{""},
+			Resources: []string{"pods", "pods/log"},
+			Verbs:     []string{"get", "list"},
 		},
 		rbacv1.PolicyRule{
 			APIGroups: []string{"internal.open-cluster-management.io"},
 			Resources: []string{"managedclusterinfos"},
-			Verbs:     []string{"get"},
+			Verbs:     []string{"get", "list", "watch"},
 		},
 		rbacv1.PolicyRule{
 			APIGroups: []string{"cluster.open-cluster-management.io"},
 			Resources: []string{"clustercurators", "managedclusters"},
-			Verbs:     []string{"get", "update", "patch", "delete"},
+			Verbs:     []string{"get", "update", "patch", "delete", "list", "watch"},
+		},
+		rbacv1.PolicyRule{
+			APIGroups: []string{"cluster.open-cluster-management.io"},
+			Resources: []string{"clustercurators/status"},
+			Verbs:     []string{"get", "update", "patch"},
 		},
 		rbacv1.PolicyRule{
 			APIGroups: []string{"view.open-cluster-management.io"},
 			Resources: []string{"managedclusterviews"},
-			Verbs:     []string{"get", "create", "update", "delete"},
+			Verbs:     []string{"get", "create", "update", "delete", "list", "watch"},
 		},
 		rbacv1.PolicyRule{
 			APIGroups: []string{"action.open-cluster-management.io"},
@@ -97,7 +109,7 @@ func getCombinedCIRules() []rbacv1.PolicyRule {
 			},
 			rbacv1.PolicyRule{
 				APIGroups: []string{"cluster.open-cluster-management.io"},
-				Resources: []string{"clustercurators"},
+				Resources: []string{"clustercurators", "clustercurators/status"},
 				Verbs:     []string{"get", "update", "patch"},
 			},
 		}...)
@@ -120,8 +132,9 @@ func TestApplyRbac(t *testing.T) {
 
 	kubeset := fake.NewSimpleClientset()
 
-	err := ApplyRBAC(kubeset, ClusterName)
+	drifted, err := ApplyRBAC(context.TODO(), kubeset, ClusterName)
 	assert.Nil(t, err, "err nil, when Roles and RoleBindings are created")
+	assert.Empty(t, drifted, "created objects did not drift")
 
 	t.Log("Validate ServiceaAccount")
 
@@ -152,6 +165,30 @@ func TestApplyRbac(t *testing.T) {
 	assert.ElementsMatch(t, subjects, roleBinding.Subjects, "subjects must match")
 }
 
+func TestApplyGeneratedSecretsRBAC(t *testing.T) {
+	kubeset := fake.NewSimpleClientset()
+	names := []string{HostedClusterName + "-creds", "toweraccess"}
+
+	_, err := ApplyGeneratedSecretsRBAC(context.TODO(), kubeset, HostedClusterName, ClusterNamespace, names)
+	assert.Nil(t, err)
+
+	for _, namespace := range []string{ClusterNamespace, HostedClusterName} {
+		role, err := kubeset.RbacV1().Roles(namespace).Get(context.TODO(),
+			generatedSecretsRoleName(HostedClusterName), v1.GetOptions{})
+		assert.Nil(t, err)
+		assert.Equal(t, HostedClusterName, role.Labels[ClusterLabel], "removed with the ClusterCurator")
+		assert.True(t, RulesAllow(role.Rules, "delete", groupCore, "secrets", HostedClusterName+"-creds"))
+		assert.False(t, RulesAllow(role.Rules, "update", groupCore, "secrets", "other-cluster-creds"),
+			"the secrets of the other clusters of the namespace are not exposed")
+		assert.False(t, RulesAllow(role.Rules, "list", groupCore, "secrets", ""))
+	}
+
+	clusterRole := getClusterRole(ClusterName)
+	assert.False(t, RulesAllow(clusterRole.Rules, "update", groupCore, "secrets", "other-cluster-creds"),
+		"the curator ClusterRole can not change the secrets of the hub")
+	assert.False(t, RulesAllow(clusterRole.Rules, "delete", groupCore, "secrets", "other-cluster-creds"))
+}
+
 func TestExtendClusterInstallerRole(t *testing.T) {
 
 	kubeset := fake.NewSimpleClientset()
@@ -167,7 +204,7 @@ func TestExtendClusterInstallerRole(t *testing.T) {
 		assert.Nil(t, err, "err is nil, when cluster-installer role is created")
 	}()
 
-	err := ExtendClusterInstallerRole(kubeset, ClusterName)
+	err := ExtendClusterInstallerRole(context.TODO(), kubeset, ClusterName)
 	assert.Nil(t, err, "err is nil, when cluster-installer role is extended")
 
 	role, err := kubeset.RbacV1().Roles(ClusterName).Get(context.TODO(), clusterInstaller, v1.GetOptions{})
@@ -183,7 +220,7 @@ func TestExtendClusterInstallerRoleTimeout(t *testing.T) {
 	testRole := getRole(ClusterName)
 	testRole.Name = clusterInstaller
 
-	err := ExtendClusterInstallerRole(kubeset, ClusterName)
+	err := ExtendClusterInstallerRole(context.TODO(), kubeset, ClusterName)
 
 	assert.NotNil(t, err, "err not nil, when failure or timeout")
 	t.Log(err.Error())
@@ -193,7 +230,107 @@ func TestExtendClusterInstallerRoleTimeout(t *testing.T) {
 func TestApplyRBACHypershift(t *testing.T) {
 	kubeset := fake.NewSimpleClientset()
 
-	_ = ApplyRBAC(kubeset, ClusterName)
-	err := ApplyRBACHypershift(kubeset, ClusterName, ClusterNamespace)
+	_, _ = ApplyRBAC(context.TODO(), kubeset, ClusterName)
+	_, err := ApplyRBACHypershift(context.TODO(), kubeset, ClusterName, ClusterNamespace)
 	assert.Nil(t, err, "err nil, when ClusterRoles and RoleBindings are created")
+
+	t.Log("A second ClusterCurator namespace is added to the ClusterRoleBinding")
+	drifted, err := ApplyRBACHypershift(context.TODO(), kubeset, "other-cluster", "other-clusters")
+	assert.Nil(t, err)
+	assert.Empty(t, drifted)
+
+	crb, err := kubeset.RbacV1().ClusterRoleBindings().Get(context.TODO(), "curator-crb", v1.GetOptions{})
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []rbacv1.Subject{
+		{Kind: "ServiceAccount", Name: clusterInstaller, Namespace: ClusterNamespace},
+		{Kind: "ServiceAccount", Name: clusterInstaller, Namespace: "other-clusters"},
+	}, crb.Subjects)
+
+	_, err = ApplyRBACHypershift(context.TODO(), kubeset, ClusterName, ClusterNamespace)
+	assert.Nil(t, err)
+	crb, _ = kubeset.RbacV1().ClusterRoleBindings().Get(context.TODO(), "curator-crb", v1.GetOptions{})
+	assert.Len(t, crb.Subjects, 2, "the subjects are not duplicated")
+}
+
+func TestApplyRBACDrift(t *testing.T) {
+	staleRole := getClusterRole(ClusterName)
+	staleRole.Rules = staleRole.Rules[:3]
+	staleBinding := getRoleBinding(ClusterName)
+	staleBinding.Namespace = ClusterName
+	staleBinding.Subjects[0].Name = "default"
+	wrongRoleRef := getRoleBinding(ClusterNamespace)
+	wrongRoleRef.Namespace = ClusterNamespace
+	wrongRoleRef.RoleRef.Name = "admin"
+
+	kubeset := fake.NewSimpleClientset(staleRole, staleBinding, wrongRoleRef)
+
+	drifted, err := ApplyRBAC(context.TODO(), kubeset, ClusterName)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"ClusterRole/curator", "RoleBinding/" + ClusterName + "/curator"}, drifted)
+
+	role, _ := kubeset.RbacV1().ClusterRoles().Get(context.TODO(), "curator", v1.GetOptions{})
+	assert.ElementsMatch(t, getRules(ClusterName), role.Rules, "the missing rules are added")
+
+	roleBinding, _ := kubeset.RbacV1().RoleBindings(ClusterName).Get(context.TODO(), "curator", v1.GetOptions{})
+	assert.Equal(t, clusterInstaller, roleBinding.Subjects[0].Name)
+
+	drifted, err = ApplyRBAC(context.TODO(), kubeset, ClusterName)
+	assert.Nil(t, err)
+	assert.Empty(t, drifted, "nothing drifted once reconciled")
+
+	t.Log("The immutable roleRef is fixed by recreating the RoleBinding")
+	drifted, err = ApplyRBAC(context.TODO(), kubeset, ClusterNamespace)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"RoleBinding/" + ClusterNamespace + "/curator"}, drifted)
+
+	roleBinding, _ = kubeset.RbacV1().RoleBindings(ClusterNamespace).Get(context.TODO(), "curator", v1.GetOptions{})
+	assert.Equal(t, "curator", roleBinding.RoleRef.Name)
+}
+
+func TestRemoveRBAC(t *testing.T) {
+	kubeset := fake.NewSimpleClientset()
+
+	for _, namespace := range []string{ClusterNamespace, "other-clusters"} {
+		_, err := ApplyRBAC(context.TODO(), kubeset, namespace)
+		assert.Nil(t, err)
+		_, err = ApplyRBACHypershift(context.TODO(), kubeset, ClusterName+"-"+namespace, namespace)
+		assert.Nil(t, err)
+	}
+
+	assert.Nil(t, RemoveRBACHypershift(context.TODO(), kubeset, ClusterName+"-"+ClusterNamespace))
+	assert.Nil(t, RemoveRBAC(context.TODO(), kubeset, ClusterNamespace))
+
+	_, err := kubeset.RbacV1().RoleBindings(ClusterNamespace).Get(context.TODO(), "curator", v1.GetOptions{})
+	assert.True(t, k8serrors.IsNotFound(err), "the RoleBinding is removed")
+	_, err = kubeset.RbacV1().RoleBindings(ClusterName+"-"+ClusterNamespace).Get(context.TODO(), "curator", v1.GetOptions{})
+	assert.True(t, k8serrors.IsNotFound(err), "the hosted cluster RoleBinding is removed")
+
+	crb, err := kubeset.RbacV1().ClusterRoleBindings().Get(context.TODO(), "curator-crb", v1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []rbacv1.Subject{{Kind: "ServiceAccount", Name: clusterInstaller, Namespace: "other-clusters"}},
+		crb.Subjects, "the other namespaces keep their subject")
+
+	_, err = kubeset.CoreV1().ServiceAccounts(ClusterNamespace).Get(context.TODO(), clusterInstaller, v1.GetOptions{})
+	assert.Nil(t, err, "the service account is kept")
+
+	assert.Nil(t, RemoveRBAC(context.TODO(), kubeset, "other-clusters"))
+	_, err = kubeset.RbacV1().ClusterRoleBindings().Get(context.TODO(), "curator-crb", v1.GetOptions{})
+	assert.True(t, k8serrors.IsNotFound(err), "the ClusterRoleBinding is removed with its last subject")
+
+	assert.Nil(t, RemoveRBAC(context.TODO(), kubeset, "other-clusters"), "removing twice is not an error")
+}
+
+func TestExtendClusterInstallerRoleTwice(t *testing.T) {
+	testRole := getRole(ClusterName)
+	testRole.Rules = getRules(ClusterName)
+	testRole.Name = clusterInstaller
+	testRole.Namespace = ClusterName
+	kubeset := fake.NewSimpleClientset(testRole)
+
+	assert.Nil(t, ExtendClusterInstallerRole(context.TODO(), kubeset, ClusterName))
+	assert.Nil(t, ExtendClusterInstallerRole(context.TODO(), kubeset, ClusterName))
+
+	role, err := kubeset.RbacV1().Roles(ClusterName).Get(context.TODO(), clusterInstaller, v1.GetOptions{})
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, getCombinedCIRules(), role.Rules, "the rules are only added once")
 }
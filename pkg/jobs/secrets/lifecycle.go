@@ -0,0 +1,370 @@
+// Copyright Contributors to the Open Cluster Management project.
+package secrets
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+
+	clustercuratorv1 "github.com/stolostron/cluster-curator-controller/pkg/api/v1beta1"
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/utils"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+// Label of the ACM credential secrets, the controller watches them to re-sync the generated secrets
+const CredentialsLabel = "cluster.open-cluster-management.io/credentials"
+
+// Labels and annotations of the secrets the curator generates from a source credential
+const (
+	// Set to "true" on every generated secret
+	GeneratedLabel = "cluster.open-cluster-management.io/curator-generated"
+	// Hash of the source credential path, to select the secrets generated from one credential
+	SourceLabel = "cluster.open-cluster-management.io/curator-source"
+	// Path of the source credential, namespace/secretName or vault://mount/path
+	SourceAnnotation = "cluster.open-cluster-management.io/curator-source-credential"
+	// Hash of the source credential data the secret was generated from, it changes on key rotation
+	SourceHashAnnotation = "cluster.open-cluster-management.io/curator-source-hash"
+	// Provider the secret was generated for
+	ProviderAnnotation = "cluster.open-cluster-management.io/curator-provider"
+)
+
+// Source is the credential generated secrets come from
+type Source struct {
+	Path     string
+	Provider string
+	Hash     string
+}
+
+// CredentialUsage lists the clusters with secrets generated from a source credential
+type CredentialUsage struct {
+	Source   string   `json:"source"`
+	Clusters []string `json:"clusters"`
+}
+
+// Hash of a credential path or data, short enough for a label value
+func hashOf(values ...string) string {
+	h := sha256.New()
+	for _, value := range values {
+		h.Write([]byte(value))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:40]
+}
+
+// SourceLabelValue - Value of the SourceLabel of the secrets generated from the credential path
+func SourceLabelValue(path string) string {
+	return hashOf(path)
+}
+
+// CredentialHash - Hash of the credential data, it changes when a key is rotated
+func CredentialHash(cpSecretData map[string]string) string {
+	keys := make([]string, 0, len(cpSecretData))
+	for key := range cpSecretData {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	values := []string{}
+	for _, key := range keys {
+		values = append(values, key, cpSecretData[key])
+	}
+	return hashOf(values...)
+}
+
+/* GeneratedSecretNames - Names of the secrets CreateProviderSecrets and CreateAnsibleSecret create for
+ * the provider from the credential. The provider is ProviderAnsible when only the Tower secret is created.
+ */
+func GeneratedSecretNames(provider string, cpSecretData map[string]string, clusterName string) []string {
+	names := []string{}
+
+	switch normalizeProvider(provider) {
+	case ProviderAnsible:
+	case ProviderVSphere:
+		names = append(names, clusterName+suffixCreds, clusterName+suffixVSphereCerts)
+	case ProviderKubeVirt:
+		if cpSecretData["kubeconfig"] != "" {
+			names = append(names, clusterName+suffixInfraKubeconfig)
+		}
+	case ProviderBareMetal:
+		names = append(names, clusterName+suffixLibvirtSsh)
+		if cpSecretData["bmcUsername"] != "" {
+			names = append(names, clusterName+suffixBmcCreds)
+		}
+	default:
+		names = append(names, clusterName+suffixCreds)
+	}
+	if provider != ProviderAnsible {
+		names = append(names, clusterName+suffixPull, clusterName+suffixSsh)
+	}
+
+	if cpSecretData["ansibleHost"] != "" && cpSecretData["ansibleToken"] != "" {
+		names = append(names, AnsibleSecretName)
+	}
+	return names
+}
+
+/* DeprovisionSecretNames - Names of the generated secrets Hive or HyperShift need to deprovision the cluster,
+ * its cloud credentials. They are kept when the ClusterCurator is deleted, until the cluster is destroyed.
+ */
+func DeprovisionSecretNames(clusterName string) []string {
+	return []string{clusterName + suffixCreds, clusterName + suffixVSphereCerts, clusterName + suffixInfraKubeconfig}
+}
+
+/* ClusterSecretNames - Names of every secret the curator can generate for the cluster from its Provider
+ * credential, whatever the provider. The RBAC of the curator is limited to them.
+ */
+func ClusterSecretNames(clusterName string) []string {
+	names := []string{}
+	for _, suffix := range []string{suffixCreds, suffixPull, suffixSsh, suffixVSphereCerts, suffixInfraKubeconfig,
+		suffixLibvirtSsh, suffixBmcCreds} {
+		names = append(names, clusterName+suffix)
+	}
+	return append(names, AnsibleSecretName)
+}
+
+/* CuratorSecretNames - Names of the secrets the curator can generate for the ClusterCurator, those of its
+ * cluster and the Ansible Tower secrets of the towerAuthSecrets kept in an external store.
+ */
+func CuratorSecretNames(curator *clustercuratorv1.ClusterCurator) []string {
+	return append(ClusterSecretNames(curator.Name), ExternalTowerSecretNames(curator)...)
+}
+
+// ExternalTowerSecretNames - Names of the Ansible Tower secrets of the towerAuthSecrets kept in an external store
+func ExternalTowerSecretNames(curator *clustercuratorv1.ClusterCurator) []string {
+	names := []string{}
+	seen := map[string]bool{}
+	addTower := func(towerAuthSecret string) {
+		if IsExternalPath(towerAuthSecret) && !seen[towerAuthSecret] {
+			seen[towerAuthSecret] = true
+			names = append(names, ExternalTowerSecret(towerAuthSecret))
+		}
+	}
+	addHooks := func(towerAuthSecret string, hooks ...[]clustercuratorv1.Hook) {
+		addTower(towerAuthSecret)
+		for _, list := range hooks {
+			for _, hook := range list {
+				addTower(hook.TowerAuthSecret)
+			}
+		}
+	}
+	spec := curator.Spec
+	addHooks(spec.Install.TowerAuthSecret, spec.Install.Prehook, spec.Install.Posthook)
+	addHooks(spec.Upgrade.TowerAuthSecret, spec.Upgrade.Prehook, spec.Upgrade.Posthook)
+	addHooks(spec.Destroy.TowerAuthSecret, spec.Destroy.Prehook, spec.Destroy.Posthook)
+	addHooks(spec.Scale.TowerAuthSecret, spec.Scale.Prehook, spec.Scale.Posthook)
+	return names
+}
+
+/* ReserveTowerSecrets - Creates the Ansible Tower secrets of the towerAuthSecrets kept in an external store,
+ * empty, in the ClusterCurator namespace. The hooks can not create secrets, ApplyExternalTowerSecret fills
+ * in the reserved secret by its name. They are generated secrets, removed with the ClusterCurator.
+ */
+func ReserveTowerSecrets(
+	ctx context.Context,
+	kubeset kubernetes.Interface,
+	curator *clustercuratorv1.ClusterCurator) error {
+
+	for _, name := range ExternalTowerSecretNames(curator) {
+		_, err := kubeset.CoreV1().Secrets(curator.Namespace).Create(ctx, &corev1.Secret{
+			ObjectMeta: v1.ObjectMeta{Name: name, Labels: map[string]string{GeneratedLabel: "true"}},
+			Type:       corev1.SecretTypeOpaque,
+		}, v1.CreateOptions{})
+		if err != nil && !k8serrors.IsAlreadyExists(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+/* TrackGeneratedSecrets - Labels the generated secrets with their source credential, so they are found by
+ * ResyncGeneratedSecrets and GetCredentialUsage. They are not owned by the ClusterCurator, the controller
+ * removes them with it, except the DeprovisionSecretNames Hive needs to deprovision the cluster: they are
+ * deleted once the cluster is destroyed. A ClusterCurator owner set by an earlier version is removed.
+ */
+func TrackGeneratedSecrets(
+	ctx context.Context,
+	kubeset kubernetes.Interface,
+	namespace string,
+	names []string,
+	source Source) error {
+
+	for _, name := range names {
+		secret, err := kubeset.CoreV1().Secrets(namespace).Get(ctx, name, v1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		if secret.Labels == nil {
+			secret.Labels = map[string]string{}
+		}
+		secret.Labels[GeneratedLabel] = "true"
+		secret.Labels[SourceLabel] = SourceLabelValue(source.Path)
+
+		if secret.Annotations == nil {
+			secret.Annotations = map[string]string{}
+		}
+		secret.Annotations[SourceAnnotation] = source.Path
+		secret.Annotations[SourceHashAnnotation] = source.Hash
+		secret.Annotations[ProviderAnnotation] = source.Provider
+
+		secret.OwnerReferences = withoutCuratorOwner(secret.OwnerReferences)
+
+		klog.V(2).Infof("Tracking secret %v/%v generated from %v", namespace, name, source.Path)
+		if _, err = kubeset.CoreV1().Secrets(namespace).Update(ctx, secret, v1.UpdateOptions{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// The owner references, except those of a ClusterCurator
+func withoutCuratorOwner(refs []v1.OwnerReference) []v1.OwnerReference {
+	owners := []v1.OwnerReference{}
+	for _, ref := range refs {
+		if ref.Kind != "ClusterCurator" || ref.APIVersion != clustercuratorv1.GroupVersion.String() {
+			owners = append(owners, ref)
+		}
+	}
+	if len(owners) == 0 {
+		return nil
+	}
+	return owners
+}
+
+/* ResyncGeneratedSecrets - Regenerates the secrets of the namespace whose source credential changed since
+ * they were generated, after a key rotation for example. The metadata of the generated secrets of the
+ * namespace is read by the caller, the controller keeps it cached. Credentials of an external store are
+ * only read by the curator job, their secrets are regenerated by the next applycloudprovider step.
+ * Returns the source credentials that were re-synced.
+ */
+func ResyncGeneratedSecrets(
+	ctx context.Context,
+	kubeset kubernetes.Interface,
+	namespace string,
+	generated []v1.ObjectMeta) ([]string, error) {
+
+	// The secrets generated from a credential share its annotations, check each credential once
+	sources := map[string]Source{}
+	for _, secret := range generated {
+		if secret.Labels[GeneratedLabel] != "true" {
+			continue
+		}
+		path := secret.Annotations[SourceAnnotation]
+		if path == "" || IsExternalPath(path) {
+			continue
+		}
+		if _, ok := sources[path]; !ok || secret.Annotations[ProviderAnnotation] != ProviderAnsible {
+			sources[path] = Source{
+				Path:     path,
+				Provider: secret.Annotations[ProviderAnnotation],
+				Hash:     secret.Annotations[SourceHashAnnotation],
+			}
+		}
+	}
+
+	paths := make([]string, 0, len(sources))
+	for path := range sources {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	resynced := []string{}
+	for _, path := range paths {
+		source := sources[path]
+
+		cpSecretData, err := GetSecretData(ctx, kubeset, path)
+		if k8serrors.IsNotFound(err) {
+			klog.Warningf("The source credential %v of the secrets in %v no longer exists", path, namespace)
+			continue
+		} else if err != nil {
+			return resynced, err
+		}
+
+		hash := CredentialHash(*cpSecretData)
+		if hash == source.Hash {
+			continue
+		}
+
+		klog.V(0).Infof("The source credential %v changed, regenerating the secrets in %v", path, namespace)
+		if err = ValidateProviderCredential(source.Provider, *cpSecretData); err != nil {
+			return resynced, err
+		}
+		if source.Provider != ProviderAnsible {
+			if err = CreateProviderSecrets(ctx, kubeset, source.Provider, *cpSecretData, namespace); err != nil {
+				return resynced, err
+			}
+		}
+		if err = CreateAnsibleSecret(ctx, kubeset, *cpSecretData, namespace); err != nil {
+			return resynced, err
+		}
+
+		source.Hash = hash
+		if err = TrackGeneratedSecrets(ctx, kubeset, namespace,
+			GeneratedSecretNames(source.Provider, *cpSecretData, namespace), source); err != nil {
+			return resynced, err
+		}
+		resynced = append(resynced, path)
+	}
+	return resynced, nil
+}
+
+/* DeleteGeneratedSecrets - Deletes the generated secrets of the namespace among names, once the cluster is
+ * destroyed. The secrets are read by name, the curator can not list the secrets of the namespace.
+ */
+func DeleteGeneratedSecrets(ctx context.Context, kubeset kubernetes.Interface, namespace string, names []string) error {
+	for _, name := range names {
+		secret, err := kubeset.CoreV1().Secrets(namespace).Get(ctx, name, v1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return utils.LogError(err)
+		}
+		if secret.Labels[GeneratedLabel] != "true" {
+			continue
+		}
+
+		klog.V(0).Infof("Deleting the generated secret %v/%v", namespace, name)
+		err = kubeset.CoreV1().Secrets(namespace).Delete(ctx, name, v1.DeleteOptions{})
+		if err != nil && !k8serrors.IsNotFound(err) {
+			return utils.LogError(err)
+		}
+	}
+	return nil
+}
+
+// GetCredentialUsage - Lists the source credentials of the generated secrets, with the clusters using them
+func GetCredentialUsage(ctx context.Context, kubeset kubernetes.Interface) ([]CredentialUsage, error) {
+	secretList, err := kubeset.CoreV1().Secrets("").List(ctx, v1.ListOptions{LabelSelector: GeneratedLabel + "=true"})
+	if err != nil {
+		return nil, err
+	}
+
+	clusters := map[string]map[string]bool{}
+	for _, secret := range secretList.Items {
+		path := secret.Annotations[SourceAnnotation]
+		if path == "" {
+			continue
+		}
+		if clusters[path] == nil {
+			clusters[path] = map[string]bool{}
+		}
+		clusters[path][secret.Namespace] = true
+	}
+
+	usage := []CredentialUsage{}
+	for path, namespaces := range clusters {
+		u := CredentialUsage{Source: path}
+		for namespace := range namespaces {
+			u.Clusters = append(u.Clusters, namespace)
+		}
+		sort.Strings(u.Clusters)
+		usage = append(usage, u)
+	}
+	sort.Slice(usage, func(i, j int) bool { return usage[i].Source < usage[j].Source })
+	return usage, nil
+}
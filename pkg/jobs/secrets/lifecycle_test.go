@@ -0,0 +1,176 @@
+// Copyright Contributors to the Open Cluster Management project.
+package secrets
+
+import (
+	"context"
+	"testing"
+
+	clustercuratorv1 "github.com/stolostron/cluster-curator-controller/pkg/api/v1beta1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const clusterName = "my-cluster"
+
+func getAWSCredential() *corev1.Secret {
+	data := map[string][]byte{}
+	for key, value := range getValidCredential() {
+		data[key] = []byte(value)
+	}
+	data["aws_access_key_id"] = []byte(AwsKeyValue)
+	data["aws_secret_access_key"] = []byte(AwsKeySecretValue)
+
+	return &corev1.Secret{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      cpName,
+			Namespace: cpNamespace,
+			Labels:    map[string]string{CredentialsLabel: "", CredentialTypeLabel: ProviderAWS},
+		},
+		Data: data,
+	}
+}
+
+// Applies the credential to the cluster namespace, like the applycloudprovider step
+func applyCredential(t *testing.T, kubeset *fake.Clientset) {
+	cpSecretData, err := GetSecretData(context.TODO(), kubeset, cpPath)
+	assert.Nil(t, err)
+	assert.Nil(t, CreateProviderSecrets(context.TODO(), kubeset, ProviderAWS, *cpSecretData, clusterName))
+	assert.Nil(t, TrackGeneratedSecrets(context.TODO(), kubeset, clusterName,
+		GeneratedSecretNames(ProviderAWS, *cpSecretData, clusterName),
+		Source{Path: cpPath, Provider: ProviderAWS, Hash: CredentialHash(*cpSecretData)}))
+}
+
+func TestGeneratedSecretNames(t *testing.T) {
+	assert.Equal(t, []string{"c-creds", "c-pull-secret", "c-ssh-private-key"},
+		GeneratedSecretNames(ProviderAWS, map[string]string{}, "c"))
+	assert.Equal(t, []string{"c-creds", "c-vsphere-certs", "c-pull-secret", "c-ssh-private-key", AnsibleSecretName},
+		GeneratedSecretNames(ProviderVMware, map[string]string{"ansibleHost": HostURL, "ansibleToken": "t"}, "c"))
+	assert.Equal(t, []string{"c-pull-secret", "c-ssh-private-key"},
+		GeneratedSecretNames(ProviderKubeVirt, map[string]string{}, "c"), "no infrastructure kubeconfig")
+	assert.Equal(t, []string{AnsibleSecretName},
+		GeneratedSecretNames(ProviderAnsible, map[string]string{"ansibleHost": HostURL, "ansibleToken": "t"}, "c"))
+}
+
+func TestTrackGeneratedSecrets(t *testing.T) {
+	deploymentOwner := v1.OwnerReference{APIVersion: "hive.openshift.io/v1", Kind: "ClusterDeployment",
+		Name: clusterName, UID: "deployment-uid"}
+	kubeset := fake.NewSimpleClientset(getAWSCredential(), &corev1.Secret{ObjectMeta: v1.ObjectMeta{
+		Name: clusterName + "-creds", Namespace: clusterName, OwnerReferences: []v1.OwnerReference{deploymentOwner,
+			{APIVersion: clustercuratorv1.GroupVersion.String(), Kind: "ClusterCurator", Name: clusterName,
+				UID: "curator-uid"}}}})
+	applyCredential(t, kubeset)
+
+	secret, err := kubeset.CoreV1().Secrets(clusterName).Get(context.TODO(), clusterName+"-creds", v1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "true", secret.Labels[GeneratedLabel])
+	assert.Equal(t, SourceLabelValue(cpPath), secret.Labels[SourceLabel])
+	assert.Equal(t, cpPath, secret.Annotations[SourceAnnotation])
+	assert.Equal(t, ProviderAWS, secret.Annotations[ProviderAnnotation])
+	assert.Equal(t, []v1.OwnerReference{deploymentOwner}, secret.OwnerReferences,
+		"the secret is not garbage collected with the ClusterCurator")
+}
+
+// The metadata of the generated secrets of the cluster namespace, as the controller cache has it
+func generatedMeta(t *testing.T, kubeset *fake.Clientset) []v1.ObjectMeta {
+	secretList, err := kubeset.CoreV1().Secrets(clusterName).List(context.TODO(), v1.ListOptions{})
+	assert.Nil(t, err)
+	generated := []v1.ObjectMeta{}
+	for _, secret := range secretList.Items {
+		generated = append(generated, secret.ObjectMeta)
+	}
+	return generated
+}
+
+func TestResyncGeneratedSecrets(t *testing.T) {
+	kubeset := fake.NewSimpleClientset(getAWSCredential())
+	applyCredential(t, kubeset)
+
+	resynced, err := ResyncGeneratedSecrets(context.TODO(), kubeset, clusterName, generatedMeta(t, kubeset))
+	assert.Nil(t, err)
+	assert.Empty(t, resynced, "nothing to do when the credential did not change")
+
+	t.Log("Rotate the AWS keys")
+	credential := getAWSCredential()
+	credential.Data["aws_secret_access_key"] = []byte("rotated-secret")
+	_, err = kubeset.CoreV1().Secrets(cpNamespace).Update(context.TODO(), credential, v1.UpdateOptions{})
+	assert.Nil(t, err)
+
+	resynced, err = ResyncGeneratedSecrets(context.TODO(), kubeset, clusterName, generatedMeta(t, kubeset))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{cpPath}, resynced)
+
+	secret, err := kubeset.CoreV1().Secrets(clusterName).Get(context.TODO(), clusterName+"-creds", v1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "rotated-secret", secret.StringData["aws_secret_access_key"])
+
+	resynced, err = ResyncGeneratedSecrets(context.TODO(), kubeset, clusterName, generatedMeta(t, kubeset))
+	assert.Nil(t, err)
+	assert.Empty(t, resynced, "the new hash is recorded")
+
+	t.Log("Delete the credential")
+	assert.Nil(t, kubeset.CoreV1().Secrets(cpNamespace).Delete(context.TODO(), cpName, v1.DeleteOptions{}))
+	resynced, err = ResyncGeneratedSecrets(context.TODO(), kubeset, clusterName, generatedMeta(t, kubeset))
+	assert.Nil(t, err, "a deleted credential is skipped")
+	assert.Empty(t, resynced)
+}
+
+func TestDeleteGeneratedSecrets(t *testing.T) {
+	kubeset := fake.NewSimpleClientset(getAWSCredential(), &corev1.Secret{
+		ObjectMeta: v1.ObjectMeta{Name: clusterName + "-install-config", Namespace: clusterName},
+	})
+	applyCredential(t, kubeset)
+
+	assert.Nil(t, DeleteGeneratedSecrets(context.TODO(), kubeset, clusterName, ClusterSecretNames(clusterName)))
+
+	_, err := kubeset.CoreV1().Secrets(clusterName).Get(context.TODO(), clusterName+"-creds", v1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err))
+	_, err = kubeset.CoreV1().Secrets(clusterName).Get(context.TODO(), clusterName+"-install-config", v1.GetOptions{})
+	assert.Nil(t, err, "the other secrets are kept")
+}
+
+func TestCuratorSecretNames(t *testing.T) {
+	curator := &clustercuratorv1.ClusterCurator{ObjectMeta: v1.ObjectMeta{Name: "c", Namespace: "clusters"}}
+	curator.Spec.Install.TowerAuthSecret = "vault://secret/tower"
+	curator.Spec.Upgrade.TowerAuthSecret = "toweraccess-secret"
+	curator.Spec.Destroy.Posthook = []clustercuratorv1.Hook{{Name: "cleanup", TowerAuthSecret: "vault://secret/tower"}}
+
+	names := CuratorSecretNames(curator)
+	assert.Subset(t, names, []string{"c-creds", "c-pull-secret", "c-ssh-private-key", "c-bmc-creds", AnsibleSecretName})
+	assert.Contains(t, names, ExternalTowerSecret("vault://secret/tower"))
+	assert.Len(t, names, len(ClusterSecretNames("c"))+1, "one secret per external towerAuthSecret")
+}
+
+func TestReserveTowerSecrets(t *testing.T) {
+	curator := &clustercuratorv1.ClusterCurator{ObjectMeta: v1.ObjectMeta{Name: "c", Namespace: "clusters"}}
+	curator.Spec.Install.TowerAuthSecret = "vault://secret/tower"
+	curator.Spec.Upgrade.TowerAuthSecret = "toweraccess-secret"
+	kubeset := fake.NewSimpleClientset()
+
+	assert.Nil(t, ReserveTowerSecrets(context.TODO(), kubeset, curator))
+	assert.Nil(t, ReserveTowerSecrets(context.TODO(), kubeset, curator), "the reserved secret is kept")
+	secrets, err := kubeset.CoreV1().Secrets("clusters").List(context.TODO(), v1.ListOptions{})
+	assert.Nil(t, err)
+	if assert.Len(t, secrets.Items, 1, "only the towerAuthSecrets of an external store are reserved") {
+		assert.Equal(t, ExternalTowerSecret("vault://secret/tower"), secrets.Items[0].Name)
+	}
+}
+
+func TestGetCredentialUsage(t *testing.T) {
+	kubeset := fake.NewSimpleClientset(getAWSCredential())
+	applyCredential(t, kubeset)
+
+	// A second cluster using the same credential
+	cpSecretData, err := GetSecretData(context.TODO(), kubeset, cpPath)
+	assert.Nil(t, err)
+	assert.Nil(t, CreateProviderSecrets(context.TODO(), kubeset, ProviderAWS, *cpSecretData, "other-cluster"))
+	assert.Nil(t, TrackGeneratedSecrets(context.TODO(), kubeset, "other-cluster",
+		GeneratedSecretNames(ProviderAWS, *cpSecretData, "other-cluster"),
+		Source{Path: cpPath, Provider: ProviderAWS}))
+
+	usage, err := GetCredentialUsage(context.TODO(), kubeset)
+	assert.Nil(t, err)
+	assert.Equal(t, []CredentialUsage{{Source: cpPath, Clusters: []string{clusterName, "other-cluster"}}}, usage)
+}
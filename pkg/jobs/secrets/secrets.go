@@ -9,71 +9,342 @@ import (
 	"gopkg.in/yaml.v3"
 	"k8s.io/klog/v2"
 
+	hivev1 "github.com/openshift/hive/apis/hive/v1"
 	"github.com/stolostron/cluster-curator-controller/pkg/jobs/utils"
 	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
+	clientv1 "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-//  patchStringValue specifies a json patch operation for a string.
+// patchStringValue specifies a json patch operation for a string.
 type patchStringValue struct {
 	Op    string            `json:"op"`
 	Path  string            `json:"path"`
 	Value map[string]string `json:"value"`
 }
 
-//Suffix list
+// Suffix list
 const suffixCreds = "-creds"
 const suffixPull = "-pull-secret"
 const suffixSsh = "-ssh-private-key"
+const suffixVSphereCerts = "-vsphere-certs"
+const suffixInfraKubeconfig = "-infra-kubeconfig"
+const suffixLibvirtSsh = "-libvirt-ssh-private-key"
+const suffixBmcCreds = "-bmc-creds"
 const AnsibleSecretName = "toweraccess"
 
-func GetSecretData(kubeset kubernetes.Interface, providerCredentialPath string) *map[string]string {
-	secretData := make(map[string]string)
+// Keys of the Ansible Tower secret read by the AnsibleJob operator, besides host and token
+const TowerVerifySSLKey = "verify_ssl"
+const TowerCABundleKey = "ca_bundle"
+
+/* The curator step that applies a Provider credential, detecting the provider. The steps that apply
+ * the credential of a given provider are named ApplyCloudProvider-PROVIDER
+ */
+const ApplyCloudProvider = "applycloudprovider"
+
+// Label set on the ACM credential secrets, with the credential type
+const CredentialTypeLabel = "cluster.open-cluster-management.io/type"
+
+// Provider of an Ansible Tower credential, it has no cloud secrets
+const ProviderAnsible = "ansible"
+
+// Providers, named after the ClusterDeployment spec.platform keys
+const (
+	ProviderAWS       = "aws"
+	ProviderGCP       = "gcp"
+	ProviderAzure     = "azure"
+	ProviderVSphere   = "vsphere"
+	ProviderOpenStack = "openstack"
+	ProviderIBMCloud  = "ibmcloud"
+	ProviderKubeVirt  = "kubevirt"
+	ProviderBareMetal = "baremetal"
+	// Earlier name of the vSphere provider, still used by override jobs
+	ProviderVMware = "vmware"
+)
+
+type createSecretsFunc func(
+	ctx context.Context,
+	kubeset kubernetes.Interface,
+	cpSecretData map[string]string,
+	clusterName string) error
+
+var providerSecrets = map[string]createSecretsFunc{
+	ProviderAWS:       CreateAWSSecrets,
+	ProviderGCP:       CreateGCPSecrets,
+	ProviderAzure:     CreateAzureSecrets,
+	ProviderVSphere:   CreateVSphereSecrets,
+	ProviderVMware:    CreateVSphereSecrets,
+	ProviderOpenStack: CreateOpenStackSecrets,
+	ProviderIBMCloud:  CreateIBMCloudSecrets,
+	ProviderKubeVirt:  CreateKubeVirtSecrets,
+	ProviderBareMetal: CreateBareMetalSecrets,
+}
+
+// Credential types of the CredentialTypeLabel, when they differ from the provider
+var credentialTypeProviders = map[string]string{
+	"azr":    ProviderAzure,
+	"vmw":    ProviderVSphere,
+	"ost":    ProviderOpenStack,
+	"ibm":    ProviderIBMCloud,
+	"bmc":    ProviderBareMetal,
+	"hybrid": ProviderBareMetal,
+	"ans":    ProviderAnsible,
+}
+
+// Credential keys that only one provider has, used when the credential has no type label
+var credentialKeyProviders = []struct {
+	key      string
+	provider string
+}{
+	{"awsAccessKeyID", ProviderAWS},
+	{"gcServiceAccountKey", ProviderGCP},
+	{"osServicePrincipal.json", ProviderAzure},
+	{"subscriptionId", ProviderAzure},
+	{"vCenter", ProviderVSphere},
+	{"clouds.yaml", ProviderOpenStack},
+	{"ibmCloudApiKey", ProviderIBMCloud},
+	{"libvirtURI", ProviderBareMetal},
+	{"bmcUsername", ProviderBareMetal},
+}
+
+// Providers that have a ClusterDeployment platform, KubeVirt clusters are HostedClusters
+var platformProviders = []string{
+	ProviderAWS,
+	ProviderGCP,
+	ProviderAzure,
+	ProviderVSphere,
+	ProviderOpenStack,
+	ProviderIBMCloud,
+	ProviderBareMetal,
+}
+
+/* GetSecretData - Reads the Provider credential, in the legacy format, where a YAML metadata key holds every
+ * field, or in the current ACM format, with one data key per field. When a field is in both, the data key
+ * wins. The fields are returned under their legacy names, see credentialKeys.
+ */
+func GetSecretData(
+	ctx context.Context,
+	kubeset kubernetes.Interface,
+	providerCredentialPath string) (*map[string]string, error) {
+
 	// Read Cloud Provider Secret and create Hive cluster secrets, Cloud Provider Credential, pull-secret & ssh-private-key
+	if IsExternalPath(providerCredentialPath) {
+		credential, err := GetExternalCredential(ctx, providerCredentialPath)
+		if err != nil {
+			return nil, err
+		}
+		data := map[string][]byte{}
+		for key, value := range credential {
+			data[key] = []byte(value)
+		}
+		klog.V(0).Info("Found Cloud Provider credential \"" + providerCredentialPath + "\" ✓")
+		return readCredential(providerCredentialPath, nil, data)
+	}
+
 	// Determine kube path for Provider credential
 	secretNamespace, secretName, err := utils.PathSplitterFromEnv(providerCredentialPath)
-	utils.CheckError(err)
+	if err != nil {
+		return nil, err
+	}
 
 	klog.V(2).Info("=> Retrieving  Provider credential namespace \"" + secretNamespace +
 		"\" secret \"" + secretName + "\"")
 
-	secret, err := kubeset.CoreV1().Secrets(secretNamespace).Get(
-		context.TODO(), secretName, v1.GetOptions{})
+	secret, err := kubeset.CoreV1().Secrets(secretNamespace).Get(ctx, secretName, v1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	klog.V(0).Info("Found Cloud Provider secret \"" + secret.GetName() + "\" ✓")
+	return readCredential(providerCredentialPath, secret.Labels, secret.Data)
+}
+
+// Reads the legacy metadata key and the keys of the current format of a credential
+func readCredential(
+	providerCredentialPath string,
+	labels map[string]string,
+	data map[string][]byte) (*map[string]string, error) {
 
-	utils.CheckError(err)
+	secretData := make(map[string]string)
+	if err := yaml.Unmarshal(data["metadata"], &secretData); err != nil {
+		return nil, utils.NewError(utils.ReasonInvalidCredential,
+			"Provider credential %s has an invalid metadata key: %w", providerCredentialPath, err)
+	}
 
-	err = yaml.Unmarshal(secret.Data["metadata"], &secretData)
-	utils.CheckError(err)
-	klog.V(0).Info("Found Cloud Provider secret \"" + secret.GetName() + "\" ✓")
-	return &secretData
+	// The keys of the current format take precedence over the legacy metadata
+	for key, value := range data {
+		if key == "metadata" || len(value) == 0 {
+			continue
+		}
+		if err := addCredentialKey(secretData, key, string(value)); err != nil {
+			return nil, utils.NewError(utils.ReasonInvalidCredential,
+				"Provider credential %s has an invalid %s key: %w", providerCredentialPath, key, err)
+		}
+	}
+
+	// The Ansible Tower keys are generic, a cloud credential can have a host key of its own
+	if isAnsibleCredential(labels, secretData) {
+		for key, legacyKey := range ansibleCredentialKeys {
+			if value, ok := secretData[key]; ok {
+				delete(secretData, key)
+				secretData[legacyKey] = value
+			}
+		}
+	}
+	return &secretData, nil
 }
 
-func CreateAnsibleSecret(kubeset kubernetes.Interface, cpSecretData map[string]string, clusterName string) error {
-	// Generate the Ansible Tower credential secret
-	klog.V(2).Info("Check if Ansible Tower credentials are present")
-	if cpSecretData["ansibleHost"] != "" && cpSecretData["ansibleToken"] != "" {
-		stringData := map[string]string{
-			"host":  cpSecretData["ansibleHost"],
-			"token": cpSecretData["ansibleToken"],
-		}
-		if err := createPatchSecret(
-			kubeset,
-			stringData,
-			AnsibleSecretName,
-			clusterName,
-			corev1.SecretTypeOpaque); err != nil {
+/* isAnsibleCredential - The credential has the Ansible type label. A credential without a type label is an
+ * Ansible Tower credential when it has none of the keys of a provider.
+ */
+func isAnsibleCredential(labels map[string]string, cpSecretData map[string]string) bool {
+	if credentialType := strings.ToLower(labels[CredentialTypeLabel]); credentialType != "" {
+		return credentialTypeProviders[credentialType] == ProviderAnsible
+	}
+	return CredentialProvider(nil, cpSecretData) == ""
+}
+
+/* Keys of the current ACM credential format, one key per field in the secret data, that have another
+ * name in the legacy metadata format. The other keys, like pullSecret or clouds.yaml, have the same name.
+ */
+var credentialKeys = map[string]string{
+	"aws_access_key_id":     "awsAccessKeyID",
+	"aws_secret_access_key": "awsSecretAccessKeyID",
+	"osServiceAccount.json": "gcServiceAccountKey",
+	"projectID":             "gcProjectID",
+	"ssh-privatekey":        "sshPrivatekey",
+	"ssh-publickey":         "sshPublickey",
+	"ibmcloud_api_key":      "ibmCloudApiKey",
+}
 
+// Keys of the current format of an Ansible Tower credential, with their legacy name, see isAnsibleCredential
+var ansibleCredentialKeys = map[string]string{
+	"host":       "ansibleHost",
+	"token":      "ansibleToken",
+	"verify_ssl": "ansibleVerifySSL",
+	"ca_bundle":  "ansibleCABundle",
+}
+
+// Adds a key of the current credential format to the legacy secret data, under its legacy name
+func addCredentialKey(cpSecretData map[string]string, key string, value string) error {
+	// Azure keeps the service principal as JSON, the legacy format has one key per field
+	if key == "osServicePrincipal.json" {
+		servicePrincipal := map[string]string{}
+		if err := json.Unmarshal([]byte(value), &servicePrincipal); err != nil {
 			return err
 		}
-	} else {
-		klog.Warning("No Ansible Tower credentials found.")
+		for _, field := range []string{"clientId", "clientSecret", "tenantId", "subscriptionId"} {
+			if servicePrincipal[field] != "" {
+				cpSecretData[field] = servicePrincipal[field]
+			}
+		}
+		return nil
 	}
+
+	if legacyKey, ok := credentialKeys[key]; ok {
+		key = legacyKey
+	}
+	cpSecretData[key] = value
 	return nil
 }
 
-func CreateAzureSecrets(kubeset kubernetes.Interface, cpSecretData map[string]string, clusterName string) error {
+func CreateAnsibleSecret(
+	ctx context.Context,
+	kubeset kubernetes.Interface,
+	cpSecretData map[string]string,
+	clusterName string) error {
+	// Generate the Ansible Tower credential secret
+	klog.V(2).Info("Check if Ansible Tower credentials are present")
+	if cpSecretData["ansibleHost"] != "" && cpSecretData["ansibleToken"] != "" {
+		return createAnsibleSecret(ctx, kubeset, cpSecretData, AnsibleSecretName, clusterName)
+	}
+	klog.Warning("No Ansible Tower credentials found.")
+	return nil
+}
+
+/* ApplyExternalTowerSecret - Fills in the Ansible Tower secret of a towerAuthSecret kept in an external
+ * store, the AnsibleJob can only reference a Secret of its namespace. Each reference gets its own secret,
+ * as hooks can use different Ansible Tower instances. The secret is reserved by the controller, see
+ * ReserveTowerSecrets, the hooks only update it. Returns the name of the secret.
+ */
+func ApplyExternalTowerSecret(
+	ctx context.Context,
+	kubeset kubernetes.Interface,
+	towerAuthSecret string,
+	namespace string) (string, error) {
+
+	cpSecretData, err := GetSecretData(ctx, kubeset, towerAuthSecret)
+	if err != nil {
+		return "", err
+	}
+	if (*cpSecretData)["ansibleHost"] == "" || (*cpSecretData)["ansibleToken"] == "" {
+		return "", utils.NewError(utils.ReasonInvalidCredential,
+			"The towerAuthSecret %s has no host and token", towerAuthSecret)
+	}
+	if err = ValidateProviderCredential(ProviderAnsible, *cpSecretData); err != nil {
+		return "", err
+	}
+
+	secretName := ExternalTowerSecret(towerAuthSecret)
+	secret, err := kubeset.CoreV1().Secrets(namespace).Get(ctx, secretName, v1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return "", utils.NewError(utils.ReasonInvalidCredential,
+			"The Ansible Tower secret %s of the towerAuthSecret %s is not reserved", secretName, towerAuthSecret)
+	} else if err != nil {
+		return "", err
+	}
+	klog.V(0).Info("Applying secret " + secretName + " in namespace " + namespace)
+	secret.StringData = ansibleSecretData(*cpSecretData)
+	secret.Data = nil
+	if _, err = kubeset.CoreV1().Secrets(namespace).Update(ctx, secret, v1.UpdateOptions{}); err != nil {
+		return "", err
+	}
+	return secretName, TrackGeneratedSecrets(ctx, kubeset, namespace, []string{secretName},
+		Source{Path: towerAuthSecret, Provider: ProviderAnsible, Hash: CredentialHash(*cpSecretData)})
+}
+
+// ExternalTowerSecret - Name of the Ansible Tower secret created for a towerAuthSecret of an external store
+func ExternalTowerSecret(towerAuthSecret string) string {
+	return ExternalTowerSecretName + "-" + SourceLabelValue(towerAuthSecret)[:8]
+}
+
+func createAnsibleSecret(
+	ctx context.Context,
+	kubeset kubernetes.Interface,
+	cpSecretData map[string]string,
+	secretName string,
+	namespace string) error {
+
+	return createPatchSecret(ctx, kubeset, ansibleSecretData(cpSecretData), secretName, namespace,
+		corev1.SecretTypeOpaque)
+}
+
+// The keys of the Ansible Tower secret of an AnsibleJob
+func ansibleSecretData(cpSecretData map[string]string) map[string]string {
+	stringData := map[string]string{
+		"host":  cpSecretData["ansibleHost"],
+		"token": cpSecretData["ansibleToken"],
+	}
+	// Self-signed Ansible Tower instances need their CA, or the TLS verification turned off
+	if cpSecretData["ansibleCABundle"] != "" {
+		stringData[TowerCABundleKey] = cpSecretData["ansibleCABundle"]
+	}
+	if cpSecretData["ansibleVerifySSL"] != "" {
+		stringData[TowerVerifySSLKey] = strings.ToLower(strings.TrimSpace(cpSecretData["ansibleVerifySSL"]))
+	}
+	return stringData
+}
+
+func CreateAzureSecrets(
+	ctx context.Context,
+	kubeset kubernetes.Interface,
+	cpSecretData map[string]string,
+	clusterName string) error {
 
 	// Generate the AWS Credential secret
 	osServicePrincipal := map[string]string{
@@ -89,29 +360,37 @@ func CreateAzureSecrets(kubeset kubernetes.Interface, cpSecretData map[string]st
 	stringData := map[string]string{
 		"osServicePrincipal.json": string(bytes),
 	}
-	if err := createPatchSecret(kubeset, stringData, clusterName+suffixCreds, clusterName,
+	if err := createPatchSecret(ctx, kubeset, stringData, clusterName+suffixCreds, clusterName,
 		corev1.SecretTypeOpaque); err != nil {
 
 		return err
 	}
-	return createCommonSecrets(kubeset, cpSecretData, clusterName)
+	return createCommonSecrets(ctx, kubeset, cpSecretData, clusterName)
 }
 
-func CreateGCPSecrets(kubeset kubernetes.Interface, cpSecretData map[string]string, clusterName string) error {
+func CreateGCPSecrets(
+	ctx context.Context,
+	kubeset kubernetes.Interface,
+	cpSecretData map[string]string,
+	clusterName string) error {
 
 	// Generate the AWS Credential secret
 	stringData := map[string]string{
 		"osServiceAccount.json": cpSecretData["gcServiceAccountKey"],
 	}
-	if err := createPatchSecret(kubeset, stringData, clusterName+suffixCreds, clusterName,
+	if err := createPatchSecret(ctx, kubeset, stringData, clusterName+suffixCreds, clusterName,
 		corev1.SecretTypeOpaque); err != nil {
 
 		return err
 	}
-	return createCommonSecrets(kubeset, cpSecretData, clusterName)
+	return createCommonSecrets(ctx, kubeset, cpSecretData, clusterName)
 }
 
-func CreateAWSSecrets(kubeset kubernetes.Interface, cpSecretData map[string]string, clusterName string) error {
+func CreateAWSSecrets(
+	ctx context.Context,
+	kubeset kubernetes.Interface,
+	cpSecretData map[string]string,
+	clusterName string) error {
 
 	// Generate the AWS Credential secret
 	stringData := map[string]string{
@@ -119,20 +398,305 @@ func CreateAWSSecrets(kubeset kubernetes.Interface, cpSecretData map[string]stri
 		"aws_secret_access_key": cpSecretData["awsSecretAccessKeyID"],
 	}
 
-	if err := createPatchSecret(kubeset, stringData, clusterName+suffixCreds, clusterName,
+	if err := createPatchSecret(ctx, kubeset, stringData, clusterName+suffixCreds, clusterName,
+		corev1.SecretTypeOpaque); err != nil {
+
+		return err
+	}
+	return createCommonSecrets(ctx, kubeset, cpSecretData, clusterName)
+}
+
+func CreateVSphereSecrets(
+	ctx context.Context,
+	kubeset kubernetes.Interface,
+	cpSecretData map[string]string,
+	clusterName string) error {
+
+	// Generate the vSphere Credential secret
+	stringData := map[string]string{
+		"username": cpSecretData["username"],
+		"password": cpSecretData["password"],
+	}
+	if err := createPatchSecret(ctx, kubeset, stringData, clusterName+suffixCreds, clusterName,
+		corev1.SecretTypeOpaque); err != nil {
+
+		return err
+	}
+
+	// Generate the vCenter CA certificates secret
+	stringData = map[string]string{
+		".cacert": cpSecretData["cacertificate"],
+	}
+	if err := createPatchSecret(ctx, kubeset, stringData, clusterName+suffixVSphereCerts, clusterName,
+		corev1.SecretTypeOpaque); err != nil {
+
+		return err
+	}
+	return createCommonSecrets(ctx, kubeset, cpSecretData, clusterName)
+}
+
+func CreateOpenStackSecrets(
+	ctx context.Context,
+	kubeset kubernetes.Interface,
+	cpSecretData map[string]string,
+	clusterName string) error {
+
+	// Generate the OpenStack Credential secret
+	stringData := map[string]string{
+		"clouds.yaml": cpSecretData["clouds.yaml"],
+	}
+	if err := createPatchSecret(ctx, kubeset, stringData, clusterName+suffixCreds, clusterName,
+		corev1.SecretTypeOpaque); err != nil {
+
+		return err
+	}
+	return createCommonSecrets(ctx, kubeset, cpSecretData, clusterName)
+}
+
+func CreateIBMCloudSecrets(
+	ctx context.Context,
+	kubeset kubernetes.Interface,
+	cpSecretData map[string]string,
+	clusterName string) error {
+
+	// Generate the IBM Cloud Credential secret
+	stringData := map[string]string{
+		"ibmcloud_api_key": cpSecretData["ibmCloudApiKey"],
+	}
+	if err := createPatchSecret(ctx, kubeset, stringData, clusterName+suffixCreds, clusterName,
+		corev1.SecretTypeOpaque); err != nil {
+
+		return err
+	}
+	return createCommonSecrets(ctx, kubeset, cpSecretData, clusterName)
+}
+
+func CreateKubeVirtSecrets(
+	ctx context.Context,
+	kubeset kubernetes.Interface,
+	cpSecretData map[string]string,
+	clusterName string) error {
+
+	// The infrastructure kubeconfig is only present when the VMs run on an external cluster
+	if cpSecretData["kubeconfig"] != "" {
+		stringData := map[string]string{
+			"kubeconfig": cpSecretData["kubeconfig"],
+		}
+		if err := createPatchSecret(ctx, kubeset, stringData, clusterName+suffixInfraKubeconfig, clusterName,
+			corev1.SecretTypeOpaque); err != nil {
+
+			return err
+		}
+	}
+	return createCommonSecrets(ctx, kubeset, cpSecretData, clusterName)
+}
+
+func CreateBareMetalSecrets(
+	ctx context.Context,
+	kubeset kubernetes.Interface,
+	cpSecretData map[string]string,
+	clusterName string) error {
+
+	// Generate the libvirt SSH key secret, used to reach the provisioning host
+	stringData := map[string]string{
+		"ssh-privatekey": cpSecretData["sshPrivatekey"],
+	}
+	if err := createPatchSecret(ctx, kubeset, stringData, clusterName+suffixLibvirtSsh, clusterName,
 		corev1.SecretTypeOpaque); err != nil {
 
 		return err
 	}
-	return createCommonSecrets(kubeset, cpSecretData, clusterName)
+
+	// Generate the BMC Credential secret, when the credential has one
+	if cpSecretData["bmcUsername"] != "" {
+		stringData = map[string]string{
+			"username": cpSecretData["bmcUsername"],
+			"password": cpSecretData["bmcPassword"],
+		}
+		if err := createPatchSecret(ctx, kubeset, stringData, clusterName+suffixBmcCreds, clusterName,
+			corev1.SecretTypeOpaque); err != nil {
+
+			return err
+		}
+	}
+	return createCommonSecrets(ctx, kubeset, cpSecretData, clusterName)
 }
 
-func createCommonSecrets(kubeset kubernetes.Interface, cpSecretData map[string]string, clusterName string) error {
+// Creates the Hive secrets of the provider from the Provider credential
+func CreateProviderSecrets(
+	ctx context.Context,
+	kubeset kubernetes.Interface,
+	provider string,
+	cpSecretData map[string]string,
+	clusterName string) error {
+
+	createSecrets, ok := providerSecrets[provider]
+	if !ok {
+		return utils.NewError(utils.ReasonInvalidSpec, "Unsupported provider: %s", provider)
+	}
+	return createSecrets(ctx, kubeset, cpSecretData, clusterName)
+}
+
+/* GetClusterDeploymentProvider - Returns the provider of the ClusterDeployment platform, empty when the
+ * platform has no provider credential, like agent installs.
+ */
+func GetClusterDeploymentProvider(ctx context.Context, client clientv1.Client, clusterName string) (string, error) {
+	cd := &unstructured.Unstructured{}
+	cd.SetGroupVersionKind(hivev1.SchemeGroupVersion.WithKind("ClusterDeployment"))
+
+	if err := client.Get(ctx, types.NamespacedName{Namespace: clusterName, Name: clusterName}, cd); err != nil {
+		return "", err
+	}
+
+	platform, _, err := unstructured.NestedMap(cd.Object, "spec", "platform")
+	if err != nil {
+		return "", err
+	}
+	for _, provider := range platformProviders {
+		if _, ok := platform[provider]; ok {
+			return provider, nil
+		}
+	}
+	return "", nil
+}
+
+/* CredentialProvider - Returns the provider of a Provider credential, from its type label or else from the
+ * keys only that provider has. Empty when it can not be told.
+ */
+func CredentialProvider(labels map[string]string, cpSecretData map[string]string) string {
+	if credentialType := strings.ToLower(labels[CredentialTypeLabel]); credentialType != "" {
+		if provider, ok := credentialTypeProviders[credentialType]; ok {
+			return provider
+		}
+		if _, ok := providerSecrets[credentialType]; ok {
+			return normalizeProvider(credentialType)
+		}
+	}
+
+	for _, kp := range credentialKeyProviders {
+		if cpSecretData[kp.key] != "" {
+			return kp.provider
+		}
+	}
+	return ""
+}
+
+// Reads the type label of the Provider credential and returns its provider, see CredentialProvider
+func GetCredentialProvider(
+	ctx context.Context,
+	kubeset kubernetes.Interface,
+	providerCredentialPath string,
+	cpSecretData map[string]string) (string, error) {
+
+	// External credentials have no labels
+	if IsExternalPath(providerCredentialPath) {
+		return CredentialProvider(nil, cpSecretData), nil
+	}
+
+	secretNamespace, secretName, err := utils.PathSplitterFromEnv(providerCredentialPath)
+	if err != nil {
+		return "", err
+	}
+
+	secret, err := kubeset.CoreV1().Secrets(secretNamespace).Get(ctx, secretName, v1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	return CredentialProvider(secret.Labels, cpSecretData), nil
+}
+
+/* GetClusterProvider - Returns the provider of the cluster platform, from the ClusterDeployment or else
+ * from the HostedCluster. Empty when neither exists or the platform has no provider credential.
+ */
+func GetClusterProvider(
+	ctx context.Context,
+	client clientv1.Client,
+	clusterName string,
+	clusterNamespace string) (string, error) {
+
+	provider, err := GetClusterDeploymentProvider(ctx, client, clusterName)
+	if !isAbsent(err) {
+		return provider, err
+	}
+
+	hc := &unstructured.Unstructured{}
+	hc.SetGroupVersionKind(utils.HCGVR.GroupVersion().WithKind("HostedCluster"))
+	err = client.Get(ctx, types.NamespacedName{Namespace: clusterNamespace, Name: clusterName}, hc)
+	if isAbsent(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	platformType, _, _ := unstructured.NestedString(hc.Object, "spec", "platform", "type")
+	if _, ok := providerSecrets[strings.ToLower(platformType)]; ok {
+		return strings.ToLower(platformType), nil
+	}
+	return "", nil
+}
+
+// The resource, or its kind, does not exist
+func isAbsent(err error) bool {
+	return err != nil &&
+		(k8serrors.IsNotFound(err) || meta.IsNoMatchError(err) || runtime.IsNotRegisteredError(err))
+}
+
+func normalizeProvider(provider string) string {
+	if provider == ProviderVMware {
+		return ProviderVSphere
+	}
+	return provider
+}
+
+/* ResolveProvider - Chooses the provider to create the secrets for. requested is the provider of the
+ * applycloudprovider-PROVIDER step, empty for applycloudprovider. The provider of the credential and of
+ * the cluster platform must agree with each other and with requested, when they are known.
+ */
+func ResolveProvider(requested string, credential string, platform string) (string, error) {
+	known := map[string]string{
+		"applycloudprovider step": normalizeProvider(requested),
+		"Provider credential":     credential,
+		"cluster platform":        platform,
+	}
+
+	provider := ""
+	source := ""
+	for _, name := range []string{"applycloudprovider step", "Provider credential", "cluster platform"} {
+		value := known[name]
+		if value == "" {
+			continue
+		}
+		if provider == "" {
+			provider = value
+			source = name
+		} else if value != provider {
+			return "", utils.NewError(utils.ReasonInvalidCredential,
+				"The %s is %s, but the %s is %s", source, provider, name, value)
+		}
+	}
+
+	switch provider {
+	case "":
+		return "", utils.NewError(utils.ReasonInvalidCredential,
+			"Unable to detect the provider, the credential has no %s label and the cluster platform is unknown",
+			CredentialTypeLabel)
+	case ProviderAnsible:
+		return "", utils.NewError(utils.ReasonInvalidCredential,
+			"The Provider credential is an Ansible Tower credential, it has no cloud provider secrets")
+	}
+	return provider, nil
+}
+
+func createCommonSecrets(
+	ctx context.Context,
+	kubeset kubernetes.Interface,
+	cpSecretData map[string]string,
+	clusterName string) error {
 	// Generate Pull Secret
 	stringData := map[string]string{
 		".dockerconfigjson": cpSecretData["pullSecret"],
 	}
-	if err := createPatchSecret(kubeset, stringData, clusterName+suffixPull,
+	if err := createPatchSecret(ctx, kubeset, stringData, clusterName+suffixPull,
 		clusterName, corev1.SecretTypeDockerConfigJson); err != nil {
 
 		return err
@@ -142,7 +706,7 @@ func createCommonSecrets(kubeset kubernetes.Interface, cpSecretData map[string]s
 	stringData = map[string]string{
 		"ssh-privatekey": cpSecretData["sshPrivatekey"],
 	}
-	if err := createPatchSecret(kubeset, stringData, clusterName+suffixSsh,
+	if err := createPatchSecret(ctx, kubeset, stringData, clusterName+suffixSsh,
 		clusterName, corev1.SecretTypeOpaque); err != nil {
 
 		return err
@@ -151,6 +715,7 @@ func createCommonSecrets(kubeset kubernetes.Interface, cpSecretData map[string]s
 }
 
 func createPatchSecret(
+	ctx context.Context,
 	kubeset kubernetes.Interface,
 	stringData map[string]string,
 	secretName string,
@@ -161,9 +726,9 @@ func createPatchSecret(
 
 	mObj := v1.ObjectMeta{Name: secretName}
 	newSecret := &corev1.Secret{StringData: stringData, ObjectMeta: mObj, Type: secretType}
-	_, err := kubeset.CoreV1().Secrets(clusterName).Create(context.TODO(), newSecret, v1.CreateOptions{})
+	_, err := kubeset.CoreV1().Secrets(clusterName).Create(ctx, newSecret, v1.CreateOptions{})
 	// This is where we patch. To save permissions we use the error instead of a list
-	if err != nil && strings.Contains(err.Error(), "already exists") {
+	if k8serrors.IsAlreadyExists(err) {
 		klog.V(2).Info(" X (already exists)")
 		patch := []patchStringValue{{
 			Op:    "replace",
@@ -173,7 +738,7 @@ func createPatchSecret(
 		patchInBytes, _ := json.Marshal(patch)
 		klog.V(2).Info(" > Patching secret " + secretName + " in namespace " + clusterName)
 		_, err = kubeset.CoreV1().Secrets(clusterName).Patch(
-			context.TODO(), secretName, types.JSONPatchType, patchInBytes, v1.PatchOptions{})
+			ctx, secretName, types.JSONPatchType, patchInBytes, v1.PatchOptions{})
 	}
 
 	if err = utils.LogError(err); err != nil {
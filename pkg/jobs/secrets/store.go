@@ -0,0 +1,54 @@
+// Copyright Contributors to the Open Cluster Management project.
+package secrets
+
+import (
+	"context"
+	"strings"
+
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/utils"
+)
+
+// Scheme of the credentials kept in HashiCorp Vault, vault://MOUNT/PATH
+const VaultScheme = "vault"
+
+// Prefix of the Ansible Tower secrets created from a towerAuthSecret kept in an external store
+const ExternalTowerSecretName = "toweraccess-external"
+
+// Store is a source of credentials kept outside of the hub, referenced as SCHEME://PATH
+type Store interface {
+	// Returns the fields of the credential at path
+	GetCredential(ctx context.Context, path string) (map[string]string, error)
+}
+
+type newStoreFunc func() (Store, error)
+
+// The stores are configured from the environment of the curator job
+var stores = map[string]newStoreFunc{
+	VaultScheme: NewVaultStoreFromEnv,
+}
+
+// The providerCredentialPath or towerAuthSecret references an external store, instead of a hub Secret
+func IsExternalPath(path string) bool {
+	return strings.Contains(path, "://")
+}
+
+/* GetExternalCredential - Reads the credential referenced by SCHEME://PATH from its store. The store is
+ * resolved at run time, so the credential is never kept in a hub Secret by the user.
+ */
+func GetExternalCredential(ctx context.Context, reference string) (map[string]string, error) {
+	scheme, path, _ := strings.Cut(reference, "://")
+
+	newStore, ok := stores[scheme]
+	if !ok {
+		return nil, utils.NewError(utils.ReasonInvalidSpec, "Unsupported secret store \"%s\" in %s", scheme, reference)
+	}
+	if strings.Trim(path, "/") == "" {
+		return nil, utils.NewError(utils.ReasonInvalidSpec, "The secret store reference has no path: %s", reference)
+	}
+
+	store, err := newStore()
+	if err != nil {
+		return nil, err
+	}
+	return store.GetCredential(ctx, strings.Trim(path, "/"))
+}
@@ -0,0 +1,166 @@
+// Copyright Contributors to the Open Cluster Management project.
+package secrets
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/utils"
+	"gopkg.in/yaml.v3"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// Keys of the Provider credential that each provider requires, besides pullSecret and sshPrivatekey
+var requiredProviderKeys = map[string][]string{
+	ProviderAWS:       {"awsAccessKeyID", "awsSecretAccessKeyID"},
+	ProviderGCP:       {"gcServiceAccountKey"},
+	ProviderAzure:     {"clientId", "clientSecret", "tenantId", "subscriptionId"},
+	ProviderVSphere:   {"username", "password"},
+	ProviderOpenStack: {"clouds.yaml"},
+	ProviderIBMCloud:  {"ibmCloudApiKey"},
+}
+
+type credentialProblems []string
+
+func (p *credentialProblems) add(format string, args ...interface{}) {
+	*p = append(*p, fmt.Sprintf(format, args...))
+}
+
+func (p *credentialProblems) require(cpSecretData map[string]string, keys ...string) {
+	for _, key := range keys {
+		if strings.TrimSpace(cpSecretData[key]) == "" {
+			p.add("%s is missing or empty", key)
+		}
+	}
+}
+
+/* ValidateProviderCredential - Checks the Provider credential has what the Hive secrets of the provider
+ * need, before any secret is written. All the problems found are returned in one ReasonInvalidCredential
+ * error, so the credential can be fixed in one go. The Ansible Tower keys are checked for every provider.
+ */
+func ValidateProviderCredential(provider string, cpSecretData map[string]string) error {
+	problems := credentialProblems{}
+	provider = normalizeProvider(provider)
+
+	if provider != ProviderAnsible {
+		if _, ok := providerSecrets[provider]; !ok {
+			return utils.NewError(utils.ReasonInvalidSpec, "Unsupported provider: %s", provider)
+		}
+		problems.require(cpSecretData, requiredProviderKeys[provider]...)
+		problems.require(cpSecretData, "pullSecret", "sshPrivatekey")
+
+		validatePullSecret(&problems, cpSecretData["pullSecret"])
+		validatePEM(&problems, "sshPrivatekey", cpSecretData["sshPrivatekey"])
+	}
+
+	switch provider {
+	case ProviderGCP:
+		validateGCPServiceAccountKey(&problems, cpSecretData["gcServiceAccountKey"])
+	case ProviderVSphere:
+		validatePEM(&problems, "cacertificate", cpSecretData["cacertificate"])
+	case ProviderOpenStack:
+		validateCloudsYaml(&problems, cpSecretData["clouds.yaml"])
+	case ProviderKubeVirt:
+		if cpSecretData["kubeconfig"] != "" {
+			if _, err := clientcmd.Load([]byte(cpSecretData["kubeconfig"])); err != nil {
+				problems.add("kubeconfig is not a valid kubeconfig: %v", err)
+			}
+		}
+	case ProviderBareMetal:
+		if cpSecretData["bmcUsername"] != "" {
+			problems.require(cpSecretData, "bmcPassword")
+		}
+	}
+
+	validateAnsible(&problems, cpSecretData)
+
+	if len(problems) > 0 {
+		return utils.NewError(utils.ReasonInvalidCredential,
+			"The Provider credential is invalid for %s: %s", provider, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// The pull secret must be a dockerconfigjson with at least one registry
+func validatePullSecret(problems *credentialProblems, pullSecret string) {
+	if strings.TrimSpace(pullSecret) == "" {
+		return
+	}
+	dockerConfig := struct {
+		Auths map[string]json.RawMessage `json:"auths"`
+	}{}
+	if err := json.Unmarshal([]byte(pullSecret), &dockerConfig); err != nil {
+		problems.add("pullSecret is not a valid dockerconfigjson: %v", err)
+	} else if len(dockerConfig.Auths) == 0 {
+		problems.add("pullSecret is not a valid dockerconfigjson: it has no auths")
+	}
+}
+
+// Optional keys are only checked when present
+func validatePEM(problems *credentialProblems, key string, value string) {
+	if strings.TrimSpace(value) == "" {
+		return
+	}
+	if block, _ := pem.Decode([]byte(value)); block == nil {
+		problems.add("%s is not PEM encoded", key)
+	}
+}
+
+func validateGCPServiceAccountKey(problems *credentialProblems, key string) {
+	if strings.TrimSpace(key) == "" {
+		return
+	}
+	serviceAccount := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(key), &serviceAccount); err != nil {
+		problems.add("gcServiceAccountKey is not valid JSON: %v", err)
+		return
+	}
+	if serviceAccount["type"] != "service_account" {
+		problems.add("gcServiceAccountKey type is \"%v\", expected \"service_account\"", serviceAccount["type"])
+	}
+	for _, field := range []string{"project_id", "private_key", "client_email"} {
+		if value, _ := serviceAccount[field].(string); value == "" {
+			problems.add("gcServiceAccountKey has no %s", field)
+		}
+	}
+}
+
+func validateCloudsYaml(problems *credentialProblems, cloudsYaml string) {
+	if strings.TrimSpace(cloudsYaml) == "" {
+		return
+	}
+	clouds := struct {
+		Clouds map[string]interface{} `yaml:"clouds"`
+	}{}
+	if err := yaml.Unmarshal([]byte(cloudsYaml), &clouds); err != nil {
+		problems.add("clouds.yaml is not valid YAML: %v", err)
+	} else if len(clouds.Clouds) == 0 {
+		problems.add("clouds.yaml has no clouds")
+	}
+}
+
+// The Ansible Tower keys are optional, but the host and token go together
+func validateAnsible(problems *credentialProblems, cpSecretData map[string]string) {
+	host := strings.TrimSpace(cpSecretData["ansibleHost"])
+	token := strings.TrimSpace(cpSecretData["ansibleToken"])
+	if host == "" && token == "" {
+		return
+	}
+	problems.require(cpSecretData, "ansibleHost", "ansibleToken")
+	if host == "" {
+		return
+	}
+	if u, err := url.Parse(host); err != nil || u.Scheme == "" || u.Host == "" {
+		problems.add("ansibleHost \"%s\" is not a URL", host)
+	}
+	if verifySSL := strings.TrimSpace(cpSecretData["ansibleVerifySSL"]); verifySSL != "" {
+		if _, err := strconv.ParseBool(verifySSL); err != nil {
+			problems.add("ansibleVerifySSL \"%s\" is not true or false", verifySSL)
+		}
+	}
+	validatePEM(problems, "ansibleCABundle", cpSecretData["ansibleCABundle"])
+}
@@ -0,0 +1,213 @@
+// Copyright Contributors to the Open Cluster Management project.
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/utils"
+	"k8s.io/klog/v2"
+)
+
+// Environment of the curator job that configures the Vault store
+const (
+	VaultAddrEnv      = "VAULT_ADDR"
+	VaultTokenEnv     = "VAULT_TOKEN"
+	VaultNamespaceEnv = "VAULT_NAMESPACE"
+	VaultCACertEnv    = "VAULT_CACERT"
+	// Role of the Vault Kubernetes auth method, used when there is no VAULT_TOKEN
+	VaultRoleEnv = "VAULT_ROLE"
+	// Mount path of the Vault Kubernetes auth method, kubernetes by default
+	VaultAuthPathEnv = "VAULT_AUTH_PATH"
+	// Version of the KV secrets engine, 1 or 2 (default)
+	VaultKVVersionEnv = "VAULT_KV_VERSION"
+)
+
+// Service account token of the curator job, sent to the Vault Kubernetes auth method
+var serviceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+/* VaultStore reads the credentials from a Vault KV secrets engine, over the HTTP API. The path of a
+ * credential starts with the mount of the engine, vault://secret/clusters/aws reads clusters/aws from
+ * the engine mounted at secret.
+ */
+type VaultStore struct {
+	Address   string
+	Token     string
+	Namespace string
+	Role      string
+	AuthPath  string
+	KVVersion int
+	Client    *http.Client
+}
+
+// Builds the Vault store from the VAULT_* environment of the curator job
+func NewVaultStoreFromEnv() (Store, error) {
+	store := &VaultStore{
+		Address:   strings.TrimSuffix(os.Getenv(VaultAddrEnv), "/"),
+		Token:     os.Getenv(VaultTokenEnv),
+		Namespace: os.Getenv(VaultNamespaceEnv),
+		Role:      os.Getenv(VaultRoleEnv),
+		AuthPath:  os.Getenv(VaultAuthPathEnv),
+		KVVersion: 2,
+		Client:    &http.Client{Timeout: 30 * time.Second},
+	}
+
+	if store.Address == "" {
+		return nil, utils.NewError(utils.ReasonInvalidSpec, "%s is not set, it is needed to read the vault:// credentials",
+			VaultAddrEnv)
+	}
+	if store.Token == "" && store.Role == "" {
+		return nil, utils.NewError(utils.ReasonInvalidSpec, "Set %s or %s to authenticate to Vault",
+			VaultTokenEnv, VaultRoleEnv)
+	}
+	if store.AuthPath == "" {
+		store.AuthPath = "kubernetes"
+	}
+
+	if version := os.Getenv(VaultKVVersionEnv); version != "" {
+		kvVersion, err := strconv.Atoi(version)
+		if err != nil || (kvVersion != 1 && kvVersion != 2) {
+			return nil, utils.NewError(utils.ReasonInvalidSpec, "%s must be 1 or 2, found: %s", VaultKVVersionEnv, version)
+		}
+		store.KVVersion = kvVersion
+	}
+
+	if caCertPath := os.Getenv(VaultCACertEnv); caCertPath != "" {
+		caCert, err := os.ReadFile(caCertPath)
+		if err != nil {
+			return nil, utils.WrapError(utils.ReasonInvalidSpec, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, utils.NewError(utils.ReasonInvalidSpec, "%s has no PEM certificate: %s", VaultCACertEnv, caCertPath)
+		}
+		store.Client.Transport = &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12},
+		}
+	}
+	return store, nil
+}
+
+func (v *VaultStore) GetCredential(ctx context.Context, path string) (map[string]string, error) {
+	if v.Token == "" {
+		if err := v.login(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	mount, secretPath, _ := strings.Cut(path, "/")
+	url := v.Address + "/v1/" + mount + "/" + secretPath
+	if v.KVVersion == 2 {
+		url = v.Address + "/v1/" + mount + "/data/" + secretPath
+	}
+
+	klog.V(2).Infof("=> Reading the credential %v from Vault", path)
+	response := struct {
+		Data map[string]interface{} `json:"data"`
+	}{}
+	if err := v.do(ctx, http.MethodGet, url, nil, &response); err != nil {
+		return nil, fmt.Errorf("vault://%s: %w", path, err)
+	}
+
+	fields := response.Data
+	if v.KVVersion == 2 {
+		fields, _ = response.Data["data"].(map[string]interface{})
+	}
+	if len(fields) == 0 {
+		return nil, utils.NewError(utils.ReasonNotFound, "The Vault credential vault://%s has no data", path)
+	}
+
+	credential := map[string]string{}
+	for key, value := range fields {
+		if s, ok := value.(string); ok {
+			credential[key] = s
+			continue
+		}
+		// Nested values, like a GCP service account key, are kept as JSON
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		credential[key] = string(encoded)
+	}
+	return credential, nil
+}
+
+// Exchanges the service account token of the curator job for a Vault token
+func (v *VaultStore) login(ctx context.Context) error {
+	jwt, err := os.ReadFile(serviceAccountTokenPath)
+	if err != nil {
+		return utils.WrapError(utils.ReasonForbidden, err)
+	}
+
+	body, _ := json.Marshal(map[string]string{"role": v.Role, "jwt": strings.TrimSpace(string(jwt))})
+	response := struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}{}
+	if err = v.do(ctx, http.MethodPost, v.Address+"/v1/auth/"+v.AuthPath+"/login", body, &response); err != nil {
+		return fmt.Errorf("Vault login with role %s: %w", v.Role, err)
+	}
+	if response.Auth.ClientToken == "" {
+		return utils.NewError(utils.ReasonForbidden, "Vault login with role %s returned no token", v.Role)
+	}
+	v.Token = response.Auth.ClientToken
+	return nil
+}
+
+func (v *VaultStore) do(ctx context.Context, method string, url string, body []byte, result interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if v.Token != "" {
+		request.Header.Set("X-Vault-Token", v.Token)
+	}
+	if v.Namespace != "" {
+		request.Header.Set("X-Vault-Namespace", v.Namespace)
+	}
+
+	response, err := v.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	payload, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case response.StatusCode == http.StatusNotFound:
+		return utils.NewError(utils.ReasonNotFound, "not found in Vault")
+	case response.StatusCode == http.StatusForbidden || response.StatusCode == http.StatusUnauthorized:
+		return utils.NewError(utils.ReasonForbidden, "Vault denied the request: %s", vaultErrors(payload))
+	case response.StatusCode >= 300:
+		return fmt.Errorf("Vault returned %s: %s", response.Status, vaultErrors(payload))
+	}
+	return json.Unmarshal(payload, result)
+}
+
+// The errors of a Vault response, or its body when it has none
+func vaultErrors(payload []byte) string {
+	response := struct {
+		Errors []string `json:"errors"`
+	}{}
+	if err := json.Unmarshal(payload, &response); err == nil && len(response.Errors) > 0 {
+		return strings.Join(response.Errors, ", ")
+	}
+	return strings.TrimSpace(string(payload))
+}
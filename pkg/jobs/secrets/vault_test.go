@@ -0,0 +1,170 @@
+// Copyright Contributors to the Open Cluster Management project.
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	clustercuratorv1 "github.com/stolostron/cluster-curator-controller/pkg/api/v1beta1"
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/utils"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const vaultToken = "s.curator-token"
+
+// A stand-in for the Vault HTTP API, with a KV version 2 engine mounted at secret and version 1 at kv
+func newVaultServer(t *testing.T) *httptest.Server {
+	fields := map[string]interface{}{
+		"aws_access_key_id":     AwsKeyValue,
+		"aws_secret_access_key": AwsKeySecretValue,
+		"pullSecret":            getCPMap()["pullSecret"],
+		"ssh-privatekey":        getCPMap()["SshPrivatekey"],
+	}
+	tower := map[string]interface{}{"host": HostURL, "token": "tower-token"}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/auth/kubernetes/login" {
+			login := map[string]string{}
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&login))
+			if login["role"] != "cluster-curator" || login["jwt"] != "sa-token" {
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(`{"errors": ["permission denied"]}`))
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"auth": map[string]string{"client_token": vaultToken}})
+			return
+		}
+
+		if r.Header.Get("X-Vault-Token") != vaultToken {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors": ["permission denied"]}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/clusters/aws":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"data": fields}})
+		case "/v1/secret/data/tower":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"data": tower}})
+		case "/v1/kv/clusters/aws":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": fields})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors": []}`))
+		}
+	}))
+}
+
+func TestVaultStoreKVVersions(t *testing.T) {
+	server := newVaultServer(t)
+	defer server.Close()
+
+	t.Setenv(VaultAddrEnv, server.URL)
+	t.Setenv(VaultTokenEnv, vaultToken)
+
+	credential, err := GetExternalCredential(context.TODO(), "vault://secret/clusters/aws")
+	assert.Nil(t, err)
+	assert.Equal(t, AwsKeyValue, credential["aws_access_key_id"])
+
+	t.Setenv(VaultKVVersionEnv, "1")
+	credential, err = GetExternalCredential(context.TODO(), "vault://kv/clusters/aws")
+	assert.Nil(t, err)
+	assert.Equal(t, AwsKeySecretValue, credential["aws_secret_access_key"])
+
+	_, err = GetExternalCredential(context.TODO(), "vault://kv/clusters/missing")
+	assert.Equal(t, utils.ReasonNotFound, utils.ReasonForError(err))
+	assert.Contains(t, err.Error(), "vault://kv/clusters/missing")
+}
+
+func TestVaultStoreKubernetesLogin(t *testing.T) {
+	server := newVaultServer(t)
+	defer server.Close()
+
+	tokenPath := filepath.Join(t.TempDir(), "token")
+	assert.Nil(t, os.WriteFile(tokenPath, []byte("sa-token\n"), 0600))
+	defer func(path string) { serviceAccountTokenPath = path }(serviceAccountTokenPath)
+	serviceAccountTokenPath = tokenPath
+
+	t.Setenv(VaultAddrEnv, server.URL)
+	t.Setenv(VaultRoleEnv, "cluster-curator")
+
+	credential, err := GetExternalCredential(context.TODO(), "vault://secret/clusters/aws")
+	assert.Nil(t, err, "the service account token is exchanged for a Vault token")
+	assert.Equal(t, AwsKeyValue, credential["aws_access_key_id"])
+
+	t.Setenv(VaultRoleEnv, "other-role")
+	_, err = GetExternalCredential(context.TODO(), "vault://secret/clusters/aws")
+	assert.Equal(t, utils.ReasonForbidden, utils.ReasonForError(err))
+	assert.Contains(t, err.Error(), "permission denied")
+}
+
+func TestVaultStoreConfiguration(t *testing.T) {
+	t.Setenv(VaultAddrEnv, "")
+	_, err := GetExternalCredential(context.TODO(), "vault://secret/clusters/aws")
+	assert.Equal(t, utils.ReasonInvalidSpec, utils.ReasonForError(err), "VAULT_ADDR is required")
+
+	t.Setenv(VaultAddrEnv, "https://vault.example.com")
+	t.Setenv(VaultTokenEnv, "")
+	t.Setenv(VaultRoleEnv, "")
+	_, err = GetExternalCredential(context.TODO(), "vault://secret/clusters/aws")
+	assert.Equal(t, utils.ReasonInvalidSpec, utils.ReasonForError(err), "a token or a role is required")
+
+	t.Setenv(VaultTokenEnv, vaultToken)
+	t.Setenv(VaultKVVersionEnv, "3")
+	_, err = GetExternalCredential(context.TODO(), "vault://secret/clusters/aws")
+	assert.Equal(t, utils.ReasonInvalidSpec, utils.ReasonForError(err))
+
+	_, err = GetExternalCredential(context.TODO(), "vault://")
+	assert.Equal(t, utils.ReasonInvalidSpec, utils.ReasonForError(err), "the path is required")
+
+	_, err = GetExternalCredential(context.TODO(), "aws-sm://clusters/aws")
+	assert.Equal(t, utils.ReasonInvalidSpec, utils.ReasonForError(err), "unsupported store")
+}
+
+func TestGetSecretDataFromVault(t *testing.T) {
+	server := newVaultServer(t)
+	defer server.Close()
+
+	t.Setenv(VaultAddrEnv, server.URL)
+	t.Setenv(VaultTokenEnv, vaultToken)
+	kubeset := fake.NewSimpleClientset()
+
+	secretData, err := GetSecretData(context.TODO(), kubeset, "vault://secret/clusters/aws")
+	assert.Nil(t, err)
+	assert.Equal(t, AwsKeyValue, (*secretData)["awsAccessKeyID"], "the Vault keys are read like the current format")
+
+	provider, err := GetCredentialProvider(context.TODO(), kubeset, "vault://secret/clusters/aws", *secretData)
+	assert.Nil(t, err)
+	assert.Equal(t, ProviderAWS, provider)
+
+	_, err = ApplyExternalTowerSecret(context.TODO(), kubeset, "vault://secret/tower", cpNamespace)
+	assert.Equal(t, utils.ReasonInvalidCredential, utils.ReasonForError(err), "the hooks can not create secrets")
+
+	curator := &clustercuratorv1.ClusterCurator{ObjectMeta: v1.ObjectMeta{Name: "c", Namespace: cpNamespace}}
+	curator.Spec.Install.TowerAuthSecret = "vault://secret/tower"
+	curator.Spec.Install.Posthook = []clustercuratorv1.Hook{{Name: "hook", TowerAuthSecret: "vault://secret/clusters/aws"}}
+	assert.Nil(t, ReserveTowerSecrets(context.TODO(), kubeset, curator))
+
+	secretName, err := ApplyExternalTowerSecret(context.TODO(), kubeset, "vault://secret/tower", cpNamespace)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(secretName, ExternalTowerSecretName+"-"), "one secret per reference")
+
+	otherSecretName, err := ApplyExternalTowerSecret(context.TODO(), kubeset, "vault://secret/tower", cpNamespace)
+	assert.Nil(t, err)
+	assert.Equal(t, secretName, otherSecretName, "the same reference reuses its secret")
+
+	towerSecret, err := kubeset.CoreV1().Secrets(cpNamespace).Get(context.TODO(), secretName, v1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, HostURL, towerSecret.StringData["host"])
+	assert.Equal(t, "tower-token", towerSecret.StringData["token"])
+
+	_, err = ApplyExternalTowerSecret(context.TODO(), kubeset, "vault://secret/clusters/aws", cpNamespace)
+	assert.Equal(t, utils.ReasonInvalidCredential, utils.ReasonForError(err), "the credential has no Tower host")
+}
@@ -0,0 +1,385 @@
+// Copyright Contributors to the Open Cluster Management project.
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	clustercuratorv1 "github.com/stolostron/cluster-curator-controller/pkg/api/v1beta1"
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/secrets"
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/utils"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// Step states, in the order a step goes through them
+const (
+	StepPending   = "Pending"
+	StepRunning   = "Running"
+	StepSucceeded = "Succeeded"
+	StepFailed    = "Failed"
+)
+
+// Output formats of Print
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+)
+
+// Condition types and reasons written by the curator steps that are not steps themselves
+const currentAnsibleJob = "current-ansiblejob"
+const ansibleJobURLReason = "ansiblejob_url"
+const ansibleJobArtifactsReason = "ansiblejob_artifacts"
+const curatorJobCondition = "clustercurator-job"
+
+// Step - One init container, or the final container, of the curator job
+type Step struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Status      string   `json:"status"`
+	Reason      string   `json:"reason,omitempty"`
+	Message     string   `json:"message,omitempty"`
+	StartTime   *v1.Time `json:"startTime,omitempty"`
+	Elapsed     string   `json:"elapsed,omitempty"`
+}
+
+// AnsibleJob - The last AnsibleJob started by a prehook or posthook step
+type AnsibleJob struct {
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	URL       string `json:"url,omitempty"`
+	Artifacts string `json:"artifacts,omitempty"`
+}
+
+// Curation - The progress of the current curation of a ClusterCurator
+type Curation struct {
+	Cluster         string      `json:"cluster"`
+	Namespace       string      `json:"namespace"`
+	DesiredCuration string      `json:"desiredCuration,omitempty"`
+	CuratorJob      string      `json:"curatorJob,omitempty"`
+	Status          string      `json:"status"`
+	Message         string      `json:"message,omitempty"`
+	Steps           []Step      `json:"steps,omitempty"`
+	AnsibleJob      *AnsibleJob `json:"ansibleJob,omitempty"`
+	UpgradeProgress string      `json:"upgradeProgress,omitempty"`
+}
+
+/* GetCuration - Builds the timeline of the current curation from the ClusterCurator conditions, the
+ * curator Job and its pod. The steps come from the containers of the Job, described by the Job
+ * annotations the launcher writes. When the Job is gone, the step conditions are used instead.
+ */
+func GetCuration(
+	ctx context.Context,
+	c client.Client,
+	clusterName string,
+	clusterNamespace string,
+	now time.Time) (*Curation, error) {
+
+	curator := &clustercuratorv1.ClusterCurator{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: clusterNamespace, Name: clusterName}, curator); err != nil {
+		return nil, err
+	}
+
+	curation := &Curation{
+		Cluster:         clusterName,
+		Namespace:       clusterNamespace,
+		DesiredCuration: curator.Spec.DesiredCuration,
+		CuratorJob:      utils.CuratingJob(curator),
+		Status:          StepPending,
+	}
+
+	conditions := curator.Status.Conditions
+	if cond := meta.FindStatusCondition(conditions, curatorJobCondition); cond != nil {
+		curation.Status = conditionState(cond)
+		curation.Message = cond.Message
+	}
+
+	job, pod, err := getJobAndPod(ctx, c, clusterNamespace, utils.CuratingJob(curator))
+	if err != nil {
+		return nil, err
+	}
+
+	if job != nil {
+		curation.Steps = jobSteps(job, pod, conditions, now)
+	} else {
+		curation.Steps = conditionSteps(conditions, now)
+	}
+
+	curation.AnsibleJob = ansibleJob(conditions)
+
+	for _, stepName := range []string{"monitor-upgrade", "intermediate-monitor-upgrade", "hypershift-upgrade-job"} {
+		if cond := meta.FindStatusCondition(conditions, stepName); cond != nil &&
+			strings.HasPrefix(cond.Message, "Upgrade status") {
+			curation.UpgradeProgress = cond.Message
+		}
+	}
+
+	return curation, nil
+}
+
+// Returns the curator Job and its latest pod, nil when they no longer exist
+func getJobAndPod(
+	ctx context.Context,
+	c client.Client,
+	namespace string,
+	jobName string) (*batchv1.Job, *corev1.Pod, error) {
+
+	if jobName == "" {
+		return nil, nil, nil
+	}
+
+	job := &batchv1.Job{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: jobName}, job); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+
+	pods := &corev1.PodList{}
+	if err := c.List(ctx, pods, client.InNamespace(namespace), client.MatchingLabels{"job-name": jobName}); err != nil {
+		return nil, nil, err
+	}
+
+	var pod *corev1.Pod
+	for i := range pods.Items {
+		if pod == nil || pod.CreationTimestamp.Before(&pods.Items[i].CreationTimestamp) {
+			pod = &pods.Items[i]
+		}
+	}
+	return job, pod, nil
+}
+
+func jobSteps(job *batchv1.Job, pod *corev1.Pod, conditions []v1.Condition, now time.Time) []Step {
+	containerStatuses := map[string]corev1.ContainerStatus{}
+	if pod != nil {
+		for _, cs := range pod.Status.InitContainerStatuses {
+			containerStatuses[cs.Name] = cs
+		}
+		for _, cs := range pod.Status.ContainerStatuses {
+			containerStatuses[cs.Name] = cs
+		}
+	}
+
+	var steps []Step
+	var containers []corev1.Container
+	containers = append(containers, job.Spec.Template.Spec.InitContainers...)
+	containers = append(containers, job.Spec.Template.Spec.Containers...)
+	for _, container := range containers {
+		step := Step{
+			Name:        container.Name,
+			Description: job.Annotations[container.Name],
+			Status:      StepPending,
+		}
+
+		// The final container records the end of the curator job
+		cond := meta.FindStatusCondition(conditions, container.Name)
+		if container.Name == "done" {
+			cond = meta.FindStatusCondition(conditions, curatorJobCondition)
+			if cond != nil && cond.Status != v1.ConditionTrue {
+				cond = nil
+			}
+		}
+		if cond != nil {
+			step.Status = conditionState(cond)
+			step.Reason = cond.Reason
+			step.Message = cond.Message
+		}
+
+		if cs, ok := containerStatuses[container.Name]; ok {
+			setContainerState(&step, cs.State, now)
+		}
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+// Refines the step with the state of its container, the source of the timing
+func setContainerState(step *Step, state corev1.ContainerState, now time.Time) {
+	switch {
+	case state.Running != nil:
+		step.StartTime = &state.Running.StartedAt
+		step.Elapsed = elapsed(state.Running.StartedAt.Time, now)
+		if step.Status == StepPending {
+			step.Status = StepRunning
+		}
+	case state.Terminated != nil:
+		step.StartTime = &state.Terminated.StartedAt
+		step.Elapsed = elapsed(state.Terminated.StartedAt.Time, state.Terminated.FinishedAt.Time)
+		if state.Terminated.ExitCode != 0 {
+			step.Status = StepFailed
+			if step.Reason == "" || step.Reason == utils.JobHasFinished {
+				step.Reason = state.Terminated.Reason
+			}
+		} else if step.Status != StepFailed {
+			step.Status = StepSucceeded
+		}
+	case state.Waiting != nil && state.Waiting.Reason != "PodInitializing":
+		step.Reason = state.Waiting.Reason
+		step.Message = state.Waiting.Message
+	}
+}
+
+// Steps from the ClusterCurator conditions alone, in the order they were last updated
+func conditionSteps(conditions []v1.Condition, now time.Time) []Step {
+	var steps []Step
+	for _, cond := range conditions {
+		if cond.Type == curatorJobCondition || cond.Type == currentAnsibleJob ||
+			cond.Reason == ansibleJobURLReason || cond.Reason == ansibleJobArtifactsReason {
+			continue
+		}
+		step := Step{
+			Name:    cond.Type,
+			Status:  conditionState(&cond),
+			Reason:  cond.Reason,
+			Message: cond.Message,
+		}
+		if step.Status == StepRunning {
+			step.StartTime = cond.LastTransitionTime.DeepCopy()
+			step.Elapsed = elapsed(cond.LastTransitionTime.Time, now)
+		}
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+func ansibleJob(conditions []v1.Condition) *AnsibleJob {
+	cond := meta.FindStatusCondition(conditions, currentAnsibleJob)
+	if cond == nil {
+		return nil
+	}
+
+	aj := &AnsibleJob{Name: cond.Message, Status: StepRunning}
+	if cond.Status == v1.ConditionTrue {
+		aj.Status = StepSucceeded
+	}
+	for _, c := range conditions {
+		if c.Type == cond.Message && c.Reason == ansibleJobURLReason {
+			aj.URL = c.Message
+		}
+		if c.Type == cond.Message+"-artifacts" && c.Reason == ansibleJobArtifactsReason {
+			aj.Artifacts = c.Message
+		}
+	}
+	return aj
+}
+
+/* The curator records a step with status False while it runs, and True once it ends, with the reason
+ * Job_has_finished when it succeeded or the reason of the failure
+ */
+func conditionState(cond *v1.Condition) string {
+	switch {
+	case cond.Status != v1.ConditionTrue:
+		return StepRunning
+	case cond.Reason == utils.JobHasFinished:
+		return StepSucceeded
+	}
+	return StepFailed
+}
+
+func elapsed(start time.Time, end time.Time) string {
+	if start.IsZero() || end.Before(start) {
+		return ""
+	}
+	return end.Sub(start).Round(time.Second).String()
+}
+
+// Print - Renders the curation as a table, JSON or YAML
+func Print(out io.Writer, curation *Curation, output string) error {
+	if output == OutputTable || output == "" {
+		return printTable(out, curation)
+	}
+	return printObject(out, curation, output)
+}
+
+// PrintCredentialUsage - Prints the source credentials and the clusters using them, see Print for the output
+func PrintCredentialUsage(out io.Writer, usage []secrets.CredentialUsage, output string) error {
+	if output != OutputTable && output != "" {
+		return printObject(out, usage, output)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "SOURCE CREDENTIAL\tCLUSTERS")
+	for _, u := range usage {
+		fmt.Fprintf(w, "%s\t%s\n", u.Source, strings.Join(u.Clusters, ","))
+	}
+	return w.Flush()
+}
+
+func printObject(out io.Writer, object interface{}, output string) error {
+	switch output {
+	case OutputJSON:
+		data, err := json.MarshalIndent(object, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, string(data))
+		return err
+	case OutputYAML:
+		data, err := yaml.Marshal(object)
+		if err != nil {
+			return err
+		}
+		_, err = out.Write(data)
+		return err
+	}
+	return utils.NewError(utils.ReasonInvalidSpec, "unsupported output format: %s, use table, json or yaml", output)
+}
+
+func printTable(out io.Writer, curation *Curation) error {
+	fmt.Fprintf(out, "Cluster:      %s/%s\n", curation.Namespace, curation.Cluster)
+	fmt.Fprintf(out, "Curation:     %s\n", valueOrNone(curation.DesiredCuration))
+	fmt.Fprintf(out, "Curator Job:  %s\n", valueOrNone(curation.CuratorJob))
+	fmt.Fprintf(out, "Status:       %s\n", curation.Status)
+	if curation.Message != "" {
+		fmt.Fprintf(out, "Message:      %s\n", curation.Message)
+	}
+	if curation.AnsibleJob != nil {
+		fmt.Fprintf(out, "AnsibleJob:   %s (%s)\n", curation.AnsibleJob.Name, curation.AnsibleJob.Status)
+		if curation.AnsibleJob.URL != "" {
+			fmt.Fprintf(out, "Tower URL:    %s\n", curation.AnsibleJob.URL)
+		}
+		if curation.AnsibleJob.Artifacts != "" {
+			fmt.Fprintf(out, "Artifacts:    %s\n", curation.AnsibleJob.Artifacts)
+		}
+	}
+	if curation.UpgradeProgress != "" {
+		fmt.Fprintf(out, "Upgrade:      %s\n", curation.UpgradeProgress)
+	}
+	fmt.Fprintln(out)
+
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "STEP\tSTATUS\tSTARTED\tELAPSED\tREASON\tMESSAGE")
+	for _, step := range curation.Steps {
+		started := ""
+		if step.StartTime != nil {
+			started = step.StartTime.UTC().Format(time.RFC3339)
+		}
+		message := step.Message
+		if message == "" {
+			message = step.Description
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			step.Name, step.Status, started, step.Elapsed, step.Reason, message)
+	}
+	return w.Flush()
+}
+
+func valueOrNone(value string) string {
+	if value == "" {
+		return "<none>"
+	}
+	return value
+}
@@ -0,0 +1,247 @@
+// Copyright Contributors to the Open Cluster Management project.
+package status
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	clustercuratorv1 "github.com/stolostron/cluster-curator-controller/pkg/api/v1beta1"
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/secrets"
+	"github.com/stolostron/cluster-curator-controller/pkg/jobs/utils"
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+)
+
+const clusterName = "my-cluster"
+const jobName = "curator-job-abcde"
+
+var now = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func minutesAgo(m int) v1.Time {
+	return v1.NewTime(now.Add(-time.Duration(m) * time.Minute))
+}
+
+func getScheme() *runtime.Scheme {
+	s := runtime.NewScheme()
+	_ = clustercuratorv1.AddToScheme(s)
+	_ = batchv1.AddToScheme(s)
+	_ = corev1.AddToScheme(s)
+	return s
+}
+
+func getClusterCurator(curatorJob string, conditions []v1.Condition) *clustercuratorv1.ClusterCurator {
+	return &clustercuratorv1.ClusterCurator{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      clusterName,
+			Namespace: clusterName,
+		},
+		Spec: clustercuratorv1.ClusterCuratorSpec{
+			DesiredCuration: "upgrade",
+			CuratingJob:     curatorJob,
+		},
+		Status: clustercuratorv1.ClusterCuratorStatus{
+			Conditions: conditions,
+		},
+	}
+}
+
+func getUpgradeConditions() []v1.Condition {
+	return []v1.Condition{
+		{Type: curatorJobCondition, Status: v1.ConditionFalse, Reason: utils.JobHasFinished,
+			Message: jobName + " DesiredCuration: upgrade", LastTransitionTime: minutesAgo(30)},
+		{Type: "prehook-ansiblejob", Status: v1.ConditionTrue, Reason: utils.JobHasFinished,
+			Message: "Completed executing init container", LastTransitionTime: minutesAgo(20)},
+		{Type: currentAnsibleJob, Status: v1.ConditionTrue, Reason: utils.JobHasFinished,
+			Message: "prehookjob-xyz", LastTransitionTime: minutesAgo(20)},
+		{Type: "prehookjob-xyz", Status: v1.ConditionTrue, Reason: ansibleJobURLReason,
+			Message: "https://tower.example.com/#/jobs/playbook/42", LastTransitionTime: minutesAgo(25)},
+		{Type: "upgrade-cluster", Status: v1.ConditionTrue, Reason: utils.JobHasFinished,
+			Message: "Completed executing init container", LastTransitionTime: minutesAgo(18)},
+		{Type: "monitor-upgrade", Status: v1.ConditionFalse, Reason: utils.JobHasFinished,
+			Message: "Upgrade status - Working towards 4.13.37: 42% complete", LastTransitionTime: minutesAgo(18)},
+	}
+}
+
+func getJob() *batchv1.Job {
+	return &batchv1.Job{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      jobName,
+			Namespace: clusterName,
+			Annotations: map[string]string{
+				"prehook-ansiblejob": "Running pre-upgrade AnsibleJob",
+				"upgrade-cluster":    "Start Upgrading the Cluster and monitor to completion",
+				"monitor-upgrade":    "Monitor upgrade status to completion",
+				"done":               "Cluster Curator job has completed",
+			},
+		},
+		Spec: batchv1.JobSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					InitContainers: []corev1.Container{
+						{Name: "prehook-ansiblejob"},
+						{Name: "upgrade-cluster"},
+						{Name: "monitor-upgrade"},
+					},
+					Containers: []corev1.Container{
+						{Name: "done"},
+					},
+				},
+			},
+		},
+	}
+}
+
+func getPod() *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      jobName + "-pod",
+			Namespace: clusterName,
+			Labels:    map[string]string{"job-name": jobName},
+		},
+		Status: corev1.PodStatus{
+			InitContainerStatuses: []corev1.ContainerStatus{
+				{Name: "prehook-ansiblejob", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+					StartedAt: minutesAgo(30), FinishedAt: minutesAgo(20)}}},
+				{Name: "upgrade-cluster", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+					StartedAt: minutesAgo(20), FinishedAt: minutesAgo(18)}}},
+				{Name: "monitor-upgrade", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{
+					StartedAt: minutesAgo(18)}}},
+			},
+			ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "done", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{
+					Reason: "PodInitializing"}}},
+			},
+		},
+	}
+}
+
+func TestGetCurationFromJob(t *testing.T) {
+	client := clientfake.NewClientBuilder().WithScheme(getScheme()).WithObjects(
+		getClusterCurator(jobName, getUpgradeConditions()), getJob(), getPod()).Build()
+
+	curation, err := GetCuration(context.TODO(), client, clusterName, clusterName, now)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "upgrade", curation.DesiredCuration)
+	assert.Equal(t, jobName, curation.CuratorJob)
+	assert.Equal(t, StepRunning, curation.Status)
+	assert.Equal(t, "Upgrade status - Working towards 4.13.37: 42% complete", curation.UpgradeProgress)
+	assert.Equal(t, &AnsibleJob{
+		Name:   "prehookjob-xyz",
+		Status: StepSucceeded,
+		URL:    "https://tower.example.com/#/jobs/playbook/42",
+	}, curation.AnsibleJob)
+
+	assert.Len(t, curation.Steps, 4, "the init containers and the final container")
+	expected := []struct{ name, status, elapsed string }{
+		{"prehook-ansiblejob", StepSucceeded, "10m0s"},
+		{"upgrade-cluster", StepSucceeded, "2m0s"},
+		{"monitor-upgrade", StepRunning, "18m0s"},
+		{"done", StepPending, ""},
+	}
+	for i, e := range expected {
+		assert.Equal(t, e.name, curation.Steps[i].Name, "the steps follow the job containers")
+		assert.Equal(t, e.status, curation.Steps[i].Status, e.name)
+		assert.Equal(t, e.elapsed, curation.Steps[i].Elapsed, e.name)
+	}
+	assert.Equal(t, "Monitor upgrade status to completion", curation.Steps[2].Description,
+		"the description comes from the job annotations")
+}
+
+func TestGetCurationFailedStep(t *testing.T) {
+	conditions := []v1.Condition{
+		{Type: "upgrade-cluster", Status: v1.ConditionTrue, Reason: string(utils.ReasonInvalidSpec),
+			Message: "Provide valid upgrade version", LastTransitionTime: minutesAgo(1)},
+	}
+	pod := getPod()
+	pod.Status.InitContainerStatuses[1].State.Terminated.ExitCode = 3
+	pod.Status.InitContainerStatuses[1].State.Terminated.Reason = "Error"
+
+	client := clientfake.NewClientBuilder().WithScheme(getScheme()).WithObjects(
+		getClusterCurator(jobName, conditions), getJob(), pod).Build()
+
+	curation, err := GetCuration(context.TODO(), client, clusterName, clusterName, now)
+	assert.Nil(t, err)
+	assert.Equal(t, StepFailed, curation.Steps[1].Status)
+	assert.Equal(t, string(utils.ReasonInvalidSpec), curation.Steps[1].Reason,
+		"the reason recorded by the step is kept over the container reason")
+	assert.Equal(t, "Provide valid upgrade version", curation.Steps[1].Message)
+}
+
+func TestGetCurationWithoutJob(t *testing.T) {
+	client := clientfake.NewClientBuilder().WithScheme(getScheme()).WithObjects(
+		getClusterCurator(jobName, getUpgradeConditions())).Build()
+
+	curation, err := GetCuration(context.TODO(), client, clusterName, clusterName, now)
+	assert.Nil(t, err)
+
+	var names []string
+	for _, step := range curation.Steps {
+		names = append(names, step.Name)
+	}
+	assert.Equal(t, []string{"prehook-ansiblejob", "upgrade-cluster", "monitor-upgrade"}, names,
+		"the step conditions are used when the job is gone")
+	assert.Equal(t, StepRunning, curation.Steps[2].Status)
+	assert.Equal(t, "18m0s", curation.Steps[2].Elapsed)
+}
+
+func TestGetCurationNoClusterCurator(t *testing.T) {
+	client := clientfake.NewClientBuilder().WithScheme(getScheme()).Build()
+
+	_, err := GetCuration(context.TODO(), client, clusterName, clusterName, now)
+	assert.Equal(t, utils.ReasonNotFound, utils.ReasonForError(err))
+}
+
+func TestPrint(t *testing.T) {
+	client := clientfake.NewClientBuilder().WithScheme(getScheme()).WithObjects(
+		getClusterCurator(jobName, getUpgradeConditions()), getJob(), getPod()).Build()
+
+	curation, err := GetCuration(context.TODO(), client, clusterName, clusterName, now)
+	assert.Nil(t, err)
+
+	out := &bytes.Buffer{}
+	assert.Nil(t, Print(out, curation, OutputTable))
+	assert.Contains(t, out.String(), "Tower URL:    https://tower.example.com/#/jobs/playbook/42")
+	assert.Contains(t, out.String(), "Upgrade:      Upgrade status - Working towards 4.13.37: 42% complete")
+	assert.Regexp(t, `monitor-upgrade\s+Running\s+2024-05-01T11:42:00Z\s+18m0s`, out.String())
+
+	out.Reset()
+	assert.Nil(t, Print(out, curation, OutputJSON))
+	printed := &Curation{}
+	assert.Nil(t, json.Unmarshal(out.Bytes(), printed))
+	assert.Equal(t, curation.Steps[0].Name, printed.Steps[0].Name)
+
+	out.Reset()
+	assert.Nil(t, Print(out, curation, OutputYAML))
+	printed = &Curation{}
+	assert.Nil(t, yaml.Unmarshal(out.Bytes(), printed))
+	assert.Equal(t, curation.AnsibleJob, printed.AnsibleJob)
+
+	assert.Equal(t, utils.ReasonInvalidSpec, utils.ReasonForError(Print(out, curation, "xml")))
+}
+
+func TestPrintCredentialUsage(t *testing.T) {
+	usage := []secrets.CredentialUsage{
+		{Source: "default/aws", Clusters: []string{"cluster-a", "cluster-b"}},
+		{Source: "vault://secret/gcp", Clusters: []string{"cluster-c"}},
+	}
+
+	out := &bytes.Buffer{}
+	assert.Nil(t, PrintCredentialUsage(out, usage, OutputTable))
+	assert.Regexp(t, `default/aws\s+cluster-a,cluster-b`, out.String())
+	assert.Regexp(t, `vault://secret/gcp\s+cluster-c`, out.String())
+
+	out.Reset()
+	assert.Nil(t, PrintCredentialUsage(out, usage, OutputJSON))
+	printed := []secrets.CredentialUsage{}
+	assert.Nil(t, json.Unmarshal(out.Bytes(), &printed))
+	assert.Equal(t, usage, printed)
+}
@@ -0,0 +1,32 @@
+// Copyright Contributors to the Open Cluster Management project.
+package utils
+
+import (
+	"os"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// Kubeconfig file and context used to reach the hub, set from the curator --kubeconfig and --context flags
+var Kubeconfig string
+var KubeContext string
+
+/* GetConfig - Returns the hub connection. Inside the curator job this is the in-cluster config of the
+ * service account. When a kubeconfig, a context or the KUBECONFIG environment variable is set, the
+ * kubeconfig is loaded instead, so the steps can be run from a workstation.
+ */
+func GetConfig() (*rest.Config, error) {
+	if Kubeconfig == "" && KubeContext == "" && os.Getenv(clientcmd.RecommendedConfigPathEnvVar) == "" {
+		return rest.InClusterConfig()
+	}
+
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = Kubeconfig
+
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		rules,
+		&clientcmd.ConfigOverrides{CurrentContext: KubeContext}).ClientConfig()
+
+	return config, WrapError(ReasonInvalidSpec, err)
+}
@@ -0,0 +1,59 @@
+// Copyright Contributors to the Open Cluster Management project.
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: hub
+  cluster:
+    server: https://api.hub.example.com:6443
+- name: other
+  cluster:
+    server: https://api.other.example.com:6443
+users:
+- name: admin
+  user:
+    token: sha256~token
+contexts:
+- name: hub
+  context:
+    cluster: hub
+    user: admin
+- name: other
+  context:
+    cluster: other
+    user: admin
+current-context: hub
+`
+
+func TestGetConfigKubeconfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kubeconfig")
+	assert.Nil(t, os.WriteFile(path, []byte(testKubeconfig), 0600))
+
+	defer func() {
+		Kubeconfig = ""
+		KubeContext = ""
+	}()
+
+	Kubeconfig = path
+	config, err := GetConfig()
+	assert.Nil(t, err)
+	assert.Equal(t, "https://api.hub.example.com:6443", config.Host, "the current context is used")
+
+	KubeContext = "other"
+	config, err = GetConfig()
+	assert.Nil(t, err)
+	assert.Equal(t, "https://api.other.example.com:6443", config.Host, "the context overrides the current context")
+
+	KubeContext = "missing"
+	_, err = GetConfig()
+	assert.Equal(t, ReasonInvalidSpec, ReasonForError(err), "InvalidSpec, when the context does not exist")
+}
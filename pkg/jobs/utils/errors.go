@@ -0,0 +1,120 @@
+// Copyright Contributors to the Open Cluster Management project.
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+)
+
+// Machine-readable reason carried by the errors returned from the curator steps
+type Reason string
+
+const (
+	ReasonUnknown           Reason = "Unknown"
+	ReasonNotFound          Reason = "NotFound"
+	ReasonForbidden         Reason = "Forbidden"
+	ReasonTimeout           Reason = "Timeout"
+	ReasonCanceled          Reason = "Canceled"
+	ReasonInvalidSpec       Reason = "InvalidSpec"
+	ReasonInvalidCredential Reason = "InvalidCredential"
+	ReasonProvisionFailed   Reason = "ProvisionFailed"
+	ReasonDestroyFailed     Reason = "DestroyFailed"
+	ReasonUpgradeFailed     Reason = "UpgradeFailed"
+	ReasonImportFailed      Reason = "ImportFailed"
+	ReasonAnsibleJobFailed  Reason = "AnsibleJobFailed"
+	// The curator job terminated before its step could record the failure
+	ReasonOOMKilled        Reason = "OOMKilled"
+	ReasonEvicted          Reason = "Evicted"
+	ReasonImagePullBackOff Reason = "ImagePullBackOff"
+	ReasonDeadlineExceeded Reason = "DeadlineExceeded"
+	ReasonJobFailed        Reason = "JobFailed"
+	ReasonJobDeleted       Reason = "JobDeleted"
+)
+
+// CuratorError - An error with a Reason, wrapping the underlying error
+type CuratorError struct {
+	Reason Reason
+	Err    error
+}
+
+func (e *CuratorError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CuratorError) Unwrap() error {
+	return e.Err
+}
+
+// Creates an error with the given reason
+func NewError(reason Reason, format string, args ...interface{}) error {
+	return &CuratorError{Reason: reason, Err: fmt.Errorf(format, args...)}
+}
+
+// Attaches a reason to err, nil stays nil
+func WrapError(reason Reason, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &CuratorError{Reason: reason, Err: err}
+}
+
+/* ReasonForError - Returns the reason carried by err. Errors without one are classified from the
+ * context and API machinery errors they wrap, everything else is ReasonUnknown.
+ */
+func ReasonForError(err error) Reason {
+	var curatorErr *CuratorError
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &curatorErr):
+		return curatorErr.Reason
+	case errors.Is(err, context.Canceled):
+		return ReasonCanceled
+	case errors.Is(err, context.DeadlineExceeded), k8serrors.IsTimeout(err), k8serrors.IsServerTimeout(err):
+		return ReasonTimeout
+	case k8serrors.IsNotFound(err), meta.IsNoMatchError(err):
+		return ReasonNotFound
+	case k8serrors.IsForbidden(err):
+		return ReasonForbidden
+	}
+	return ReasonUnknown
+}
+
+/* IsTransientError - The error of an API request that can succeed when it is retried, a server timeout,
+ * throttling, an unavailable API server or a dropped connection. The errors of the steps are not transient.
+ */
+func IsTransientError(err error) bool {
+	var netErr net.Error
+	switch {
+	case err == nil, errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		// The deadline of a step is a net.Error timeout
+		return false
+	case k8serrors.IsServerTimeout(err), k8serrors.IsTimeout(err), k8serrors.IsTooManyRequests(err),
+		k8serrors.IsInternalError(err), k8serrors.IsServiceUnavailable(err), k8serrors.IsUnexpectedServerError(err):
+		return true
+	case utilnet.IsConnectionRefused(err), utilnet.IsConnectionReset(err), utilnet.IsProbableEOF(err):
+		return true
+	case errors.As(err, &netErr):
+		return netErr.Timeout()
+	}
+	return false
+}
+
+// Sleeps for d, returning early with the context error when ctx is done
+func Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
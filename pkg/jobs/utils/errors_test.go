@@ -0,0 +1,107 @@
+// Copyright Contributors to the Open Cluster Management project.
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"syscall"
+	"testing"
+	"time"
+
+	hivev1 "github.com/openshift/hive/apis/hive/v1"
+	"github.com/stretchr/testify/assert"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReasonForError(t *testing.T) {
+	gr := schema.GroupResource{Group: "hive.openshift.io", Resource: "clusterdeployments"}
+
+	assert.Equal(t, Reason(""), ReasonForError(nil))
+	assert.Equal(t, ReasonUnknown, ReasonForError(errors.New("failed")))
+	assert.Equal(t, ReasonNotFound, ReasonForError(k8serrors.NewNotFound(gr, ClusterName)))
+	assert.Equal(t, ReasonForbidden, ReasonForError(k8serrors.NewForbidden(gr, ClusterName, errors.New("denied"))))
+	assert.Equal(t, ReasonCanceled, ReasonForError(context.Canceled))
+	assert.Equal(t, ReasonTimeout, ReasonForError(context.DeadlineExceeded))
+	assert.Equal(t, ReasonTimeout, ReasonForError(ErrWaitTimeout))
+	assert.Equal(t, ReasonAnsibleJobFailed,
+		ReasonForError(fmt.Errorf("prehook: %w", NewError(ReasonAnsibleJobFailed, "job %s failed", "aj"))),
+		"the reason survives wrapping")
+}
+
+func TestCuratorError(t *testing.T) {
+	cause := errors.New("cause")
+	err := WrapError(ReasonUpgradeFailed, cause)
+
+	assert.Equal(t, "cause", err.Error())
+	assert.True(t, errors.Is(err, cause), "the cause is unwrapped")
+	assert.Nil(t, WrapError(ReasonUpgradeFailed, nil), "nil stays nil")
+	assert.EqualError(t, NewError(ReasonInvalidSpec, "bad %s", "value"), "bad value")
+}
+
+func TestIsTransientError(t *testing.T) {
+	gr := schema.GroupResource{Group: "hive.openshift.io", Resource: "clusterdeployments"}
+
+	assert.False(t, IsTransientError(nil))
+	assert.False(t, IsTransientError(errors.New("failed")))
+	assert.False(t, IsTransientError(k8serrors.NewNotFound(gr, ClusterName)))
+	assert.False(t, IsTransientError(NewError(ReasonUpgradeFailed, "failed")))
+	assert.False(t, IsTransientError(context.DeadlineExceeded), "a step timeout is not retried")
+	assert.True(t, IsTransientError(k8serrors.NewServerTimeout(gr, "get", 1)))
+	assert.True(t, IsTransientError(k8serrors.NewTooManyRequests("throttled", 1)))
+	assert.True(t, IsTransientError(k8serrors.NewServiceUnavailable("unavailable")))
+	assert.True(t, IsTransientError(fmt.Errorf("get: %w", syscall.ECONNREFUSED)), "the API server restarted")
+	assert.True(t, IsTransientError(io.ErrUnexpectedEOF), "the watch was dropped")
+}
+
+func TestSleepCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	assert.Equal(t, context.Canceled, Sleep(ctx, time.Hour))
+	assert.Less(t, time.Since(start), time.Minute, "a canceled context ends the sleep")
+	assert.Nil(t, Sleep(context.Background(), time.Millisecond))
+}
+
+func TestGetClusterTypeForbidden(t *testing.T) {
+	s := runtime.NewScheme()
+	_ = hivev1.AddToScheme(s)
+	client := clientfake.NewClientBuilder().WithScheme(s).Build()
+	dynclient := dynfake.NewSimpleDynamicClient(runtime.NewScheme())
+	dynclient.PrependReactor("get", "hostedclusters",
+		func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, k8serrors.NewForbidden(HCGVR.GroupResource(), ClusterName, errors.New("denied"))
+		})
+
+	_, err := GetClusterType(context.TODO(), client, dynclient, ClusterName, ClusterName, false)
+	assert.Equal(t, ReasonNotFound, ReasonForError(err), "NotFound, when neither cluster kind can be read")
+
+	clusterType, err := GetClusterType(context.TODO(), client, dynclient, ClusterName, ClusterName, true)
+	assert.Nil(t, err, "err nil, when upgrading an imported cluster")
+	assert.Equal(t, StandaloneClusterType, clusterType)
+}
+
+func TestGetClusterTypeHostedClusterError(t *testing.T) {
+	s := runtime.NewScheme()
+	_ = hivev1.AddToScheme(s)
+	client := clientfake.NewClientBuilder().WithScheme(s).Build()
+	dynclient := dynfake.NewSimpleDynamicClient(runtime.NewScheme())
+	dynclient.PrependReactor("get", "hostedclusters",
+		func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, k8serrors.NewInternalError(errors.New("etcd unavailable"))
+		})
+
+	_, err := GetClusterType(context.TODO(), client, dynclient, ClusterName, ClusterName, false)
+	assert.True(t, k8serrors.IsInternalError(err), "API errors other than NotFound and Forbidden are returned")
+
+	clusterType, err := GetClusterType(context.TODO(), client, dynclient, "hc", ClusterName, false)
+	assert.Nil(t, err)
+	assert.Equal(t, HypershiftClusterType, clusterType, "name and namespace differ for HostedClusters")
+}
@@ -4,7 +4,6 @@ package utils
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"flag"
 	"fmt"
 	"strconv"
@@ -20,8 +19,8 @@ import (
 
 	"k8s.io/klog/v2"
 
-	ajv1 "github.com/stolostron/cluster-curator-controller/pkg/api/v1alpha1"
 	hivev1 "github.com/openshift/hive/apis/hive/v1"
+	ajv1 "github.com/stolostron/cluster-curator-controller/pkg/api/v1alpha1"
 	clustercuratorv1 "github.com/stolostron/cluster-curator-controller/pkg/api/v1beta1"
 	managedclusteractionv1beta1 "github.com/stolostron/cluster-lifecycle-api/action/v1beta1"
 	managedclusterinfov1beta1 "github.com/stolostron/cluster-lifecycle-api/clusterinfo/v1beta1"
@@ -32,7 +31,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
+	managedclusterv1 "open-cluster-management.io/api/cluster/v1"
 	clientv1 "sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -44,6 +43,12 @@ const CurrentAnsibleJob = "active-ansible-job"
 const CurrentHiveJob = "hive-provisioning-job"
 const CurrentCuratorContainer = "curating-with-container"
 const CurrentCuratorJob = "curatorJob"
+
+// Label of the objects created for a ClusterCurator, they are removed or orphaned with it
+const ClusterCuratorLabel = "cluster.open-cluster-management.io/clustercurator"
+
+// Label of the objects created by a curator job, with the name of the job, the objects of a run share it
+const CurationRunLabel = "cluster.open-cluster-management.io/curation-run"
 const DefaultImageURI = "registry.ci.openshift.org/open-cluster-management/cluster-curator-controller:latest"
 
 const JobHasFinished = "Job_has_finished"
@@ -91,21 +96,12 @@ func InitKlog(logLevel int) {
 
 	klog.InitFlags(nil)
 
-	err := flag.Set("v", strconv.Itoa(logLevel))
-	CheckError(err)
+	LogWarning(flag.Set("v", strconv.Itoa(logLevel)))
 
 	flag.Parse()
 
 }
 
-// Simple error function
-func CheckError(err error) {
-	if err != nil {
-		klog.Error(err.Error())
-		panic(err)
-	}
-}
-
 func LogError(err error) error {
 	if err != nil {
 		klog.Warning(err.Error())
@@ -124,17 +120,19 @@ func LogWarning(err error) {
 func PathSplitterFromEnv(path string) (namespace string, resource string, err error) {
 	values := strings.Split(path, "/")
 	if len(values) != 2 {
-		return "", "", errors.New("Resource name was not provided NAMESPACE/RESOURCE_NAME, found: " + path)
+		return "", "", NewError(ReasonInvalidSpec, "Resource name was not provided NAMESPACE/RESOURCE_NAME, found: %s", path)
 	}
 	if values[0] == "" || values[1] == "" {
-		return "", "", errors.New("NameSpace was not provided NAMESPACE/RESORUCE_NAME, found: " + path)
+		return "", "", NewError(ReasonInvalidSpec, "NameSpace was not provided NAMESPACE/RESORUCE_NAME, found: %s", path)
 	}
 	return values[0], values[1], nil
 }
 
 func RecordCuratorJob(clusterName, containerName string) error {
 	dynset, err := GetDynset(nil)
-	CheckError(err)
+	if err != nil {
+		return err
+	}
 
 	return patchDyn(dynset, clusterName, containerName, CurrentCuratorJob)
 }
@@ -149,9 +147,24 @@ func RecordCuratorJobName(
 		return err
 	}
 
-	cc.Spec.CuratingJob = curatorJobName
+	op := CurrentOperation(cc)
+	if last := cc.Status.LastOperation; op != nil && last != nil && last.Result == clustercuratorv1.OperationRunning {
+		last.CuratorJob = curatorJobName
+	}
 
-	return client.Update(context.Background(), cc)
+	// The spec is not changed with the Generation trigger, the curated spec is recorded with the job. An
+	// operation does not curate the spec.
+	if GenerationTriggered(cc) {
+		cc.Status.CuratorJob = curatorJobName
+		if op == nil {
+			cc.Status.ObservedGeneration = cc.Generation
+			cc.Status.ObservedSpecHash = SpecHash(cc)
+		}
+		return client.Status().Update(context.Background(), cc)
+	}
+
+	cc.Spec.CuratingJob = curatorJobName
+	return UpdateClusterCurator(context.Background(), client, cc)
 }
 
 func patchDyn(dynset dynamic.Interface, clusterName string, containerName string, specKey string) error {
@@ -174,7 +187,7 @@ func patchDyn(dynset dynamic.Interface, clusterName string, containerName string
 
 func GetDynset(dynset dynamic.Interface) (dynamic.Interface, error) {
 
-	config, err := rest.InClusterConfig()
+	config, err := GetConfig()
 	if err != nil {
 		return nil, err
 	}
@@ -184,27 +197,35 @@ func GetDynset(dynset dynamic.Interface) (dynamic.Interface, error) {
 
 func GetClient() (clientv1.Client, error) {
 
-	config, err := rest.InClusterConfig()
+	config, err := GetConfig()
 	if err != nil {
 		return nil, err
 	}
 
 	curatorScheme := runtime.NewScheme()
-	CheckError(clustercuratorv1.AddToScheme(curatorScheme))
-	CheckError(batchv1.AddToScheme(curatorScheme))
-	CheckError(ajv1.AddToScheme(curatorScheme))
-	CheckError(hivev1.AddToScheme(curatorScheme))
-	CheckError(managedclusteractionv1beta1.AddToScheme(curatorScheme))
-	CheckError(managedclusterviewv1beta1.AddToScheme(curatorScheme))
-	CheckError(managedclusterinfov1beta1.AddToScheme(curatorScheme))
-	CheckError(corev1.AddToScheme(curatorScheme))
+	for _, addToScheme := range []func(*runtime.Scheme) error{
+		clustercuratorv1.AddToScheme,
+		batchv1.AddToScheme,
+		ajv1.AddToScheme,
+		hivev1.AddToScheme,
+		managedclusteractionv1beta1.AddToScheme,
+		managedclusterviewv1beta1.AddToScheme,
+		managedclusterinfov1beta1.AddToScheme,
+		managedclusterv1.AddToScheme,
+		corev1.AddToScheme,
+	} {
+		if err := addToScheme(curatorScheme); err != nil {
+			return nil, err
+		}
+	}
 
-	return clientv1.New(config, clientv1.Options{Scheme: curatorScheme})
+	// Watch support lets the monitors react to changes instead of polling
+	return clientv1.NewWithWatch(config, clientv1.Options{Scheme: curatorScheme})
 }
 
 func GetKubeset() (kubernetes.Interface, error) {
 
-	config, err := rest.InClusterConfig()
+	config, err := GetConfig()
 	if err != nil {
 		return nil, err
 	}
@@ -248,6 +269,24 @@ func RecordAnsibleJobStatusUrlCondition(
 		url)
 }
 
+func RecordAnsibleJobArtifactsCondition(
+	client clientv1.Client,
+	clusterName string,
+	clusterNamespace string,
+	containerName string,
+	conditionStatus v1.ConditionStatus,
+	configMap string) error {
+
+	return recordCuratedStatusCondition(
+		client,
+		clusterName,
+		clusterNamespace,
+		containerName,
+		conditionStatus,
+		"ansiblejob_artifacts",
+		configMap)
+}
+
 func recordCuratedStatusCondition(
 	client clientv1.Client,
 	clusterName string,
@@ -271,7 +310,7 @@ func recordCuratedStatusCondition(
 
 	meta.SetStatusCondition(&curator.Status.Conditions, newCondition)
 
-	if err := client.Update(context.TODO(), curator); err != nil {
+	if err := client.Status().Update(context.TODO(), curator); err != nil {
 		return err
 	}
 	klog.V(4).Infof("newCondition: %v", newCondition)
@@ -296,6 +335,84 @@ func RecordFailedCuratorStatusCondition(
 		message)
 }
 
+// Records a failed step, the condition reason is the machine-readable reason carried by err
+func RecordFailedCuratorStatusError(
+	client clientv1.Client,
+	clusterName string,
+	clusterNamespace string,
+	containerName string,
+	err error) error {
+
+	return recordCuratedStatusCondition(
+		client,
+		clusterName,
+		clusterNamespace,
+		containerName,
+		v1.ConditionTrue,
+		string(ReasonForError(err)),
+		err.Error())
+}
+
+/* SetCurationMetadata - Labels an object created for a curation with its ClusterCurator and curation run.
+ * The ClusterCurator owns the object when they are in the same namespace, it is garbage collected with it.
+ */
+func SetCurationMetadata(obj v1.Object, curator *clustercuratorv1.ClusterCurator) {
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[ClusterCuratorLabel] = curator.Name
+	if run := CuratingJob(curator); run != "" {
+		labels[CurationRunLabel] = run
+	}
+	obj.SetLabels(labels)
+
+	if curator.UID == "" || obj.GetNamespace() != curator.Namespace {
+		return
+	}
+	for _, owner := range obj.GetOwnerReferences() {
+		if owner.UID == curator.UID {
+			return
+		}
+	}
+	obj.SetOwnerReferences(append(obj.GetOwnerReferences(), v1.OwnerReference{
+		APIVersion: clustercuratorv1.GroupVersion.String(),
+		Kind:       "ClusterCurator",
+		Name:       curator.Name,
+		UID:        curator.UID,
+	}))
+}
+
+/* StepCompleted - The step completed in the curator job created at jobCreation, a retried job skips it.
+ * The condition of a step completed by an earlier curation transitioned before the job was created.
+ */
+func StepCompleted(curator *clustercuratorv1.ClusterCurator, step string, jobCreation time.Time) bool {
+	cond := meta.FindStatusCondition(curator.Status.Conditions, step)
+	return cond != nil && cond.Status == v1.ConditionTrue && cond.Reason == JobHasFinished &&
+		!cond.LastTransitionTime.Time.Before(jobCreation)
+}
+
+/* UpdateFailingClusterCurator - Removes the curatorJob and desiredCuration of a failed curation, so a new
+ * one can be started. The desiredCuration of an upgrade is kept. With the Generation trigger, the failure is
+ * recorded in the status instead, the failure of an operation in status.lastOperation.
+ */
+func UpdateFailingClusterCurator(client clientv1.Client, curator *clustercuratorv1.ClusterCurator) error {
+	if CurrentOperation(curator) != nil {
+		return RecordOperationResult(client, curator, clustercuratorv1.OperationFailed)
+	}
+	if GenerationTriggered(curator) {
+		return RecordCompletedCuration(client, curator, clustercuratorv1.CurationFailed)
+	}
+
+	if curator.Spec.DesiredCuration == "upgrade" {
+		patch := []byte(`{"spec":{"curatorJob": null}, "operation": null}`)
+		return client.Patch(context.Background(), curator, clientv1.RawPatch(types.MergePatchType, patch))
+	}
+
+	patch := []byte(`{"spec":{"curatorJob": null, "desiredCuration": null}, "operation": null}`)
+	return client.Patch(context.Background(), curator, clientv1.RawPatch(types.MergePatchType, patch))
+}
+
 func GetClusterCurator(
 	client clientv1.Client,
 	clusterName string,
@@ -312,9 +429,21 @@ func GetClusterCurator(
 	return curator, nil
 }
 
-func DeleteClusterNamespace(client kubernetes.Interface, clusterName string) error {
+/* UpdateClusterCurator - Updates the spec and the status of the ClusterCurator. The status is a subresource,
+ * the update of the spec returns the stored status, so the status is updated after it.
+ */
+func UpdateClusterCurator(ctx context.Context, client clientv1.Client, curator *clustercuratorv1.ClusterCurator) error {
+	status := curator.Status.DeepCopy()
+	if err := client.Update(ctx, curator); err != nil {
+		return err
+	}
+	curator.Status = *status
+	return client.Status().Update(ctx, curator)
+}
+
+func DeleteClusterNamespace(ctx context.Context, client kubernetes.Interface, clusterName string) error {
 
-	pods, err := client.CoreV1().Pods(clusterName).List(context.Background(), v1.ListOptions{})
+	pods, err := client.CoreV1().Pods(clusterName).List(ctx, v1.ListOptions{})
 
 	if err != nil && !k8serrors.IsNotFound(err) {
 		return err
@@ -323,13 +452,14 @@ func DeleteClusterNamespace(client kubernetes.Interface, clusterName string) err
 	for _, pod := range pods.Items {
 		if pod.Status.Phase != "" && pod.Status.Phase == "Running" {
 			if !strings.Contains(pod.Name, clusterName+"-uninstall") {
-				return errors.New("There was a running pod: " + pod.Name + ", in the cluster namespace " + clusterName)
+				return NewError(ReasonDestroyFailed,
+					"There was a running pod: %s, in the cluster namespace %s", pod.Name, clusterName)
 			}
 		}
 	}
 
 	// Delete the namespace
-	return client.CoreV1().Namespaces().Delete(context.Background(), clusterName, v1.DeleteOptions{})
+	return client.CoreV1().Namespaces().Delete(ctx, clusterName, v1.DeleteOptions{})
 }
 
 // Because Unmarshal for map[string]interface{}, uses map[interface{}]interface{} above the root leaf, runtime client does not support it.
@@ -503,6 +633,7 @@ func parseVersionInfo(msg string) (channel, upstream string, semversion semver.V
 }
 
 func GetClusterType(
+	ctx context.Context,
 	hiveset clientv1.Client,
 	dc dynamic.Interface,
 	clusterName string,
@@ -515,7 +646,7 @@ func GetClusterType(
 
 	// if clusterName and clusterNamespace are equal we need more info
 	cluster := &hivev1.ClusterDeployment{}
-	err := hiveset.Get(context.TODO(), types.NamespacedName{
+	err := hiveset.Get(ctx, types.NamespacedName{
 		Name:      clusterName,
 		Namespace: clusterName,
 	}, cluster)
@@ -526,12 +657,11 @@ func GetClusterType(
 	}
 
 	hostedCluster, hcErr := dc.Resource(HCGVR).Namespace(clusterNamespace).Get(
-		context.TODO(), clusterName, v1.GetOptions{})
+		ctx, clusterName, v1.GetOptions{})
 	if hcErr == nil && hostedCluster != nil {
 		return HypershiftClusterType, nil
-	} else if !strings.Contains(hcErr.Error(), "not found") &&
-		!strings.Contains(hcErr.Error(), "could not find") &&
-		!strings.Contains(hcErr.Error(), "is forbidden") {
+	} else if reason := ReasonForError(hcErr); reason != ReasonNotFound && reason != ReasonForbidden {
+		// The HostedCluster kind is missing (NotFound) or not readable (Forbidden) on hubs without HyperShift
 		return "", hcErr
 	}
 
@@ -542,7 +672,8 @@ func GetClusterType(
 			klog.V(0).Info("No ClusterDeployment or HostedCluster found. Since this is upgrade, will treat it as an imported cluster")
 			return StandaloneClusterType, nil
 		}
-		return "", errors.New("Failed to determine the cluster type, cannot find ClusterDeployment or HostedCluster")
+		return "", NewError(ReasonNotFound,
+			"Failed to determine the cluster type, cannot find ClusterDeployment or HostedCluster")
 	}
 
 	return StandaloneClusterType, hcErr
@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	clustercuratorv1 "github.com/stolostron/cluster-curator-controller/pkg/api/v1beta1"
 	"github.com/stretchr/testify/assert"
@@ -17,17 +18,6 @@ import (
 	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
-func TestCheckErrorNil(t *testing.T) {
-
-	InitKlog(4)
-	assert.NotPanics(t, func() { CheckError(nil) }, "No panic, when err is not present")
-}
-
-func TestCheckErrorNotNil(t *testing.T) {
-
-	assert.Panics(t, func() { CheckError(errors.New("TeST")) }, "Panics when a err is received")
-}
-
 func TestLogErrorNil(t *testing.T) {
 
 	assert.Nil(t, LogError(nil), "err nil, when no err message")
@@ -147,7 +137,7 @@ func TestRecordCuratedStatusCondition(t *testing.T) {
 	s := scheme.Scheme
 	s.AddKnownTypes(CCGVR.GroupVersion(), &clustercuratorv1.ClusterCurator{})
 
-	client := clientfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(cc).Build()
+	client := clientfake.NewClientBuilder().WithScheme(s).WithStatusSubresource(&clustercuratorv1.ClusterCurator{}).WithRuntimeObjects(cc).Build()
 
 	assert.Nil(t,
 		recordCuratedStatusCondition(
@@ -221,7 +211,7 @@ func TestRecordCuratorJobName(t *testing.T) {
 
 	s := scheme.Scheme
 	s.AddKnownTypes(CCGVR.GroupVersion(), &clustercuratorv1.ClusterCurator{})
-	client := clientfake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(cc).Build()
+	client := clientfake.NewClientBuilder().WithScheme(s).WithStatusSubresource(&clustercuratorv1.ClusterCurator{}).WithRuntimeObjects(cc).Build()
 
 	err := RecordCuratorJobName(client, ClusterName, ClusterName, "my-job-ABCDE")
 
@@ -229,6 +219,25 @@ func TestRecordCuratorJobName(t *testing.T) {
 
 }
 
+func TestUpdateClusterCurator(t *testing.T) {
+
+	s := scheme.Scheme
+	s.AddKnownTypes(CCGVR.GroupVersion(), &clustercuratorv1.ClusterCurator{})
+	client := clientfake.NewClientBuilder().WithScheme(s).WithStatusSubresource(&clustercuratorv1.ClusterCurator{}).
+		WithRuntimeObjects(getClusterCurator()).Build()
+
+	cc, err := GetClusterCurator(client, ClusterName, ClusterName)
+	assert.Nil(t, err)
+	cc.Spec.CuratingJob = "my-job-ABCDE"
+	cc.Status.CuratorJob = "my-job-ABCDE"
+	assert.Nil(t, UpdateClusterCurator(context.TODO(), client, cc), "err nil, when the spec and status are updated")
+
+	cc, err = GetClusterCurator(client, ClusterName, ClusterName)
+	assert.Nil(t, err)
+	assert.Equal(t, "my-job-ABCDE", cc.Spec.CuratingJob, "the spec is updated")
+	assert.Equal(t, "my-job-ABCDE", cc.Status.CuratorJob, "the status is updated with its subresource")
+}
+
 func TestRecordCuratorJobNameInvalidCurator(t *testing.T) {
 
 	s := scheme.Scheme
@@ -254,7 +263,7 @@ func TestDeleteNamespaceNoPods(t *testing.T) {
 
 	kubeset := fake.NewSimpleClientset(getClusterNamespace())
 
-	assert.Nil(t, DeleteClusterNamespace(kubeset, ClusterName))
+	assert.Nil(t, DeleteClusterNamespace(context.TODO(), kubeset, ClusterName))
 
 	_, err := kubeset.CoreV1().Namespaces().Get(context.Background(), ClusterName, v1.GetOptions{})
 	assert.Contains(t, err.Error(), " not found")
@@ -265,7 +274,7 @@ func TestDeleteNamespaceNoNamespace(t *testing.T) {
 
 	kubeset := fake.NewSimpleClientset()
 
-	assert.Contains(t, DeleteClusterNamespace(kubeset, ClusterName).Error(), " not found")
+	assert.Contains(t, DeleteClusterNamespace(context.TODO(), kubeset, ClusterName).Error(), " not found")
 }
 
 func getPod(podName string, podPhase corev1.PodPhase) *corev1.Pod {
@@ -288,7 +297,7 @@ func TestDeleteNamespacePodsRunning(t *testing.T) {
 		getPod(ClusterName+"-uninstall", corev1.PodRunning),
 		getPod("pod2", corev1.PodSucceeded))
 
-	assert.NotNil(t, DeleteClusterNamespace(kubeset, ClusterName), "not nil, when namespace can not be deleted")
+	assert.NotNil(t, DeleteClusterNamespace(context.TODO(), kubeset, ClusterName), "not nil, when namespace can not be deleted")
 
 	_, err := kubeset.CoreV1().Namespaces().Get(context.Background(), ClusterName, v1.GetOptions{})
 	assert.Nil(t, err, "nil when namespace was found")
@@ -302,7 +311,7 @@ func TestDeleteNamespacePodsSuceeded(t *testing.T) {
 		getPod(ClusterName+"-uninstall", corev1.PodRunning),
 		getPod("pod2", corev1.PodSucceeded))
 
-	assert.Nil(t, DeleteClusterNamespace(kubeset, ClusterName), "nil, when namespace can be deleted")
+	assert.Nil(t, DeleteClusterNamespace(context.TODO(), kubeset, ClusterName), "nil, when namespace can be deleted")
 
 	_, err := kubeset.CoreV1().Namespaces().Get(context.Background(), ClusterName, v1.GetOptions{})
 	assert.Contains(t, err.Error(), " not found")
@@ -674,3 +683,44 @@ func TestGetMonitorAttempts(t *testing.T) {
 	})
 	assert.Equal(t, 450, attempts)
 }
+
+func TestStepCompleted(t *testing.T) {
+	jobCreation := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	curator := getClusterCurator()
+	curator.Status.Conditions = []v1.Condition{
+		{Type: "prehook-ansiblejob", Status: v1.ConditionTrue, Reason: JobHasFinished,
+			LastTransitionTime: v1.NewTime(jobCreation.Add(time.Minute))},
+		{Type: "activate-and-monitor", Status: v1.ConditionFalse, Reason: "Job_has_finished",
+			LastTransitionTime: v1.NewTime(jobCreation.Add(time.Minute))},
+		{Type: "monitor-import", Status: v1.ConditionTrue, Reason: JobHasFinished,
+			LastTransitionTime: v1.NewTime(jobCreation.Add(-time.Hour))},
+		{Type: "posthook-ansiblejob", Status: v1.ConditionTrue, Reason: JobFailed,
+			LastTransitionTime: v1.NewTime(jobCreation.Add(time.Minute))},
+	}
+
+	assert.True(t, StepCompleted(curator, "prehook-ansiblejob", jobCreation))
+	assert.False(t, StepCompleted(curator, "activate-and-monitor", jobCreation), "the step is running")
+	assert.False(t, StepCompleted(curator, "monitor-import", jobCreation), "completed by an earlier curation")
+	assert.False(t, StepCompleted(curator, "posthook-ansiblejob", jobCreation), "the step failed")
+	assert.False(t, StepCompleted(curator, "destroy-cluster", jobCreation))
+}
+
+func TestSetCurationMetadata(t *testing.T) {
+	curator := getClusterCurator()
+	curator.UID = "curator-uid"
+	curator.Spec.CuratingJob = "curator-job-d9pwh"
+
+	cm := &corev1.ConfigMap{ObjectMeta: v1.ObjectMeta{Name: "artifacts", Namespace: ClusterName,
+		Labels: map[string]string{"open-cluster-management": "curator-ansiblejob-artifacts"}}}
+	SetCurationMetadata(cm, curator)
+	SetCurationMetadata(cm, curator)
+	assert.Equal(t, map[string]string{"open-cluster-management": "curator-ansiblejob-artifacts",
+		ClusterCuratorLabel: ClusterName, CurationRunLabel: "curator-job-d9pwh"}, cm.Labels)
+	assert.Len(t, cm.OwnerReferences, 1, "the ClusterCurator owns the object once")
+	assert.Equal(t, types.UID("curator-uid"), cm.OwnerReferences[0].UID)
+
+	other := &corev1.ConfigMap{ObjectMeta: v1.ObjectMeta{Name: "artifacts", Namespace: "other"}}
+	SetCurationMetadata(other, curator)
+	assert.Equal(t, ClusterName, other.Labels[ClusterCuratorLabel])
+	assert.Empty(t, other.OwnerReferences, "owners are in the namespace of the object")
+}
@@ -0,0 +1,133 @@
+// Copyright Contributors to the Open Cluster Management project.
+package utils
+
+import (
+	"context"
+	"strings"
+
+	clustercuratorv1 "github.com/stolostron/cluster-curator-controller/pkg/api/v1beta1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	clientv1 "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The hook phases of a curation, the other phases are the steps of its curator job
+const (
+	PrehookPhase  = "prehook"
+	PosthookPhase = "posthook"
+)
+
+/* RequestedOperation - The operation of the ClusterCurator, a retryPosthook is the rerun of the posthook
+ * phase of its curation. It is nil when no operation is requested.
+ */
+func RequestedOperation(curator *clustercuratorv1.ClusterCurator) *clustercuratorv1.Operation {
+	if curator.Operation == nil {
+		return nil
+	}
+	if curator.Operation.RetryPosthook != "" {
+		return &clustercuratorv1.Operation{
+			RetryPosthook: curator.Operation.RetryPosthook,
+			Action:        clustercuratorv1.OperationRerun,
+			Phase:         PosthookPhase,
+			Curation:      strings.TrimSuffix(curator.Operation.RetryPosthook, "Posthook"),
+		}
+	}
+	if curator.Operation.Action == "" {
+		return nil
+	}
+	return curator.Operation.DeepCopy()
+}
+
+/* CurrentOperation - The requested operation, until it ran. An operation ran once status.lastOperation
+ * completed with its nonce, a retryPosthook is removed once it ran.
+ */
+func CurrentOperation(curator *clustercuratorv1.ClusterCurator) *clustercuratorv1.Operation {
+	op := RequestedOperation(curator)
+	if op == nil {
+		return nil
+	}
+	last := curator.Status.LastOperation
+	if last != nil && last.Result != clustercuratorv1.OperationRunning && op.RetryPosthook == "" &&
+		sameOperation(op, last) {
+		return nil
+	}
+	return op
+}
+
+// OperationPending - The current operation has no curator job running it yet
+func OperationPending(curator *clustercuratorv1.ClusterCurator) bool {
+	op := CurrentOperation(curator)
+	if op == nil {
+		return false
+	}
+	last := curator.Status.LastOperation
+	return last == nil || last.Result != clustercuratorv1.OperationRunning || !sameOperation(op, last) ||
+		last.CuratorJob == ""
+}
+
+func sameOperation(op *clustercuratorv1.Operation, last *clustercuratorv1.OperationStatus) bool {
+	return op.Action == last.Action && op.Phase == last.Phase && op.Curation == last.Curation &&
+		op.Nonce == last.Nonce
+}
+
+// NewOperationStatus - The status of the operation, started now
+func NewOperationStatus(
+	op *clustercuratorv1.Operation,
+	result clustercuratorv1.OperationResult) *clustercuratorv1.OperationStatus {
+
+	return &clustercuratorv1.OperationStatus{
+		Action:    op.Action,
+		Phase:     op.Phase,
+		Curation:  op.Curation,
+		Nonce:     op.Nonce,
+		Result:    result,
+		StartTime: v1.Now(),
+	}
+}
+
+/* RecordOperationResult - Records the result of the current operation in status.lastOperation, and removes
+ * its curator job so a curation can be started. The curation of the ClusterCurator is not changed, a
+ * retryPosthook is removed. A failed operation reports the failure message of the curator job condition.
+ */
+func RecordOperationResult(
+	client clientv1.Client,
+	curator *clustercuratorv1.ClusterCurator,
+	result clustercuratorv1.OperationResult) error {
+
+	curator, err := GetClusterCurator(client, curator.Name, curator.Namespace)
+	if err != nil {
+		return err
+	}
+	op := CurrentOperation(curator)
+	if op == nil {
+		return nil
+	}
+
+	last := curator.Status.LastOperation
+	if last == nil || !sameOperation(op, last) {
+		last = NewOperationStatus(op, result)
+	}
+	now := v1.Now()
+	last.Result = result
+	last.CompletionTime = &now
+	if last.CuratorJob == "" {
+		last.CuratorJob = CuratingJob(curator)
+	}
+	if cond := meta.FindStatusCondition(curator.Status.Conditions, "clustercurator-job"); result ==
+		clustercuratorv1.OperationFailed && cond != nil && cond.Status == v1.ConditionTrue {
+		last.Message = cond.Message
+	}
+	klog.V(2).Infof("Operation %v of the %v phase of the %v curation: %v", op.Action, op.Phase, op.Curation, result)
+
+	curator.Status.LastOperation = last
+	if GenerationTriggered(curator) {
+		curator.Status.CuratorJob = ""
+	} else {
+		curator.Spec.CuratingJob = ""
+	}
+	if op.RetryPosthook != "" {
+		curator.Operation = nil
+	}
+	return UpdateClusterCurator(context.Background(), client, curator)
+}
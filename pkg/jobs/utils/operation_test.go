@@ -0,0 +1,78 @@
+// Copyright Contributors to the Open Cluster Management project.
+package utils
+
+import (
+	"testing"
+
+	clustercuratorv1 "github.com/stolostron/cluster-curator-controller/pkg/api/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func getRerunOperation(nonce string) *clustercuratorv1.Operation {
+	return &clustercuratorv1.Operation{Action: clustercuratorv1.OperationRerun, Phase: "monitor-import",
+		Curation: "install", Nonce: nonce}
+}
+
+func TestCurrentOperation(t *testing.T) {
+	cc := getClusterCurator()
+	assert.Nil(t, CurrentOperation(cc))
+
+	cc.Operation = &clustercuratorv1.Operation{RetryPosthook: "upgradePosthook"}
+	op := CurrentOperation(cc)
+	if assert.NotNil(t, op) {
+		assert.Equal(t, clustercuratorv1.OperationRerun, op.Action)
+		assert.Equal(t, PosthookPhase, op.Phase)
+		assert.Equal(t, "upgrade", op.Curation)
+	}
+
+	cc.Operation = getRerunOperation("1")
+	assert.NotNil(t, CurrentOperation(cc))
+	assert.True(t, OperationPending(cc))
+
+	cc.Status.LastOperation = NewOperationStatus(cc.Operation, clustercuratorv1.OperationRunning)
+	cc.Status.LastOperation.CuratorJob = "curator-job-ABCDE"
+	assert.NotNil(t, CurrentOperation(cc))
+	assert.False(t, OperationPending(cc), "the operation is running")
+
+	cc.Status.LastOperation.Result = clustercuratorv1.OperationSucceeded
+	assert.Nil(t, CurrentOperation(cc), "the operation ran")
+
+	cc.Operation.Nonce = "2"
+	assert.NotNil(t, CurrentOperation(cc), "a new nonce runs the phase again")
+	assert.True(t, OperationPending(cc))
+}
+
+func TestRecordOperationResult(t *testing.T) {
+	cc := getClusterCurator()
+	cc.Spec.DesiredCuration = "install"
+	cc.Spec.CuratingJob = "curator-job-ABCDE"
+	cc.Operation = getRerunOperation("1")
+	cc.Status.LastOperation = NewOperationStatus(cc.Operation, clustercuratorv1.OperationRunning)
+	meta.SetStatusCondition(&cc.Status.Conditions, v1.Condition{Type: "clustercurator-job",
+		Status: v1.ConditionTrue, Reason: "JobFailed", Message: "curator-job-ABCDE DesiredCuration: install Failed"})
+
+	s := scheme.Scheme
+	s.AddKnownTypes(CCGVR.GroupVersion(), &clustercuratorv1.ClusterCurator{})
+	client := clientfake.NewClientBuilder().WithScheme(s).WithStatusSubresource(&clustercuratorv1.ClusterCurator{}).WithRuntimeObjects(cc).Build()
+
+	assert.Nil(t, UpdateFailingClusterCurator(client, cc))
+
+	cc, err := GetClusterCurator(client, ClusterName, ClusterName)
+	assert.Nil(t, err)
+	assert.Equal(t, "install", cc.Spec.DesiredCuration, "the curation is not changed")
+	assert.Empty(t, cc.Spec.CuratingJob)
+	assert.NotNil(t, cc.Operation, "the operation is kept, its nonce ran")
+	assert.Nil(t, CurrentOperation(cc))
+	last := cc.Status.LastOperation
+	if assert.NotNil(t, last) {
+		assert.Equal(t, "1", last.Nonce)
+		assert.Equal(t, "curator-job-ABCDE", last.CuratorJob)
+		assert.Equal(t, clustercuratorv1.OperationFailed, last.Result)
+		assert.Equal(t, "curator-job-ABCDE DesiredCuration: install Failed", last.Message)
+		assert.NotNil(t, last.CompletionTime)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright Contributors to the Open Cluster Management project.
+package utils
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	clustercuratorv1 "github.com/stolostron/cluster-curator-controller/pkg/api/v1beta1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	clientv1 "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// GenerationTriggered - The curations are started by the changes of the spec, which is never changed
+func GenerationTriggered(curator *clustercuratorv1.ClusterCurator) bool {
+	return curator.Spec.CurationTrigger == clustercuratorv1.CurationTriggerGeneration
+}
+
+// CuratingJob - The curator job of the running curation, it is in the status with the Generation trigger
+func CuratingJob(curator *clustercuratorv1.ClusterCurator) string {
+	if GenerationTriggered(curator) {
+		return curator.Status.CuratorJob
+	}
+	return curator.Spec.CuratingJob
+}
+
+// SpecHash - Hash of the spec, it tells whether the spec changed since it was curated
+func SpecHash(curator *clustercuratorv1.ClusterCurator) string {
+	spec, err := json.Marshal(curator.Spec)
+	if err != nil {
+		klog.Warningf("The spec of the ClusterCurator %v/%v is not hashed: %v", curator.Namespace, curator.Name, err)
+		return ""
+	}
+	h := sha256.Sum256(spec)
+	return hex.EncodeToString(h[:])[:40]
+}
+
+/* CurationPending - With the Generation trigger, the spec changed since the last curation started. A change
+ * of the operation also increments the generation, so the spec must differ from the curated one.
+ */
+func CurationPending(curator *clustercuratorv1.ClusterCurator) bool {
+	return curator.Generation != curator.Status.ObservedGeneration &&
+		SpecHash(curator) != curator.Status.ObservedSpecHash
+}
+
+/* RecordCompletedCuration - With the Generation trigger, records the result of the curation in the status
+ * and removes its curator job, the spec is not changed. A failed curation reports the failure message of the
+ * curator job condition.
+ */
+func RecordCompletedCuration(
+	client clientv1.Client,
+	curator *clustercuratorv1.ClusterCurator,
+	result clustercuratorv1.CurationResult) error {
+
+	curator, err := GetClusterCurator(client, curator.Name, curator.Namespace)
+	if err != nil {
+		return err
+	}
+
+	curation := curator.Spec.DesiredCuration
+	completed := &clustercuratorv1.CompletedCuration{
+		Curation:       curation,
+		Generation:     curator.Status.ObservedGeneration,
+		CuratorJob:     curator.Status.CuratorJob,
+		Result:         result,
+		CompletionTime: v1.Now(),
+	}
+	if cond := meta.FindStatusCondition(curator.Status.Conditions, "clustercurator-job"); result ==
+		clustercuratorv1.CurationFailed && cond != nil && cond.Status == v1.ConditionTrue {
+		completed.Message = cond.Message
+	}
+	klog.V(2).Infof("Curation %v of generation %v: %v", curation, completed.Generation, result)
+
+	curator.Status.LastCompletedCuration = completed
+	curator.Status.CuratorJob = ""
+	return client.Status().Update(context.Background(), curator)
+}
@@ -0,0 +1,81 @@
+// Copyright Contributors to the Open Cluster Management project.
+package utils
+
+import (
+	"testing"
+
+	clustercuratorv1 "github.com/stolostron/cluster-curator-controller/pkg/api/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func getGenerationCurator() *clustercuratorv1.ClusterCurator {
+	cc := getClusterCurator()
+	cc.Generation = 1
+	cc.Spec.DesiredCuration = "install"
+	cc.Spec.CurationTrigger = clustercuratorv1.CurationTriggerGeneration
+	return cc
+}
+
+func TestCurationPending(t *testing.T) {
+	cc := getGenerationCurator()
+	assert.True(t, CurationPending(cc), "the spec was never curated")
+
+	cc.Status.ObservedGeneration = 1
+	cc.Status.ObservedSpecHash = SpecHash(cc)
+	assert.False(t, CurationPending(cc))
+
+	cc.Generation = 2
+	assert.False(t, CurationPending(cc), "a status change increments the generation")
+
+	cc.Spec.Install.Prehook = []clustercuratorv1.Hook{{Name: "Service now App Update"}}
+	cc.Generation = 3
+	assert.True(t, CurationPending(cc))
+}
+
+func TestRecordCuratorJobNameGenerationTrigger(t *testing.T) {
+	s := scheme.Scheme
+	s.AddKnownTypes(CCGVR.GroupVersion(), &clustercuratorv1.ClusterCurator{})
+	client := clientfake.NewClientBuilder().WithScheme(s).WithStatusSubresource(&clustercuratorv1.ClusterCurator{}).WithRuntimeObjects(getGenerationCurator()).Build()
+
+	assert.Nil(t, RecordCuratorJobName(client, ClusterName, ClusterName, "my-job-ABCDE"))
+
+	cc, err := GetClusterCurator(client, ClusterName, ClusterName)
+	assert.Nil(t, err)
+	assert.Empty(t, cc.Spec.CuratingJob, "the spec is not changed")
+	assert.Equal(t, "my-job-ABCDE", CuratingJob(cc))
+	assert.Equal(t, int64(1), cc.Status.ObservedGeneration)
+	assert.Equal(t, SpecHash(cc), cc.Status.ObservedSpecHash)
+	assert.False(t, CurationPending(cc))
+}
+
+func TestRecordCompletedCuration(t *testing.T) {
+	cc := getGenerationCurator()
+	cc.Status.CuratorJob = "my-job-ABCDE"
+	cc.Status.ObservedGeneration = 1
+	meta.SetStatusCondition(&cc.Status.Conditions, v1.Condition{Type: "clustercurator-job",
+		Status: v1.ConditionTrue, Reason: "JobFailed", Message: "my-job-ABCDE DesiredCuration: install Failed"})
+
+	s := scheme.Scheme
+	s.AddKnownTypes(CCGVR.GroupVersion(), &clustercuratorv1.ClusterCurator{})
+	client := clientfake.NewClientBuilder().WithScheme(s).WithStatusSubresource(&clustercuratorv1.ClusterCurator{}).WithRuntimeObjects(cc).Build()
+
+	assert.Nil(t, UpdateFailingClusterCurator(client, cc))
+
+	cc, err := GetClusterCurator(client, ClusterName, ClusterName)
+	assert.Nil(t, err)
+	assert.Equal(t, "install", cc.Spec.DesiredCuration, "the spec is not changed")
+	assert.Empty(t, cc.Status.CuratorJob)
+	completed := cc.Status.LastCompletedCuration
+	if assert.NotNil(t, completed) {
+		assert.Equal(t, "install", completed.Curation)
+		assert.Equal(t, int64(1), completed.Generation)
+		assert.Equal(t, "my-job-ABCDE", completed.CuratorJob)
+		assert.Equal(t, clustercuratorv1.CurationFailed, completed.Result)
+		assert.Equal(t, "my-job-ABCDE DesiredCuration: install Failed", completed.Message)
+		assert.False(t, completed.CompletionTime.IsZero())
+	}
+}
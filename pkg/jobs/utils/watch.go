@@ -0,0 +1,153 @@
+// Copyright Contributors to the Open Cluster Management project.
+package utils
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+	clientv1 "sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Returned by WaitForCondition when the context deadline expires before the condition is met
+var ErrWaitTimeout error = &CuratorError{Reason: ReasonTimeout, Err: errors.New("timed out waiting for the condition")}
+
+// Reports whether a wait is over, a non-nil error ends the wait unless it is transient
+type ConditionFunc func(ctx context.Context) (done bool, err error)
+
+// Opens a watch on resources a wait depends on
+type WatchFunc func(ctx context.Context) (watch.Interface, error)
+
+/* WaitForCondition - Evaluates condition immediately, every time one of the watches delivers an event and
+ * at least once per resync interval. A watch that can not be opened, or is closed by the API server, is
+ * reopened after the resync interval, so a wait degrades to polling instead of failing.
+ * The transient API errors of condition are retried, see IsTransientError.
+ *  ctx              # bounds the wait, ErrWaitTimeout is returned when its deadline expires
+ *  resync           # the poll interval of the monitor, it is all the wait relies on when the watches fail
+ *  watches          # nil entries are ignored
+ */
+func WaitForCondition(
+	ctx context.Context,
+	resync time.Duration,
+	condition ConditionFunc,
+	watches ...WatchFunc) error {
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	events := make(chan struct{}, 1)
+	for _, w := range watches {
+		if w != nil {
+			go runWatch(watchCtx, w, resync, events)
+		}
+	}
+
+	ticker := time.NewTicker(resync)
+	defer ticker.Stop()
+
+	for {
+		done, err := condition(ctx)
+		if err != nil {
+			// A request cut short by the deadline is reported as a timeout
+			if ctx.Err() != nil {
+				return waitError(ctx)
+			}
+			if !IsTransientError(err) {
+				return err
+			}
+			// An API blip does not end a wait, the condition is evaluated again
+			klog.Warningf("Retrying the wait after a transient error: %v", err)
+			done = false
+		}
+		if done {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return waitError(ctx)
+		case <-events:
+		case <-ticker.C:
+		}
+	}
+}
+
+func waitError(ctx context.Context) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return ErrWaitTimeout
+	}
+	return ctx.Err()
+}
+
+// Keeps a watch open until ctx is done, signalling events without blocking
+func runWatch(ctx context.Context, open WatchFunc, retry time.Duration, events chan<- struct{}) {
+	for {
+		w, err := open(ctx)
+		if err != nil {
+			klog.V(4).Infof("Could not open watch, falling back to resync: %v", err)
+		} else {
+			consumeWatch(ctx, w, events)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retry):
+		}
+	}
+}
+
+func consumeWatch(ctx context.Context, w watch.Interface, events chan<- struct{}) {
+	defer w.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-w.ResultChan():
+			if !ok || event.Type == watch.Error {
+				return
+			}
+			select {
+			case events <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// Watches the named object (or every object in the namespace when name is "") through a controller-runtime
+// client. Returns nil when the client does not support watches, the wait then relies on resync.
+func WatchObject(client clientv1.Client, list clientv1.ObjectList, namespace string, name string) WatchFunc {
+	wc, ok := client.(clientv1.WithWatch)
+	if !ok {
+		return nil
+	}
+
+	return func(ctx context.Context) (watch.Interface, error) {
+		opts := []clientv1.ListOption{clientv1.InNamespace(namespace)}
+		if name != "" {
+			opts = append(opts, clientv1.MatchingFields{"metadata.name": name})
+		}
+		return wc.Watch(ctx, list.DeepCopyObject().(clientv1.ObjectList), opts...)
+	}
+}
+
+// Watches the named object (or every object in the namespace when name is "") through a dynamic client
+func WatchDynamic(dc dynamic.Interface, gvr schema.GroupVersionResource, namespace string, name string) WatchFunc {
+	return func(ctx context.Context) (watch.Interface, error) {
+		opts := v1.ListOptions{}
+		if name != "" {
+			opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+		}
+		if namespace == "" {
+			return dc.Resource(gvr).Watch(ctx, opts)
+		}
+		return dc.Resource(gvr).Namespace(namespace).Watch(ctx, opts)
+	}
+}
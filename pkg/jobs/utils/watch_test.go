@@ -0,0 +1,109 @@
+// Copyright Contributors to the Open Cluster Management project.
+package utils
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	clientfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestWaitForConditionDoneImmediately(t *testing.T) {
+	calls := 0
+	assert.Nil(t, WaitForCondition(context.Background(), time.Hour, func(ctx context.Context) (bool, error) {
+		calls++
+		return true, nil
+	}), "err nil, when the condition is met")
+	assert.Equal(t, 1, calls, "the condition is evaluated before waiting")
+}
+
+func TestWaitForConditionError(t *testing.T) {
+	assert.EqualError(t, WaitForCondition(context.Background(), time.Hour, func(ctx context.Context) (bool, error) {
+		return false, errors.New("failed")
+	}), "failed", "the condition error ends the wait")
+}
+
+func TestWaitForConditionTransientError(t *testing.T) {
+	calls := 0
+	assert.Nil(t, WaitForCondition(context.Background(), 10*time.Millisecond, func(ctx context.Context) (bool, error) {
+		calls++
+		if calls == 1 {
+			return false, k8serrors.NewServiceUnavailable("etcdserver: leader changed")
+		}
+		return true, nil
+	}), "a transient error does not end the wait")
+	assert.Equal(t, 2, calls)
+}
+
+func TestWaitForConditionTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	err := WaitForCondition(ctx, time.Hour, func(ctx context.Context) (bool, error) {
+		return false, nil
+	})
+	assert.True(t, errors.Is(err, ErrWaitTimeout), "ErrWaitTimeout, when the deadline expires")
+}
+
+func TestWaitForConditionResync(t *testing.T) {
+	calls := 0
+	assert.Nil(t, WaitForCondition(context.Background(), 10*time.Millisecond, func(ctx context.Context) (bool, error) {
+		calls++
+		return calls == 3, nil
+	}), "err nil, when the condition is met on resync")
+	assert.Equal(t, 3, calls)
+}
+
+func TestWaitForConditionWatchEvent(t *testing.T) {
+	fakeWatch := watch.NewFake()
+	var done atomic.Bool
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		done.Store(true)
+		fakeWatch.Modify(&corev1.ConfigMap{})
+	}()
+
+	start := time.Now()
+	assert.Nil(t, WaitForCondition(context.Background(), time.Hour, func(ctx context.Context) (bool, error) {
+		return done.Load(), nil
+	}, nil, func(ctx context.Context) (watch.Interface, error) {
+		return fakeWatch, nil
+	}), "err nil, when a watch event is received")
+	assert.Less(t, time.Since(start), time.Minute, "the event triggers the evaluation, not the resync")
+}
+
+func TestWaitForConditionWatchObject(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "my-configmap",
+			Namespace: "my-namespace",
+		},
+	}
+	client := clientfake.NewClientBuilder().WithRuntimeObjects(cm).Build()
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		cm.Data = map[string]string{"ready": "true"}
+		assert.Nil(t, client.Update(context.TODO(), cm))
+	}()
+
+	start := time.Now()
+	assert.Nil(t, WaitForCondition(context.Background(), time.Hour, func(ctx context.Context) (bool, error) {
+		current := &corev1.ConfigMap{}
+		if err := client.Get(ctx, types.NamespacedName{Namespace: cm.Namespace, Name: cm.Name}, current); err != nil {
+			return false, err
+		}
+		return current.Data["ready"] == "true", nil
+	}, WatchObject(client, &corev1.ConfigMapList{}, cm.Namespace, cm.Name)), "err nil, when the object is updated")
+	assert.Less(t, time.Since(start), time.Minute, "the update triggers the evaluation, not the resync")
+}